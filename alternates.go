@@ -0,0 +1,35 @@
+package feeds
+
+// a format a feed is also published in, advertised by the others
+type alternateFeed struct {
+	Type FeedType
+	Url  string
+}
+
+// the AlternateFeeds of the feed other than the format t and those without
+// a url, in the order of their types
+func (f *Feed) alternates(t FeedType) []alternateFeed {
+	var alternates []alternateFeed
+	for _, other := range []FeedType{FeedTypeRss, FeedTypeAtom, FeedTypeJSON, FeedTypeAmazonRss} {
+		if url := f.AlternateFeeds[other]; other != t && url != "" {
+			alternates = append(alternates, alternateFeed{other, url})
+		}
+	}
+	return alternates
+}
+
+// the media type of opensearch descriptions
+const openSearchType = "application/opensearchdescription+xml"
+
+// the atom:link elements of a channel of format t, for the alternates and
+// the OpenSearchURL
+func (f *Feed) rssAtomLinks(t FeedType) []*RssAtomLink {
+	var links []*RssAtomLink
+	for _, a := range f.alternates(t) {
+		links = append(links, &RssAtomLink{Href: a.Url, Rel: "alternate", Type: a.Type.MIMEType()})
+	}
+	if f.OpenSearchURL != "" {
+		links = append(links, &RssAtomLink{Href: f.OpenSearchURL, Rel: "search", Type: openSearchType})
+	}
+	return links
+}
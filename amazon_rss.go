@@ -6,7 +6,9 @@ package feeds
 
 import (
 	"encoding/xml"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -14,9 +16,11 @@ import (
 type AmazonRssFeedXml struct {
 	XMLName             xml.Name `xml:"rss"`
 	Version             string   `xml:"version,attr"`
-	ContentNamespace    string   `xml:"xmlns:content,attr"`
+	ContentNamespace    string   `xml:"xmlns:content,attr,omitempty"`
 	DublinCoreNamespace string   `xml:"xmlns:dc,attr"`
 	AmazonNamespace     string   `xml:"xmlns:amzn,attr"`
+	WebfeedsNamespace   string   `xml:"xmlns:webfeeds,attr,omitempty"`
+	AtomNamespace       string   `xml:"xmlns:atom,attr,omitempty"`
 	Channel             *AmazonRssFeed
 }
 
@@ -41,17 +45,21 @@ type AmazonRssFeed struct {
 	SkipHours      string   `xml:"skipHours,omitempty"`
 	SkipDays       string   `xml:"skipDays,omitempty"`
 	AmznRssVersion float32  `xml:"amzn:rssVersion,omitempty"`
+	PublisherId    string   `xml:"amzn:publisherId,omitempty"`
+	ProgramId      string   `xml:"amzn:programId,omitempty"`
 	Image          *RssImage
 	TextInput      *RssTextInput
+	AtomLinks      []*RssAtomLink   `xml:"atom:link"` // alternate and search links
 	Items          []*AmazonRssItem `xml:"item"`
+	*RssWebfeeds
 }
 
 // AmazonRssItem has amazon-specific item elements
 type AmazonRssItem struct {
 	XMLName      xml.Name `xml:"item"`
-	Title        string   `xml:"title"`       // required
-	Link         string   `xml:"link"`        // required
-	Description  string   `xml:"description"` // required
+	Title        string   `xml:"title,omitempty"` // required without a description
+	Link         string   `xml:"link,omitempty"`
+	Description  string   `xml:"description"` // required without a title
 	Content      *RssContent
 	Author       string `xml:"author,omitempty"`
 	Category     string `xml:"category,omitempty"`
@@ -64,7 +72,70 @@ type AmazonRssItem struct {
 	HeroImage    string          `xml:"amzn:heroImage,omitempty"`
 	IntroText    string          `xml:"amzn:introText,omitempty"`
 	IndexContent string          `xml:"amzn:indexContent,omitempty"`
+	Section      string          `xml:"amzn:section,omitempty"`
+	Position     int             `xml:"amzn:position,omitempty"`
+	VideoPoster  string          `xml:"amzn:videoPosterImage,omitempty"`
 	Products     *AmazonProducts `xml:"amzn:products"`
+	Status       string          `xml:"amzn:status,omitempty"` // deleted for unpublished items
+
+	// Creators are written as dc:creators in place of the Creator when
+	// there are any, like those of RoleCreators
+	Creators []string `xml:"-"`
+
+	cdataDescription bool // set from the CDATADescription of the Feed
+}
+
+// MarshalXML implements the xml.Marshaler interface.
+// The description is written in a cdata section when the feed has
+// CDATADescription, the Creators in place of the Creator, and all other
+// fields based upon their struct tags.
+func (i *AmazonRssItem) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type EmbeddedAmazonRssItem AmazonRssItem
+	type cdata struct {
+		Text string `xml:",cdata"`
+	}
+	if !i.cdataDescription && len(i.Creators) == 0 {
+		return e.EncodeElement((*EmbeddedAmazonRssItem)(i), start)
+	}
+	var description interface{} = i.Description
+	if i.cdataDescription {
+		description = cdata{i.Description}
+	}
+	creators := i.Creators
+	if len(creators) == 0 && i.Creator != "" {
+		creators = []string{i.Creator}
+	}
+	// the shallower fields up to the creators replace those of the
+	// embedded item, which come first in it too
+	return e.EncodeElement(&struct {
+		Title       string      `xml:"title,omitempty"`
+		Link        string      `xml:"link,omitempty"`
+		Description interface{} `xml:"description"`
+		Content     *RssContent
+		Author      string `xml:"author,omitempty"`
+		Category    string `xml:"category,omitempty"`
+		Comments    string `xml:"comments,omitempty"`
+		Enclosure   *RssEnclosure
+		Guid        string   `xml:"guid,omitempty"`
+		PubDate     string   `xml:"pubDate,omitempty"`
+		Source      string   `xml:"source,omitempty"`
+		Creator     []string `xml:"dc:creator,omitempty"`
+		*EmbeddedAmazonRssItem
+	}{
+		Title:                 i.Title,
+		Link:                  i.Link,
+		Description:           description,
+		Content:               i.Content,
+		Author:                i.Author,
+		Category:              i.Category,
+		Comments:              i.Comments,
+		Enclosure:             i.Enclosure,
+		Guid:                  i.Guid,
+		PubDate:               i.PubDate,
+		Source:                i.Source,
+		Creator:               creators,
+		EmbeddedAmazonRssItem: (*EmbeddedAmazonRssItem)(i),
+	}, start)
 }
 
 // AmazonProducts is a slice of products
@@ -72,29 +143,174 @@ type AmazonProducts struct {
 	Products []*AmazonProduct `xml:"amzn:product"`
 }
 
-// AmazonProduct has the slide-specific fields
+// AmazonProduct has the slide-specific fields. The fields not written to
+// Amazon RSS are used by MerchantRss.
 type AmazonProduct struct {
 	URL      string `xml:"amzn:productURL"`
 	Headline string `xml:"amzn:productHeadline"`
 	Award    string `xml:"amzn:award"`
 	Summary  string `xml:"amzn:productSummary"`
+	ASIN     string `xml:"-"` // taken from a /dp/ URL when empty
+	ImageURL string `xml:"-"`
+	Price    string `xml:"-"` // like 159.00
+	Currency string `xml:"-"` // ISO 4217 code, like USD
 }
 
+// AmazonItem holds the amazon-specific options of an Item. Items without
+// AmazonItem options get placeholder amzn: values that have to be edited
+// before the feed is submitted.
+//
+// Each of the amzn: elements of an item is taken from the first of:
+//
+//	the item's value
+//	nothing, when the item's Suppress has the element
+//	the AmazonDefaults value of the Feed
+//	the item's Thumbnail as the hero image, True as indexContent, the
+//	amazon value of the feed's Category in its TaxonomyMap as the section,
+//	or the placeholders of items without AmazonItem options
+type AmazonItem struct {
+	HeroImage    string
+	IntroText    string
+	IndexContent *bool  // amzn:indexContent, True when nil
+	Section      string // amzn:section, the section the item is shown in
+	Position     int    // amzn:position in an ordered collection, unset when 0
+	Products     []*AmazonProduct
+
+	// ContentKind is the kind of post. The enclosure of a video post must
+	// be a video, and its Thumbnail is used as the amzn:videoPosterImage.
+	ContentKind AmazonContentKind
+
+	// Suppress leaves elements out of the item even when the feed has a
+	// default for them or they can be derived.
+	Suppress AmazonElement
+
+	// Marketplaces are the country codes of the marketplaces the item is
+	// shown in, like US or UK, all of them when empty.
+	Marketplaces []string
+}
+
+// AmazonChannel holds the amazon-specific options of a Feed, the ids of the
+// publisher and program Amazon associates the feed with. They are opaque,
+// and only checked for surrounding whitespace.
+type AmazonChannel struct {
+	PublisherID string // amzn:publisherId, required by AmazonStrict
+	ProgramID   string // amzn:programId, the program or campaign of the feed
+}
+
+func (c *AmazonChannel) validate() error {
+	if c == nil {
+		return nil
+	}
+	for _, id := range []struct{ name, value string }{{"amzn:publisherId", c.PublisherID}, {"amzn:programId", c.ProgramID}} {
+		if strings.TrimSpace(id.value) != id.value {
+			return fmt.Errorf("%s %q has surrounding whitespace", id.name, id.value)
+		}
+	}
+	return nil
+}
+
+// AmazonContentKind is the kind of post an amazon rss item is.
+type AmazonContentKind int
+
+const (
+	AmazonArticle AmazonContentKind = iota
+	AmazonVideo
+)
+
+// check the enclosure of a video item is a video
+func validateAmazonVideo(i *Item) error {
+	if i.Amazon == nil || i.Amazon.ContentKind != AmazonVideo {
+		return nil
+	}
+	if i.Enclosure == nil || i.Enclosure.Url == "" {
+		return errors.New("amazon video item has no video enclosure")
+	}
+	if !strings.HasPrefix(i.Enclosure.Type, "video/") {
+		return fmt.Errorf("amazon video item enclosure type %q is not video/*", i.Enclosure.Type)
+	}
+	return nil
+}
+
+// AmazonElement is a set of the amzn: elements of an item, combined with |.
+type AmazonElement uint
+
+const (
+	AmazonHeroImage AmazonElement = 1 << iota
+	AmazonIntroText
+	AmazonIndexContent
+	AmazonSection
+)
+
 type AmazonRss struct {
 	*Feed
 }
 
-// create a new AmazonRssItem with a generic Item struct's data
-func newAmazonRssItem(i *Item) *AmazonRssItem {
+// whether an unpublished item is still written as deleted, dropping it
+// once it was unpublished longer ago than KeepUnpublishedFor
+func (r *AmazonRss) keepUnpublished(i *Item) bool {
+	if i.UnpublishedAt.IsZero() || r.KeepUnpublishedFor <= 0 {
+		return false
+	}
+	if r.now().Before(i.UnpublishedAt.Add(r.KeepUnpublishedFor)) {
+		return true
+	}
+	r.dropItem(i, fmt.Sprintf("unpublished more than %v ago", r.KeepUnpublishedFor))
+	return false
+}
+
+// the value of an amzn: element, in the order documented on AmazonItem
+func amazonValue(value string, suppress bool, def, derived string) string {
+	switch {
+	case value != "":
+		return value
+	case suppress:
+		return ""
+	case def != "":
+		return def
+	}
+	return derived
+}
+
+// the amzn:indexContent value of an IndexContent option
+func amazonIndexContent(index *bool) string {
+	switch {
+	case index == nil:
+		return ""
+	case *index:
+		return "True"
+	}
+	return "False"
+}
+
+// create a new AmazonRssItem with a generic Item struct's data, the
+// feed's defaults and the item's thumbnail
+func newAmazonRssItem(i *Item, defaults *AmazonItem, thumbnail string) *AmazonRssItem {
 	item := &AmazonRssItem{
-		Title:        i.Title,
-		Link:         i.Link.Href,
-		Description:  i.Description,
-		Guid:         i.Id,
-		PubDate:      anyTimeFormat(time.RFC1123Z, i.Created, i.Updated),
-		HeroImage:    "POST THUMBNAIL (Prefer 2x1 at least 1000px wide)",
-		IntroText:    "META DESCRIPTION",
-		IndexContent: "True",
+		Title:       i.Title,
+		Description: i.Description,
+		Guid:        i.Id,
+		PubDate:     FormatTime(time.RFC1123Z, nil, i.pubDate()),
+	}
+	if i.Link != nil {
+		item.Link = i.Link.Href
+	}
+	a := i.Amazon
+	hero, intro := thumbnail, ""
+	if a == nil {
+		if hero == "" {
+			hero = "POST THUMBNAIL (Prefer 2x1 at least 1000px wide)"
+		}
+		intro = "META DESCRIPTION"
+		a = &AmazonItem{}
+	}
+	item.HeroImage = amazonValue(a.HeroImage, a.Suppress&AmazonHeroImage != 0, defaults.HeroImage, hero)
+	item.IntroText = amazonValue(a.IntroText, a.Suppress&AmazonIntroText != 0, defaults.IntroText, intro)
+	item.IndexContent = amazonValue(amazonIndexContent(a.IndexContent), a.Suppress&AmazonIndexContent != 0,
+		amazonIndexContent(defaults.IndexContent), "True")
+	item.Section = amazonValue(a.Section, a.Suppress&AmazonSection != 0, defaults.Section, "")
+	item.Position = a.Position
+	if len(a.Products) > 0 {
+		item.Products = &AmazonProducts{Products: a.Products}
 	}
 	if len(i.Content) > 0 {
 		item.Content = &RssContent{Content: i.Content}
@@ -104,20 +320,34 @@ func newAmazonRssItem(i *Item) *AmazonRssItem {
 	}
 
 	// Define a closure
-	if i.Enclosure != nil && i.Enclosure.Type != "" && i.Enclosure.Length != "" {
+	if i.Enclosure != nil && i.Enclosure.Type != "" {
 		item.Enclosure = &RssEnclosure{Url: i.Enclosure.Url, Type: i.Enclosure.Type, Length: i.Enclosure.Length}
 	}
 
 	if i.Author != nil {
 		item.Author = i.Author.Name
+		item.Creator = i.Author.Name
 	}
 	return item
 }
 
 // AmazonRssFeed will create a new AmazonRssFeed with a generic Feed struct's data
 func (r *AmazonRss) AmazonRssFeed() *AmazonRssFeed {
-	pub := anyTimeFormat(time.RFC1123Z, r.Created, r.Updated)
-	build := anyTimeFormat(time.RFC1123Z, r.Updated)
+	feed, _ := r.amazonRssFeed()
+	return feed
+}
+
+// create a new AmazonRssFeed, returning an error along with it if the feed
+// has invalid values
+func (r *AmazonRss) amazonRssFeed() (*AmazonRssFeed, error) {
+	if jittered := r.jitterDates(); jittered != r.Feed {
+		amazon := *r
+		amazon.Feed = jittered
+		r = &amazon
+	}
+	pubDate, buildDate := r.channelDates()
+	pub := FormatTime(time.RFC1123Z, nil, pubDate)
+	build := FormatTime(time.RFC1123Z, nil, buildDate)
 	author := ""
 	if r.Author != nil {
 		author = r.Author.Email
@@ -132,20 +362,136 @@ func (r *AmazonRss) AmazonRssFeed() *AmazonRssFeed {
 	}
 
 	channel := &AmazonRssFeed{
-		Title:          r.Title,
-		Link:           r.Link.Href,
+		Title:          r.title(),
 		Description:    r.Description,
+		Language:       r.Language,
 		ManagingEditor: author,
 		PubDate:        pub,
 		LastBuildDate:  build,
-		Copyright:      r.Copyright,
+		Copyright:      r.copyright(),
+		Docs:           r.Docs,
+		Generator:      r.generator(),
+		Ttl:            r.TTL,
+		Rating:         r.AudienceRating,
 		Image:          image,
 		AmznRssVersion: 1.0,
+		RssWebfeeds:    newRssWebfeeds(r.Webfeeds),
+		AtomLinks:      r.rssAtomLinks(FeedTypeAmazonRss),
+	}
+	if r.Link != nil {
+		channel.Link = r.Link.Href
+	}
+	if c := r.Amazon; c != nil {
+		channel.PublisherId, channel.ProgramId = c.PublisherID, c.ProgramID
 	}
+	err := validateWebfeeds(r.Feed)
+	if e := r.Amazon.validate(); e != nil && err == nil {
+		err = fmt.Errorf("feeds: %v", e)
+	}
+	var domain string
+	if r.Marketplace != "" {
+		var e error
+		if domain, e = amazonMarketplaceDomain(r.Marketplace); e != nil && err == nil {
+			err = e
+		}
+	}
+	defaults := r.AmazonDefaults
+	if defaults == nil {
+		defaults = &AmazonItem{}
+	}
+	ids := map[string]int{}
+	for n, i := range r.Items {
+		if r.outsideMarketplace(i) || (i.published() && (r.scrubItem(i) || r.duplicateItem(ids, n, i))) {
+			continue
+		}
+		i = r.enclosureType(r.sanitize(i))
+		item := newAmazonRssItem(i, defaults, r.thumbnail(i))
+		if item.Products != nil && domain != "" {
+			item.Products = &AmazonProducts{Products: marketplaceProducts(item.Products.Products, domain)}
+		}
+		item.Guid = r.itemId(i)
+		if section, ok := r.taxonomy("amazon"); ok && item.Section == "" && (i.Amazon == nil || i.Amazon.Suppress&AmazonSection == 0) {
+			item.Section = section
+		}
+		if r.Preview != nil {
+			item.IndexContent = "False"
+		}
+		if item.Content == nil && i.Description != "" && r.ContentFromDescription {
+			item.Content = &RssContent{Content: i.Description}
+		}
+		item.cdataDescription = r.CDATADescription
+		if r.RoleCreators {
+			item.Creators = i.roleCreators()
+		}
+		if r.SplitOversizedItems.oversized(i) && i.published() {
+			e := errContentOversized(i, r.SplitOversizedItems)
+			if r.skipItem(i, e) {
+				continue
+			}
+			if err == nil {
+				err = fmt.Errorf("feeds: item %d: %v", n, e)
+			}
+		}
+		if i.Amazon != nil && i.Amazon.ContentKind == AmazonVideo {
+			item.VideoPoster = r.thumbnail(i)
+			if e := validateAmazonVideo(i); e != nil && i.published() {
+				if r.skipItem(i, e) {
+					continue
+				}
+				if err == nil {
+					err = fmt.Errorf("feeds: item %d: %v", n, e)
+				}
+			}
+		}
+		if !i.published() {
+			if !r.keepUnpublished(i) {
+				continue
+			}
+			item.Status = "deleted"
+		}
+		channel.Items = append(channel.Items, item)
+		r.wrote(i)
+	}
+	if e := channel.setPositions(r.AutoPosition); e != nil && err == nil {
+		err = e
+	}
+	r.rewriteAmazonRssURLs(channel)
+	return channel, err
+}
+
+// check the explicit positions of the items are unique and, with auto,
+// number the other items which are not deleted
+func (r *AmazonRssFeed) setPositions(auto bool) error {
+	used := map[int]int{} // the item with each position
+	for n, i := range r.Items {
+		if i.Position == 0 {
+			continue
+		}
+		if i.Position < 0 {
+			return fmt.Errorf("feeds: item %d: amzn:position %d is negative", n, i.Position)
+		}
+		if other, ok := used[i.Position]; ok {
+			return fmt.Errorf("feeds: item %d: amzn:position %d is already used by item %d", n, i.Position, other)
+		}
+		used[i.Position] = n
+	}
+	if !auto {
+		return nil
+	}
+	next := 1
 	for _, i := range r.Items {
-		channel.Items = append(channel.Items, newAmazonRssItem(i))
+		if i.Position != 0 || i.Status == "deleted" {
+			continue
+		}
+		for ; ; next++ {
+			if _, ok := used[next]; !ok {
+				break
+			}
+		}
+		i.Position = next
+		next++
 	}
-	return channel
+	return nil
 }
 
 // FeedXml returns an XML-Ready object for an Rss object
@@ -155,13 +501,33 @@ func (r *AmazonRss) FeedXml() interface{} {
 
 }
 
+func (r *AmazonRss) buildFeedXml() (interface{}, error) {
+	feed, err := r.amazonRssFeed()
+	if err != nil {
+		return nil, err
+	}
+	return feed.FeedXml(), nil
+}
+
 // FeedXml returns an XML-ready object for an RssFeed object
 func (r *AmazonRssFeed) FeedXml() interface{} {
-	return &AmazonRssFeedXml{
+	x := &AmazonRssFeedXml{
 		Version:             "2.0",
 		Channel:             r,
-		ContentNamespace:    "http://purl.org/rss/1.0/modules/content/",
-		DublinCoreNamespace: "http://purl.org/dc/elements/1.1/",
-		AmazonNamespace:     "https://amazon.com/ospublishing/1.0/",
+		DublinCoreNamespace: dublinCoreNamespace,
+		AmazonNamespace:     amazonNamespace,
+	}
+	for _, i := range r.Items {
+		if i.Content != nil {
+			x.ContentNamespace = contentNamespace
+			break
+		}
+	}
+	if r.RssWebfeeds != nil {
+		x.WebfeedsNamespace = webfeedsNamespace
+	}
+	if len(r.AtomLinks) > 0 {
+		x.AtomNamespace = ns
 	}
+	return x
 }
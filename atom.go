@@ -2,8 +2,12 @@ package feeds
 
 import (
 	"encoding/xml"
+	"errors"
 	"fmt"
+	"io"
 	"net/url"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -23,12 +27,71 @@ type AtomSummary struct {
 	Type    string   `xml:"type,attr"`
 }
 
+// AtomContent is the content of an AtomEntry. The Content of "xhtml" content
+// is markup written as is inside an xhtml div, and must be well-formed xml.
 type AtomContent struct {
 	XMLName xml.Name `xml:"content"`
 	Content string   `xml:",chardata"`
 	Type    string   `xml:"type,attr"`
 }
 
+const xhtmlNamespace = "http://www.w3.org/1999/xhtml"
+
+// the div wrapping xhtml content
+func xhtmlDiv(content string) string {
+	return `<div xmlns="` + xhtmlNamespace + `">` + content + "</div>"
+}
+
+// validateXHTML returns an error if content is not well-formed xml once
+// wrapped in its div, which would make the whole feed invalid
+func validateXHTML(content string) error {
+	d := xml.NewDecoder(strings.NewReader(xhtmlDiv(content)))
+	depth, closed := 0, false
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("xhtml content is not well-formed: %v", err)
+		}
+		if closed {
+			return errors.New("xhtml content is not well-formed: it closes its div")
+		}
+		switch tok.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+			closed = depth == 0
+		}
+	}
+}
+
+// MarshalXML implements the xml.Marshaler interface.
+// Content of any type but "xhtml" is escaped, xhtml content is validated and
+// written as markup in its div.
+func (c *AtomContent) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if c.Type != "xhtml" {
+		type EmbeddedAtomContent AtomContent
+		return e.EncodeElement((*EmbeddedAtomContent)(c), start)
+	}
+	if err := validateXHTML(c.Content); err != nil {
+		return err
+	}
+	return e.EncodeElement(struct {
+		Type  string `xml:"type,attr"`
+		Inner string `xml:",innerxml"`
+	}{c.Type, xhtmlDiv(c.Content)}, start)
+}
+
+// the subtitle element of an AtomFeed; Type is "text" when empty
+type atomSubtitle struct {
+	XMLName xml.Name `xml:"subtitle"`
+	Content string   `xml:",chardata"`
+	Type    string   `xml:"type,attr,omitempty"`
+}
+
 type AtomAuthor struct {
 	XMLName xml.Name `xml:"author"`
 	AtomPerson
@@ -42,6 +105,7 @@ type AtomContributor struct {
 type AtomEntry struct {
 	XMLName     xml.Name `xml:"entry"`
 	Xmlns       string   `xml:"xmlns,attr,omitempty"`
+	Lang        string   `xml:"xml:lang,attr,omitempty"`
 	Title       string   `xml:"title"`   // required
 	Updated     string   `xml:"updated"` // required
 	Id          string   `xml:"id"`      // required
@@ -54,21 +118,37 @@ type AtomEntry struct {
 	Links       []AtomLink   // required if no child 'content' elements
 	Summary     *AtomSummary // required if content has src or content is base64
 	Author      *AtomAuthor  // required if feed lacks an author
+
+	// the authors and contributors after the first ones, which are only
+	// written; documents are read into Author and Contributor
+	Authors      []*AtomAuthor
+	Contributors []*AtomContributor
+}
+
+// AtomGenerator is the software generating the feed, and its version
+type AtomGenerator struct {
+	XMLName xml.Name `xml:"generator"`
+	Uri     string   `xml:"uri,attr,omitempty"`
+	Version string   `xml:"version,attr,omitempty"`
+	Content string   `xml:",chardata"`
 }
 
 // Multiple links with different rel can coexist
 type AtomLink struct {
 	//Atom 1.0 <link rel="enclosure" type="audio/mpeg" title="MP3" href="http://www.example.org/myaudiofile.mp3" length="1234" />
-	XMLName xml.Name `xml:"link"`
-	Href    string   `xml:"href,attr"`
-	Rel     string   `xml:"rel,attr,omitempty"`
-	Type    string   `xml:"type,attr,omitempty"`
-	Length  string   `xml:"length,attr,omitempty"`
+	XMLName  xml.Name `xml:"link"`
+	Href     string   `xml:"href,attr"`
+	Rel      string   `xml:"rel,attr,omitempty"`
+	Type     string   `xml:"type,attr,omitempty"`
+	Length   string   `xml:"length,attr,omitempty"`
+	HrefLang string   `xml:"hreflang,attr,omitempty"`
+	Title    string   `xml:"title,attr,omitempty"`
 }
 
 type AtomFeed struct {
 	XMLName     xml.Name `xml:"feed"`
 	Xmlns       string   `xml:"xmlns,attr"`
+	Lang        string   `xml:"xml:lang,attr,omitempty"`
 	Title       string   `xml:"title"`   // required
 	Id          string   `xml:"id"`      // required
 	Updated     string   `xml:"updated"` // required
@@ -76,26 +156,93 @@ type AtomFeed struct {
 	Icon        string   `xml:"icon,omitempty"`
 	Logo        string   `xml:"logo,omitempty"`
 	Rights      string   `xml:"rights,omitempty"` // copyright used
-	Subtitle    string   `xml:"subtitle,omitempty"`
+	Generator   *AtomGenerator
+	Subtitle    string `xml:"subtitle,omitempty"`
 	Link        *AtomLink
+	Links       []AtomLink
 	Author      *AtomAuthor `xml:"author,omitempty"`
 	Contributor *AtomContributor
 	Entries     []*AtomEntry `xml:"entry"`
+
+	// SubtitleType is the type attribute of the subtitle, "text" when empty
+	SubtitleType string `xml:"-"`
+}
+
+// MarshalXML implements the xml.Marshaler interface.
+// It writes the Subtitle with the SubtitleType as its type attribute, which
+// a string field cannot have.
+func (f *AtomFeed) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	var subtitle *atomSubtitle
+	if f.Subtitle != "" {
+		subtitle = &atomSubtitle{Content: f.Subtitle, Type: f.SubtitleType}
+	}
+	// the start element is named after the type rather than the XMLName
+	start.Name = xml.Name{Local: "feed"}
+	return e.EncodeElement(struct {
+		XMLName     xml.Name `xml:"feed"`
+		Xmlns       string   `xml:"xmlns,attr"`
+		Lang        string   `xml:"xml:lang,attr,omitempty"`
+		Title       string   `xml:"title"`
+		Id          string   `xml:"id"`
+		Updated     string   `xml:"updated"`
+		Category    string   `xml:"category,omitempty"`
+		Icon        string   `xml:"icon,omitempty"`
+		Logo        string   `xml:"logo,omitempty"`
+		Rights      string   `xml:"rights,omitempty"`
+		Generator   *AtomGenerator
+		Subtitle    *atomSubtitle
+		Link        *AtomLink
+		Links       []AtomLink
+		Author      *AtomAuthor `xml:"author,omitempty"`
+		Contributor *AtomContributor
+		Entries     []*AtomEntry `xml:"entry"`
+	}{
+		f.XMLName, f.Xmlns, f.Lang, f.Title, f.Id, f.Updated, f.Category, f.Icon, f.Logo, f.Rights,
+		f.Generator, subtitle, f.Link, f.Links, f.Author, f.Contributor, f.Entries,
+	}, start)
 }
 
 type Atom struct {
 	*Feed
 }
 
-func newAtomEntry(i *Item) *AtomEntry {
+// rels of the rfc 5005 paging links, which always point at another atom feed
+var atomPagingRels = map[string]bool{
+	"first":    true,
+	"previous": true,
+	"prev":     true,
+	"next":     true,
+	"last":     true,
+}
+
+// create an AtomLink from a generic Link, typing paging links as atom; the
+// length is only meaningful for enclosures and left out of other links
+func newAtomLink(l *Link) AtomLink {
+	link := AtomLink{Href: l.Href, Rel: l.Rel, Type: l.Type, HrefLang: l.HrefLang, Title: l.Title}
+	if link.Rel == "enclosure" {
+		link.Length = l.Length
+	}
+	if link.Type == "" && atomPagingRels[link.Rel] {
+		link.Type = FeedTypeAtom.MIMEType()
+	}
+	return link
+}
+
+var errAtomUpdated = errors.New("feeds: atom feeds require an updated date; set Updated or Created on the feed or its items")
+
+var errAtomId = errors.New("feeds: atom feeds require an id; set the Link of the feed")
+
+// newAtomEntry creates an AtomEntry from an Item. updated is used when the
+// item has neither an Updated nor a Created date.
+func newAtomEntry(i *Item, updated string) *AtomEntry {
 	id := i.Id
 	// assume the description is html
 	s := &AtomSummary{Content: i.Description, Type: "html"}
 
 	if len(id) == 0 {
 		// if there's no id set, try to create one, either from data or just a uuid
-		if len(i.Link.Href) > 0 && (!i.Created.IsZero() || !i.Updated.IsZero()) {
-			dateStr := anyTimeFormat("2006-01-02", i.Updated, i.Created)
+		if i.Link != nil && len(i.Link.Href) > 0 && (!i.Created.IsZero() || !i.Updated.IsZero()) {
+			dateStr := FormatTime("2006-01-02", nil, i.lastModified())
 			host, path := i.Link.Href, "/invalid.html"
 			if url, err := url.Parse(i.Link.Href); err == nil {
 				host, path = url.Host, url.Path
@@ -105,21 +252,31 @@ func newAtomEntry(i *Item) *AtomEntry {
 			id = "urn:uuid:" + NewUUID().String()
 		}
 	}
-	var name, email string
-	if i.Author != nil {
-		name, email = i.Author.Name, i.Author.Email
+	var link_rel string
+	var links []AtomLink
+	if i.Link != nil {
+		link_rel = i.Link.Rel
+		if link_rel == "" {
+			link_rel = "alternate"
+		}
+		links = append(links, AtomLink{Href: i.Link.Href, Rel: link_rel, Type: i.Link.Type, HrefLang: i.Link.HrefLang, Title: i.Link.Title})
 	}
-
-	link_rel := i.Link.Rel
-	if link_rel == "" {
-		link_rel = "alternate"
+	for _, l := range i.Links {
+		if l != nil {
+			links = append(links, newAtomLink(l))
+		}
 	}
 	x := &AtomEntry{
 		Title:   i.Title,
-		Links:   []AtomLink{{Href: i.Link.Href, Rel: link_rel, Type: i.Link.Type}},
+		Links:   links,
 		Id:      id,
-		Updated: anyTimeFormat(time.RFC3339, i.Updated, i.Created),
+		Updated: FormatTime(time.RFC3339, nil, i.lastModified()),
 		Summary: s,
+		Lang:    i.Language,
+	}
+
+	if x.Updated == "" {
+		x.Updated = updated
 	}
 
 	// if there's a content, assume it's html
@@ -130,32 +287,156 @@ func newAtomEntry(i *Item) *AtomEntry {
 	if i.Enclosure != nil && link_rel != "enclosure" {
 		x.Links = append(x.Links, AtomLink{Href: i.Enclosure.Url, Rel: "enclosure", Type: i.Enclosure.Type, Length: i.Enclosure.Length})
 	}
-
-	if len(name) > 0 || len(email) > 0 {
-		x.Author = &AtomAuthor{AtomPerson: AtomPerson{Name: name, Email: email}}
+	if i.ViaURL != "" {
+		x.Links = append(x.Links, AtomLink{Href: i.ViaURL, Rel: "via"})
 	}
+
+	setAtomPeople(x, i)
 	return x
 }
 
+// the updated date of the feed: its own Updated or Created date, else the
+// most recent one of its items, else the generation time when allowed
+func (a *Atom) updated() string {
+	if updated := FormatTime(time.RFC3339, nil, a.lastModified()); updated != "" {
+		return updated
+	}
+	latest := a.latestItem()
+	if latest.IsZero() && (a.AllowGenerationTimeFallback || !a.hasItems()) {
+		latest = a.now()
+	}
+	return FormatTime(time.RFC3339, nil, latest)
+}
+
 // create a new AtomFeed with a generic Feed struct's data
 func (a *Atom) AtomFeed() *AtomFeed {
-	updated := anyTimeFormat(time.RFC3339, a.Updated, a.Created)
+	feed, _ := a.atomFeed()
+	return feed
+}
+
+// create a new AtomFeed, returning an error along with it if it has no id
+// or no updated date could be found for it
+func (a *Atom) atomFeed() (*AtomFeed, error) {
+	if split := a.splitOversized(); split != a.Feed {
+		atom := *a
+		atom.Feed = split
+		a = &atom
+	}
+	updated := a.updated()
 	feed := &AtomFeed{
-		Xmlns:    ns,
-		Title:    a.Title,
-		Link:     &AtomLink{Href: a.Link.Href, Rel: a.Link.Rel},
-		Subtitle: a.Description,
-		Id:       a.Link.Href,
-		Updated:  updated,
-		Rights:   a.Copyright,
+		Xmlns:   ns,
+		Title:   a.title(),
+		Updated: updated,
+		Rights:  a.copyright(),
+		Lang:    a.Language,
+		Icon:    a.Favicon,
+		Logo:    a.Icon,
+
+		Generator: a.atomGenerator(),
+	}
+	if feed.Logo == "" && a.Image != nil {
+		feed.Logo = a.Image.Url
+	}
+	a.checkIcons()
+	subtitle := a.Subtitle
+	if subtitle == "" {
+		subtitle = a.Description
+	}
+	feed.Subtitle = subtitle
+	if subtitle != "" {
+		feed.SubtitleType = a.SubtitleType
+	}
+	if a.Link != nil {
+		feed.Link = &AtomLink{Href: a.Link.Href, Rel: a.Link.Rel, HrefLang: a.Link.HrefLang, Title: a.Link.Title}
+		feed.Id = a.Link.Href
+	}
+	for _, l := range a.Links {
+		if l != nil {
+			feed.Links = append(feed.Links, newAtomLink(l))
+		}
+	}
+	for _, alt := range a.alternates(FeedTypeAtom) {
+		feed.Links = append(feed.Links, AtomLink{Href: alt.Url, Rel: "alternate", Type: alt.Type.MIMEType()})
+	}
+	if a.OpenSearchURL != "" {
+		feed.Links = append(feed.Links, AtomLink{Href: a.OpenSearchURL, Rel: "search", Type: openSearchType})
+	}
+	if a.EmitImageLinks {
+		if feed.Icon != "" {
+			feed.Links = append(feed.Links, AtomLink{Href: feed.Icon, Rel: "icon"})
+		}
+		if feed.Logo != "" {
+			feed.Links = append(feed.Links, AtomLink{Href: feed.Logo, Rel: "logo"})
+		}
 	}
 	if a.Author != nil {
 		feed.Author = &AtomAuthor{AtomPerson: AtomPerson{Name: a.Author.Name, Email: a.Author.Email}}
 	}
-	for _, e := range a.Items {
-		feed.Entries = append(feed.Entries, newAtomEntry(e))
+	err := a.Generator.validate()
+	if err != nil {
+		err = fmt.Errorf("feeds: %v", err)
 	}
-	return feed
+	var missing []string
+	ids := map[string]int{}
+	items := map[*AtomEntry]*Item{} // the item of each entry, for WriteStats
+	for n, i := range a.Items {
+		i = a.enclosureType(a.sanitize(i))
+		if !i.published() || a.scrubItem(i) || a.duplicateItem(ids, n, i) {
+			continue
+		}
+		if a.StrictEntryUpdated && i.lastModified().IsZero() {
+			if a.skipItem(i, errEntryUpdated) {
+				continue
+			}
+			missing = append(missing, entryUpdatedRequired(n, i))
+		}
+		if e := i.validateVia(); e != nil {
+			if a.skipItem(i, e) {
+				continue
+			}
+			if err == nil {
+				err = fmt.Errorf("feeds: item %d: %v", n, e)
+			}
+		}
+		entry := newAtomEntry(i, updated)
+		if i.Id != "" {
+			entry.Id = a.itemId(i)
+		}
+		if entry.Content != nil && a.XHTMLContent {
+			entry.Content.Type = "xhtml"
+			if e := validateXHTML(entry.Content.Content); e != nil {
+				if a.skipItem(i, e) {
+					continue
+				}
+				if err == nil {
+					err = fmt.Errorf("feeds: item %d: %v", n, e)
+				}
+			}
+		}
+		feed.Entries = append(feed.Entries, entry)
+		items[entry] = i
+	}
+	a.rewriteAtomURLs(feed)
+	if a.OldestFirst {
+		sort.SliceStable(feed.Entries, func(i, j int) bool {
+			ti, _ := time.Parse(time.RFC3339, feed.Entries[i].Updated)
+			tj, _ := time.Parse(time.RFC3339, feed.Entries[j].Updated)
+			return ti.Before(tj)
+		})
+	}
+	for _, entry := range feed.Entries {
+		a.wrote(items[entry])
+	}
+	if feed.Id == "" {
+		return feed, errAtomId
+	}
+	if updated == "" {
+		return feed, errAtomUpdated
+	}
+	if len(missing) > 0 && err == nil {
+		err = &ValidationError{Type: FeedTypeAtom, Violations: missing}
+	}
+	return feed, err
 }
 
 // FeedXml returns an XML-Ready object for an Atom object
@@ -163,6 +444,10 @@ func (a *Atom) FeedXml() interface{} {
 	return a.AtomFeed()
 }
 
+func (a *Atom) buildFeedXml() (interface{}, error) {
+	return a.atomFeed()
+}
+
 // FeedXml returns an XML-ready object for an AtomFeed object
 func (a *AtomFeed) FeedXml() interface{} {
 	return a
@@ -0,0 +1,179 @@
+package feeds
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// an element of a document being canonicalized
+type canonicalNode struct {
+	name     string
+	attrs    []xml.Attr
+	parts    []interface{} // the character data and child elements, in order
+	preserve bool          // whether its whitespace is significant
+}
+
+var canonicalText = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// the prefixed name of an unresolved xml name
+func canonicalName(n xml.Name) string {
+	if n.Space != "" {
+		return n.Space + ":" + n.Local
+	}
+	return n.Local
+}
+
+// canonicalXML rewrites the xml document doc in a stable form meant for
+// comparing documents rather than publishing them: attributes sorted by
+// name, empty elements as a start and end tag pair, cdata as escaped text,
+// no comments, LF newlines and elements holding only elements indented by
+// two spaces. Elements mixing text and elements are written as they are, as
+// are the html pre elements and the elements with xml:space="preserve",
+// whose whitespace is significant.
+func canonicalXML(doc []byte) ([]byte, error) {
+	var out bytes.Buffer
+	d := xml.NewDecoder(bytes.NewReader(doc))
+	var stack []*canonicalNode
+	for {
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch tok := tok.(type) {
+		case xml.ProcInst:
+			if len(stack) == 0 {
+				out.WriteString("<?" + tok.Target + " " + strings.TrimSpace(string(tok.Inst)) + "?>\n")
+			}
+		case xml.StartElement:
+			node := &canonicalNode{name: canonicalName(tok.Name), attrs: tok.Attr, preserve: tok.Name.Local == "pre"}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.parts = append(parent.parts, node)
+				node.preserve = node.preserve || parent.preserve
+			}
+			for _, a := range tok.Attr {
+				if a.Name.Space == "xml" && a.Name.Local == "space" {
+					node.preserve = a.Value == "preserve"
+				}
+			}
+			stack = append(stack, node)
+		case xml.CharData:
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.parts = append(parent.parts, string(tok))
+			}
+		case xml.EndElement:
+			node := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				node.write(&out, 0, false)
+				out.WriteByte('\n')
+			}
+		}
+	}
+	return out.Bytes(), nil
+}
+
+// write the node at the indent depth, or without any indenting inside
+// mixed content where whitespace is part of the text
+func (n *canonicalNode) write(out *bytes.Buffer, depth int, inline bool) {
+	attrs := append([]xml.Attr(nil), n.attrs...)
+	sort.SliceStable(attrs, func(a, b int) bool {
+		return canonicalName(attrs[a].Name) < canonicalName(attrs[b].Name)
+	})
+	out.WriteString("<" + n.name)
+	for _, a := range attrs {
+		out.WriteString(" " + canonicalName(a.Name) + `="` + escapeAttr(a.Value) + `"`)
+	}
+	out.WriteByte('>')
+
+	elements, text := false, false
+	for _, p := range n.parts {
+		switch p := p.(type) {
+		case string:
+			text = text || strings.TrimSpace(p) != ""
+		case *canonicalNode:
+			elements = true
+		}
+	}
+	if elements && !text && !inline && !n.preserve {
+		for _, p := range n.parts {
+			if c, ok := p.(*canonicalNode); ok {
+				out.WriteString("\n" + strings.Repeat("  ", depth+1))
+				c.write(out, depth+1, false)
+			}
+		}
+		out.WriteString("\n" + strings.Repeat("  ", depth))
+	} else {
+		for _, p := range n.parts {
+			switch p := p.(type) {
+			case string:
+				out.WriteString(canonicalText.Replace(p))
+			case *canonicalNode:
+				p.write(out, depth, true)
+			}
+		}
+	}
+	out.WriteString("</" + n.name + ">")
+}
+
+// Fingerprint returns a hash of the feed encoded as t, which only changes
+// when the content of the feed does, for detecting changes. The xml formats
+// are hashed in their Canonical form. Atom feeds with items which have
+// neither an id nor a link get random ids, and a new fingerprint each time.
+func (f *Feed) Fingerprint(t FeedType) (string, error) {
+	// hashing the feed is not logged as generating it
+	canonical := *f
+	canonical.Logger = nil
+	canonical.Canonical = true
+	h := sha256.New()
+	if err := canonical.write(h, t); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ContentHash returns a hex sha256 hash of the content of the feed rather
+// than of an encoding of it, for etags and change detection. Every field
+// of the feed and of its published items contributes, including the
+// options changing how it is written, like Preview, DefaultEnclosureType
+// and StampGenerator, except for these ones which change even when the
+// content does not:
+//
+//	the feed's Updated and Created dates, which change on every build
+//	its Clock and Logger
+//
+// The URLRewriter, IDScheme and Sanitizer are funcs, so only whether
+// they are set contributes: replacing one with another does not change
+// the hash.
+func (f *Feed) ContentHash() string {
+	feed := *f
+	// the funcs are not marshaled, being tagged json:"-"
+	feed.Updated, feed.Created = time.Time{}, time.Time{}
+	feed.Items = nil
+	for _, i := range f.Items {
+		if i.published() {
+			feed.Items = append(feed.Items, i)
+		}
+	}
+	h := sha256.New()
+	data, err := json.Marshal(&feed)
+	if err != nil {
+		// only author JSONExtensions fail, which fail writing json too
+		data = []byte(err.Error())
+	}
+	h.Write(data)
+	fmt.Fprint(h, f.URLRewriter != nil, f.IDScheme != nil, f.Sanitizer != nil)
+	return hex.EncodeToString(h.Sum(nil))
+}
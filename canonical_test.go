@@ -0,0 +1,186 @@
+package feeds
+
+import (
+	"encoding/xml"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCanonicalXML(t *testing.T) {
+	a := "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\r\n<feed xmlns=\"http://www.w3.org/2005/Atom\" xml:lang=\"en\">\r\n" +
+		"    <link rel=\"alternate\" href=\"http://jmoiron.net/blog\"/>\r\n" +
+		"    <content type=\"html\"><![CDATA[<p>footie</p>]]></content>\r\n" +
+		"    <!-- generated -->\r\n" +
+		"    <summary>a <b>b</b>  c</summary>\r\n" +
+		"</feed>"
+	b := `<?xml version="1.0" encoding="UTF-8"?><feed xml:lang="en" xmlns="http://www.w3.org/2005/Atom"><link href="http://jmoiron.net/blog" rel="alternate"></link>
+	<content type="html">&lt;p&gt;footie&lt;/p&gt;</content><summary>a <b>b</b>  c</summary></feed>`
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<feed xml:lang="en" xmlns="http://www.w3.org/2005/Atom">
+  <link href="http://jmoiron.net/blog" rel="alternate"></link>
+  <content type="html">&lt;p&gt;footie&lt;/p&gt;</content>
+  <summary>a <b>b</b>  c</summary>
+</feed>
+`
+	for _, doc := range []string{a, b} {
+		got, err := canonicalXML([]byte(doc))
+		if err != nil {
+			t.Fatalf("canonicalXML(%q): %v", doc, err)
+		}
+		if string(got) != want {
+			t.Errorf("canonicalXML(%q): got\n%s\nwant\n%s", doc, got, want)
+		}
+	}
+}
+
+func TestCanonicalXMLPreserve(t *testing.T) {
+	doc := "<feed>\n<entry xml:space=\"preserve\">\n  <title>a</title>\n  <b>b</b>\n</entry>\n" +
+		"<content><div>\n<pre>\n<code>x</code>\n  <code>y</code>\n</pre>\n</div></content></feed>"
+	want := "<feed>\n  <entry xml:space=\"preserve\">\n  <title>a</title>\n  <b>b</b>\n</entry>\n" +
+		"  <content>\n    <div>\n      <pre>\n<code>x</code>\n  <code>y</code>\n</pre>\n    </div>\n  </content>\n</feed>\n"
+	got, err := canonicalXML([]byte(doc))
+	if err != nil {
+		t.Fatalf("canonicalXML(%q): %v", doc, err)
+	}
+	if string(got) != want {
+		t.Errorf("canonicalXML(%q): got\n%s\nwant\n%s", doc, got, want)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	feed := sizeTestFeed()
+	rss, err := feed.Fingerprint(FeedTypeRss)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	same := sizeTestFeed()
+	same.SelfCloseEmpty = true
+	if got, err := same.Fingerprint(FeedTypeRss); err != nil || got != rss {
+		t.Errorf("self-closing output changed the fingerprint: got %q (%v), want %q", got, err, rss)
+	}
+	changed := sizeTestFeed()
+	changed.Items[3].Title = "Post three"
+	if got, _ := changed.Fingerprint(FeedTypeRss); got == rss {
+		t.Errorf("changing an item title should change the fingerprint")
+	}
+
+	feed.Canonical = true
+	out, err := feed.ToRss()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, `<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/">`) || strings.Contains(out, "CDATA") {
+		t.Errorf("Rss is not canonical.  Got:\n%s\n", out)
+	}
+}
+
+func TestCanonicalAttrEscaping(t *testing.T) {
+	urls := []string{
+		"http://example.com/episode.mp3?a=1&b=2",
+		`http://example.com/"quoted".mp3`,
+		"http://example.com/<tag>.mp3",
+	}
+	feed := &Feed{
+		Title:       "jmoiron.net blog",
+		Link:        &Link{Href: "http://jmoiron.net/blog"},
+		Description: "discussion about tech",
+		Webfeeds:    &Webfeeds{CoverImage: urls[1]},
+		Canonical:   true,
+	}
+	for _, url := range urls {
+		feed.Add(&Item{
+			Title:       "Episode",
+			Enclosure:   &Enclosure{Url: url, Type: "audio/mpeg", Length: "123"},
+			MediaPlayer: &MediaPlayer{URL: url},
+		})
+	}
+	rss, err := feed.ToRss()
+	if err != nil {
+		t.Fatalf("unexpected error encoding RSS: %v", err)
+	}
+	var got []string
+	d := xml.NewDecoder(strings.NewReader(rss))
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("canonical rss is not well-formed: %v\n%s", err, rss)
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			for _, a := range start.Attr {
+				if a.Name.Local == "url" || a.Name.Local == "image" {
+					got = append(got, a.Value)
+				}
+			}
+		}
+	}
+	want := []string{urls[0], urls[0], urls[1], urls[1], urls[2], urls[2], urls[1]}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got attribute values %q, want %q", got, want)
+	}
+}
+
+func TestContentHash(t *testing.T) {
+	feed := sizeTestFeed()
+	hash := feed.ContentHash()
+	if len(hash) != 64 {
+		t.Errorf("got hash %q, want 64 hex digits", hash)
+	}
+
+	// the build time and the other volatile fields do not count
+	rebuilt := sizeTestFeed()
+	rebuilt.Updated = time.Now()
+	rebuilt.Created = time.Now()
+	rebuilt.Clock = time.Now
+	rebuilt.Logger = LoggerFunc(func(string, ...interface{}) {})
+	if got := rebuilt.ContentHash(); got != hash {
+		t.Errorf("rebuilding the feed changed its hash from %s to %s", hash, got)
+	}
+
+	for name, change := range map[string]func(*Feed){
+		"title":        func(f *Feed) { f.Title = "jmoiron.net" },
+		"image":        func(f *Feed) { f.Image = &Image{Url: "http://jmoiron.net/logo.png"} },
+		"language":     func(f *Feed) { f.Language = "en-us" },
+		"copyright":    func(f *Feed) { f.Copyright = "This work is copyright © Benjamin Button" },
+		"ttl":          func(f *Feed) { f.TTL = 60 },
+		"webfeeds":     func(f *Feed) { f.Webfeeds = &Webfeeds{AccentColor: "00FF00"} },
+		"preview":      func(f *Feed) { f.Preview = &PreviewMode{} },
+		"stamp":        func(f *Feed) { f.StampGenerator = true },
+		"url rewriter": func(f *Feed) { f.URLRewriter = HostSwapRewriter(nil) },
+		"id scheme":    func(f *Feed) { f.IDScheme = func(id string) string { return id } },
+		"item id":      func(f *Feed) { f.Items[0].Id = "another-id" },
+		"item content": func(f *Feed) { f.Items[0].Content += "<p>more</p>" },
+		"item updated": func(f *Feed) { f.Items[0].Updated = f.Items[0].Created.Add(time.Hour) },
+		"enclosure": func(f *Feed) {
+			f.Items[0].Enclosure = &Enclosure{Url: "http://jmoiron.net/episode.mp3", Type: "audio/mpeg", Length: "123456"}
+		},
+		"author":       func(f *Feed) { f.Items[0].Author = &Author{Name: "Jason Moiron"} },
+		"thumbnail":    func(f *Feed) { f.Items[0].Thumbnail = "http://jmoiron.net/thumb.jpg" },
+		"media player": func(f *Feed) { f.Items[0].MediaPlayer = &MediaPlayer{URL: "http://jmoiron.net/player"} },
+		"item order":   func(f *Feed) { f.Items[0], f.Items[1] = f.Items[1], f.Items[0] },
+		"unpublished":  func(f *Feed) { f.Items[0].Status = ItemUnpublished },
+		// the same values split differently between the fields
+		"boundaries": func(f *Feed) { f.Items[0].Title, f.Items[0].Id = f.Items[0].Id+f.Items[0].Title, "" },
+	} {
+		changed := sizeTestFeed()
+		change(changed)
+		if got := changed.ContentHash(); got == hash {
+			t.Errorf("%s: changing the feed did not change its hash", name)
+		}
+	}
+
+	// a changed enclosure url changes the hash of an enclosure
+	podcast := sizeTestFeed()
+	podcast.Items[0].Enclosure = &Enclosure{Url: "http://jmoiron.net/episode.mp3", Type: "audio/mpeg", Length: "123456"}
+	before := podcast.ContentHash()
+	podcast.Items[0].Enclosure.Url = "http://jmoiron.net/episode-fixed.mp3"
+	if podcast.ContentHash() == before {
+		t.Errorf("changing the enclosure url did not change the hash")
+	}
+}
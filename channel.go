@@ -0,0 +1,193 @@
+package feeds
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// the built xml document of a channel, written with the output options of
+// the embedded Feed
+type channelOnly struct {
+	*Feed
+	x interface{}
+}
+
+func (c *channelOnly) FeedXml() interface{} {
+	return c.x
+}
+
+// BuildChannelOnly returns feed as a document of format without any items,
+// for registering its url before it has content. The document has all the
+// channel elements of the feed, and a rel="self" link to the url the feed
+// has in AlternateFeeds for format when there is one. It fails with a
+// ValidationError, rather than writing placeholders, when the feed lacks
+// the channel elements the format requires or the document does not pass
+// the linter of the format.
+func BuildChannelOnly(feed *Feed, format FeedType) ([]byte, error) {
+	channel := *feed
+	channel.Items = nil
+	self := feed.AlternateFeeds[format]
+
+	var validate func() error
+	var build func() (interface{}, error)
+	var lint func(io.Reader) ([]ValidationIssue, error)
+	switch format {
+	case FeedTypeRss:
+		validate, lint = channel.ValidateRSS, LintRSS
+		build = func() (interface{}, error) {
+			x, err := (&Rss{Feed: &channel}).rssFeed()
+			if self != "" {
+				x.AtomLinks = append([]*RssAtomLink{{Href: self, Rel: "self", Type: format.MIMEType()}}, x.AtomLinks...)
+			}
+			return x.FeedXml(), err
+		}
+	case FeedTypeAmazonRss:
+		validate, lint = channel.ValidateAmazonRss, LintAmazonRss
+		build = func() (interface{}, error) {
+			x, err := (&AmazonRss{Feed: &channel}).amazonRssFeed()
+			if self != "" {
+				x.AtomLinks = append([]*RssAtomLink{{Href: self, Rel: "self", Type: format.MIMEType()}}, x.AtomLinks...)
+			}
+			return x.FeedXml(), err
+		}
+	case FeedTypeAtom:
+		validate, lint = channel.ValidateAtom, LintAtom
+		build = func() (interface{}, error) {
+			x, err := (&Atom{Feed: &channel}).atomFeed()
+			if self != "" {
+				x.Links = append([]AtomLink{{Href: self, Rel: "self", Type: format.MIMEType()}}, x.Links...)
+			}
+			return x, err
+		}
+	case FeedTypeJSON:
+		validate, lint = channel.ValidateJSON, LintJSONFeed
+	default:
+		return nil, fmt.Errorf("feeds: unknown feed type %v", format)
+	}
+	if err := validate(); err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	err := channel.generate(format, func() error {
+		if format == FeedTypeJSON {
+			var err error
+			data, err = json.MarshalIndent((&JSON{&channel}).JSONFeed(), "", "  ")
+			return err
+		}
+		x, err := build()
+		if err != nil {
+			return err
+		}
+		s, err := ToXML(&channelOnly{&channel, x})
+		data = []byte(s)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	issues, err := lint(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if len(issues) > 0 {
+		v := &validator{t: format}
+		for _, issue := range issues {
+			v.check(false, "%s", issue)
+		}
+		return nil, v.err()
+	}
+	return data, nil
+}
+
+// WriteRssChannelFragment writes the channel element of the rss document
+// of feed to w, for embedding it in another xml document. It has no xml
+// declaration, stylesheet or rss element, and declares the namespaces of
+// the document on the channel element instead.
+func WriteRssChannelFragment(w io.Writer, feed *Feed) error {
+	var doc bytes.Buffer
+	if err := feed.WriteRss(&doc); err != nil {
+		return err
+	}
+	return writeChannelFragment(w, doc.Bytes())
+}
+
+// WriteAmazonRssChannelFragment writes the channel element of the amazon
+// rss document of feed to w like WriteRssChannelFragment.
+func WriteAmazonRssChannelFragment(w io.Writer, feed *Feed) error {
+	var doc bytes.Buffer
+	if err := feed.WriteAmazonRss(&doc); err != nil {
+		return err
+	}
+	return writeChannelFragment(w, doc.Bytes())
+}
+
+// write the channel element of the rss document doc, with the namespaces
+// declared on the rss element moved to it
+func writeChannelFragment(w io.Writer, doc []byte) error {
+	var out bytes.Buffer
+	var namespaces []byte
+	depth := 0 // of the element the scan is in, 1 inside the rss element
+	inChannel := false
+	var err error
+	scanXML(doc, func(chunk []byte, tag bool) {
+		end := tag && bytes.HasPrefix(chunk, []byte("</"))
+		start := tag && !end && !bytes.HasPrefix(chunk, []byte("<!")) && !bytes.HasPrefix(chunk, []byte("<?"))
+		selfClosing := start && bytes.HasSuffix(chunk, []byte("/>"))
+		switch {
+		case start && depth == 0:
+			namespaces, err = namespaceAttrs(chunk)
+		case start && depth == 1 && tagName(chunk) == "channel":
+			inChannel = true
+			out.WriteString("<channel")
+			out.Write(namespaces)
+			out.Write(chunk[len("<channel"):])
+		case inChannel:
+			out.Write(chunk)
+		}
+		if start && !selfClosing {
+			depth++
+		}
+		if end {
+			depth--
+			if depth == 1 && inChannel {
+				inChannel = false
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if out.Len() == 0 {
+		return errors.New("feeds: document has no channel")
+	}
+	_, err = w.Write(out.Bytes())
+	return err
+}
+
+// the namespace declarations of a start tag, as attributes to write in
+// another one; without a default namespace xmlns="" is declared, so the
+// unprefixed elements stay in no namespace in any document
+func namespaceAttrs(tag []byte) ([]byte, error) {
+	d := xml.NewDecoder(bytes.NewReader(tag))
+	tok, err := d.RawToken()
+	if err != nil {
+		return nil, err
+	}
+	def := ""
+	var prefixed bytes.Buffer
+	for _, a := range tok.(xml.StartElement).Attr {
+		switch {
+		case a.Name.Space == "" && a.Name.Local == "xmlns":
+			def = a.Value
+		case a.Name.Space == "xmlns":
+			prefixed.WriteString(" " + canonicalName(a.Name) + `="` + escapeAttr(a.Value) + `"`)
+		}
+	}
+	return append([]byte(` xmlns="`+escapeAttr(def)+`"`), prefixed.Bytes()...), nil
+}
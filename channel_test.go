@@ -0,0 +1,144 @@
+package feeds
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+func channelTestFeed() *Feed {
+	feed := testFeed(&Item{Title: "Limiting Concurrency in Go", Link: &Link{Href: "http://jmoiron.net/blog/limiting-concurrency-in-go/"}, Created: testCreated})
+	feed.TTL = 60
+	feed.Image = &Image{Url: "http://jmoiron.net/cover.jpg", Title: "jmoiron.net blog", Link: "http://jmoiron.net/blog"}
+	feed.AlternateFeeds = map[FeedType]string{
+		FeedTypeRss:       "http://jmoiron.net/blog/feed.rss",
+		FeedTypeAtom:      "http://jmoiron.net/blog/feed.atom",
+		FeedTypeJSON:      "http://jmoiron.net/blog/feed.json",
+		FeedTypeAmazonRss: "http://jmoiron.net/blog/amazon.rss",
+	}
+	return feed
+}
+
+func TestBuildChannelOnly(t *testing.T) {
+	feed := channelTestFeed()
+	for _, test := range []struct {
+		format FeedType
+		want   []string
+	}{
+		{FeedTypeRss, []string{
+			`<atom:link href="http://jmoiron.net/blog/feed.rss" rel="self" type="application/rss+xml"></atom:link>`,
+			`xmlns:atom="http://www.w3.org/2005/Atom"`,
+			"<ttl>60</ttl>",
+			"<url>http://jmoiron.net/cover.jpg</url>",
+		}},
+		{FeedTypeAmazonRss, []string{
+			`<atom:link href="http://jmoiron.net/blog/amazon.rss" rel="self" type="application/rss+xml"></atom:link>`,
+			`xmlns:amzn="https://amazon.com/ospublishing/1.0/"`,
+			"<ttl>60</ttl>",
+		}},
+		{FeedTypeAtom, []string{
+			`<link href="http://jmoiron.net/blog/feed.atom" rel="self" type="application/atom+xml"></link>`,
+			"<updated>2013-01-16T21:52:35-05:00</updated>",
+			"<logo>http://jmoiron.net/cover.jpg</logo>",
+		}},
+		{FeedTypeJSON, []string{
+			`"feed_url": "http://jmoiron.net/blog/feed.json"`,
+			`"items": []`,
+		}},
+	} {
+		data, err := BuildChannelOnly(feed, test.format)
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", test.format, err)
+			continue
+		}
+		doc := string(data)
+		for _, want := range test.want {
+			if !strings.Contains(doc, want) {
+				t.Errorf("%v missing %s.  Got:\n%s\n", test.format, want, doc)
+			}
+		}
+		if strings.Contains(doc, "Limiting Concurrency") {
+			t.Errorf("%v should have no items.  Got:\n%s\n", test.format, doc)
+		}
+	}
+	if len(feed.Items) != 1 {
+		t.Errorf("BuildChannelOnly should not change the feed's items")
+	}
+}
+
+func TestBuildChannelOnlyRequired(t *testing.T) {
+	for _, test := range []struct {
+		format FeedType
+		unset  func(f *Feed)
+		want   string
+	}{
+		{FeedTypeRss, func(f *Feed) { f.Description = "" }, "feed has no description"},
+		{FeedTypeAmazonRss, func(f *Feed) { f.Link = nil }, "feed has no link"},
+		{FeedTypeAtom, func(f *Feed) { f.Link = nil }, "feed has no id"},
+		{FeedTypeJSON, func(f *Feed) { f.Title = "" }, "feed has no title"},
+		{FeedTypeRss, func(f *Feed) { f.Image.Link = "" }, "rss/channel/image: has no link"},
+	} {
+		feed := channelTestFeed()
+		test.unset(feed)
+		if _, err := BuildChannelOnly(feed, test.format); err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%v: got error %v, want %q", test.format, err, test.want)
+		}
+	}
+}
+
+func TestChannelFragment(t *testing.T) {
+	feed := channelTestFeed()
+	feed.Stylesheet = "http://jmoiron.net/feed.xsl"
+	feed.Items = []*Item{{
+		Title:          "Limiting Concurrency in Go",
+		Link:           &Link{Href: "http://jmoiron.net/blog/limiting-concurrency-in-go/"},
+		Content:        "<p>channels</p>",
+		MediaBackLinks: []string{"http://golangweekly.com/"},
+	}}
+
+	for _, test := range []struct {
+		name  string
+		write func(io.Writer, *Feed) error
+		want  []xml.Name
+	}{
+		{"Rss", WriteRssChannelFragment, []xml.Name{{Space: contentNamespace, Local: "encoded"}, {Space: mediaNamespace, Local: "backLink"}}},
+		{"AmazonRss", WriteAmazonRssChannelFragment, []xml.Name{{Space: contentNamespace, Local: "encoded"}, {Space: amazonNamespace, Local: "indexContent"}}},
+	} {
+		var fragment bytes.Buffer
+		if err := test.write(&fragment, feed); err != nil {
+			t.Fatalf("%s: unexpected error: %v", test.name, err)
+		}
+		out := fragment.String()
+		if !strings.HasPrefix(out, `<channel xmlns="" xmlns:`) || !strings.HasSuffix(out, "</channel>") || strings.Contains(out, "<?") || strings.Contains(out, "<rss") {
+			t.Errorf("%s: expected only the channel element.  Got:\n%s\n", test.name, out)
+		}
+
+		doc := `<?xml version="1.0" encoding="UTF-8"?><envelope xmlns="urn:jmoiron:envelope"><feed>` + out + `</feed></envelope>`
+		found := map[xml.Name]bool{}
+		d := xml.NewDecoder(strings.NewReader(doc))
+		for {
+			tok, err := d.Token()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%s: the composite document does not parse: %v\n%s", test.name, err, doc)
+			}
+			if start, ok := tok.(xml.StartElement); ok {
+				found[start.Name] = true
+			}
+		}
+		for _, name := range append(test.want, xml.Name{Local: "channel"}, xml.Name{Local: "title"}) {
+			if !found[name] {
+				t.Errorf("%s: composite document has no %s element in %s.  Got:\n%s\n", test.name, name.Local, name.Space, doc)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := WriteRssChannelFragment(&buf, &Feed{Title: "\xff", CheckUTF8: UTF8Strict}); err == nil || buf.Len() != 0 {
+		t.Errorf("expected the error writing the feed to be returned")
+	}
+}
@@ -0,0 +1,76 @@
+package feeds
+
+import (
+	"sort"
+	"sync"
+)
+
+// ItemOrder is the order Collector.Flush adds items to a feed in.
+type ItemOrder int
+
+const (
+	// ArrivalOrder keeps the items in the order they were added.
+	ArrivalOrder ItemOrder = iota
+	// NewestFirst orders items by their lastModified date, newest first.
+	NewestFirst
+	// OldestFirst orders items by their lastModified date, oldest first.
+	OldestFirst
+)
+
+// Collector gathers items from many goroutines for a feed. Its methods
+// are safe for concurrent use, and the zero value is ready to use.
+type Collector struct {
+	// DedupById drops items with the Id of an item added before, including
+	// the ones already flushed. Items without an Id are always kept.
+	DedupById bool
+
+	mu    sync.Mutex
+	items []*Item
+	ids   map[string]bool
+}
+
+// Add adds an item to the collector.
+func (c *Collector) Add(item *Item) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.DedupById && item.Id != "" {
+		if c.ids[item.Id] {
+			return
+		}
+		if c.ids == nil {
+			c.ids = map[string]bool{}
+		}
+		c.ids[item.Id] = true
+	}
+	c.items = append(c.items, item)
+}
+
+// Len returns the number of items waiting to be flushed.
+func (c *Collector) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
+
+// Flush appends the collected items to the feed in the order, and empties
+// the collector. Items with the same date are ordered by their Id, link and
+// title, so the order does not depend on the order they arrived in.
+func (c *Collector) Flush(feed *Feed, order ItemOrder) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	items := c.items
+	c.items = nil
+	if order != ArrivalOrder {
+		sort.SliceStable(items, func(a, b int) bool {
+			ta, tb := items[a].lastModified(), items[b].lastModified()
+			if !ta.Equal(tb) {
+				return ta.After(tb) == (order == NewestFirst)
+			}
+			if ia, ib := reportId(items[a]), reportId(items[b]); ia != ib {
+				return ia < ib
+			}
+			return items[a].Title < items[b].Title
+		})
+	}
+	feed.Items = append(feed.Items, items...)
+}
@@ -0,0 +1,102 @@
+package feeds
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func collectorTestItems() []*Item {
+	now := testCreated
+	var items []*Item
+	for n := 0; n < 100; n++ {
+		items = append(items, &Item{
+			Title: fmt.Sprintf("Post %d", n),
+			Id:    fmt.Sprintf("post-%02d", n),
+			// several items share each date
+			Created: now.Add(time.Duration(n%10) * time.Hour),
+		})
+	}
+	return items
+}
+
+func TestCollectorConcurrent(t *testing.T) {
+	c := &Collector{DedupById: true}
+	var wg sync.WaitGroup
+	for _, i := range collectorTestItems() {
+		wg.Add(1)
+		go func(i *Item) {
+			defer wg.Done()
+			c.Add(i)
+			// every item arrives twice
+			c.Add(&Item{Title: i.Title, Id: i.Id})
+			c.Len()
+		}(i)
+	}
+	wg.Wait()
+	if c.Len() != 100 {
+		t.Errorf("got %d items, want 100", c.Len())
+	}
+	feed := &Feed{}
+	c.Flush(feed, NewestFirst)
+	if len(feed.Items) != 100 || c.Len() != 0 {
+		t.Errorf("flushed %d items leaving %d, want 100 leaving 0", len(feed.Items), c.Len())
+	}
+	c.Add(&Item{Id: "post-00"})
+	c.Add(&Item{Title: "no id"})
+	c.Add(&Item{Title: "no id"})
+	if c.Len() != 2 {
+		t.Errorf("got %d items, want the 2 without an id", c.Len())
+	}
+}
+
+func TestCollectorFlushOrder(t *testing.T) {
+	var want []string
+	for run := 0; run < 10; run++ {
+		items := collectorTestItems()
+		rand.New(rand.NewSource(int64(run))).Shuffle(len(items), func(a, b int) {
+			items[a], items[b] = items[b], items[a]
+		})
+		c := &Collector{}
+		for _, i := range items {
+			c.Add(i)
+		}
+		feed := &Feed{Items: []*Item{{Id: "already-there"}}}
+		c.Flush(feed, NewestFirst)
+
+		var got []string
+		for _, i := range feed.Items {
+			got = append(got, i.Id)
+		}
+		if got[0] != "already-there" || got[1] != "post-09" || got[len(got)-1] != "post-90" {
+			t.Errorf("run %d: got order %v", run, got)
+		}
+		if want == nil {
+			want = got
+		} else if !reflect.DeepEqual(got, want) {
+			t.Errorf("run %d: got order %v, want %v", run, got, want)
+		}
+	}
+
+	c := &Collector{}
+	items := collectorTestItems()[:3]
+	for n := len(items) - 1; n >= 0; n-- {
+		c.Add(items[n])
+	}
+	feed := &Feed{}
+	c.Flush(feed, OldestFirst)
+	if feed.Items[0] != items[0] || feed.Items[2] != items[2] {
+		t.Errorf("got %v oldest first", feed.Items)
+	}
+	for n := len(items) - 1; n >= 0; n-- {
+		c.Add(items[n])
+	}
+	feed = &Feed{}
+	c.Flush(feed, ArrivalOrder)
+	if feed.Items[0] != items[2] || feed.Items[2] != items[0] {
+		t.Errorf("got %v in arrival order", feed.Items)
+	}
+}
@@ -2,10 +2,15 @@ package feeds
 
 import (
 	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/kr/pretty"
 )
@@ -190,6 +195,10 @@ var testAtomFeedXML = AtomFeed{
 	Icon:     "",
 	Logo:     "",
 	Rights:   "",
+	Generator: &AtomGenerator{
+		XMLName: xml.Name{Space: "", Local: "generator"},
+		Content: "RSS for Node",
+	},
 	Subtitle: "",
 	Link: &AtomLink{
 		XMLName: xml.Name{Space: "", Local: "link"},
@@ -399,3 +408,484 @@ func TestAtomUnmarshal(t *testing.T) {
 		t.Error("object was not unmarshalled correctly")
 	}
 }
+
+func TestAmazonRssRoundTrip(t *testing.T) {
+	now, err := time.Parse(time.RFC3339, "2013-01-16T21:52:35-05:00")
+	if err != nil {
+		t.Error(err)
+	}
+	noIndex := false
+
+	feed := &Feed{
+		Title:       "jmoiron.net blog",
+		Link:        &Link{Href: "http://jmoiron.net/blog"},
+		Description: "discussion about tech, footie, photos",
+		Author:      &Author{Name: "Jason Moiron", Email: "jmoiron@jmoiron.net"},
+		Created:     now,
+		Updated:     now.Add(time.Hour),
+		Copyright:   "This work is copyright © Benjamin Button",
+		Language:    "en-us",
+		Image:       &Image{Url: "http://jmoiron.net/logo.png", Title: "jmoiron.net", Link: "http://jmoiron.net/blog", Width: 144, Height: 72},
+	}
+	feed.Items = []*Item{
+		{
+			Title:       "Best Headphones of 2019",
+			Link:        &Link{Href: "http://jmoiron.net/blog/best-headphones/"},
+			Source:      &Link{Href: "http://example.com/headphones"},
+			Author:      &Author{Name: "Jason Moiron"},
+			Description: "Our favourite headphones",
+			Id:          "http://jmoiron.net/blog/best-headphones/",
+			Created:     now,
+			Enclosure:   &Enclosure{Url: "http://example.com/cover.jpg", Length: "123456", Type: "image/jpg"},
+			Content:     "<p>Listen up</p>",
+			Amazon: &AmazonItem{
+				HeroImage: "http://example.com/hero.jpg",
+				IntroText: "The best headphones you can buy",
+				Section:   "reviews",
+				Position:  1,
+				Products: []*AmazonProduct{
+					{URL: "https://www.amazon.com/dp/B01", Headline: "Best Overall", Award: "Editor's Choice", Summary: "Great sound"},
+					{URL: "https://www.amazon.com/dp/B02", Headline: "Best Budget", Summary: "Cheap and cheerful"},
+				},
+			},
+		},
+		{
+			Title:       "Logic-less Template Redux",
+			Link:        &Link{Href: "http://jmoiron.net/blog/logicless-template-redux/"},
+			Description: "More thoughts on logicless templates",
+			Created:     now,
+			Amazon:      &AmazonItem{HeroImage: "http://example.com/redux.jpg", IntroText: "Templates", IndexContent: &noIndex},
+		},
+		{
+			Title:       "Unboxing the Best Headphones",
+			Link:        &Link{Href: "http://jmoiron.net/blog/unboxing/"},
+			Description: "Our favourite headphones, out of the box",
+			Created:     now,
+			Thumbnail:   "http://example.com/poster.jpg",
+			Enclosure:   &Enclosure{Url: "http://example.com/unboxing.mp4", Length: "123456", Type: "video/mp4"},
+			Amazon:      &AmazonItem{HeroImage: "http://example.com/unboxing.jpg", IntroText: "Unboxing", ContentKind: AmazonVideo},
+		},
+		{
+			Title:         "Best Headphones of 2018",
+			Link:          &Link{Href: "http://jmoiron.net/blog/best-headphones-2018/"},
+			Description:   "Last year's favourite headphones",
+			Created:       now,
+			Status:        ItemUnpublished,
+			UnpublishedAt: now,
+			Amazon:        &AmazonItem{HeroImage: "http://example.com/2018.jpg", IntroText: "Old news", Suppress: AmazonSection, Marketplaces: []string{"US"}},
+		},
+	}
+
+	// every AmazonItem field is set on one of the items, so new ones fail
+	// here until they are parsed or listed as not written
+	notWritten := map[string]bool{"Suppress": true, "Marketplaces": true}
+	set := map[string]bool{}
+	for _, i := range feed.Items {
+		v := reflect.ValueOf(*i.Amazon)
+		for n := 0; n < v.NumField(); n++ {
+			if f := v.Field(n); !reflect.DeepEqual(f.Interface(), reflect.Zero(f.Type()).Interface()) {
+				set[v.Type().Field(n).Name] = true
+			}
+		}
+	}
+	for n := 0; n < reflect.TypeOf(AmazonItem{}).NumField(); n++ {
+		if name := reflect.TypeOf(AmazonItem{}).Field(n).Name; !set[name] {
+			t.Errorf("AmazonItem.%s is not set on any item of the round trip", name)
+		}
+	}
+
+	written := *feed
+	written.KeepUnpublishedFor = time.Hour
+	written.Clock = func() time.Time { return now }
+	amazon, err := written.ToAmazonRss()
+	if err != nil {
+		t.Fatalf("unexpected error encoding Amazon RSS: %v", err)
+	}
+	parsed, err := ParseAmazonRss(strings.NewReader(amazon))
+	if err != nil {
+		t.Fatalf("unexpected error parsing Amazon RSS: %v", err)
+	}
+
+	if !parsed.Created.Equal(feed.Created) || !parsed.Updated.Equal(feed.Updated) {
+		t.Errorf("feed dates not preserved: got %v, %v", parsed.Created, parsed.Updated)
+	}
+	for i, item := range parsed.Items {
+		if !item.Created.Equal(feed.Items[i].Created) {
+			t.Errorf("item %d date not preserved: got %v", i, item.Created)
+		}
+		item.Created = feed.Items[i].Created
+		item.UnpublishedAt = feed.Items[i].UnpublishedAt
+		if a := feed.Items[i].Amazon; item.Amazon != nil {
+			for name := range notWritten {
+				field := reflect.ValueOf(item.Amazon).Elem().FieldByName(name)
+				field.Set(reflect.ValueOf(a).Elem().FieldByName(name))
+			}
+		}
+	}
+	parsed.Created, parsed.Updated = feed.Created, feed.Updated
+
+	if !reflect.DeepEqual(feed, parsed) {
+		diffs := pretty.Diff(feed, parsed)
+		t.Log(pretty.Println(diffs))
+		t.Error("feed was not round-tripped through Amazon RSS correctly")
+	}
+}
+
+func TestParseFeedTime(t *testing.T) {
+	want, _ := time.Parse(time.RFC3339, "2018-10-30T23:22:00Z")
+	for _, value := range []string{
+		"Tue, 30 Oct 2018 23:22:00 GMT",
+		"Tue, 30 Oct 2018 23:22:00 +0000",
+		"Tue, 30 Oct 2018 23:22:00 +00:00",
+		"2018-10-30T23:22:00Z",
+		"2018-10-30T23:22:00+0000",
+		"2018-10-30 23:22:00Z",
+		"  30 Oct 2018 23:22:00 +0000\n",
+	} {
+		if got := parseFeedTime(value); !got.Equal(want) {
+			t.Errorf("parseFeedTime(%q) = %v, want %v", value, got, want)
+		}
+	}
+	if got := parseFeedTime("yesterday"); !got.IsZero() {
+		t.Errorf("parseFeedTime(\"yesterday\") = %v, want zero time", got)
+	}
+}
+
+func TestParseRSSStream(t *testing.T) {
+	xmlFile, err := os.Open("test.rss")
+	if err != nil {
+		panic("AHH file bad")
+	}
+	defer xmlFile.Close()
+
+	var titles []string
+	feed, err := ParseRSSStream(xmlFile, func(item *Item) error {
+		titles = append(titles, item.Title)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error parsing RSS: %v", err)
+	}
+	if feed.Title != testRssFeedXML.Channel.Title || feed.Link.Href != testRssFeedXML.Channel.Link {
+		t.Errorf("channel not parsed correctly: got %q, %q", feed.Title, feed.Link.Href)
+	}
+	if len(feed.Items) != 0 {
+		t.Errorf("streamed items should not be retained, got %d", len(feed.Items))
+	}
+	if len(titles) != len(testRssFeedXML.Channel.Items) {
+		t.Fatalf("expected %d items, got %d", len(testRssFeedXML.Channel.Items), len(titles))
+	}
+	for i, title := range titles {
+		if want := testRssFeedXML.Channel.Items[i].Title; title != want {
+			t.Errorf("item %d: got title %q, want %q", i, title, want)
+		}
+	}
+}
+
+func TestParseRSSStreamStops(t *testing.T) {
+	xmlFile, err := os.Open("test.rss")
+	if err != nil {
+		panic("AHH file bad")
+	}
+	defer xmlFile.Close()
+
+	stop := errors.New("stop")
+	calls := 0
+	feed, err := ParseRSSStream(xmlFile, func(item *Item) error {
+		calls++
+		if calls == 3 {
+			return stop
+		}
+		return nil
+	})
+	if err != stop {
+		t.Errorf("expected the callback's error, got %v", err)
+	}
+	if feed != nil || calls != 3 {
+		t.Errorf("expected parsing to stop after 3 items, got %d calls", calls)
+	}
+}
+
+func TestParseRss(t *testing.T) {
+	xmlFile, err := os.Open("test.rss")
+	if err != nil {
+		panic("AHH file bad")
+	}
+	defer xmlFile.Close()
+
+	feed, err := ParseRss(xmlFile)
+	if err != nil {
+		t.Fatalf("unexpected error parsing RSS: %v", err)
+	}
+	if len(feed.Items) != len(testRssFeedXML.Channel.Items) {
+		t.Fatalf("expected %d items, got %d", len(testRssFeedXML.Channel.Items), len(feed.Items))
+	}
+	want, _ := time.Parse(time.RFC1123, "Tue, 30 Oct 2018 23:22:00 GMT")
+	item := feed.Items[0]
+	if item.Id != "http://example.com/test/1540941720" || !item.Created.Equal(want) || item.Author == nil || item.Author.Name != "John Smith" {
+		t.Errorf("item not parsed correctly: %+v", item)
+	}
+}
+
+func TestParseRssExtensions(t *testing.T) {
+	explicit := false
+	feed := &Feed{
+		Title:            "jmoiron.net podcast",
+		Link:             &Link{Href: "http://jmoiron.net/podcast"},
+		Description:      "discussion about tech, footie, photos",
+		ITunesNewFeedURL: "https://jmoiron.net/podcast.rss",
+		Explicit:         &explicit,
+		Webfeeds:         &Webfeeds{Icon: "http://jmoiron.net/icon.svg", Logo: "http://jmoiron.net/logo.svg", AccentColor: "00FF00", CoverImage: "http://jmoiron.net/cover.jpg"},
+		AtomItemLinks:    true,
+		Items: []*Item{{
+			Title:       "Limiting Concurrency in Go",
+			Link:        &Link{Href: "http://jmoiron.net/podcast/1/"},
+			Description: "A discussion on controlled parallelism in golang",
+			Id:          "http://jmoiron.net/podcast/1/",
+			ViaURL:      "http://example.com/concurrency",
+			Links:       []*Link{{Href: "http://jmoiron.net/podcast/fr/1/", Rel: "alternate", HrefLang: "fr"}},
+			Enclosure: &Enclosure{
+				Url: "http://jmoiron.net/1.mp3", Length: "123456", Type: "audio/mpeg", Bitrate: 128,
+				Hash:  &MediaHash{Algo: "md5", Value: "dfdec888b72151965a34b4b59031290a"},
+				Embed: &MediaEmbed{URL: "http://jmoiron.net/player", Width: 512, Height: 323, Params: map[string]string{"autoplay": "false"}},
+			},
+			MediaPlayer:    &MediaPlayer{URL: "http://jmoiron.net/podcast/1/player", Width: 400},
+			MediaTitle:     &MediaTitle{Text: "Limiting <i>Concurrency</i>", Type: "html"},
+			MediaBackLinks: []string{"http://example.com/podcasts"},
+			PodcastSeason:  &PodcastSeason{Number: 2, Name: "Go"},
+			PodcastEpisode: &PodcastEpisode{Number: 1.5, Display: "Bonus"},
+		}},
+	}
+	rss, err := feed.ToRss()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseRss(strings.NewReader(rss))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.ITunesNewFeedURL != feed.ITunesNewFeedURL || !reflect.DeepEqual(parsed.Explicit, feed.Explicit) || !reflect.DeepEqual(parsed.Webfeeds, feed.Webfeeds) {
+		t.Errorf("got channel %q, %v, %+v", parsed.ITunesNewFeedURL, parsed.Explicit, parsed.Webfeeds)
+	}
+	want, got := *feed.Items[0], *parsed.Items[0]
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got item:\n%s", strings.Join(pretty.Diff(want, got), "\n"))
+	}
+}
+
+func TestParseRssRootElement(t *testing.T) {
+	atom := `<feed xmlns="http://www.w3.org/2005/Atom"><title>jmoiron.net blog</title></feed>`
+	for name, parse := range map[string]func(io.Reader) (*Feed, error){
+		"ParseRss":       ParseRss,
+		"ParseAmazonRss": ParseAmazonRss,
+		"ParseRSSStream": func(r io.Reader) (*Feed, error) {
+			return ParseRSSStream(r, func(*Item) error { return nil })
+		},
+	} {
+		feed, err := parse(strings.NewReader(atom))
+		if err == nil || err.Error() != "feeds: root element is <feed>, not <rss>" {
+			t.Errorf("%s: expected an error for an atom document, got %v and %+v", name, err, feed)
+		}
+	}
+}
+
+// a channel with numbers which do not parse or overflow, and a long title
+var hostileRss = `<rss version="2.0" xmlns:amzn="https://amazon.com/ospublishing/1.0/">
+  <channel>
+    <title>jmoiron.net blog ` + strings.Repeat("é", 8) + `</title>
+    <link>http://jmoiron.net/blog</link>
+    <ttl>99999999999999999999</ttl>
+    <image><url>http://jmoiron.net/logo.png</url><width>-1</width><height>lots</height></image>
+    <item>
+      <title>Limiting Concurrency in Go</title>
+      <enclosure url="http://jmoiron.net/episode.mp3" length="99999999999999999999999" type="audio/mpeg"/>
+      <amzn:position>1e9</amzn:position>
+    </item>
+    <item>
+      <title>Logic-less Template Redux</title>
+      <enclosure url="http://jmoiron.net/episode.mp3" length="123456" type="audio/mpeg"/>
+    </item>
+  </channel>
+</rss>`
+
+func TestParseRssWithWarnings(t *testing.T) {
+	feed, warnings, err := ParseRssWithWarnings(strings.NewReader(hostileRss), ParseOptions{MaxTextLength: 32})
+	if err != nil {
+		t.Fatalf("unexpected error parsing RSS: %v", err)
+	}
+	want := []string{
+		`rss/channel/item[0]/amzn:position: "1e9" is not a number, left at 0`,
+		`rss/channel/item[0]/enclosure/length: "99999999999999999999999" is not a number of bytes, left out`,
+		"rss/channel/title: cut from 33 to 31 bytes",
+		`rss/channel/ttl: "99999999999999999999" is not a number, left at 0`,
+		`rss/channel/image/width: "-1" is not a number, left at 0`,
+		`rss/channel/image/height: "lots" is not a number, left at 0`,
+	}
+	if got := issueStrings(warnings); !reflect.DeepEqual(got, want) {
+		t.Errorf("got warnings:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if feed.Title != "jmoiron.net blog éééééééé"[:31] || feed.TTL != 0 || feed.Image.Width != 0 || len(feed.Items) != 2 {
+		t.Errorf("feed not parsed correctly: %+v", feed)
+	}
+	if e := feed.Items[0].Enclosure; e.Length != "" || e.Url != "http://jmoiron.net/episode.mp3" {
+		t.Errorf("got enclosure %+v, want it without a length", e)
+	}
+	if e := feed.Items[1].Enclosure; e.Length != "123456" {
+		t.Errorf("got enclosure %+v, want its length", e)
+	}
+
+	feed, err = ParseRss(strings.NewReader(hostileRss))
+	if err != nil || feed.TTL != 0 || !strings.HasSuffix(feed.Title, "é") {
+		t.Errorf("ParseRss should leave numbers at 0 and keep short texts, got %+v, %v", feed, err)
+	}
+	feed, _, err = ParseRssWithWarnings(strings.NewReader(hostileRss), ParseOptions{MaxTextLength: -1})
+	if err != nil || !strings.HasSuffix(feed.Title, "é") {
+		t.Errorf("a negative MaxTextLength should not cut texts, got %+v, %v", feed, err)
+	}
+}
+
+// items 1, 3 and 5 are broken: a bad entity, a mismatched tag and an item
+// without an end tag, whose description must not end up in the next item
+var corruptedRss = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/" xmlns:amzn="https://amazon.com/ospublishing/1.0/">
+  <channel>
+    <title>jmoiron.net blog</title>
+    <link>http://jmoiron.net/blog</link>
+    <item>
+      <title>Limiting Concurrency in Go</title>
+      <content:encoded><![CDATA[<p>a </item> in cdata</p>]]></content:encoded>
+    </item>
+    <item>
+      <title>Broken &nbsp; entity</title>
+    </item>
+    <item>
+      <title>Logic-less Template Redux</title>
+      <amzn:section>tech</amzn:section>
+    </item>
+    <item>
+      <title>Mismatched</titl>
+    </item>
+    <item/>
+    <item>
+      <title>Never closed</title>
+      <description>lost</description>
+    <item>
+      <title>Idiomatic Code Reuse in Go</title>
+    </item>
+    <ttl>60</ttl>
+  </channel>
+</rss>`
+
+func TestParseRssRecover(t *testing.T) {
+	if _, err := ParseRss(strings.NewReader(corruptedRss)); err == nil {
+		t.Errorf("ParseRss should fail on the corrupted items")
+	}
+
+	feed, skipped, err := ParseRssRecover(strings.NewReader(corruptedRss), ParseOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error parsing RSS: %v", err)
+	}
+	var titles []string
+	for _, i := range feed.Items {
+		titles = append(titles, i.Title)
+	}
+	want := []string{"Limiting Concurrency in Go", "Logic-less Template Redux", "", "Idiomatic Code Reuse in Go"}
+	if !reflect.DeepEqual(titles, want) {
+		t.Errorf("got items %q, want %q", titles, want)
+	}
+	if feed.Title != "jmoiron.net blog" || feed.TTL != 60 {
+		t.Errorf("channel not parsed correctly: %+v", feed)
+	}
+	if c := feed.Items[0].Content; c != "<p>a </item> in cdata</p>" {
+		t.Errorf("got content %q, want the cdata", c)
+	}
+	if a := feed.Items[1].Amazon; a == nil || a.Section != "tech" {
+		t.Errorf("got amazon options %+v, want the section", a)
+	}
+	if d := feed.Items[3].Description; d != "" {
+		t.Errorf("the description of the unclosed item ended up in the next one: %q", d)
+	}
+
+	var got []string
+	for _, s := range skipped {
+		got = append(got, s.String())
+	}
+	wantSkipped := []string{
+		fmt.Sprintf("rss/channel/item[1] at byte %d: XML syntax error on line 11: invalid character entity &nbsp;", strings.Index(corruptedRss, "<item>\n      <title>Broken")),
+		fmt.Sprintf("rss/channel/item[3] at byte %d: XML syntax error on line 18: element <title> closed by </titl>", strings.Index(corruptedRss, "<item>\n      <title>Mismatched")),
+		fmt.Sprintf("rss/channel/item[5] at byte %d: %v", strings.Index(corruptedRss, "<item>\n      <title>Never"), errItemUnclosed),
+	}
+	if !reflect.DeepEqual(got, wantSkipped) {
+		t.Errorf("got skipped items:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(wantSkipped, "\n"))
+	}
+	for _, s := range skipped {
+		if item := corruptedRss[s.Offset : s.Offset+s.Length]; !strings.HasPrefix(item, "<item>") || strings.Count(item, "<item") != 1 {
+			t.Errorf("%s should span a single item, got %q", s.Path, item)
+		}
+	}
+
+	doc, err := ioutil.ReadFile("test.rss")
+	if err != nil {
+		t.Fatal(err)
+	}
+	strict, err := ParseRss(strings.NewReader(string(doc)))
+	if err != nil {
+		t.Fatalf("unexpected error parsing test.rss: %v", err)
+	}
+	feed, skipped, err = ParseAmazonRssRecover(strings.NewReader(string(doc)), ParseOptions{})
+	if err != nil || len(skipped) != 0 || !reflect.DeepEqual(feed, strict) {
+		t.Errorf("well-formed documents should parse as ParseRss does, got %v, %v", skipped, err)
+	}
+}
+
+func TestParseRssWhitespace(t *testing.T) {
+	code := "\n<pre>\n\tfunc main() {\n\t\tfmt.Println(\"footie\")\n\t}\n</pre>\n\n"
+	doc := `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/"><channel>
+<title>jmoiron.net blog</title><link>http://jmoiron.net/blog</link><description>discussion about tech</description>
+<item><title>cdata</title><description xml:space="preserve">  indented
+</description><content:encoded><![CDATA[` + code + `]]></content:encoded></item>
+<item><title>escaped</title><description>   </description><content:encoded>&lt;pre&gt;
+  x := 1
+&lt;/pre&gt;
+</content:encoded></item>
+</channel></rss>`
+	want := [][2]string{
+		{"  indented\n", code},
+		{"   ", "<pre>\n  x := 1\n</pre>\n"},
+	}
+	check := func(name string, feed *Feed) {
+		for n, w := range want {
+			if i := feed.Items[n]; i.Description != w[0] || i.Content != w[1] {
+				t.Errorf("%s: expected item %d to keep %q and %q, got %q and %q", name, n, w[0], w[1], i.Description, i.Content)
+			}
+		}
+	}
+	feed, err := ParseRss(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("unexpected error parsing: %v", err)
+	}
+	check("ParseRss", feed)
+
+	for _, opts := range []struct {
+		name string
+		set  func(f *Feed)
+	}{
+		{"default", func(f *Feed) {}},
+		{"SelfCloseEmpty", func(f *Feed) { f.SelfCloseEmpty = true }},
+		{"Canonical", func(f *Feed) { f.Canonical = true }},
+		{"ExpandSelfClosing", func(f *Feed) { f.Quirks.ExpandSelfClosing = true }},
+	} {
+		f := *feed
+		opts.set(&f)
+		out, err := f.ToRss()
+		if err != nil {
+			t.Fatalf("%s: unexpected error encoding: %v", opts.name, err)
+		}
+		parsed, err := ParseRss(strings.NewReader(out))
+		if err != nil {
+			t.Fatalf("%s: unexpected error parsing:\n%s", opts.name, out)
+		}
+		check(opts.name, parsed)
+	}
+}
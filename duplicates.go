@@ -0,0 +1,132 @@
+package feeds
+
+import (
+	"fmt"
+	"time"
+)
+
+// DuplicateIDMode is what writing a feed does with items with the same Id.
+type DuplicateIDMode int
+
+const (
+	// DuplicateIDAllow writes all of them, which readers may show as one.
+	DuplicateIDAllow DuplicateIDMode = iota
+	// DuplicateIDKeepFirst writes only the first of them, logging the
+	// others as skipped and reporting them to WriteWithReport.
+	DuplicateIDKeepFirst
+	// DuplicateIDError fails writing the feed with an error naming the id.
+	DuplicateIDError
+)
+
+// whether to leave out the nth item of the feed as it has the Id of an
+// earlier one, with DuplicateIDKeepFirst; first holds the index of the
+// first item written with each id
+func (f *Feed) duplicateItem(first map[string]int, n int, i *Item) bool {
+	if f.OnDuplicateID != DuplicateIDKeepFirst || i.Id == "" {
+		return false
+	}
+	if m, seen := first[i.Id]; seen {
+		f.dropItem(i, fmt.Sprintf("item %d has the same id", m))
+		return true
+	}
+	first[i.Id] = n
+	return false
+}
+
+// the error of the first published item with the Id of an earlier one,
+// with DuplicateIDError
+func (f *Feed) checkDuplicateIDs() error {
+	if f.OnDuplicateID != DuplicateIDError {
+		return nil
+	}
+	first := map[string]int{}
+	for n, i := range f.Items {
+		if i == nil || !i.published() || i.Id == "" {
+			continue
+		}
+		if m, seen := first[i.Id]; seen {
+			return fmt.Errorf("feeds: items %d and %d have the same id %q", m, n, i.Id)
+		}
+		first[i.Id] = n
+	}
+	return nil
+}
+
+// returns the feed with the published items without an Id which share a
+// pubDate, to the second, with an earlier one moved to the next second no
+// other such item has, in the order of the items, with JitterIdenticalDates,
+// or the feed itself when none are. readers without a guid to go by take
+// such items for the same one.
+func (f *Feed) jitterDates() *Feed {
+	if !f.JitterIdenticalDates {
+		return f
+	}
+	jittered := func(i *Item) bool {
+		return i != nil && i.Id == "" && i.published() && !i.pubDate().IsZero()
+	}
+	dates := map[int64]bool{} // the pubDates of the items, so none is moved onto another
+	for _, i := range f.Items {
+		if jittered(i) {
+			dates[i.pubDate().Unix()] = true
+		}
+	}
+	var items []*Item
+	taken := map[int64]bool{} // the pubDates of the items so far
+	for n, i := range f.Items {
+		if !jittered(i) {
+			continue
+		}
+		date := i.pubDate().Unix()
+		if !taken[date] {
+			taken[date] = true
+			continue
+		}
+		free := date + 1
+		for taken[free] || dates[free] {
+			free++
+		}
+		taken[free] = true
+		if items == nil {
+			items = append([]*Item(nil), f.Items...)
+		}
+		item := *i
+		after := time.Duration(free-date) * time.Second
+		if !item.Created.IsZero() {
+			item.Created = item.Created.Add(after)
+		} else {
+			item.Updated = item.Updated.Add(after)
+		}
+		items[n] = &item
+	}
+	if items == nil {
+		return f
+	}
+	feed := *f
+	feed.Items = items
+	return &feed
+}
+
+// add a violation for each published item without an Id with the pubDate,
+// to the second, and the title of an earlier one, unless
+// JitterIdenticalDates moves them apart
+func (v *validator) checkIdenticalDates(f *Feed) {
+	if f.JitterIdenticalDates {
+		return
+	}
+	type key struct {
+		date  int64
+		title string
+	}
+	first := map[key]int{}
+	for n, i := range f.Items {
+		if !i.published() || i.Id != "" || i.pubDate().IsZero() {
+			continue
+		}
+		k := key{i.pubDate().Unix(), i.Title}
+		if m, seen := first[k]; seen {
+			v.check(false, "item %d has no id and the pubDate and title of item %d, readers may drop it as the same item", n, m)
+		} else {
+			first[k] = n
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package feeds
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func duplicatesTestFeed() *Feed {
+	return testFeed(
+		&Item{Id: "limiting-concurrency-in-go", Title: "Limiting Concurrency in Go"},
+		&Item{Id: "logicless-template-redux", Title: "Logic-less Template Redux"},
+		&Item{Id: "limiting-concurrency-in-go", Title: "Limiting Concurrency in Go, again"},
+		&Item{Title: "No id"},
+		&Item{Title: "No id, again"},
+	)
+}
+
+func TestOnDuplicateID(t *testing.T) {
+	feed := duplicatesTestFeed()
+	out, err := feed.ToRss()
+	if err != nil || !strings.Contains(out, "Limiting Concurrency in Go, again") {
+		t.Errorf("expected the duplicate to be allowed by default, got %v:\n%s", err, out)
+	}
+
+	feed.OnDuplicateID = DuplicateIDError
+	for _, encode := range []func() (string, error){feed.ToRss, feed.ToAtom, feed.ToJSON, feed.ToAmazonRss} {
+		_, err := encode()
+		if err == nil || err.Error() != `feeds: items 0 and 2 have the same id "limiting-concurrency-in-go"` {
+			t.Errorf("expected an error naming the duplicated id, got %v", err)
+		}
+	}
+	feed.Items[2].Status = ItemUnpublished
+	if _, err := feed.ToRss(); err != nil {
+		t.Errorf("unexpected error for an unpublished duplicate: %v", err)
+	}
+
+	feed = duplicatesTestFeed()
+	feed.OnDuplicateID = DuplicateIDKeepFirst
+	for _, format := range []FeedType{FeedTypeRss, FeedTypeAtom, FeedTypeJSON, FeedTypeAmazonRss} {
+		var buf bytes.Buffer
+		report, err := feed.WriteWithReport(&buf, format)
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", format, err)
+		}
+		if len(report.Dropped) != 1 || report.Dropped[0].Id != "limiting-concurrency-in-go" || report.Dropped[0].Reason != "item 0 has the same id" {
+			t.Errorf("%v: expected the second item with the id to be dropped, got %+v", format, report.Dropped)
+		}
+		out := buf.String()
+		if strings.Contains(out, "Limiting Concurrency in Go, again") || !strings.Contains(out, "Limiting Concurrency in Go<") && !strings.Contains(out, `"Limiting Concurrency in Go"`) {
+			t.Errorf("%v: expected only the first item with the id.  Got:\n%s\n", format, out)
+		}
+		if !strings.Contains(out, "No id, again") {
+			t.Errorf("%v: expected the items without an id to be kept.  Got:\n%s\n", format, out)
+		}
+	}
+}
+
+func jitterTestFeed() *Feed {
+	now := testCreated
+	feed := testFeed(
+		&Item{Title: "Imported", Created: now},
+		&Item{Title: "Imported", Created: now.Add(500 * time.Millisecond)},
+		&Item{Id: "with-a-guid", Title: "Imported", Created: now},
+		&Item{Title: "Imported", Created: now},
+		&Item{Title: "Updated only", Updated: now},
+		&Item{Title: "Earlier", Created: now.Add(-time.Hour)},
+	)
+	feed.Created = time.Time{}
+	return feed
+}
+
+func TestJitterIdenticalDates(t *testing.T) {
+	feed := jitterTestFeed()
+	rss, err := feed.ToRss()
+	if err != nil {
+		t.Fatalf("unexpected error encoding RSS: %v", err)
+	}
+	if n := strings.Count(rss, "<pubDate>Wed, 16 Jan 2013 21:52:35 -0500</pubDate>"); n != 5 {
+		t.Errorf("expected the dates to be left as they are by default, got %d shared.  Got:\n%s\n", n, rss)
+	}
+
+	feed.JitterIdenticalDates = true
+	want := []string{
+		"Wed, 16 Jan 2013 21:52:35 -0500",
+		"Wed, 16 Jan 2013 21:52:36 -0500",
+		"Wed, 16 Jan 2013 21:52:35 -0500", // has a guid
+		"Wed, 16 Jan 2013 21:52:37 -0500",
+		"Wed, 16 Jan 2013 21:52:38 -0500",
+		"Wed, 16 Jan 2013 20:52:35 -0500",
+	}
+	for _, to := range []func() (string, error){feed.ToRss, feed.ToAmazonRss} {
+		first, err := to()
+		if err != nil {
+			t.Fatalf("unexpected error encoding: %v", err)
+		}
+		var got []string
+		for _, part := range strings.Split(first, "<pubDate>")[1:] {
+			got = append(got, part[:strings.Index(part, "</pubDate>")])
+		}
+		if strings.Join(got, "|") != strings.Join(want, "|") {
+			t.Errorf("expected the dates %q, got %q", want, got)
+		}
+		for run := 0; run < 3; run++ {
+			if out, _ := to(); out != first {
+				t.Errorf("expected the same output on every run.\nGot:\n%s\nWant:\n%s\n", out, first)
+			}
+		}
+	}
+	if !feed.Items[3].Created.Equal(feed.Items[0].Created) {
+		t.Errorf("expected the items to be left as they are")
+	}
+}
+
+func TestJitterIdenticalDatesCollisions(t *testing.T) {
+	now := testCreated
+	feed := jitterTestFeed()
+	feed.JitterIdenticalDates = true
+	// b would be moved onto the date of c, and d onto those of b and c
+	feed.Items = []*Item{
+		{Title: "a", Created: now},
+		{Title: "b", Created: now},
+		{Title: "c", Created: now.Add(time.Second)},
+		{Title: "d", Created: now},
+		{Title: "e", Created: now.Add(time.Second)},
+	}
+	want := []string{
+		"Wed, 16 Jan 2013 21:52:35 -0500",
+		"Wed, 16 Jan 2013 21:52:37 -0500",
+		"Wed, 16 Jan 2013 21:52:36 -0500",
+		"Wed, 16 Jan 2013 21:52:38 -0500",
+		"Wed, 16 Jan 2013 21:52:39 -0500",
+	}
+	rss, err := feed.ToRss()
+	if err != nil {
+		t.Fatalf("unexpected error encoding RSS: %v", err)
+	}
+	var got []string
+	for _, part := range strings.Split(rss, "<pubDate>")[1:] {
+		got = append(got, part[:strings.Index(part, "</pubDate>")])
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("expected the dates %q, got %q", want, got)
+	}
+}
+
+func TestLintIdenticalDates(t *testing.T) {
+	rss, err := jitterTestFeed().ToRss()
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues, err := LintRSS(strings.NewReader(rss))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"rss/channel/item[1]: has no id and the pubDate and title of item 0, readers may drop it as the same item",
+		"rss/channel/item[3]: has no id and the pubDate and title of item 0, readers may drop it as the same item",
+	}
+	if got := issueStrings(issues); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected the issues:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+
+	feed := jitterTestFeed()
+	feed.JitterIdenticalDates = true
+	rss, _ = feed.ToRss()
+	if issues, _ := LintRSS(strings.NewReader(rss)); len(issues) != 0 {
+		t.Errorf("expected no issues once the dates are jittered, got %v", issueStrings(issues))
+	}
+}
+
+func TestValidateIdenticalDates(t *testing.T) {
+	feed := jitterTestFeed()
+	violation := "item 3 has no id and the pubDate and title of item 0, readers may drop it as the same item"
+	for _, jitter := range []bool{false, true} {
+		feed.JitterIdenticalDates = jitter
+		for name, validate := range map[string]func() error{"rss": feed.ValidateRSS, "amazon": feed.ValidateAmazonRss} {
+			err := validate()
+			if found := err != nil && strings.Contains(err.Error(), violation); found == jitter {
+				t.Errorf("%s: got %v with JitterIdenticalDates %v", name, err, jitter)
+			}
+		}
+	}
+}
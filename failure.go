@@ -0,0 +1,47 @@
+package feeds
+
+import (
+	"io"
+)
+
+// FailureMode is what writing a feed does with an item which fails to encode.
+type FailureMode int
+
+const (
+	// Abort fails writing the feed with the error of the first such item.
+	Abort FailureMode = iota
+	// SkipAndReport leaves the item out, logs it as skipped and writes the
+	// rest of the feed. WriteWithReport returns the skipped items.
+	SkipAndReport
+)
+
+// WriteWithReport writes the feed as t like the Write methods, and returns
+// the items left out because they failed to encode with the SkipAndReport
+// FailureMode, or by a Scrub LinkPolicy. It is empty when no item was left
+// out, or with Abort, where the first failure is returned as the error
+// instead.
+func (f *Feed) WriteWithReport(w io.Writer, t FeedType) (*Report, error) {
+	report := &Report{}
+	feed := *f
+	feed.skipped = report
+	return report, feed.write(w, t)
+}
+
+// leave out an item which failed to encode with the SkipAndReport mode,
+// recording and logging it, or return false to fail writing the feed
+func (f *Feed) skipItem(i *Item, failure error) bool {
+	if f.FailureMode != SkipAndReport {
+		return false
+	}
+	f.dropItem(i, failure.Error())
+	return true
+}
+
+// record an item left out of the feed being written and log it
+func (f *Feed) dropItem(i *Item, reason string) {
+	id := reportId(i)
+	if f.skipped != nil {
+		f.skipped.Dropped = append(f.skipped.Dropped, DroppedItem{Id: id, Reason: reason})
+	}
+	f.log(EventItemSkipped, "id", id, "reason", reason)
+}
@@ -0,0 +1,52 @@
+package feeds
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestFailureMode(t *testing.T) {
+	feed := sizeTestFeed()
+	feed.Items = feed.Items[:3]
+	feed.Items[1].ViaURL = "http://jmoiron.net/%zz"
+
+	for _, format := range []FeedType{FeedTypeRss, FeedTypeAtom} {
+		var buf bytes.Buffer
+		report, err := feed.WriteWithReport(&buf, format)
+		if err == nil || !strings.Contains(err.Error(), "item 1: via url is invalid") {
+			t.Errorf("%v: expected the via url to abort writing, got %v", format, err)
+		}
+		if len(report.Dropped) != 0 {
+			t.Errorf("%v: expected nothing skipped when aborting, got %+v", format, report.Dropped)
+		}
+	}
+
+	logger := &testLogger{}
+	feed.FailureMode = SkipAndReport
+	feed.Logger = logger
+	for _, format := range []FeedType{FeedTypeRss, FeedTypeAtom} {
+		logger.events = nil
+		var buf bytes.Buffer
+		report, err := feed.WriteWithReport(&buf, format)
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", format, err)
+		}
+		if len(report.Dropped) != 1 || report.Dropped[0].Id != "post-1" || !strings.HasPrefix(report.Dropped[0].Reason, "via url is invalid") {
+			t.Errorf("%v: expected post-1 to be skipped, got %+v", format, report.Dropped)
+		}
+		out := buf.String()
+		if strings.Contains(out, "Post 1<") || !strings.Contains(out, "Post 0<") || !strings.Contains(out, "Post 2<") {
+			t.Errorf("%v: expected only post-1 to be left out.  Got:\n%s\n", format, out)
+		}
+		if len(logger.events) != 3 || logger.events[1].event != EventItemSkipped || logger.events[1].fields["id"] != "post-1" {
+			t.Errorf("%v: expected the skipped item to be logged, got %+v", format, logger.events)
+		}
+	}
+
+	// the To methods skip the item as well, without a report
+	out, err := feed.ToRss()
+	if err != nil || strings.Contains(out, "Post 1<") {
+		t.Errorf("expected ToRss to leave out post-1, got %v.  Got:\n%s\n", err, out)
+	}
+}
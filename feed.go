@@ -1,30 +1,61 @@
 package feeds
 
 import (
+	"bytes"
 	"encoding/json"
 	"encoding/xml"
+	"fmt"
 	"io"
+	"net/url"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
 type Link struct {
 	Href, Rel, Type, Length string
+	HrefLang, Title         string // the language and title of atom links
 }
 
 type Author struct {
 	Name, Email string
+	Role        string // like "photographer", an author when empty or "author"
+
+	// JSONExtensions are _-prefixed keys added to the json feed author
+	JSONExtensions map[string]interface{}
 }
 
 type Image struct {
 	Url, Title, Link string
 	Width, Height    int
+	Type             string // the mime type, for urls without an image extension
 }
 
+// Enclosure is a file attached to an item. Its Length is the size of the
+// file in bytes as a decimal string; an empty Length is unset and leaves
+// the length out, while "0" is an explicit zero length written as
+// length="0", which some podcast hosts expect of placeholder enclosures.
 type Enclosure struct {
 	Url, Length, Type string
+	Hash              *MediaHash    // written as media rss media:content in rss
+	Bitrate           int           // kilobits per second, media:content bitrate in rss and json _bitrate
+	SupportsRanges    *bool         // whether the url serves byte range requests, json _ranges
+	Duration          time.Duration // the length of the audio or video, json duration_in_seconds
+	Title             string        // a title of the file, the json attachment title
+	Embed             *MediaEmbed   // a player for the file, media:embed in rss
 }
 
+// ItemStatus is the editorial status of an Item.
+type ItemStatus int
+
+const (
+	ItemActive ItemStatus = iota
+	// unpublished items are left out of the feeds, except for a while in
+	// amazon rss feeds keeping them as deleted
+	ItemUnpublished
+)
+
 type Item struct {
 	Title       string
 	Link        *Link
@@ -32,24 +63,352 @@ type Item struct {
 	Author      *Author
 	Description string // used as description in rss, summary in atom
 	Id          string // used as guid in rss, id in atom
+	IsPermaLink *bool  // whether the Id is a url of the item, the isPermaLink of the rss guid
 	Updated     time.Time
 	Created     time.Time
 	Enclosure   *Enclosure
 	Content     string
+	Language    string // overrides the feed language in atom and json
+	Amazon      *AmazonItem
+	MediaPlayer *MediaPlayer
+	MediaTitle  *MediaTitle // media:title in rss, the Title for items with media when nil
+	Thumbnail   string      // image url, used as amzn:heroImage and the json image
+	ViaURL      string      // where the item was found, a via link in atom and rss
+	Links       []*Link     // additional links, like language alternates
+
+	// Contributors are the others credited for the item, like its editor
+	// and photographer, with their Role.
+	Contributors []*Author
+
+	// MediaBackLinks are the urls of pages referencing the item's media,
+	// as the media:backLinks of rss items.
+	MediaBackLinks []string
+
+	PodcastSeason  *PodcastSeason  // podcast:season in rss
+	PodcastEpisode *PodcastEpisode // podcast:episode in rss
+
+	Status        ItemStatus
+	UnpublishedAt time.Time // when an unpublished item was unpublished
+	Expires       time.Time // when the item stops being current, like a deal
+
+	// SkipSanitize writes the description and content of a trusted item,
+	// like editorial content with intended markup, without passing them
+	// through the feed's Sanitizer.
+	SkipSanitize bool
+}
+
+// check the via url of an item parses
+func (i *Item) validateVia() error {
+	if i.ViaURL == "" {
+		return nil
+	}
+	if _, err := url.Parse(i.ViaURL); err != nil {
+		return fmt.Errorf("via url is invalid: %v", err)
+	}
+	return nil
+}
+
+// the unpublished items are not written
+func (i *Item) published() bool {
+	return i.Status != ItemUnpublished
 }
 
 type Feed struct {
 	Title       string
 	Link        *Link
+	Links       []*Link // additional links, like rfc 5005 paging links
 	Description string
 	Author      *Author
 	Updated     time.Time
 	Created     time.Time
 	Id          string
-	Subtitle    string
+	Subtitle    string // atom subtitle, Description is used when empty
 	Items       []*Item
 	Copyright   string
 	Image       *Image
+	Icon        string // large icon, json icon and atom logo over the Image
+	Favicon     string // small icon, json favicon and atom icon
+	Language    string // used as language in rss and json, xml:lang in atom
+	TTL         int    // minutes a feed can be cached, used as ttl in rss
+	Docs        string // url of the format's documentation, used as docs in rss
+	Syndication *Syndication
+	Webfeeds    *Webfeeds  // feedly's cover, icons and color in rss
+	Generator   *Generator // the software generating the feed
+	Amazon      *AmazonChannel
+
+	// CopyrightTemplate is used as the copyright when Copyright is empty,
+	// with {{year}} replaced by the year the feed is generated in. Json
+	// feeds have no copyright, so neither is used there.
+	CopyrightTemplate string
+
+	// LeadImageThumbnails takes the Thumbnail of items without one from the
+	// first image of their Content.
+	LeadImageThumbnails bool
+
+	// StableLastBuildDate takes the rss lastBuildDate from the newest item
+	// rather than Updated, and leaves out the channel pubDate when it is the
+	// same date, so rebuilding a feed without changing its items does not
+	// change its output.
+	StableLastBuildDate bool
+
+	// Clock returns the generation time of the feed, time.Now when nil.
+	Clock func() time.Time `json:"-"`
+
+	// Logger receives the events of generating this feed, nothing is
+	// logged when nil.
+	Logger Logger `json:"-"`
+
+	// SelfCloseEmpty writes the elements without any content of the xml
+	// formats as <foo/>, for consumers which do not accept <foo></foo>.
+	SelfCloseEmpty bool
+
+	// Canonical writes the xml formats in a stable form for diffing and
+	// hashing them rather than publishing them: attributes sorted, empty
+	// elements as <foo></foo>, cdata as text and a fixed indent. It takes
+	// precedence over SelfCloseEmpty and the Quirks.
+	Canonical bool
+
+	// Quirks work around consumers which fail on some valid xml.
+	Quirks Quirks
+
+	// CheckUTF8 is how strings which are not valid utf-8 are handled.
+	CheckUTF8 UTF8Mode
+
+	// URLRewriter changes the urls written for each format, like serving
+	// media from another host in some of them, when it is not nil.
+	URLRewriter URLRewriter `json:"-"`
+
+	// Preview writes the feed as a preview of it when it is not nil.
+	Preview *PreviewMode
+
+	// IDScheme formats the ids of the items, which are written as they
+	// are when it is nil. The ids atom makes for items without one are
+	// already tag uris or urns.
+	IDScheme IDScheme `json:"-"`
+
+	// AlternateFeeds are the urls the feed is published at in each format.
+	// Every format links to the others as alternates, and json feeds use
+	// their own url as their feed_url.
+	AlternateFeeds map[FeedType]string
+
+	// OpenSearchURL is the url of an opensearch description of the site,
+	// linked with rel="search" from atom feeds and rss channels so readers
+	// can offer to search it.
+	OpenSearchURL string
+
+	// Stylesheet is the url of an xslt or css stylesheet which browsers
+	// show the xml formats with, written in an xml-stylesheet processing
+	// instruction before the root element.
+	Stylesheet string
+
+	// StampGenerator appends the Version of this package to the Generator,
+	// as "(feeds vX.Y.Z)" in the rss generator and json _generator, and as
+	// the version attribute of the atom generator.
+	StampGenerator bool
+
+	// Sanitizer is applied to the description and content of every item
+	// without SkipSanitize when the feed is written, the Items are left as
+	// they are.
+	Sanitizer Sanitizer `json:"-"`
+
+	// FailureMode is what writing the feed does with an item which cannot
+	// be encoded, like one with invalid media or xhtml content.
+	FailureMode FailureMode
+
+	// LinkPolicy restricts the hosts items link to when it is not nil.
+	LinkPolicy *LinkPolicy
+
+	// OnDuplicateID is what writing the feed does with items with the Id
+	// of an earlier item, which readers can take for the same item.
+	OnDuplicateID DuplicateIDMode
+
+	// ITunesNewFeedURL is the url a podcast has moved to, written as the
+	// itunes:new-feed-url of rss channels so podcast apps move their
+	// subscribers to it. The old feed should be kept up until they have.
+	ITunesNewFeedURL string
+
+	// Explicit is whether the feed has explicit content, and unsaid when
+	// nil. It is written as the itunes:explicit of rss podcasts, those with
+	// itunes or podcast elements, and the _explicit extension of json.
+	Explicit *bool
+
+	// AudienceRating is the PICS rating of the feed, the rating of the rss
+	// and amazon rss channels.
+	AudienceRating string
+
+	// Category is the internal category of the feed, which is only written
+	// through the TaxonomyMap.
+	Category string
+
+	// TaxonomyMap maps the Category to the values of the taxonomies of the
+	// destinations, keyed by destination then category. The "itunes"
+	// values are written as the itunes:category of rss channels and the
+	// "amazon" values as the amzn:section of the items without one, while
+	// the "googlenews" values are only checked by the GoogleNews profile. A
+	// Category the taxonomy of a destination has no value for is written as
+	// it is, and fails the AmazonStrict, GoogleNews and ApplePodcasts
+	// profiles.
+	TaxonomyMap map[string]map[string]string
+
+	// DefaultEnclosureType is the type written for the enclosures without
+	// a Type, like audio/mpeg for a podcast, when it is not empty.
+	DefaultEnclosureType string
+
+	// SplitOversizedItems splits the items with too long a Content into
+	// continuation items when it is not nil.
+	SplitOversizedItems *SplitOversizedItems
+
+	// JitterIdenticalDates moves the items without an Id which share a
+	// pubDate with an earlier one to the next second no other such item
+	// has in the rss formats, in the order of the items, as readers without
+	// a guid to go by take them for the same item. The Items are left as
+	// they are.
+	JitterIdenticalDates bool
+
+	// DeriveTTLFromSyndication sets the rss ttl from the feed's Syndication
+	// update period and frequency when the feed has no explicit TTL, so
+	// aggregators reading either get the same hint.
+	DeriveTTLFromSyndication bool
+
+	// DCTermsDates adds the dcterms:created and dcterms:modified dates of
+	// the rss items, from their Created and Updated dates, next to pubDate.
+	DCTermsDates bool
+
+	// DefaultDocs uses the rss specification at rssboard.org as the docs
+	// url of rss feeds without a Docs url.
+	DefaultDocs bool
+
+	// AtomItemLinks writes the Links of the rss items, like their language
+	// alternates, as atom:link elements, which rss has no element for.
+	AtomItemLinks bool
+
+	// DefaultIsPermaLink is the isPermaLink of the rss item guids which are
+	// not known to be permalinks or not. Each guid takes it from the first
+	// of:
+	//
+	//	the IsPermaLink of the item
+	//	true, when the guid is the same url as the item's link
+	//	false, when the guid is not an http or https url, like a tag uri
+	//	DefaultIsPermaLink
+	//
+	// This is false by default, as the ids of items are usually uuids or tag
+	// uris rather than urls readers can open. The link of an item is written
+	// as it is whatever its guid.
+	DefaultIsPermaLink bool
+
+	// RoleCreators writes a dc:creator for the Author and each Contributor
+	// of the rss and amazon rss items, with their Role in parentheses, like
+	// "Jason Moiron (photographer)", rather than only the name of the
+	// Author.
+	RoleCreators bool
+
+	// AllowGenerationTimeFallback uses the feed's generation time as the
+	// atom updated date when neither the feed nor any of its items have a
+	// date. Without it such atom feeds fail to encode, keeping the output
+	// deterministic. Feeds without any items always fall back to the
+	// generation time.
+	AllowGenerationTimeFallback bool
+
+	// SubtitleType is the type of the atom subtitle, "text" or "html". The
+	// subtitle is the feed's Subtitle, or its Description without one.
+	SubtitleType string
+
+	// XHTMLContent writes the Content of the atom entries as xhtml rather
+	// than html, which fails for content that is not well-formed xml.
+	XHTMLContent bool
+
+	// EmitImageLinks adds the non-standard but harmless atom links with rel
+	// icon and logo to the feed's icon and logo, for readers which look
+	// for those rather than the icon and logo elements.
+	EmitImageLinks bool
+
+	// StrictEntryUpdated fails to encode atom entries without an Updated or
+	// Created date of their own, listing each of them, rather than using
+	// the feed's updated date for them.
+	StrictEntryUpdated bool
+
+	// OldestFirst writes the atom entries oldest first by their updated
+	// date, as archive pages are read, rather than in the order of the
+	// feed's Items, which are not reordered.
+	OldestFirst bool
+
+	// KeepUnpublishedFor keeps unpublished items in amazon rss feeds,
+	// marked with an amzn:status of deleted, for this long after their
+	// UnpublishedAt date so Amazon removes its copy of them, and then drops
+	// them as reported by WriteWithReport. Other formats, and amazon rss
+	// without it, leave unpublished items out.
+	KeepUnpublishedFor time.Duration
+
+	// AmazonDefaults holds the amzn: values of items which do not set them.
+	// Its Products and Suppress are not used.
+	AmazonDefaults *AmazonItem
+
+	// AutoPosition numbers the amazon rss items without a Position in the
+	// order they are written, skipping the positions other items have, so
+	// Amazon shows them as an ordered collection. Deleted items are not
+	// numbered.
+	AutoPosition bool
+
+	// CDATADescription writes the descriptions of the amazon rss items in
+	// cdata sections, so their html is written as it is rather than
+	// escaped.
+	CDATADescription bool
+
+	// ContentFromDescription writes the description of amazon rss items
+	// without Content as their content:encoded too, as Amazon renders the
+	// full content of an item from it.
+	ContentFromDescription bool
+
+	// Marketplace is the country code of the amazon marketplace the feed is
+	// for, like US or UK. Items whose Marketplaces do not include it are
+	// left out of amazon rss feeds, as reported by WriteWithReport, and the
+	// product urls of other amazon marketplaces are moved to its domain. All
+	// items are written with their urls as they are when it is empty.
+	Marketplace string
+
+	skipped *Report  // the items skipped by WriteWithReport
+	written *[]*Item // the items written, in order, recorded for WriteStats
+}
+
+// FeedType identifies one of the formats a Feed can be written as.
+type FeedType int
+
+const (
+	FeedTypeRss FeedType = iota
+	FeedTypeAtom
+	FeedTypeJSON
+	FeedTypeAmazonRss
+	FeedTypeMerchantRss // a google merchant center product feed, see MerchantRss
+)
+
+// String returns the name of the type as used in log events.
+func (t FeedType) String() string {
+	switch t {
+	case FeedTypeRss:
+		return "rss"
+	case FeedTypeAtom:
+		return "atom"
+	case FeedTypeJSON:
+		return "json"
+	case FeedTypeAmazonRss:
+		return "amazon-rss"
+	case FeedTypeMerchantRss:
+		return "merchant-rss"
+	}
+	return fmt.Sprintf("FeedType(%d)", int(t))
+}
+
+// MIMEType returns the media type of documents of this type, the registered
+// application/feed+json for json feeds.
+func (t FeedType) MIMEType() string {
+	switch t {
+	case FeedTypeAtom:
+		return "application/atom+xml"
+	case FeedTypeJSON:
+		return "application/feed+json"
+	default:
+		return "application/rss+xml"
+	}
 }
 
 // add a new Item to a Feed
@@ -57,14 +416,133 @@ func (f *Feed) Add(item *Item) {
 	f.Items = append(f.Items, item)
 }
 
-// returns the first non-zero time formatted as a string or ""
-func anyTimeFormat(format string, times ...time.Time) string {
+// the generation time of the feed
+func (f *Feed) now() time.Time {
+	if f.Clock != nil {
+		return f.Clock()
+	}
+	return time.Now()
+}
+
+// warn when the feed's small and large icons are the same image, which
+// readers would show at the wrong size for one of them
+func (f *Feed) checkIcons() {
+	if f.Icon != "" && f.Icon == f.Favicon {
+		f.log(EventWarning, "reason", "favicon and icon are the same url "+f.Icon)
+	}
+}
+
+// the copyright of the feed, rendering its CopyrightTemplate without one
+func (f *Feed) copyright() string {
+	if f.Copyright != "" || f.CopyrightTemplate == "" {
+		return f.Copyright
+	}
+	return strings.Replace(f.CopyrightTemplate, "{{year}}", strconv.Itoa(f.now().Year()), -1)
+}
+
+// FirstNonZeroTime returns the first of the times which is not the zero
+// time, or the zero time if they all are.
+func FirstNonZeroTime(times ...time.Time) time.Time {
 	for _, t := range times {
 		if !t.IsZero() {
-			return t.Format(format)
+			return t
 		}
 	}
-	return ""
+	return time.Time{}
+}
+
+// FormatTime formats the first non-zero time with layout, converted to loc
+// unless loc is nil. It returns "" if all the times are zero.
+func FormatTime(layout string, loc *time.Location, times ...time.Time) string {
+	t := FirstNonZeroTime(times...)
+	if t.IsZero() {
+		return ""
+	}
+	if loc != nil {
+		t = t.In(loc)
+	}
+	return t.Format(layout)
+}
+
+// The dates used by the generators, each picking the first non-zero one of:
+//
+//	rss, amazon rss item pubDate     Item.Created, Item.Updated  (pubDate)
+//	rss, amazon rss channel pubDate  Feed.Created, Feed.Updated  (pubDate)
+//	  without items                  then the generation time
+//	  StableLastBuildDate            Feed.Created, left out when it is the
+//	                                 lastBuildDate
+//	rss, amazon rss lastBuildDate    Feed.Updated
+//	  StableLastBuildDate            the latest item instead
+//	  without items                  then Feed.Created, the generation time
+//	rss dcterms:created              Item.Created
+//	rss dcterms:modified             Item.Updated
+//	atom entry updated, tag id date  Item.Updated, Item.Created  (lastModified)
+//	atom feed updated                Feed.Updated, Feed.Created  (lastModified)
+//	                                 then the latest entry, or the generation
+//	                                 time without entries
+//	json date_published              Item.Created
+//	json date_modified               Item.Updated
+//
+// Items are ordered by recency, as in FitToSize, by their lastModified date.
+
+// the date an item was published, falling back to when it was updated
+func (i *Item) pubDate() time.Time {
+	return FirstNonZeroTime(i.Created, i.Updated)
+}
+
+// the date an item was last changed, falling back to when it was created
+func (i *Item) lastModified() time.Time {
+	return FirstNonZeroTime(i.Updated, i.Created)
+}
+
+// the date a feed was published, falling back to when it was updated
+func (f *Feed) pubDate() time.Time {
+	return FirstNonZeroTime(f.Created, f.Updated)
+}
+
+// the date a feed was last changed, falling back to when it was created
+func (f *Feed) lastModified() time.Time {
+	return FirstNonZeroTime(f.Updated, f.Created)
+}
+
+// whether any of the feed's items are written
+func (f *Feed) hasItems() bool {
+	for _, i := range f.Items {
+		if i.published() {
+			return true
+		}
+	}
+	return false
+}
+
+// the lastModified date of the newest written item, or the zero time
+func (f *Feed) latestItem() time.Time {
+	var latest time.Time
+	for _, i := range f.Items {
+		if t := i.lastModified(); i.published() && t.After(latest) {
+			latest = t
+		}
+	}
+	return latest
+}
+
+// the channel pubDate and lastBuildDate of the rss formats, which feeds
+// without items fall back to the generation time for so that an empty feed
+// still says when it was built
+func (f *Feed) channelDates() (pub, build time.Time) {
+	pub, build = f.pubDate(), f.Updated
+	if f.StableLastBuildDate {
+		pub, build = f.Created, f.latestItem()
+	}
+	if !f.hasItems() {
+		now := f.now()
+		pub = FirstNonZeroTime(pub, now)
+		build = FirstNonZeroTime(build, f.Created, now)
+	}
+	if f.StableLastBuildDate && pub.Equal(build) {
+		pub = time.Time{}
+	}
+	return pub, build
 }
 
 // interface used by ToXML to get a object suitable for exporting XML.
@@ -72,74 +550,336 @@ type XmlFeed interface {
 	FeedXml() interface{}
 }
 
+// implemented by XmlFeeds that can tell they would produce an invalid
+// document while building the object to export
+type xmlFeedBuilder interface {
+	buildFeedXml() (interface{}, error)
+}
+
+// the object to export for feed, or an error if it would be invalid
+func feedXml(feed XmlFeed) (interface{}, error) {
+	if b, ok := feed.(xmlFeedBuilder); ok {
+		return b.buildFeedXml()
+	}
+	return feed.FeedXml(), nil
+}
+
+// the item as it is written, with the DefaultEnclosureType of the feed as
+// the type of its enclosure when it has none
+func (f *Feed) enclosureType(i *Item) *Item {
+	if f.DefaultEnclosureType == "" || i.Enclosure == nil || i.Enclosure.Type != "" {
+		return i
+	}
+	item, enclosure := *i, *i.Enclosure
+	enclosure.Type = f.DefaultEnclosureType
+	item.Enclosure = &enclosure
+	return &item
+}
+
+// the Feed of the wrappers like Rss and Atom, for their output options
+func (f *Feed) outputFeed() *Feed {
+	return f
+}
+
+// the Feed holding the output options of feed, or nil for the XmlFeeds
+// which are not built from a Feed
+func outputOptions(feed XmlFeed) *Feed {
+	if f, ok := feed.(interface {
+		outputFeed() *Feed
+	}); ok {
+		return f.outputFeed()
+	}
+	return nil
+}
+
+// whether the marshaled xml of feed is rewritten by rewriteXML
+func rewritesXML(feed XmlFeed) bool {
+	f := outputOptions(feed)
+	return f != nil && (f.SelfCloseEmpty || f.Canonical || f.Quirks.any() || f.CheckUTF8 == UTF8Replace ||
+		f.Preview != nil)
+}
+
+// rewrite the marshaled xml of feed according to its output options
+func rewriteXML(feed XmlFeed, data []byte) ([]byte, error) {
+	f := outputOptions(feed)
+	if f == nil {
+		return data, nil
+	}
+	if f.CheckUTF8 == UTF8Replace {
+		data = toValidUTF8(data)
+	}
+	if f.Canonical {
+		var err error
+		if data, err = canonicalXML(data); err != nil {
+			return nil, err
+		}
+	} else {
+		if f.SelfCloseEmpty {
+			data = selfCloseEmpty(data)
+		}
+		data = f.Quirks.apply(data)
+	}
+	if f.Preview != nil {
+		data = append(f.previewComment(), data...)
+	}
+	return data, nil
+}
+
+// rewrite the empty element pairs of doc, like <link></link>, as <link/>
+// leaving cdata sections, comments and processing instructions as they are
+func selfCloseEmpty(doc []byte) []byte {
+	var out bytes.Buffer
+	var start []byte // a start tag which may be closed right away
+	scanXML(doc, func(chunk []byte, tag bool) {
+		if start != nil {
+			if tag && bytes.HasPrefix(chunk, []byte("</")) && tagName(chunk) == tagName(start) {
+				out.Write(start[:len(start)-1])
+				out.WriteString("/>")
+				start = nil
+				return
+			}
+			out.Write(start)
+			start = nil
+		}
+		if tag && chunk[1] != '/' && !bytes.HasSuffix(chunk, []byte("/>")) {
+			start = chunk
+			return
+		}
+		out.Write(chunk)
+	})
+	out.Write(start)
+	return out.Bytes()
+}
+
+// escape s as the value of a double quoted attribute written by hand
+// rather than by encoding/xml, replacing the characters xml does not allow
+// with U+FFFD as encoding/xml does
+func escapeAttr(s string) string {
+	var out bytes.Buffer
+	for _, r := range s {
+		switch {
+		case r == '&':
+			out.WriteString("&amp;")
+		case r == '<':
+			out.WriteString("&lt;")
+		case r == '"':
+			out.WriteString("&quot;")
+		case r == '\t':
+			out.WriteString("&#x9;")
+		case r == '\n':
+			out.WriteString("&#xA;")
+		case r == '\r':
+			out.WriteString("&#xD;")
+		case r < ' ' || r == 0xFFFE || r == 0xFFFF || r >= 0xD800 && r <= 0xDFFF || r > 0x10FFFF:
+			out.WriteRune('\uFFFD')
+		default:
+			out.WriteRune(r)
+		}
+	}
+	return out.String()
+}
+
+// the name of the format of feed in the errors of marshaling it
+func xmlFormatName(feed XmlFeed) string {
+	switch feed.(type) {
+	case *Rss, *RssFeed:
+		return FeedTypeRss.String()
+	case *Atom, *AtomFeed:
+		return FeedTypeAtom.String()
+	case *AmazonRss, *AmazonRssFeed:
+		return FeedTypeAmazonRss.String()
+	case *MerchantRss, *MerchantRssFeed:
+		return FeedTypeMerchantRss.String()
+	}
+	return "xml"
+}
+
+// the error of marshaling feed, naming its format
+func marshalError(feed XmlFeed, err error) error {
+	return fmt.Errorf("feeds: encoding %s: %v", xmlFormatName(feed), err)
+}
+
 // turn a feed object (either a Feed, AtomFeed, or RssFeed) into xml
-// returns an error if xml marshaling fails
+// returns an error if xml marshaling fails, and never a partial document
 func ToXML(feed XmlFeed) (string, error) {
-	x := feed.FeedXml()
+	x, err := feedXml(feed)
+	if err != nil {
+		return "", err
+	}
 	data, err := xml.MarshalIndent(x, "", "  ")
 	if err != nil {
+		return "", marshalError(feed, err)
+	}
+	if data, err = rewriteXML(feed, data); err != nil {
 		return "", err
 	}
-	// strip empty line from default xml header
-	s := xml.Header[:len(xml.Header)-1] + string(data)
+	// default xml header without its empty line, and the stylesheet
+	s := xmlPrologue(feed) + string(data)
 	return s, nil
 }
 
+// the xml declaration of feed without its newline, followed by the
+// processing instruction of its stylesheet when it has one
+func xmlPrologue(feed XmlFeed) string {
+	header := xml.Header[:len(xml.Header)-1]
+	f := outputOptions(feed)
+	if f == nil || f.Stylesheet == "" {
+		return header
+	}
+	typ := "text/xsl"
+	if strings.HasSuffix(strings.ToLower(f.Stylesheet), ".css") {
+		typ = "text/css"
+	}
+	return header + "\n" + `<?xml-stylesheet type="` + typ + `" href="` + escapeAttr(f.Stylesheet) + `"?>`
+}
+
 // WriteXML writes a feed object (either a Feed, AtomFeed, or RssFeed) as XML into
-// the writer. Returns an error if XML marshaling fails.
+// the writer. Returns an error if XML marshaling fails, after writing the part
+// of the document before the failure.
 func WriteXML(feed XmlFeed, w io.Writer) error {
-	x := feed.FeedXml()
-	// write default xml header, without the newline
-	if _, err := w.Write([]byte(xml.Header[:len(xml.Header)-1])); err != nil {
+	x, err := feedXml(feed)
+	if err != nil {
+		return err
+	}
+	// write default xml header, without the newline, and the stylesheet
+	if _, err := w.Write([]byte(xmlPrologue(feed))); err != nil {
+		return err
+	}
+	if rewritesXML(feed) {
+		data, err := xml.MarshalIndent(x, "", "  ")
+		if err != nil {
+			return marshalError(feed, err)
+		}
+		if data, err = rewriteXML(feed, data); err != nil {
+			return err
+		}
+		_, err = w.Write(data)
 		return err
 	}
 	e := xml.NewEncoder(w)
 	e.Indent("", "  ")
-	return e.Encode(x)
+	if err := e.Encode(x); err != nil {
+		return marshalError(feed, err)
+	}
+	return nil
 }
 
 // creates an Atom representation of this feed
-func (f *Feed) ToAtom() (string, error) {
-	a := &Atom{f}
-	return ToXML(a)
+func (f *Feed) ToAtom() (s string, err error) {
+	err = f.generate(FeedTypeAtom, func() error {
+		s, err = ToXML(&Atom{Feed: f})
+		return err
+	})
+	return s, err
 }
 
 // WriteAtom writes an Atom representation of this feed to the writer.
 func (f *Feed) WriteAtom(w io.Writer) error {
-	return WriteXML(&Atom{f}, w)
+	return f.generate(FeedTypeAtom, func() error {
+		return WriteXML(&Atom{Feed: f}, w)
+	})
 }
 
 // creates an Rss representation of this feed
-func (f *Feed) ToRss() (string, error) {
-	r := &Rss{f}
-	return ToXML(r)
+func (f *Feed) ToRss() (s string, err error) {
+	err = f.generate(FeedTypeRss, func() error {
+		s, err = ToXML(&Rss{Feed: f})
+		return err
+	})
+	return s, err
 }
 
 // creates an AmazonRss representation of this feed
-func (f *Feed) ToAmazonRss() (string, error) {
-	r := &AmazonRss{f}
-	return ToXML(r)
+func (f *Feed) ToAmazonRss() (s string, err error) {
+	err = f.generate(FeedTypeAmazonRss, func() error {
+		s, err = ToXML(&AmazonRss{Feed: f})
+		return err
+	})
+	return s, err
+}
+
+// creates a Google Merchant Center product feed from the products of this
+// feed's items, see MerchantRss
+func (f *Feed) ToMerchantRss() (s string, err error) {
+	err = f.generate(FeedTypeMerchantRss, func() error {
+		s, err = ToXML(&MerchantRss{f})
+		return err
+	})
+	return s, err
 }
 
 // WriteRss writes an RSS representation of this feed to the writer.
 func (f *Feed) WriteRss(w io.Writer) error {
-	return WriteXML(&Rss{f}, w)
+	return f.generate(FeedTypeRss, func() error {
+		return WriteXML(&Rss{Feed: f}, w)
+	})
+}
+
+// WriteAmazonRss writes an AmazonRss representation of this feed to the writer.
+func (f *Feed) WriteAmazonRss(w io.Writer) error {
+	return f.generate(FeedTypeAmazonRss, func() error {
+		return WriteXML(&AmazonRss{Feed: f}, w)
+	})
+}
+
+// WriteMerchantRss writes a Google Merchant Center product feed of the
+// products of this feed's items to the writer, see MerchantRss.
+func (f *Feed) WriteMerchantRss(w io.Writer) error {
+	return f.generate(FeedTypeMerchantRss, func() error {
+		return WriteXML(&MerchantRss{f}, w)
+	})
 }
 
 // ToJSON creates a JSON Feed representation of this feed
-func (f *Feed) ToJSON() (string, error) {
-	j := &JSON{f}
-	return j.ToJSON()
+func (f *Feed) ToJSON() (s string, err error) {
+	err = f.generate(FeedTypeJSON, func() error {
+		s, err = (&JSON{f}).ToJSON()
+		return err
+	})
+	return s, err
 }
 
 // WriteJSON writes an JSON representation of this feed to the writer.
 func (f *Feed) WriteJSON(w io.Writer) error {
-	j := &JSON{f}
-	feed := j.JSONFeed()
+	return f.generate(FeedTypeJSON, func() error {
+		feed := (&JSON{f}).JSONFeed()
+		e := json.NewEncoder(w)
+		e.SetIndent("", "  ")
+		if err := e.Encode(feed); err != nil {
+			return fmt.Errorf("feeds: encoding json: %v", err)
+		}
+		return nil
+	})
+}
+
+// ToMap returns the JSON Feed representation of this feed as a generic map
+// for templates, or the error of encoding it.
+func (f *Feed) ToMap() (map[string]interface{}, error) {
+	var m map[string]interface{}
+	err := f.generate(FeedTypeJSON, func() (err error) {
+		m, err = (&JSON{f}).JSONFeed().ToMap()
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
 
-	e := json.NewEncoder(w)
-	e.SetIndent("", "  ")
-	return e.Encode(feed)
+// write the representation of this feed selected by t to the writer
+func (f *Feed) write(w io.Writer, t FeedType) error {
+	switch t {
+	case FeedTypeAtom:
+		return f.WriteAtom(w)
+	case FeedTypeJSON:
+		return f.WriteJSON(w)
+	case FeedTypeAmazonRss:
+		return f.WriteAmazonRss(w)
+	case FeedTypeMerchantRss:
+		return f.WriteMerchantRss(w)
+	default:
+		return f.WriteRss(w)
+	}
 }
 
 // Sort sorts the Items in the feed with the given less function.
@@ -149,3 +889,45 @@ func (f *Feed) Sort(less func(a, b *Item) bool) {
 	}
 	sort.SliceStable(f.Items, lessFunc)
 }
+
+// MostRecent returns the published item with the latest Updated date, or
+// Created date without one, the first of them when several share it. It is
+// nil when the feed has no published items. The Items are left as they are
+// and need not be sorted.
+func (f *Feed) MostRecent() *Item {
+	var recent *Item
+	for _, i := range f.Items {
+		if i == nil || !i.published() {
+			continue
+		}
+		if recent == nil || i.lastModified().After(recent.lastModified()) {
+			recent = i
+		}
+	}
+	return recent
+}
+
+// TrimEmpty removes the items whose title, description and content are all
+// empty or whitespace, and returns how many it removed. Items with a link or
+// an enclosure are kept, as they can be valid without any text.
+func (f *Feed) TrimEmpty() int {
+	kept := f.Items[:0]
+	for _, i := range f.Items {
+		if i == nil || !i.empty() {
+			kept = append(kept, i)
+		}
+	}
+	removed := len(f.Items) - len(kept)
+	for n := len(kept); n < len(f.Items); n++ {
+		f.Items[n] = nil
+	}
+	f.Items = kept
+	return removed
+}
+
+// whether the item has no text, link or enclosure
+func (i *Item) empty() bool {
+	blank := func(s string) bool { return strings.TrimSpace(s) == "" }
+	return blank(i.Title) && blank(i.Description) && blank(i.Content) &&
+		(i.Link == nil || blank(i.Link.Href)) && (i.Enclosure == nil || blank(i.Enclosure.Url))
+}
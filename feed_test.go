@@ -2,7 +2,16 @@ package feeds
 
 import (
 	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
 	"testing"
+	"text/template"
 	"time"
 )
 
@@ -107,7 +116,7 @@ var rssOutput = `<?xml version="1.0" encoding="UTF-8"?><rss version="2.0" xmlns:
 </rss>`
 
 var jsonOutput = `{
-  "version": "https://jsonfeed.org/version/1",
+  "version": "https://jsonfeed.org/version/1.1",
   "title": "jmoiron.net blog",
   "home_page_url": "http://jmoiron.net/blog",
   "description": "discussion about tech, footie, photos",
@@ -146,7 +155,14 @@ var jsonOutput = `{
       "url": "http://example.com/RickRoll.mp3",
       "title": "Never Gonna Give You Up Mp3",
       "summary": "Never gonna give you up - Never gonna let you down.",
-      "date_published": "2013-01-16T21:52:35-05:00"
+      "date_published": "2013-01-16T21:52:35-05:00",
+      "attachments": [
+        {
+          "url": "http://example.com/RickRoll.mp3",
+          "mime_type": "audio/mpeg",
+          "size": 123456
+        }
+      ]
     },
     {
       "id": "",
@@ -347,7 +363,7 @@ var rssOutputSorted = `<?xml version="1.0" encoding="UTF-8"?><rss version="2.0"
 </rss>`
 
 var jsonOutputSorted = `{
-  "version": "https://jsonfeed.org/version/1",
+  "version": "https://jsonfeed.org/version/1.1",
   "title": "jmoiron.net blog",
   "home_page_url": "http://jmoiron.net/blog",
   "description": "discussion about tech, footie, photos",
@@ -481,3 +497,2082 @@ func TestFeedSorted(t *testing.T) {
 		t.Errorf("JSON not what was expected.  Got:\n||%s||\n\nExpected:\n||%s||\n", got, jsonOutputSorted)
 	}
 }
+
+func TestFeedLanguage(t *testing.T) {
+	feed := &Feed{
+		Title:    "jmoiron.net blog",
+		Link:     &Link{Href: "http://jmoiron.net/blog"},
+		Language: "en-us",
+		Created:  time.Now(),
+	}
+	feed.Items = []*Item{
+		{Title: "Limiting Concurrency in Go", Link: &Link{Href: "http://jmoiron.net/blog/limiting-concurrency-in-go/"}},
+		{Title: "Concurrence limitée en Go", Link: &Link{Href: "http://jmoiron.net/blog/fr/limiting-concurrency-in-go/"}, Language: "fr"},
+	}
+
+	atom, err := feed.ToAtom()
+	if err != nil {
+		t.Errorf("unexpected error encoding Atom: %v", err)
+	}
+	for _, want := range []string{`<feed xmlns="http://www.w3.org/2005/Atom" xml:lang="en-us">`, `<entry xml:lang="fr">`} {
+		if !strings.Contains(atom, want) {
+			t.Errorf("Atom missing %q.  Got:\n%s\n", want, atom)
+		}
+	}
+	if strings.Count(atom, "xml:lang") != 2 {
+		t.Errorf("Atom should only override the language on one entry.  Got:\n%s\n", atom)
+	}
+
+	rss, err := feed.ToRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding RSS: %v", err)
+	}
+	if !strings.Contains(rss, "<language>en-us</language>") {
+		t.Errorf("Rss missing language.  Got:\n%s\n", rss)
+	}
+
+	amazon, err := feed.ToAmazonRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding Amazon RSS: %v", err)
+	}
+	if !strings.Contains(amazon, "<language>en-us</language>") {
+		t.Errorf("AmazonRss missing language.  Got:\n%s\n", amazon)
+	}
+
+	jsonFeed := (&JSON{Feed: feed}).JSONFeed()
+	if jsonFeed.Language != "en-us" {
+		t.Errorf("JSON feed language = %q, want %q", jsonFeed.Language, "en-us")
+	}
+	if jsonFeed.Items[0].Language != "" || jsonFeed.Items[1].Language != "fr" {
+		t.Errorf("JSON item languages = %q, %q, want \"\", \"fr\"", jsonFeed.Items[0].Language, jsonFeed.Items[1].Language)
+	}
+}
+
+func TestRssDeriveTTLFromSyndication(t *testing.T) {
+	tests := []struct {
+		ttl         int
+		syndication *Syndication
+		derive      bool
+		want        int
+	}{
+		{0, &Syndication{UpdatePeriod: "daily", UpdateFrequency: 2}, true, 720},
+		{0, &Syndication{UpdatePeriod: "hourly"}, true, 60},
+		{0, &Syndication{UpdatePeriod: "weekly", UpdateFrequency: 7}, true, 1440},
+		{0, &Syndication{UpdatePeriod: "fortnightly"}, true, 0},
+		{0, nil, true, 0},
+		{30, &Syndication{UpdatePeriod: "daily", UpdateFrequency: 2}, true, 30},
+		{0, &Syndication{UpdatePeriod: "daily", UpdateFrequency: 2}, false, 0},
+	}
+	for _, test := range tests {
+		feed := &Feed{
+			Title:       "jmoiron.net blog",
+			Link:        &Link{Href: "http://jmoiron.net/blog"},
+			TTL:         test.ttl,
+			Syndication: test.syndication,
+		}
+		feed.DeriveTTLFromSyndication = test.derive
+		r := &Rss{Feed: feed}
+		if got := r.RssFeed().Ttl; got != test.want {
+			t.Errorf("ttl %d, %+v, derive %v: got ttl %d, want %d", test.ttl, test.syndication, test.derive, got, test.want)
+		}
+	}
+}
+
+func TestRssSyndication(t *testing.T) {
+	feed := &Feed{
+		Title: "jmoiron.net blog",
+		Link:  &Link{Href: "http://jmoiron.net/blog"},
+	}
+	rss, err := feed.ToRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding RSS: %v", err)
+	}
+	if strings.Contains(rss, "xmlns:sy") {
+		t.Errorf("Rss should not declare the syndication namespace.  Got:\n%s\n", rss)
+	}
+
+	base, _ := time.Parse(time.RFC3339, "2013-01-16T21:52:35-05:00")
+	feed.Syndication = &Syndication{UpdatePeriod: "daily", UpdateFrequency: 2, UpdateBase: base}
+	rss, err = feed.ToRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding RSS: %v", err)
+	}
+	for _, want := range []string{
+		`xmlns:sy="http://purl.org/rss/1.0/modules/syndication/"`,
+		"<sy:updatePeriod>daily</sy:updatePeriod>",
+		"<sy:updateFrequency>2</sy:updateFrequency>",
+		"<sy:updateBase>2013-01-16T21:52:35-05:00</sy:updateBase>",
+	} {
+		if !strings.Contains(rss, want) {
+			t.Errorf("Rss missing %q.  Got:\n%s\n", want, rss)
+		}
+	}
+}
+
+func TestAtomUpdatedFallback(t *testing.T) {
+	now, err := time.Parse(time.RFC3339, "2013-01-16T21:52:35-05:00")
+	if err != nil {
+		t.Error(err)
+	}
+	tz := time.FixedZone("EST", -5*60*60)
+	now = now.In(tz)
+	later := now.Add(time.Hour)
+	clock := func() time.Time { return later.Add(time.Hour) }
+
+	tests := []struct {
+		name         string
+		feed         *Feed
+		allow        bool
+		feedUpdated  string
+		entryUpdated []string
+	}{
+		{
+			name:         "updated",
+			feed:         &Feed{Updated: later, Created: now, Items: []*Item{{Updated: later, Created: now}}},
+			feedUpdated:  "2013-01-16T22:52:35-05:00",
+			entryUpdated: []string{"2013-01-16T22:52:35-05:00"},
+		},
+		{
+			name:         "created",
+			feed:         &Feed{Created: now, Items: []*Item{{Created: now}}},
+			feedUpdated:  "2013-01-16T21:52:35-05:00",
+			entryUpdated: []string{"2013-01-16T21:52:35-05:00"},
+		},
+		{
+			name:         "entry falls back to feed",
+			feed:         &Feed{Updated: later, Items: []*Item{{Created: now}, {}}},
+			feedUpdated:  "2013-01-16T22:52:35-05:00",
+			entryUpdated: []string{"2013-01-16T21:52:35-05:00", "2013-01-16T22:52:35-05:00"},
+		},
+		{
+			name:         "feed falls back to latest entry",
+			feed:         &Feed{Items: []*Item{{Created: now}, {Updated: later, Created: now}, {}}},
+			feedUpdated:  "2013-01-16T22:52:35-05:00",
+			entryUpdated: []string{"2013-01-16T21:52:35-05:00", "2013-01-16T22:52:35-05:00", "2013-01-16T22:52:35-05:00"},
+		},
+		{
+			name:         "generation time",
+			feed:         &Feed{Clock: clock, Items: []*Item{{}}},
+			allow:        true,
+			feedUpdated:  "2013-01-16T23:52:35-05:00",
+			entryUpdated: []string{"2013-01-16T23:52:35-05:00"},
+		},
+	}
+	for _, test := range tests {
+		test.feed.Title = "jmoiron.net blog"
+		test.feed.Link = &Link{Href: "http://jmoiron.net/blog"}
+		for _, i := range test.feed.Items {
+			i.Link = &Link{Href: "http://jmoiron.net/blog/post"}
+		}
+		test.feed.AllowGenerationTimeFallback = test.allow
+		a := &Atom{Feed: test.feed}
+		if _, err := ToXML(a); err != nil {
+			t.Errorf("%s: unexpected error encoding Atom: %v", test.name, err)
+		}
+		feed := a.AtomFeed()
+		if feed.Updated != test.feedUpdated {
+			t.Errorf("%s: feed updated = %q, want %q", test.name, feed.Updated, test.feedUpdated)
+		}
+		for i, e := range feed.Entries {
+			if e.Updated != test.entryUpdated[i] {
+				t.Errorf("%s: entry %d updated = %q, want %q", test.name, i, e.Updated, test.entryUpdated[i])
+			}
+		}
+	}
+}
+
+func TestAtomUpdatedMissing(t *testing.T) {
+	feed := &Feed{
+		Title: "jmoiron.net blog",
+		Link:  &Link{Href: "http://jmoiron.net/blog"},
+		Clock: func() time.Time { panic("the clock should not be consulted") },
+		Items: []*Item{{Title: "Undated", Link: &Link{Href: "http://jmoiron.net/blog/undated"}}},
+	}
+	if atom, err := feed.ToAtom(); err == nil || atom != "" {
+		t.Errorf("expected an error and no output for an undated feed, got %v:\n%s", err, atom)
+	}
+	var buf bytes.Buffer
+	if err := feed.WriteAtom(&buf); err == nil || buf.Len() != 0 {
+		t.Errorf("expected an error and no output for an undated feed, got %v:\n%s", err, buf.String())
+	}
+}
+
+func TestAtomPagingLinks(t *testing.T) {
+	feed := &Feed{
+		Title:   "jmoiron.net blog",
+		Link:    &Link{Href: "http://jmoiron.net/blog"},
+		Created: time.Now(),
+		Links: []*Link{
+			{Href: "http://jmoiron.net/blog/atom?page=1", Rel: "first"},
+			{Href: "http://jmoiron.net/blog/atom?page=2", Rel: "previous"},
+			{Href: "http://jmoiron.net/blog/atom?page=4", Rel: "next"},
+			{Href: "http://jmoiron.net/blog/atom?page=9", Rel: "last"},
+			{Href: "http://jmoiron.net/blog/archive.xml", Rel: "next", Type: "application/rss+xml"},
+			{Href: "http://jmoiron.net/blog/hub", Rel: "hub"},
+		},
+	}
+	atom, err := feed.ToAtom()
+	if err != nil {
+		t.Errorf("unexpected error encoding Atom: %v", err)
+	}
+	for _, want := range []string{
+		`<link href="http://jmoiron.net/blog/atom?page=1" rel="first" type="application/atom+xml"></link>`,
+		`<link href="http://jmoiron.net/blog/atom?page=2" rel="previous" type="application/atom+xml"></link>`,
+		`<link href="http://jmoiron.net/blog/atom?page=4" rel="next" type="application/atom+xml"></link>`,
+		`<link href="http://jmoiron.net/blog/atom?page=9" rel="last" type="application/atom+xml"></link>`,
+		`<link href="http://jmoiron.net/blog/archive.xml" rel="next" type="application/rss+xml"></link>`,
+		`<link href="http://jmoiron.net/blog/hub" rel="hub"></link>`,
+	} {
+		if !strings.Contains(atom, want) {
+			t.Errorf("Atom missing %s.  Got:\n%s\n", want, atom)
+		}
+	}
+}
+
+func TestCustomLinkRels(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2013-01-16T21:52:35-05:00")
+	feed := &Feed{
+		Title:   "jmoiron.net blog",
+		Link:    &Link{Href: "http://jmoiron.net/blog"},
+		Author:  &Author{Name: "Jason Moiron"},
+		Created: now,
+		Links:   []*Link{{Href: "http://jmoiron.net/blog/payment", Rel: "payment"}},
+		Items: []*Item{{
+			Id:      "tag:jmoiron.net,2013-01-16:/blog/episode-1/",
+			Title:   "Episode 1",
+			Content: "<p>The first episode.</p>",
+			Created: now,
+			Link:    &Link{Href: "http://jmoiron.net/blog/episode-1/", Rel: "http://podlove.org/simple-chapters"},
+			Links: []*Link{
+				{Href: "http://jmoiron.net/blog/episode-1/#t=1:30", Rel: "http://podlove.org/deep-link", Type: "text/html"},
+				{Href: "http://jmoiron.net/blog/episode-1/chapters", Rel: "HTTP://Example.com/Rels/Mixed-Case?a=1&b=2"},
+			},
+		}},
+	}
+	atom, err := feed.ToAtom()
+	if err != nil {
+		t.Fatalf("unexpected error encoding Atom: %v", err)
+	}
+	feed.AtomItemLinks = true
+	rss, err := ToXML(&Rss{Feed: feed})
+	if err != nil {
+		t.Fatalf("unexpected error encoding RSS: %v", err)
+	}
+	for _, want := range []string{
+		`<link href="http://jmoiron.net/blog/payment" rel="payment"></link>`,
+		`<link href="http://jmoiron.net/blog/episode-1/" rel="http://podlove.org/simple-chapters"></link>`,
+		`<link href="http://jmoiron.net/blog/episode-1/#t=1:30" rel="http://podlove.org/deep-link" type="text/html"></link>`,
+		`<link href="http://jmoiron.net/blog/episode-1/chapters" rel="HTTP://Example.com/Rels/Mixed-Case?a=1&amp;b=2"></link>`,
+	} {
+		if !strings.Contains(atom, want) {
+			t.Errorf("Atom missing %s.  Got:\n%s\n", want, atom)
+		}
+	}
+	for _, want := range []string{
+		`<atom:link href="http://jmoiron.net/blog/episode-1/#t=1:30" rel="http://podlove.org/deep-link" type="text/html"></atom:link>`,
+		`<atom:link href="http://jmoiron.net/blog/episode-1/chapters" rel="HTTP://Example.com/Rels/Mixed-Case?a=1&amp;b=2"></atom:link>`,
+	} {
+		if !strings.Contains(rss, want) {
+			t.Errorf("Rss missing %s.  Got:\n%s\n", want, rss)
+		}
+	}
+	if err := feed.ValidateAtom(); err != nil {
+		t.Errorf("unexpected error validating custom rels: %v", err)
+	}
+	if issues, err := LintAtom(strings.NewReader(atom)); err != nil || len(issues) != 0 {
+		t.Errorf("unexpected issues linting custom rels: %v, %v", issues, err)
+	}
+}
+
+func TestFeedItemWithoutLink(t *testing.T) {
+	now, err := time.Parse(time.RFC3339, "2013-01-16T21:52:35-05:00")
+	if err != nil {
+		t.Error(err)
+	}
+	feed := &Feed{
+		Title:   "jmoiron.net microblog",
+		Link:    &Link{Href: "http://jmoiron.net/micro"},
+		Created: now,
+	}
+	feed.Items = []*Item{{
+		Description: "Just realised footie season starts next week",
+		Id:          "tag:jmoiron.net,2013-01-16:micro/1",
+		Created:     now,
+	}}
+
+	rss, err := feed.ToRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding RSS: %v", err)
+	}
+	item := rss[strings.Index(rss, "<item>"):]
+	want := `<item>
+      <description>Just realised footie season starts next week</description>
+      <guid isPermaLink="false">tag:jmoiron.net,2013-01-16:micro/1</guid>`
+	if !strings.HasPrefix(item, want) || strings.Contains(item, "<link>") || strings.Contains(item, "<title>") {
+		t.Errorf("Rss item not what was expected.  Got:\n%s\n\nExpected it to start with:\n%s\n", item, want)
+	}
+
+	atom, err := feed.ToAtom()
+	if err != nil {
+		t.Errorf("unexpected error encoding Atom: %v", err)
+	}
+	entry := atom[strings.Index(atom, "<entry>"):]
+	if !strings.Contains(entry, "<id>tag:jmoiron.net,2013-01-16:micro/1</id>") || strings.Contains(entry, "<link") {
+		t.Errorf("Atom entry not what was expected.  Got:\n%s\n", entry)
+	}
+
+	if _, err := feed.ToJSON(); err != nil {
+		t.Errorf("unexpected error encoding JSON: %v", err)
+	}
+}
+
+func TestFeedNilPointers(t *testing.T) {
+	now, err := time.Parse(time.RFC3339, "2013-01-16T21:52:35-05:00")
+	if err != nil {
+		t.Error(err)
+	}
+	// each bit of the mask sets one of the optional pointers
+	const (
+		feedLink = 1 << iota
+		feedAuthor
+		feedImage
+		itemLink
+		itemSource
+		itemAuthor
+		itemEnclosure
+		itemAmazon
+		all
+	)
+	for mask := 0; mask < all; mask++ {
+		feed := &Feed{Title: "jmoiron.net blog", Created: now}
+		item := &Item{Title: "Limiting Concurrency in Go", Created: now}
+		feed.Items = []*Item{item}
+		if mask&feedLink != 0 {
+			feed.Link = &Link{Href: "http://jmoiron.net/blog"}
+		}
+		if mask&feedAuthor != 0 {
+			feed.Author = &Author{Name: "Jason Moiron", Email: "jmoiron@jmoiron.net"}
+		}
+		if mask&feedImage != 0 {
+			feed.Image = &Image{Url: "http://jmoiron.net/logo.png"}
+		}
+		if mask&itemLink != 0 {
+			item.Link = &Link{Href: "http://jmoiron.net/blog/limiting-concurrency-in-go/"}
+		}
+		if mask&itemSource != 0 {
+			item.Source = &Link{Href: "http://example.com/limiting-concurrency-in-go/"}
+		}
+		if mask&itemAuthor != 0 {
+			item.Author = &Author{Name: "Jason Moiron"}
+		}
+		if mask&itemEnclosure != 0 {
+			item.Enclosure = &Enclosure{Url: "http://example.com/cover.jpg", Length: "123456", Type: "image/jpg"}
+		}
+		if mask&itemAmazon != 0 {
+			item.Amazon = &AmazonItem{Products: []*AmazonProduct{{URL: "https://www.amazon.com/dp/B01"}}}
+		}
+
+		encoders := map[string]func() (string, error){
+			"Atom":        feed.ToAtom,
+			"Rss":         feed.ToRss,
+			"JSON":        feed.ToJSON,
+			"AmazonRss":   feed.ToAmazonRss,
+			"MerchantRss": feed.ToMerchantRss,
+		}
+		for name, encode := range encoders {
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						t.Errorf("%s panicked with pointers %b set: %v", name, mask, r)
+					}
+				}()
+				_, err := encode()
+				if name == "Atom" && mask&feedLink == 0 {
+					// the id of an atom feed is taken from its link
+					if err != errAtomId {
+						t.Errorf("%s without a link should fail with %q, got %v", name, errAtomId, err)
+					}
+				} else if err != nil {
+					t.Errorf("%s failed with pointers %b set: %v", name, mask, err)
+				}
+			}()
+		}
+	}
+}
+
+func TestFormatTime(t *testing.T) {
+	created, _ := time.Parse(time.RFC3339, "2013-01-16T21:52:35-05:00")
+	updated := created.Add(24 * time.Hour)
+
+	if got := FirstNonZeroTime(time.Time{}, updated, created); !got.Equal(updated) {
+		t.Errorf("FirstNonZeroTime: got %v, want %v", got, updated)
+	}
+	if got := FirstNonZeroTime(time.Time{}); !got.IsZero() {
+		t.Errorf("FirstNonZeroTime of zero times: got %v, want the zero time", got)
+	}
+	if got := FormatTime(time.RFC3339, nil); got != "" {
+		t.Errorf("FormatTime without times: got %q, want \"\"", got)
+	}
+	if got := FormatTime(time.RFC3339, nil, time.Time{}, created); got != "2013-01-16T21:52:35-05:00" {
+		t.Errorf("FormatTime: got %q", got)
+	}
+	if got := FormatTime(time.RFC3339, time.UTC, created); got != "2013-01-17T02:52:35Z" {
+		t.Errorf("FormatTime in UTC: got %q", got)
+	}
+}
+
+func TestDatePrecedence(t *testing.T) {
+	created, _ := time.Parse(time.RFC3339, "2013-01-16T21:52:35-05:00")
+	updated := created.Add(24 * time.Hour)
+	feed := &Feed{
+		Title:   "jmoiron.net blog",
+		Link:    &Link{Href: "http://jmoiron.net/blog"},
+		Created: created,
+		Updated: updated,
+		Items: []*Item{
+			{Title: "both", Link: &Link{Href: "http://jmoiron.net/blog/both/"}, Created: created, Updated: updated},
+			{Title: "created", Link: &Link{Href: "http://jmoiron.net/blog/created/"}, Created: created},
+			{Title: "updated", Link: &Link{Href: "http://jmoiron.net/blog/updated/"}, Updated: updated},
+		},
+	}
+	rfc1123 := func(t time.Time) string { return t.Format(time.RFC1123Z) }
+	rfc3339 := func(t time.Time) string { return t.Format(time.RFC3339) }
+
+	rss := (&Rss{Feed: feed}).RssFeed()
+	if rss.PubDate != rfc1123(created) || rss.LastBuildDate != rfc1123(updated) {
+		t.Errorf("Rss channel: got pubDate %q lastBuildDate %q", rss.PubDate, rss.LastBuildDate)
+	}
+	amazon := (&AmazonRss{Feed: feed}).AmazonRssFeed()
+	if amazon.PubDate != rfc1123(created) || amazon.LastBuildDate != rfc1123(updated) {
+		t.Errorf("AmazonRss channel: got pubDate %q lastBuildDate %q", amazon.PubDate, amazon.LastBuildDate)
+	}
+	atom := (&Atom{Feed: feed}).AtomFeed()
+	if atom.Updated != rfc3339(updated) {
+		t.Errorf("Atom feed: got updated %q", atom.Updated)
+	}
+	json := (&JSON{feed}).JSONFeed()
+
+	// item pubDate prefers Created, atom updated prefers Updated
+	for n, want := range []struct{ pub, updated time.Time }{
+		{created, updated},
+		{created, created},
+		{updated, updated},
+	} {
+		if got := rss.Items[n].PubDate; got != rfc1123(want.pub) {
+			t.Errorf("Rss item %d: got pubDate %q, want %q", n, got, rfc1123(want.pub))
+		}
+		if got := amazon.Items[n].PubDate; got != rfc1123(want.pub) {
+			t.Errorf("AmazonRss item %d: got pubDate %q, want %q", n, got, rfc1123(want.pub))
+		}
+		if got := atom.Entries[n].Updated; got != rfc3339(want.updated) {
+			t.Errorf("Atom entry %d: got updated %q, want %q", n, got, rfc3339(want.updated))
+		}
+		if got, tag := atom.Entries[n].Id, want.updated.Format("2006-01-02"); !strings.Contains(got, tag) {
+			t.Errorf("Atom entry %d: got id %q, want date %s", n, got, tag)
+		}
+	}
+
+	// json keeps the two dates apart
+	for n, i := range feed.Items {
+		item := json.Items[n]
+		if (item.PublishedDate != nil) != !i.Created.IsZero() || (item.ModifiedDate != nil) != !i.Updated.IsZero() {
+			t.Errorf("JSON item %d: got published %v modified %v", n, item.PublishedDate, item.ModifiedDate)
+		}
+	}
+}
+
+func TestAtomSubtitle(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2013-01-16T21:52:35-05:00")
+	feed := &Feed{
+		Title:       "jmoiron.net blog",
+		Link:        &Link{Href: "http://jmoiron.net/blog"},
+		Description: "discussion about tech, footie, photos",
+		Created:     now,
+	}
+	atom, err := feed.ToAtom()
+	if err != nil {
+		t.Errorf("unexpected error encoding Atom: %v", err)
+	}
+	if want := "<subtitle>discussion about tech, footie, photos</subtitle>"; !strings.Contains(atom, want) {
+		t.Errorf("Atom should fall back to the description for its subtitle.  Got:\n%s\n", atom)
+	}
+
+	feed.Subtitle = "tech &amp; <b>footie</b>"
+	feed.SubtitleType = "html"
+	atom, err = ToXML(&Atom{Feed: feed})
+	if err != nil {
+		t.Errorf("unexpected error encoding Atom: %v", err)
+	}
+	if want := `<subtitle type="html">tech &amp;amp; &lt;b&gt;footie&lt;/b&gt;</subtitle>`; !strings.Contains(atom, want) {
+		t.Errorf("Atom missing %q.  Got:\n%s\n", want, atom)
+	}
+
+	feed.Subtitle, feed.Description = "", ""
+	if got := (&Atom{Feed: feed}).AtomFeed(); got.Subtitle != "" || got.SubtitleType != "" {
+		t.Errorf("Atom without a subtitle or description: got subtitle %q of type %q", got.Subtitle, got.SubtitleType)
+	}
+
+	// the AtomFeed can be built and modified directly
+	atomFeed := (&Atom{Feed: feed}).AtomFeed()
+	atomFeed.Subtitle = "plays the blues"
+	if atom, err = ToXML(atomFeed); err != nil || !strings.Contains(atom, "<subtitle>plays the blues</subtitle>\n  <link") {
+		t.Errorf("Atom missing the subtitle set on the AtomFeed before the link.  Got %v:\n%s\n", err, atom)
+	}
+}
+
+func TestCopyrightTemplate(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2024-01-02T03:04:05Z")
+	feed := &Feed{
+		Title:             "jmoiron.net blog",
+		Link:              &Link{Href: "http://jmoiron.net/blog"},
+		Created:           now,
+		CopyrightTemplate: "© {{year}} Example Inc. {{month}}",
+		Clock:             func() time.Time { return now },
+	}
+	want := "© 2024 Example Inc. {{month}}"
+	if got := (&Rss{Feed: feed}).RssFeed().Copyright; got != want {
+		t.Errorf("Rss copyright: got %q, want %q", got, want)
+	}
+	if got := (&AmazonRss{Feed: feed}).AmazonRssFeed().Copyright; got != want {
+		t.Errorf("AmazonRss copyright: got %q, want %q", got, want)
+	}
+	if got := (&Atom{Feed: feed}).AtomFeed().Rights; got != want {
+		t.Errorf("Atom rights: got %q, want %q", got, want)
+	}
+
+	feed.Copyright = "© 2013 Example Inc."
+	if got := (&Rss{Feed: feed}).RssFeed().Copyright; got != feed.Copyright {
+		t.Errorf("Rss copyright should not use the template when set: got %q", got)
+	}
+}
+
+func TestRssDCTermsDates(t *testing.T) {
+	created, _ := time.Parse(time.RFC3339, "2013-01-16T21:52:35-05:00")
+	feed := &Feed{
+		Title: "jmoiron.net blog",
+		Link:  &Link{Href: "http://jmoiron.net/blog"},
+		Items: []*Item{
+			{Title: "both", Created: created, Updated: created.Add(time.Hour)},
+			{Title: "created", Created: created},
+		},
+	}
+	rss, err := feed.ToRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding RSS: %v", err)
+	}
+	if strings.Contains(rss, "dcterms") {
+		t.Errorf("Rss should not use dcterms by default.  Got:\n%s\n", rss)
+	}
+
+	feed.DCTermsDates = true
+	rss, err = ToXML(&Rss{Feed: feed})
+	if err != nil {
+		t.Errorf("unexpected error encoding RSS: %v", err)
+	}
+	for _, want := range []string{
+		`xmlns:dcterms="http://purl.org/dc/terms/"`,
+		"<dcterms:created>2013-01-16T21:52:35-05:00</dcterms:created>\n      <dcterms:modified>2013-01-16T22:52:35-05:00</dcterms:modified>",
+		"<pubDate>Wed, 16 Jan 2013 21:52:35 -0500</pubDate>",
+	} {
+		if !strings.Contains(rss, want) {
+			t.Errorf("Rss missing %q.  Got:\n%s\n", want, rss)
+		}
+	}
+	if n := strings.Count(rss, "<dcterms:modified>"); n != 1 {
+		t.Errorf("Rss should only have a modified date for the updated item, got %d.  Got:\n%s\n", n, rss)
+	}
+}
+
+func TestJSONAuthorExtensions(t *testing.T) {
+	feed := &Feed{
+		Title:  "jmoiron.net blog",
+		Author: &Author{Name: "Jason Moiron", JSONExtensions: map[string]interface{}{"_internal_id": "42"}},
+	}
+	data, err := json.Marshal((&JSON{feed}).JSONFeed().Author)
+	if err != nil {
+		t.Fatalf("unexpected error encoding JSON author: %v", err)
+	}
+	if want := `{"name":"Jason Moiron","_internal_id":"42"}`; string(data) != want {
+		t.Errorf("JSON author: got %s, want %s", data, want)
+	}
+
+	var author JSONAuthor
+	if err := json.Unmarshal(data, &author); err != nil {
+		t.Fatal(err)
+	}
+	if author.Name != "Jason Moiron" || author.Extensions["_internal_id"] != "42" || len(author.Extensions) != 1 {
+		t.Errorf("JSON author round trip: got %+v", author)
+	}
+
+	feed.Author.JSONExtensions = map[string]interface{}{"internal_id": "42"}
+	if _, err := feed.ToJSON(); err == nil {
+		t.Errorf("JSON author extension without an underscore should fail to encode")
+	}
+}
+
+func TestJSONRawExtensions(t *testing.T) {
+	feed := &Feed{
+		Title: "jmoiron.net blog",
+		Items: []*Item{{
+			Id:    "1",
+			Title: "Limiting Concurrency in Go",
+			Author: &Author{Name: "Jason Moiron", JSONExtensions: map[string]interface{}{
+				"_stats": json.RawMessage(`{"views": 12345678901234567890, "b": 1, "a": 2}`),
+			}},
+		}},
+	}
+	out, err := feed.ToJSON()
+	if err != nil {
+		t.Fatalf("unexpected error encoding JSON: %v", err)
+	}
+	if want := `"_stats": {
+          "views": 12345678901234567890,
+          "b": 1,
+          "a": 2
+        }`; !strings.Contains(out, want) {
+		t.Errorf("JSON missing %s.  Got:\n%s\n", want, out)
+	}
+	if err := feed.ValidateJSON(); err != nil {
+		t.Errorf("unexpected error validating: %v", err)
+	}
+
+	feed.Items[0].Author.JSONExtensions["_broken"] = json.RawMessage(`{"views": `)
+	if out, err := feed.ToJSON(); err == nil || out != "" || !strings.Contains(err.Error(), `"_broken" is not valid json`) {
+		t.Errorf("expected an error naming the invalid raw extension, got %q, %v", out, err)
+	}
+	err = feed.ValidateJSON()
+	if err == nil || !strings.Contains(err.Error(), `item 0 author: feeds: json author extension "_broken" is not valid json`) {
+		t.Errorf("expected a validation error naming the invalid raw extension, got %v", err)
+	}
+}
+
+func TestJSONSource(t *testing.T) {
+	feed := &Feed{
+		Title: "jmoiron.net blog",
+		Items: []*Item{
+			{Id: "1", Title: "Limiting Concurrency in Go", Source: &Link{Href: "http://golangweekly.com/rss", Title: "Golang Weekly"}},
+			{Id: "2", Title: "Logic-less Template Redux"},
+		},
+	}
+	out, err := feed.ToJSON()
+	if err != nil {
+		t.Fatalf("unexpected error encoding JSON: %v", err)
+	}
+	want := `"_source": {
+        "url": "http://golangweekly.com/rss",
+        "title": "Golang Weekly"
+      }`
+	if !strings.Contains(out, want) || strings.Count(out, "_source") != 1 {
+		t.Errorf("JSON missing %s once.  Got:\n%s\n", want, out)
+	}
+
+	var parsed JSONFeed
+	if err := json.Unmarshal([]byte(out), &parsed); err != nil {
+		t.Fatal(err)
+	}
+	if s := parsed.Items[0].Source; s == nil || s.Url != "http://golangweekly.com/rss" || s.Title != "Golang Weekly" || parsed.Items[1].Source != nil {
+		t.Errorf("JSON source round trip: got %+v and %+v", parsed.Items[0].Source, parsed.Items[1].Source)
+	}
+}
+
+func TestFeedIcons(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2013-01-16T21:52:35-05:00")
+	feed := &Feed{
+		Title:   "jmoiron.net blog",
+		Link:    &Link{Href: "http://jmoiron.net/blog"},
+		Created: now,
+		Image:   &Image{Url: "http://jmoiron.net/banner.png"},
+		Favicon: "http://jmoiron.net/favicon.png",
+	}
+	atom := (&Atom{Feed: feed}).AtomFeed()
+	if atom.Icon != feed.Favicon || atom.Logo != feed.Image.Url {
+		t.Errorf("Atom should use the favicon as icon and the image as logo, got icon %q logo %q", atom.Icon, atom.Logo)
+	}
+
+	feed.Icon = "http://jmoiron.net/icon.png"
+	atom = (&Atom{Feed: feed}).AtomFeed()
+	if atom.Icon != feed.Favicon || atom.Logo != feed.Icon {
+		t.Errorf("Atom should use the icon as logo, got icon %q logo %q", atom.Icon, atom.Logo)
+	}
+	json := (&JSON{feed}).JSONFeed()
+	if json.Icon != feed.Icon || json.Favicon != feed.Favicon {
+		t.Errorf("JSON got icon %q favicon %q", json.Icon, json.Favicon)
+	}
+
+	logger := &testLogger{}
+	feed.Logger = logger
+	if _, err := feed.ToJSON(); err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range logger.events {
+		if e.event == EventWarning {
+			t.Errorf("unexpected warning for different icons: %v", e.fields)
+		}
+	}
+	feed.Favicon = feed.Icon
+	if _, err := feed.ToAtom(); err != nil {
+		t.Fatal(err)
+	}
+	warned := false
+	for _, e := range logger.events {
+		warned = warned || e.event == EventWarning
+	}
+	if !warned {
+		t.Errorf("expected a warning for the same favicon and icon, got %v", logger.events)
+	}
+}
+
+func TestAtomXHTMLContent(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2013-01-16T21:52:35-05:00")
+	feed := &Feed{
+		Title:   "jmoiron.net blog",
+		Link:    &Link{Href: "http://jmoiron.net/blog"},
+		Created: now,
+		Items: []*Item{
+			{Title: "Logic-less Template Redux", Content: "<p>More <b>thoughts</b> &amp; templates</p>"},
+		},
+	}
+	feed.XHTMLContent = true
+	atom, err := ToXML(&Atom{Feed: feed})
+	if err != nil {
+		t.Errorf("unexpected error encoding Atom: %v", err)
+	}
+	want := `<content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"><p>More <b>thoughts</b> &amp; templates</p></div></content>`
+	if !strings.Contains(atom, want) {
+		t.Errorf("Atom missing %q.  Got:\n%s\n", want, atom)
+	}
+
+	// html content is escaped text, so it is not checked
+	feed.Items[0].Content = "<p>unclosed"
+	feed.XHTMLContent = false
+	if _, err := feed.ToAtom(); err != nil {
+		t.Errorf("unexpected error encoding html content: %v", err)
+	}
+
+	feed.XHTMLContent = true
+	for _, content := range []string{"<p>unclosed", "<p>one</b></p>", "&nbsp;", "</div><script>x</script>", "a</div>b"} {
+		feed.Items[0].Content = content
+		if _, err := ToXML(&Atom{Feed: feed}); err == nil {
+			t.Errorf("Atom should fail for xhtml content %q", content)
+		}
+		if _, err := ToXML(&AtomFeed{Entries: []*AtomEntry{{Content: &AtomContent{Content: content, Type: "xhtml"}}}}); err == nil {
+			t.Errorf("AtomFeed should fail for xhtml content %q", content)
+		}
+	}
+}
+
+func TestSelfCloseEmpty(t *testing.T) {
+	for _, test := range []struct{ in, want string }{
+		{"<link></link>", "<link/>"},
+		{`<link href="http://jmoiron.net/blog" rel="alternate"></link>`, `<link href="http://jmoiron.net/blog" rel="alternate"/>`},
+		{"<entry><link></link></entry>", "<entry><link/></entry>"},
+		{"<content:encoded><![CDATA[]]></content:encoded>", "<content:encoded><![CDATA[]]></content:encoded>"},
+		{"<content:encoded><![CDATA[<p></p>]]></content:encoded>", "<content:encoded><![CDATA[<p></p>]]></content:encoded>"},
+		{"<title> </title>", "<title> </title>"},
+		{"<title>&lt;b&gt;&lt;/b&gt;</title>", "<title>&lt;b&gt;&lt;/b&gt;</title>"},
+		{"<a></b>", "<a></b>"},
+		{"<img/>", "<img/>"},
+	} {
+		if got := string(selfCloseEmpty([]byte(test.in))); got != test.want {
+			t.Errorf("selfCloseEmpty(%q): got %q, want %q", test.in, got, test.want)
+		}
+	}
+
+	now, _ := time.Parse(time.RFC3339, "2013-01-16T21:52:35-05:00")
+	feed := &Feed{
+		Title:          "jmoiron.net blog",
+		Link:           &Link{Href: "http://jmoiron.net/blog"},
+		Created:        now,
+		SelfCloseEmpty: true,
+		Items:          []*Item{{Title: "Logic-less Template Redux", Link: &Link{Href: "http://jmoiron.net/blog/logicless-template-redux/"}}},
+	}
+	atom, err := feed.ToAtom()
+	if err != nil {
+		t.Errorf("unexpected error encoding Atom: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := feed.WriteAtom(&buf); err != nil {
+		t.Errorf("unexpected error writing Atom: %v", err)
+	}
+	for _, got := range []string{atom, buf.String()} {
+		if !strings.Contains(got, `<link href="http://jmoiron.net/blog/logicless-template-redux/" rel="alternate"/>`) || strings.Contains(got, "></link>") {
+			t.Errorf("Atom should self-close empty links.  Got:\n%s\n", got)
+		}
+	}
+	rss, err := feed.ToRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding RSS: %v", err)
+	}
+	if !strings.Contains(rss, "<description/>") {
+		t.Errorf("Rss should self-close the empty description.  Got:\n%s\n", rss)
+	}
+}
+
+func TestUnpublishedItems(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2013-01-16T21:52:35-05:00")
+	feed := &Feed{
+		Title:   "jmoiron.net blog",
+		Link:    &Link{Href: "http://jmoiron.net/blog"},
+		Created: now,
+		Clock:   func() time.Time { return now },
+		Items: []*Item{
+			{Title: "active", Id: "active", Created: now},
+			{Title: "recent", Id: "recent", Created: now, Status: ItemUnpublished, UnpublishedAt: now.Add(-24 * time.Hour)},
+			{Title: "old", Id: "old", Created: now, Status: ItemUnpublished, UnpublishedAt: now.Add(-10 * 24 * time.Hour)},
+		},
+	}
+
+	encoders := map[string]func() (string, error){
+		"Atom":      feed.ToAtom,
+		"Rss":       feed.ToRss,
+		"JSON":      feed.ToJSON,
+		"AmazonRss": feed.ToAmazonRss,
+	}
+	for name, encode := range encoders {
+		out, err := encode()
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+		}
+		if !strings.Contains(out, "active") || strings.Contains(out, "recent") || strings.Contains(out, "old") {
+			t.Errorf("%s should only have the active item.  Got:\n%s\n", name, out)
+		}
+	}
+
+	feed.KeepUnpublishedFor = 7 * 24 * time.Hour
+	amazon := (&AmazonRss{Feed: feed}).AmazonRssFeed()
+	if len(amazon.Items) != 2 {
+		t.Fatalf("AmazonRss should keep the recently unpublished item, got %d items", len(amazon.Items))
+	}
+	if amazon.Items[0].Status != "" || amazon.Items[1].Guid != "recent" || amazon.Items[1].Status != "deleted" {
+		t.Errorf("AmazonRss should mark the unpublished item as deleted, got %+v and %+v", amazon.Items[0], amazon.Items[1])
+	}
+	out, err := ToXML(&AmazonRss{Feed: feed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "<amzn:status>deleted</amzn:status>") {
+		t.Errorf("AmazonRss missing the deleted status.  Got:\n%s\n", out)
+	}
+	report, err := feed.WriteWithReport(ioutil.Discard, FeedTypeAmazonRss)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []DroppedItem{{Id: "old", Reason: "unpublished more than 168h0m0s ago"}}
+	if !reflect.DeepEqual(report.Dropped, want) {
+		t.Errorf("got dropped %+v, want %+v", report.Dropped, want)
+	}
+}
+
+func TestAtomImageLinks(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2013-01-16T21:52:35-05:00")
+	feed := &Feed{
+		Title:   "jmoiron.net blog",
+		Link:    &Link{Href: "http://jmoiron.net/blog"},
+		Created: now,
+		Favicon: "http://jmoiron.net/favicon.png",
+		Icon:    "http://jmoiron.net/icon.png",
+	}
+	atom, err := feed.ToAtom()
+	if err != nil {
+		t.Errorf("unexpected error encoding Atom: %v", err)
+	}
+	if strings.Contains(atom, `rel="icon"`) || strings.Contains(atom, `rel="logo"`) {
+		t.Errorf("Atom should not have image links by default.  Got:\n%s\n", atom)
+	}
+
+	feed.EmitImageLinks = true
+	atom, err = ToXML(&Atom{Feed: feed})
+	if err != nil {
+		t.Errorf("unexpected error encoding Atom: %v", err)
+	}
+	for _, want := range []string{
+		`<link href="http://jmoiron.net/favicon.png" rel="icon"></link>`,
+		`<link href="http://jmoiron.net/icon.png" rel="logo"></link>`,
+	} {
+		if !strings.Contains(atom, want) {
+			t.Errorf("Atom missing %q.  Got:\n%s\n", want, atom)
+		}
+	}
+
+	feed.Favicon, feed.Icon = "", ""
+	atom, err = ToXML(&Atom{Feed: feed})
+	if err != nil {
+		t.Errorf("unexpected error encoding Atom: %v", err)
+	}
+	if strings.Contains(atom, `rel="icon"`) || strings.Contains(atom, `rel="logo"`) {
+		t.Errorf("Atom should not have image links without images.  Got:\n%s\n", atom)
+	}
+}
+
+func TestEmptyFeedDates(t *testing.T) {
+	created, _ := time.Parse(time.RFC3339, "2013-01-16T21:52:35-05:00")
+	generated := created.Add(2 * time.Hour)
+	newFeed := func() *Feed {
+		return &Feed{
+			Title:       "jmoiron.net blog",
+			Link:        &Link{Href: "http://jmoiron.net/blog"},
+			Description: "discussion about tech",
+			Clock:       func() time.Time { return generated },
+		}
+	}
+
+	feed := newFeed()
+	rss, err := feed.ToRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding RSS: %v", err)
+	}
+	for _, want := range []string{
+		"<pubDate>Wed, 16 Jan 2013 23:52:35 -0500</pubDate>",
+		"<lastBuildDate>Wed, 16 Jan 2013 23:52:35 -0500</lastBuildDate>",
+	} {
+		if !strings.Contains(rss, want) {
+			t.Errorf("Rss missing %s.  Got:\n%s\n", want, rss)
+		}
+	}
+	atom, err := feed.ToAtom()
+	if err != nil {
+		t.Errorf("unexpected error encoding Atom: %v", err)
+	}
+	if want := "<updated>2013-01-16T23:52:35-05:00</updated>"; !strings.Contains(atom, want) {
+		t.Errorf("Atom missing %s.  Got:\n%s\n", want, atom)
+	}
+	if err := feed.ValidateAtom(); err != nil {
+		t.Errorf("an empty feed should be valid atom: %v", err)
+	}
+	json, err := feed.ToJSON()
+	if err != nil {
+		t.Errorf("unexpected error encoding JSON: %v", err)
+	}
+	if want := `"items": []`; !strings.Contains(json, want) {
+		t.Errorf("JSON missing %s.  Got:\n%s\n", want, json)
+	}
+
+	// the feed's own dates are used over the generation time
+	feed = newFeed()
+	feed.Created = created
+	rss, err = feed.ToRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding RSS: %v", err)
+	}
+	for _, want := range []string{
+		"<pubDate>Wed, 16 Jan 2013 21:52:35 -0500</pubDate>",
+		"<lastBuildDate>Wed, 16 Jan 2013 21:52:35 -0500</lastBuildDate>",
+	} {
+		if !strings.Contains(rss, want) {
+			t.Errorf("Rss missing %s.  Got:\n%s\n", want, rss)
+		}
+	}
+	atom, err = feed.ToAtom()
+	if err != nil {
+		t.Errorf("unexpected error encoding Atom: %v", err)
+	}
+	if want := "<updated>2013-01-16T21:52:35-05:00</updated>"; !strings.Contains(atom, want) {
+		t.Errorf("Atom missing %s.  Got:\n%s\n", want, atom)
+	}
+
+	// feeds with items are left without a lastBuildDate
+	feed.Add(&Item{Title: "Limiting Concurrency in Go", Created: created})
+	rss, err = feed.ToRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding RSS: %v", err)
+	}
+	if strings.Contains(rss, "lastBuildDate") {
+		t.Errorf("Rss should not have a lastBuildDate.  Got:\n%s\n", rss)
+	}
+}
+
+func TestRssDocs(t *testing.T) {
+	feed := &Feed{
+		Title:       "jmoiron.net blog",
+		Link:        &Link{Href: "http://jmoiron.net/blog"},
+		Description: "discussion about tech",
+	}
+	rss, err := feed.ToRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding RSS: %v", err)
+	}
+	if strings.Contains(rss, "<docs>") {
+		t.Errorf("Rss should not have docs by default.  Got:\n%s\n", rss)
+	}
+
+	feed.DefaultDocs = true
+	rss, err = ToXML(&Rss{Feed: feed})
+	if err != nil {
+		t.Errorf("unexpected error encoding RSS: %v", err)
+	}
+	if want := "<docs>https://www.rssboard.org/rss-specification</docs>"; !strings.Contains(rss, want) {
+		t.Errorf("Rss missing %s.  Got:\n%s\n", want, rss)
+	}
+
+	feed.Docs = "http://jmoiron.net/blog/about-this-feed"
+	rss, err = ToXML(&Rss{Feed: feed})
+	if err != nil {
+		t.Errorf("unexpected error encoding RSS: %v", err)
+	}
+	if want := "<docs>http://jmoiron.net/blog/about-this-feed</docs>"; !strings.Contains(rss, want) {
+		t.Errorf("Rss missing %s.  Got:\n%s\n", want, rss)
+	}
+}
+
+func TestAmazonDefaults(t *testing.T) {
+	yes, no := true, false
+	defaults := AmazonItem{
+		HeroImage:    "http://jmoiron.net/default.jpg",
+		IntroText:    "default intro",
+		IndexContent: &no,
+		Section:      "news",
+	}
+	all := AmazonHeroImage | AmazonIntroText | AmazonIndexContent | AmazonSection
+	tests := []struct {
+		name      string
+		amazon    *AmazonItem
+		thumbnail string
+		defaults  AmazonItem
+		want      AmazonRssItem
+	}{
+		{
+			name:   "item value over suppress and default",
+			amazon: &AmazonItem{HeroImage: "http://jmoiron.net/item.jpg", IntroText: "item intro", IndexContent: &yes, Section: "reviews", Suppress: all},
+			// the thumbnail is derived, below the defaults
+			thumbnail: "http://jmoiron.net/thumbnail.jpg",
+			defaults:  defaults,
+			want:      AmazonRssItem{HeroImage: "http://jmoiron.net/item.jpg", IntroText: "item intro", IndexContent: "True", Section: "reviews"},
+		},
+		{
+			name:      "suppress over default",
+			amazon:    &AmazonItem{Suppress: all},
+			thumbnail: "http://jmoiron.net/thumbnail.jpg",
+			defaults:  defaults,
+			want:      AmazonRssItem{},
+		},
+		{
+			name:      "default over derived",
+			amazon:    &AmazonItem{},
+			thumbnail: "http://jmoiron.net/thumbnail.jpg",
+			defaults:  defaults,
+			want:      AmazonRssItem{HeroImage: "http://jmoiron.net/default.jpg", IntroText: "default intro", IndexContent: "False", Section: "news"},
+		},
+		{
+			name:      "default over placeholder",
+			defaults:  defaults,
+			thumbnail: "http://jmoiron.net/thumbnail.jpg",
+			want:      AmazonRssItem{HeroImage: "http://jmoiron.net/default.jpg", IntroText: "default intro", IndexContent: "False", Section: "news"},
+		},
+		{
+			name:      "derived",
+			amazon:    &AmazonItem{},
+			thumbnail: "http://jmoiron.net/thumbnail.jpg",
+			want:      AmazonRssItem{HeroImage: "http://jmoiron.net/thumbnail.jpg", IndexContent: "True"},
+		},
+		{
+			name:   "nothing",
+			amazon: &AmazonItem{},
+			want:   AmazonRssItem{IndexContent: "True"},
+		},
+		{
+			name: "placeholder",
+			want: AmazonRssItem{HeroImage: "POST THUMBNAIL (Prefer 2x1 at least 1000px wide)", IntroText: "META DESCRIPTION", IndexContent: "True"},
+		},
+		{
+			name:   "partly suppressed",
+			amazon: &AmazonItem{IntroText: "item intro", Suppress: AmazonHeroImage | AmazonSection},
+			// the thumbnail is derived, below the defaults
+			thumbnail: "http://jmoiron.net/thumbnail.jpg",
+			defaults:  defaults,
+			want:      AmazonRssItem{IntroText: "item intro", IndexContent: "False"},
+		},
+	}
+	for _, test := range tests {
+		feed := &Feed{
+			Title: "jmoiron.net blog",
+			Link:  &Link{Href: "http://jmoiron.net/blog"},
+			Items: []*Item{{Title: "Limiting Concurrency in Go", Amazon: test.amazon, Thumbnail: test.thumbnail}},
+		}
+		feed.AmazonDefaults = &test.defaults
+		item := (&AmazonRss{Feed: feed}).AmazonRssFeed().Items[0]
+		got := AmazonRssItem{HeroImage: item.HeroImage, IntroText: item.IntroText, IndexContent: item.IndexContent, Section: item.Section}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %+v, want %+v", test.name, got, test.want)
+		}
+	}
+
+	out, err := ToXML(&AmazonRss{Feed: &Feed{
+		Title: "jmoiron.net blog",
+		Link:  &Link{Href: "http://jmoiron.net/blog"},
+		Items: []*Item{{Title: "Limiting Concurrency in Go", Amazon: &AmazonItem{Section: "news"}}},
+	}})
+	if err != nil {
+		t.Errorf("unexpected error encoding Amazon RSS: %v", err)
+	}
+	if want := "<amzn:section>news</amzn:section>"; !strings.Contains(out, want) {
+		t.Errorf("AmazonRss missing %s.  Got:\n%s\n", want, out)
+	}
+}
+
+func TestStableLastBuildDate(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2013-01-16T21:52:35-05:00")
+	build := func(updated time.Time) *Feed {
+		return &Feed{
+			Title:               "jmoiron.net blog",
+			Link:                &Link{Href: "http://jmoiron.net/blog"},
+			Description:         "discussion about tech",
+			Updated:             updated,
+			StableLastBuildDate: true,
+			Items: []*Item{
+				{Title: "Limiting Concurrency in Go", Id: "limiting-concurrency", Created: now},
+				{Title: "Logic-less Template Redux", Id: "logicless-template-redux", Created: now, Updated: now.Add(time.Hour)},
+			},
+		}
+	}
+
+	// the pipeline bumps Updated on every build
+	first, second := build(now.Add(24*time.Hour)), build(now.Add(48*time.Hour))
+	for name, write := range map[string]func(*Feed) (string, error){
+		"Rss":       (*Feed).ToRss,
+		"AmazonRss": (*Feed).ToAmazonRss,
+	} {
+		a, err := write(first)
+		if err != nil {
+			t.Errorf("unexpected error encoding %s: %v", name, err)
+		}
+		b, err := write(second)
+		if err != nil {
+			t.Errorf("unexpected error encoding %s: %v", name, err)
+		}
+		if a != b {
+			t.Errorf("%s should be the same for the same items.  Got:\n%s\n\nand:\n%s\n", name, a, b)
+		}
+		if want := "<lastBuildDate>Wed, 16 Jan 2013 22:52:35 -0500</lastBuildDate>"; !strings.Contains(a, want) {
+			t.Errorf("%s missing %s.  Got:\n%s\n", name, want, a)
+		}
+		if strings.Contains(a, "<pubDate>Wed, 16 Jan 2013 22:52:35") {
+			t.Errorf("%s should leave out the channel pubDate.  Got:\n%s\n", name, a)
+		}
+	}
+	for _, format := range []FeedType{FeedTypeRss, FeedTypeAmazonRss} {
+		a, err := first.Fingerprint(format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := second.Fingerprint(format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a != b {
+			t.Errorf("%s: fingerprints %s and %s differ for the same items", format, a, b)
+		}
+	}
+
+	// a created date other than the lastBuildDate is kept
+	first.Created = now
+	rss, err := first.ToRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding RSS: %v", err)
+	}
+	if want := "<pubDate>Wed, 16 Jan 2013 21:52:35 -0500</pubDate>\n    <lastBuildDate>Wed, 16 Jan 2013 22:52:35 -0500</lastBuildDate>"; !strings.Contains(rss, want) {
+		t.Errorf("Rss missing %s.  Got:\n%s\n", want, rss)
+	}
+}
+
+func TestEscapeAttr(t *testing.T) {
+	for in, want := range map[string]string{
+		"http://example.com/a?b=1&c=2":    "http://example.com/a?b=1&amp;c=2",
+		`http://example.com/"quoted"`:     "http://example.com/&quot;quoted&quot;",
+		"http://example.com/<script>":     "http://example.com/&lt;script>",
+		"http://example.com/it's":         "http://example.com/it's",
+		"a\tb\nc\rd":                      "a&#x9;b&#xA;c&#xD;d",
+		"http://example.com/\x00\x1b\x7f": "http://example.com/��\x7f",
+		"http://example.com/\xff":         "http://example.com/�",
+	} {
+		if got := escapeAttr(in); got != want {
+			t.Errorf("escapeAttr(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestAmazonPositions(t *testing.T) {
+	newFeed := func(positions ...int) *Feed {
+		feed := &Feed{Title: "jmoiron.net blog", Link: &Link{Href: "http://jmoiron.net/blog"}}
+		for n, p := range positions {
+			feed.Add(&Item{Title: fmt.Sprintf("Best %d", n), Amazon: &AmazonItem{Position: p}})
+		}
+		return feed
+	}
+	auto := func(feed *Feed) *Feed {
+		feed.AutoPosition = true
+		return feed
+	}
+	positions := func(a *AmazonRss) []int {
+		var got []int
+		for _, i := range a.AmazonRssFeed().Items {
+			got = append(got, i.Position)
+		}
+		return got
+	}
+
+	if got := positions(&AmazonRss{Feed: newFeed(0, 0, 0)}); !reflect.DeepEqual(got, []int{0, 0, 0}) {
+		t.Errorf("got positions %v without AutoPosition, want none", got)
+	}
+	if got := positions(&AmazonRss{Feed: auto(newFeed(0, 0, 0))}); !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Errorf("got positions %v, want 1, 2 and 3", got)
+	}
+	// explicit positions win, and are skipped by the automatic ones
+	if got := positions(&AmazonRss{Feed: auto(newFeed(0, 1, 0, 5))}); !reflect.DeepEqual(got, []int{2, 1, 3, 5}) {
+		t.Errorf("got positions %v, want 2, 1, 3 and 5", got)
+	}
+	// deleted items are not numbered
+	feed := newFeed(0, 0, 0)
+	feed.Clock = func() time.Time { return time.Date(2013, 1, 16, 0, 0, 0, 0, time.UTC) }
+	feed.Items[0].Status, feed.Items[0].UnpublishedAt = ItemUnpublished, feed.Clock()
+	feed.AutoPosition = true
+	feed.KeepUnpublishedFor = time.Hour
+	if got := positions(&AmazonRss{Feed: feed}); !reflect.DeepEqual(got, []int{0, 1, 2}) {
+		t.Errorf("got positions %v, want a deleted item then 1 and 2", got)
+	}
+
+	out, err := ToXML(&AmazonRss{Feed: auto(newFeed(0, 0))})
+	if err != nil {
+		t.Errorf("unexpected error encoding Amazon RSS: %v", err)
+	}
+	for _, want := range []string{"<amzn:position>1</amzn:position>", "<amzn:position>2</amzn:position>"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("AmazonRss missing %s.  Got:\n%s\n", want, out)
+		}
+	}
+
+	for _, p := range [][]int{{2, 0, 2}, {-1}} {
+		if out, err := ToXML(&AmazonRss{Feed: auto(newFeed(p...))}); err == nil {
+			t.Errorf("expected an error for positions %v, got:\n%s", p, out)
+		}
+	}
+}
+
+func TestAmazonVideo(t *testing.T) {
+	feed := &Feed{
+		Title: "jmoiron.net blog",
+		Link:  &Link{Href: "http://jmoiron.net/blog"},
+		Items: []*Item{
+			{
+				Title:     "Best Headphones of 2019, the video",
+				Thumbnail: "http://jmoiron.net/poster.jpg",
+				Enclosure: &Enclosure{Url: "http://jmoiron.net/headphones.mp4", Type: "video/mp4", Length: "123456"},
+				Amazon:    &AmazonItem{ContentKind: AmazonVideo},
+			},
+			{Title: "Best Headphones of 2019", Thumbnail: "http://jmoiron.net/article.jpg", Amazon: &AmazonItem{}},
+		},
+	}
+	out, err := feed.ToAmazonRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding Amazon RSS: %v", err)
+	}
+	for _, want := range []string{
+		`<enclosure url="http://jmoiron.net/headphones.mp4" length="123456" type="video/mp4"></enclosure>`,
+		"<amzn:videoPosterImage>http://jmoiron.net/poster.jpg</amzn:videoPosterImage>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("AmazonRss missing %s.  Got:\n%s\n", want, out)
+		}
+	}
+	if strings.Count(out, "videoPosterImage") != 2 {
+		t.Errorf("AmazonRss should only have a poster for the video.  Got:\n%s\n", out)
+	}
+
+	for _, e := range []*Enclosure{nil, {Url: "http://jmoiron.net/headphones.mp3", Type: "audio/mpeg", Length: "123456"}} {
+		feed.Items[0].Enclosure = e
+		if out, err := feed.ToAmazonRss(); err == nil {
+			t.Errorf("expected an error for the video enclosure %+v, got:\n%s", e, out)
+		}
+	}
+}
+
+func TestAmazonContent(t *testing.T) {
+	feed := &Feed{
+		Title:       "jmoiron.net blog",
+		Link:        &Link{Href: "http://jmoiron.net/blog"},
+		Description: "discussion about tech",
+		Items: []*Item{
+			{Title: "Limiting Concurrency in Go", Description: `<p>A <a href="http://golang.org/">golang</a> discussion</p>`, Amazon: &AmazonItem{}},
+			{Title: "Logic-less Template Redux", Description: "<p>More thoughts</p>", Content: "<p>The full post</p>", Amazon: &AmazonItem{}},
+		},
+	}
+	out, err := ToXML(&AmazonRss{Feed: feed})
+	if err != nil {
+		t.Errorf("unexpected error encoding Amazon RSS: %v", err)
+	}
+	if strings.Count(out, "content:encoded") != 2 || strings.Count(out, "CDATA") != 1 {
+		t.Errorf("AmazonRss should only have content:encoded for Content.  Got:\n%s\n", out)
+	}
+
+	feed.CDATADescription = true
+	feed.ContentFromDescription = true
+	out, err = ToXML(&AmazonRss{Feed: feed})
+	if err != nil {
+		t.Errorf("unexpected error encoding Amazon RSS: %v", err)
+	}
+	for _, want := range []string{
+		`xmlns:content="http://purl.org/rss/1.0/modules/content/" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:amzn="https://amazon.com/ospublishing/1.0/"`,
+		`<title>Limiting Concurrency in Go</title>
+      <description><![CDATA[<p>A <a href="http://golang.org/">golang</a> discussion</p>]]></description>
+      <content:encoded><![CDATA[<p>A <a href="http://golang.org/">golang</a> discussion</p>]]></content:encoded>`,
+		`<description><![CDATA[<p>More thoughts</p>]]></description>
+      <content:encoded><![CDATA[<p>The full post</p>]]></content:encoded>`,
+		"<amzn:indexContent>True</amzn:indexContent>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("AmazonRss missing %s.  Got:\n%s\n", want, out)
+		}
+	}
+
+	feed.Items[1].Content = ""
+	feed.CDATADescription, feed.ContentFromDescription = false, false
+	out, err = feed.ToAmazonRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding Amazon RSS: %v", err)
+	}
+	if strings.Contains(out, "content") {
+		t.Errorf("AmazonRss should only declare the content namespace with content.  Got:\n%s\n", out)
+	}
+	for _, want := range []string{
+		`xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:amzn="https://amazon.com/ospublishing/1.0/"`,
+		"<description>&lt;p&gt;More thoughts&lt;/p&gt;</description>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("AmazonRss missing %s.  Got:\n%s\n", want, out)
+		}
+	}
+}
+
+func TestViaLinks(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2013-01-16T21:52:35-05:00")
+	feed := &Feed{
+		Title:       "jmoiron.net blog",
+		Link:        &Link{Href: "http://jmoiron.net/blog"},
+		Description: "discussion about tech",
+		Created:     now,
+		Items: []*Item{
+			{Title: "Limiting Concurrency in Go", Link: &Link{Href: "http://jmoiron.net/blog/limiting-concurrency-in-go/"}, Created: now},
+		},
+	}
+	rss, err := feed.ToRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding RSS: %v", err)
+	}
+	if strings.Contains(rss, "atom") {
+		t.Errorf("Rss should not use the atom namespace.  Got:\n%s\n", rss)
+	}
+
+	feed.Items[0].ViaURL = "http://example.com/links?from=jmoiron&page=1"
+	feed.Items[0].Source = &Link{Href: "http://golang.org/"}
+	rss, err = feed.ToRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding RSS: %v", err)
+	}
+	for _, want := range []string{
+		`xmlns:atom="http://www.w3.org/2005/Atom"`,
+		`<source>http://golang.org/</source>`,
+		`<atom:link href="http://example.com/links?from=jmoiron&amp;page=1" rel="via"></atom:link>`,
+	} {
+		if !strings.Contains(rss, want) {
+			t.Errorf("Rss missing %s.  Got:\n%s\n", want, rss)
+		}
+	}
+	atom, err := feed.ToAtom()
+	if err != nil {
+		t.Errorf("unexpected error encoding Atom: %v", err)
+	}
+	if want := `<link href="http://example.com/links?from=jmoiron&amp;page=1" rel="via"></link>`; !strings.Contains(atom, want) {
+		t.Errorf("Atom missing %s.  Got:\n%s\n", want, atom)
+	}
+
+	feed.Items[0].ViaURL = "http://example.com/%zz"
+	if rss, err := feed.ToRss(); err == nil {
+		t.Errorf("expected an error for an invalid via url, got:\n%s", rss)
+	}
+	if atom, err := feed.ToAtom(); err == nil {
+		t.Errorf("expected an error for an invalid via url, got:\n%s", atom)
+	}
+	if err := feed.ValidateRSS(); err == nil {
+		t.Errorf("expected a validation error for an invalid via url")
+	}
+}
+
+func TestAtomOldestFirst(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2013-01-16T21:52:35-05:00")
+	feed := &Feed{
+		Title:   "jmoiron.net blog",
+		Link:    &Link{Href: "http://jmoiron.net/blog"},
+		Updated: now.Add(time.Hour),
+		Items: []*Item{
+			{Title: "newest", Id: "newest", Created: now.Add(2 * time.Hour)},
+			{Title: "undated", Id: "undated"},
+			// an earlier time in another zone
+			{Title: "oldest", Id: "oldest", Created: now.Add(-time.Hour).In(time.UTC)},
+			{Title: "middle", Id: "middle", Created: now},
+		},
+	}
+	ids := func(a *Atom) []string {
+		var got []string
+		for _, e := range a.AtomFeed().Entries {
+			got = append(got, e.Id)
+		}
+		return got
+	}
+	if got, want := ids(&Atom{Feed: feed}), []string{"newest", "undated", "oldest", "middle"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got entries %v, want %v", got, want)
+	}
+	// the undated entry has the feed's updated date
+	feed.OldestFirst = true
+	if got, want := ids(&Atom{Feed: feed}), []string{"oldest", "middle", "undated", "newest"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got entries %v oldest first, want %v", got, want)
+	}
+	if feed.Items[0].Id != "newest" || feed.Items[2].Id != "oldest" {
+		t.Errorf("OldestFirst reordered the feed's items")
+	}
+}
+
+func TestAlternateFeeds(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2013-01-16T21:52:35-05:00")
+	feed := &Feed{
+		Title:       "jmoiron.net blog",
+		Link:        &Link{Href: "http://jmoiron.net/blog"},
+		Description: "discussion about tech",
+		Created:     now,
+		AlternateFeeds: map[FeedType]string{
+			FeedTypeJSON:      "http://jmoiron.net/blog/feed.json",
+			FeedTypeRss:       "http://jmoiron.net/blog/feed.rss",
+			FeedTypeAtom:      "http://jmoiron.net/blog/feed.atom",
+			FeedTypeAmazonRss: "",
+		},
+	}
+	rss, err := feed.ToRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding RSS: %v", err)
+	}
+	want := `<atom:link href="http://jmoiron.net/blog/feed.atom" rel="alternate" type="application/atom+xml"></atom:link>
+    <atom:link href="http://jmoiron.net/blog/feed.json" rel="alternate" type="application/feed+json"></atom:link>`
+	if !strings.Contains(rss, want) || !strings.Contains(rss, `xmlns:atom="http://www.w3.org/2005/Atom"`) || strings.Contains(rss, "feed.rss") {
+		t.Errorf("Rss should link to the atom and json feeds.  Got:\n%s\n", rss)
+	}
+	amazon, err := feed.ToAmazonRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding Amazon RSS: %v", err)
+	}
+	want = `<atom:link href="http://jmoiron.net/blog/feed.rss" rel="alternate" type="application/rss+xml"></atom:link>
+    ` + want
+	if !strings.Contains(amazon, want) || !strings.Contains(amazon, `xmlns:atom="http://www.w3.org/2005/Atom"`) {
+		t.Errorf("AmazonRss should link to the rss, atom and json feeds.  Got:\n%s\n", amazon)
+	}
+
+	atom, err := feed.ToAtom()
+	if err != nil {
+		t.Errorf("unexpected error encoding Atom: %v", err)
+	}
+	want = `<link href="http://jmoiron.net/blog/feed.rss" rel="alternate" type="application/rss+xml"></link>
+  <link href="http://jmoiron.net/blog/feed.json" rel="alternate" type="application/feed+json"></link>`
+	if !strings.Contains(atom, want) || strings.Contains(atom, "feed.atom") {
+		t.Errorf("Atom should link to the rss and json feeds.  Got:\n%s\n", atom)
+	}
+
+	json, err := feed.ToJSON()
+	if err != nil {
+		t.Errorf("unexpected error encoding JSON: %v", err)
+	}
+	for _, want := range []string{
+		`"feed_url": "http://jmoiron.net/blog/feed.json"`,
+		`"_alternates": [
+    {
+      "url": "http://jmoiron.net/blog/feed.rss",
+      "type": "application/rss+xml"
+    },
+    {
+      "url": "http://jmoiron.net/blog/feed.atom",
+      "type": "application/atom+xml"
+    }
+  ]`,
+	} {
+		if !strings.Contains(json, want) {
+			t.Errorf("JSON missing %s.  Got:\n%s\n", want, json)
+		}
+	}
+
+	feed.AlternateFeeds = nil
+	if rss, _ = feed.ToRss(); strings.Contains(rss, "atom") {
+		t.Errorf("Rss should not use the atom namespace without alternates.  Got:\n%s\n", rss)
+	}
+}
+
+func TestOpenSearchURL(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2013-01-16T21:52:35-05:00")
+	feed := &Feed{
+		Title:       "jmoiron.net blog",
+		Link:        &Link{Href: "http://jmoiron.net/blog"},
+		Description: "discussion about tech",
+		Created:     now,
+	}
+	rss, _ := feed.ToRss()
+	atom, _ := feed.ToAtom()
+	if strings.Contains(rss, "search") || strings.Contains(atom, "search") {
+		t.Errorf("feeds without an OpenSearchURL should have no search link.  Got:\n%s\n%s\n", rss, atom)
+	}
+
+	feed.OpenSearchURL = "http://jmoiron.net/opensearch.xml"
+	rss, err := feed.ToRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding RSS: %v", err)
+	}
+	want := `<atom:link href="http://jmoiron.net/opensearch.xml" rel="search" type="application/opensearchdescription+xml"></atom:link>`
+	if !strings.Contains(rss, want) || !strings.Contains(rss, `xmlns:atom="http://www.w3.org/2005/Atom"`) {
+		t.Errorf("Rss missing %s.  Got:\n%s\n", want, rss)
+	}
+	amazon, err := feed.ToAmazonRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding Amazon RSS: %v", err)
+	}
+	if !strings.Contains(amazon, want) {
+		t.Errorf("AmazonRss missing %s.  Got:\n%s\n", want, amazon)
+	}
+	atom, err = feed.ToAtom()
+	if err != nil {
+		t.Errorf("unexpected error encoding Atom: %v", err)
+	}
+	want = `<link href="http://jmoiron.net/opensearch.xml" rel="search" type="application/opensearchdescription+xml"></link>`
+	if !strings.Contains(atom, want) {
+		t.Errorf("Atom missing %s.  Got:\n%s\n", want, atom)
+	}
+}
+
+func TestAmazonMarketplaces(t *testing.T) {
+	feed := &Feed{
+		Title:       "jmoiron.net blog",
+		Link:        &Link{Href: "http://jmoiron.net/blog"},
+		Description: "discussion about tech",
+		Items: []*Item{
+			{Title: "Best Headphones of 2019", Id: "headphones", Amazon: &AmazonItem{
+				Products: []*AmazonProduct{
+					{URL: "https://www.amazon.com/dp/B01?tag=jmoiron-20"},
+					{URL: "https://jmoiron.net/shop/B01"},
+				},
+			}},
+			{Title: "Best Grills of 2019", Id: "grills", Amazon: &AmazonItem{Marketplaces: []string{"US"}}},
+			{Title: "Best Kettles of 2019", Id: "kettles", Amazon: &AmazonItem{Marketplaces: []string{"uk", "DE"}}},
+		},
+	}
+	usFeed, ukFeed := *feed, *feed
+	usFeed.Marketplace, ukFeed.Marketplace = "US", "UK"
+	us, uk := &AmazonRss{Feed: &usFeed}, &AmazonRss{Feed: &ukFeed}
+	usOut, err := ToXML(us)
+	if err != nil {
+		t.Errorf("unexpected error encoding Amazon RSS: %v", err)
+	}
+	ukOut, err := ToXML(uk)
+	if err != nil {
+		t.Errorf("unexpected error encoding Amazon RSS: %v", err)
+	}
+	for _, test := range []struct {
+		name, out  string
+		want, skip []string
+	}{
+		{"US", usOut, []string{"<guid>grills</guid>", "<amzn:productURL>https://www.amazon.com/dp/B01?tag=jmoiron-20</amzn:productURL>"}, []string{"kettles"}},
+		{"UK", ukOut, []string{"<guid>kettles</guid>", "<amzn:productURL>https://www.amazon.co.uk/dp/B01?tag=jmoiron-20</amzn:productURL>"}, []string{"grills"}},
+	} {
+		for _, want := range append(test.want, "<guid>headphones</guid>", "<amzn:productURL>https://jmoiron.net/shop/B01</amzn:productURL>") {
+			if !strings.Contains(test.out, want) {
+				t.Errorf("%s AmazonRss missing %s.  Got:\n%s\n", test.name, want, test.out)
+			}
+		}
+		for _, skip := range test.skip {
+			if strings.Contains(test.out, skip) {
+				t.Errorf("%s AmazonRss should not have %s.  Got:\n%s\n", test.name, skip, test.out)
+			}
+		}
+	}
+
+	report, err := ukFeed.WriteWithReport(ioutil.Discard, FeedTypeAmazonRss)
+	if err != nil {
+		t.Errorf("unexpected error writing Amazon RSS: %v", err)
+	}
+	want := []DroppedItem{{Id: "grills", Reason: "not shown in the UK marketplace"}}
+	if !reflect.DeepEqual(report.Dropped, want) {
+		t.Errorf("got dropped %+v, want %+v", report.Dropped, want)
+	}
+	if all, _ := feed.WriteWithReport(ioutil.Discard, FeedTypeAmazonRss); len(all.Dropped) != 0 {
+		t.Errorf("got dropped %+v without a marketplace, want nothing dropped", all.Dropped)
+	}
+	if url := feed.Items[0].Amazon.Products[0].URL; url != "https://www.amazon.com/dp/B01?tag=jmoiron-20" {
+		t.Errorf("the marketplace changed the product url to %q", url)
+	}
+	feed.Marketplace = "Atlantis"
+	if out, err := ToXML(&AmazonRss{Feed: feed}); err == nil {
+		t.Errorf("expected an error for an unknown marketplace, got:\n%s", out)
+	}
+}
+
+func TestStylesheet(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2013-01-16T21:52:35-05:00")
+	feed := &Feed{
+		Title:       "jmoiron.net blog",
+		Link:        &Link{Href: "http://jmoiron.net/blog"},
+		Description: "discussion about tech",
+		Created:     now,
+	}
+	if rss, _ := feed.ToRss(); strings.Contains(rss, "xml-stylesheet") {
+		t.Errorf("Rss should have no stylesheet without one.  Got:\n%s\n", rss)
+	}
+
+	feed.Stylesheet = "/feed.xsl?v=1&theme=dark"
+	prologue := `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<?xml-stylesheet type="text/xsl" href="/feed.xsl?v=1&amp;theme=dark"?>`
+	rss, _ := feed.ToRss()
+	atom, _ := feed.ToAtom()
+	amazon, _ := feed.ToAmazonRss()
+	var buf bytes.Buffer
+	if err := feed.WriteRss(&buf); err != nil {
+		t.Errorf("unexpected error writing RSS: %v", err)
+	}
+	for _, test := range []struct{ name, out, root string }{
+		{"Rss", rss, "<rss"},
+		{"Atom", atom, "<feed"},
+		{"AmazonRss", amazon, "<rss"},
+		{"WriteRss", buf.String(), "<rss"},
+	} {
+		if !strings.HasPrefix(test.out, prologue+test.root) {
+			t.Errorf("%s should start with the stylesheet.  Got:\n%s\n", test.name, test.out)
+		}
+	}
+
+	feed.Stylesheet = "feed.css"
+	feed.Canonical = true
+	if rss, _ = feed.ToRss(); !strings.HasPrefix(rss, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+`<?xml-stylesheet type="text/css" href="feed.css"?><rss`) {
+		t.Errorf("Canonical Rss should start with the css stylesheet.  Got:\n%s\n", rss)
+	}
+}
+
+func TestTrimEmpty(t *testing.T) {
+	feed := &Feed{Title: "jmoiron.net blog", Items: []*Item{
+		{Title: "Limiting Concurrency in Go"},
+		{Title: " ", Description: "\n\t", Content: ""},
+		{Link: &Link{Href: "http://jmoiron.net/blog/microblog/"}},
+		{Link: &Link{}},
+		{Enclosure: &Enclosure{Url: "http://jmoiron.net/blog/episode.mp3", Type: "audio/mpeg", Length: "1"}},
+		{Content: "<p>Go's goroutines make it easy</p>"},
+		{},
+	}}
+	if n := feed.TrimEmpty(); n != 3 {
+		t.Errorf("TrimEmpty should remove 3 items, removed %d", n)
+	}
+	if len(feed.Items) != 4 {
+		t.Fatalf("feed should have 4 items left, has %d", len(feed.Items))
+	}
+	if feed.Items[0].Title != "Limiting Concurrency in Go" || feed.Items[1].Link == nil ||
+		feed.Items[2].Enclosure == nil || feed.Items[3].Content == "" {
+		t.Errorf("TrimEmpty kept the wrong items")
+	}
+	if n := feed.TrimEmpty(); n != 0 {
+		t.Errorf("TrimEmpty should remove nothing the second time, removed %d", n)
+	}
+}
+
+func TestMostRecent(t *testing.T) {
+	if i := (&Feed{}).MostRecent(); i != nil {
+		t.Errorf("expected no item for an empty feed, got %+v", i)
+	}
+	now, _ := time.Parse(time.RFC3339, "2013-01-16T21:52:35-05:00")
+	items := []*Item{
+		{Title: "Old", Created: now.Add(-48 * time.Hour)},
+		nil,
+		{Title: "Updated", Created: now.Add(-72 * time.Hour), Updated: now},
+		{Title: "Created", Created: now},
+		{Title: "Unpublished", Created: now.Add(time.Hour), Status: ItemUnpublished},
+		{Title: "Older", Created: now.Add(-24 * time.Hour), Updated: now.Add(-time.Hour)},
+	}
+	feed := &Feed{Title: "jmoiron.net blog", Items: append([]*Item(nil), items...)}
+	if i := feed.MostRecent(); i == nil || i.Title != "Updated" {
+		t.Errorf("expected the first of the latest items, got %+v", i)
+	}
+	for n := range items {
+		if feed.Items[n] != items[n] {
+			t.Errorf("expected the items to be left in their order, item %d moved", n)
+		}
+	}
+	feed.Items = []*Item{{Title: "Undated"}, {Title: "Also undated"}}
+	if i := feed.MostRecent(); i == nil || i.Title != "Undated" {
+		t.Errorf("expected the first undated item, got %+v", i)
+	}
+}
+
+func TestAmazonChannel(t *testing.T) {
+	feed := profileTestFeed()
+	feed.Amazon = nil
+	amazon, err := feed.ToAmazonRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding Amazon RSS: %v", err)
+	}
+	if strings.Contains(amazon, "amzn:publisherId") || strings.Contains(amazon, "amzn:programId") {
+		t.Errorf("AmazonRss should have no ids without them.  Got:\n%s\n", amazon)
+	}
+	if err := AmazonStrict().Validate(feed); err == nil || !strings.Contains(err.Error(), "feed has no publisher id") {
+		t.Errorf("AmazonStrict should require a publisher id, got %v", err)
+	}
+
+	feed.Amazon = &AmazonChannel{PublisherID: "jmoiron-blog", ProgramID: "spring/2013 & friends"}
+	amazon, err = feed.ToAmazonRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding Amazon RSS: %v", err)
+	}
+	for _, want := range []string{
+		"<amzn:publisherId>jmoiron-blog</amzn:publisherId>",
+		"<amzn:programId>spring/2013 &amp; friends</amzn:programId>",
+	} {
+		if !strings.Contains(amazon, want) {
+			t.Errorf("AmazonRss missing %s.  Got:\n%s\n", want, amazon)
+		}
+	}
+	if err := AmazonStrict().Validate(feed); err != nil {
+		t.Errorf("unexpected error validating with AmazonStrict: %v", err)
+	}
+	parsed, err := ParseAmazonRss(strings.NewReader(amazon))
+	if err != nil {
+		t.Fatalf("unexpected error parsing Amazon RSS: %v", err)
+	}
+	if !reflect.DeepEqual(parsed.Amazon, feed.Amazon) {
+		t.Errorf("got amazon ids %+v, want %+v", parsed.Amazon, feed.Amazon)
+	}
+
+	feed.Amazon.PublisherID = " jmoiron-blog\n"
+	if _, err := feed.ToAmazonRss(); err == nil || !strings.Contains(err.Error(), "amzn:publisherId") {
+		t.Errorf("ToAmazonRss should fail for the whitespace, got %v", err)
+	}
+	if err := feed.ValidateAmazonRss(); err == nil {
+		t.Errorf("ValidateAmazonRss should fail for the whitespace")
+	}
+	if err := AmazonStrict().Validate(feed); err == nil {
+		t.Errorf("AmazonStrict should fail for the whitespace")
+	}
+}
+
+func TestToMap(t *testing.T) {
+	created, _ := time.Parse(time.RFC3339Nano, "2013-01-16T21:52:35.123456789-05:00")
+	feed := &Feed{
+		Title: "jmoiron.net blog",
+		Link:  &Link{Href: "http://jmoiron.net/blog"},
+		Items: []*Item{
+			{Title: "Limiting Concurrency in Go", Id: "limiting-concurrency-in-go", Created: created},
+			{Title: "Logic-less Template Redux", Id: "logicless-template-redux"},
+		},
+	}
+	m, err := feed.ToMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m["title"] != "jmoiron.net blog" || m["home_page_url"] != "http://jmoiron.net/blog" {
+		t.Errorf("ToMap got %v", m)
+	}
+	items, ok := m["items"].([]map[string]interface{})
+	if !ok || len(items) != 2 {
+		t.Fatalf("ToMap items should be two maps, got %#v", m["items"])
+	}
+	if items[0]["date_published"] != "2013-01-16T21:52:35-05:00" {
+		t.Errorf("ToMap date should be RFC3339, got %v", items[0]["date_published"])
+	}
+	if _, ok := items[1]["date_published"]; ok {
+		t.Errorf("ToMap should leave out dates which are not set, got %v", items[1])
+	}
+
+	tmpl := template.Must(template.New("").Parse(`{{.title}}:{{range .items}} {{.id}}{{end}}`))
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, m); err != nil {
+		t.Fatal(err)
+	}
+	if want := "jmoiron.net blog: limiting-concurrency-in-go logicless-template-redux"; buf.String() != want {
+		t.Errorf("template got %q, want %q", buf.String(), want)
+	}
+
+	var errs []error
+	feed.Logger = LoggerFunc(func(event string, keyvals ...interface{}) {
+		if err, ok := keyvals[len(keyvals)-1].(error); ok && event == EventGenerateFinish {
+			errs = append(errs, err)
+		}
+	})
+	feed.Author = &Author{Name: "Jason Moiron", JSONExtensions: map[string]interface{}{"internal_id": "42"}}
+	if m, err := feed.ToMap(); m != nil || err == nil || len(errs) != 1 || errs[0] != err {
+		t.Errorf("ToMap should return and log the error for a feed which cannot be encoded, got %v, %v, %v", m, err, errs)
+	}
+}
+
+func TestEnclosureLength(t *testing.T) {
+	feed := &Feed{
+		Title:       "jmoiron.net blog",
+		Link:        &Link{Href: "http://jmoiron.net/blog"},
+		Description: "discussion about tech, footie, photos",
+		Items: []*Item{
+			{Title: "Placeholder", Enclosure: &Enclosure{Url: "http://jmoiron.net/placeholder.mp3", Type: "audio/mpeg", Length: "0"}},
+			{Title: "Unknown size", Enclosure: &Enclosure{Url: "http://jmoiron.net/unknown.mp3", Type: "audio/mpeg"}},
+		},
+	}
+	var rss string
+	for n, encode := range []func() (string, error){feed.ToRss, feed.ToAmazonRss} {
+		out, err := encode()
+		if n == 0 {
+			rss = out
+		}
+		if err != nil {
+			t.Fatalf("unexpected error encoding: %v", err)
+		}
+		for _, want := range []string{
+			`<enclosure url="http://jmoiron.net/placeholder.mp3" length="0" type="audio/mpeg"></enclosure>`,
+			`<enclosure url="http://jmoiron.net/unknown.mp3" type="audio/mpeg"></enclosure>`,
+		} {
+			if !strings.Contains(out, want) {
+				t.Errorf("Rss missing %s.  Got:\n%s\n", want, out)
+			}
+		}
+	}
+
+	parsed, err := ParseRss(strings.NewReader(rss))
+	if err != nil {
+		t.Fatalf("unexpected error parsing: %v", err)
+	}
+	if got := parsed.Items[0].Enclosure.Length; got != "0" {
+		t.Errorf("expected the explicit zero length to be kept, got %q", got)
+	}
+	if got := parsed.Items[1].Enclosure.Length; got != "" {
+		t.Errorf("expected the unset length to stay unset, got %q", got)
+	}
+}
+
+func TestDefaultEnclosureType(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2013-01-16T21:52:35-05:00")
+	typed := &Enclosure{Url: "http://jmoiron.net/episode-2.ogg", Type: "audio/ogg", Length: "2048"}
+	untyped := &Enclosure{Url: "http://jmoiron.net/episode-1.mp3", Length: "1024"}
+	feed := &Feed{
+		Title:       "jmoiron.net blog",
+		Link:        &Link{Href: "http://jmoiron.net/blog"},
+		Description: "discussion about tech, footie, photos",
+		Created:     now,
+		Items: []*Item{
+			{Id: "episode-1", Title: "Episode 1", Enclosure: untyped},
+			{Id: "episode-2", Title: "Episode 2", Enclosure: typed},
+		},
+	}
+	if rss, _ := feed.ToRss(); strings.Contains(rss, "episode-1.mp3") {
+		t.Errorf("expected the enclosure without a type to be left out.  Got:\n%s\n", rss)
+	}
+
+	feed.DefaultEnclosureType = "audio/mpeg"
+	for name, to := range map[string]func() (string, error){"Rss": feed.ToRss, "AmazonRss": feed.ToAmazonRss} {
+		out, err := to()
+		if err != nil {
+			t.Fatalf("unexpected error encoding %s: %v", name, err)
+		}
+		for _, want := range []string{
+			`<enclosure url="http://jmoiron.net/episode-1.mp3" length="1024" type="audio/mpeg"></enclosure>`,
+			`<enclosure url="http://jmoiron.net/episode-2.ogg" length="2048" type="audio/ogg"></enclosure>`,
+		} {
+			if !strings.Contains(out, want) {
+				t.Errorf("%s missing %s.  Got:\n%s\n", name, want, out)
+			}
+		}
+	}
+	atom, _ := feed.ToAtom()
+	if want := `<link href="http://jmoiron.net/episode-1.mp3" rel="enclosure" type="audio/mpeg" length="1024"></link>`; !strings.Contains(atom, want) {
+		t.Errorf("Atom missing %s.  Got:\n%s\n", want, atom)
+	}
+	if err := feed.ValidateRSS(); err != nil {
+		t.Errorf("unexpected error validating: %v", err)
+	}
+	if untyped.Type != "" {
+		t.Errorf("expected the enclosure to be left as it is, got the type %q", untyped.Type)
+	}
+}
+
+func TestDefaultIsPermaLink(t *testing.T) {
+	yes, no := true, false
+	feed := &Feed{
+		Title:       "jmoiron.net blog",
+		Link:        &Link{Href: "http://jmoiron.net/blog"},
+		Description: "discussion about tech, footie, photos",
+		Items: []*Item{
+			{Title: "uuid", Id: "urn:uuid:6b1bfa03-2f5f-4c4b-8a4c-2b8c9b0c0c8e"},
+			{Title: "link", Id: "http://jmoiron.net/blog/link/", Link: &Link{Href: "http://jmoiron.net/blog/link/"}},
+			{Title: "explicit", Id: "http://jmoiron.net/blog/explicit/", IsPermaLink: &yes},
+			{Title: "not", Id: "http://jmoiron.net/blog/not/", Link: &Link{Href: "http://jmoiron.net/blog/not/"}, IsPermaLink: &no},
+		},
+	}
+	for _, test := range []struct {
+		def  bool
+		want []string
+	}{
+		{false, []string{"false", "true", "true", "false"}},
+		{true, []string{"false", "true", "true", "false"}},
+	} {
+		feed.DefaultIsPermaLink = test.def
+		out, err := ToXML(&Rss{Feed: feed})
+		if err != nil {
+			t.Fatalf("unexpected error encoding RSS: %v", err)
+		}
+		for n, i := range feed.Items {
+			want := fmt.Sprintf(`<guid isPermaLink="%s">%s</guid>`, test.want[n], i.Id)
+			if !strings.Contains(out, want) {
+				t.Errorf("Rss with the default %v missing %s.  Got:\n%s\n", test.def, want, out)
+			}
+		}
+	}
+}
+
+func TestRssItemGuid(t *testing.T) {
+	// the guid of an RssItem built directly is a string
+	out, err := ToXML(&RssFeed{Items: []*RssItem{
+		{Title: "plain", Guid: "http://jmoiron.net/blog/plain/"},
+		{Title: "attribute", Guid: "tag:jmoiron.net,2013:attribute", IsPermaLink: "false"},
+		{Title: "none"},
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error encoding RSS: %v", err)
+	}
+	for _, want := range []string{
+		"<title>plain</title>\n      <description></description>\n      <guid>http://jmoiron.net/blog/plain/</guid>",
+		`<guid isPermaLink="false">tag:jmoiron.net,2013:attribute</guid>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Rss missing %s.  Got:\n%s\n", want, out)
+		}
+	}
+	if n := strings.Count(out, "<guid"); n != 2 {
+		t.Errorf("expected 2 guids, got %d.  Got:\n%s\n", n, out)
+	}
+}
+
+func TestTagGuidWithLink(t *testing.T) {
+	feed := &Feed{
+		Title:       "jmoiron.net blog",
+		Link:        &Link{Href: "http://jmoiron.net/blog"},
+		Description: "discussion about tech, footie, photos",
+		Items: []*Item{{
+			Title: "Limiting Concurrency in Go",
+			Id:    "tag:jmoiron.net,2013-01-16:/blog/limiting-concurrency-in-go/",
+			Link:  &Link{Href: "http://jmoiron.net/blog/limiting-concurrency-in-go/"},
+		}},
+	}
+	for _, def := range []bool{false, true} {
+		feed.DefaultIsPermaLink = def
+		out, err := ToXML(&Rss{Feed: feed})
+		if err != nil {
+			t.Fatalf("unexpected error encoding RSS: %v", err)
+		}
+		for _, want := range []string{
+			`<guid isPermaLink="false">tag:jmoiron.net,2013-01-16:/blog/limiting-concurrency-in-go/</guid>`,
+			`<link>http://jmoiron.net/blog/limiting-concurrency-in-go/</link>`,
+		} {
+			if !strings.Contains(out, want) {
+				t.Errorf("Rss with the default %v missing %s.  Got:\n%s\n", def, want, out)
+			}
+		}
+	}
+}
+
+// a value whose marshaling fails, like a broken extension
+type failingMarshaler struct{}
+
+func (failingMarshaler) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return errors.New("broken marshaler")
+}
+
+func (failingMarshaler) MarshalJSON() ([]byte, error) {
+	return nil, errors.New("broken marshaler")
+}
+
+// an XmlFeed with a failing element after some which marshal
+type failingXmlFeed struct{}
+
+func (failingXmlFeed) FeedXml() interface{} {
+	return &struct {
+		XMLName xml.Name `xml:"rss"`
+		Title   string   `xml:"title"`
+		Broken  failingMarshaler
+	}{Title: "jmoiron.net blog"}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	s, err := ToXML(failingXmlFeed{})
+	if err == nil || s != "" || !strings.Contains(err.Error(), "broken marshaler") {
+		t.Errorf("expected an error and no output, got %q, %v", s, err)
+	}
+
+	now, _ := time.Parse(time.RFC3339, "2013-01-16T21:52:35-05:00")
+	feed := &Feed{
+		Title:   "jmoiron.net blog",
+		Link:    &Link{Href: "http://jmoiron.net/blog"},
+		Author:  &Author{Name: "Jason Moiron", JSONExtensions: map[string]interface{}{"_broken": failingMarshaler{}}},
+		Created: now,
+		Items:   []*Item{{Id: "footie-season", Title: "Footie Season"}},
+	}
+	s, err = feed.ToJSON()
+	if err == nil || s != "" || !strings.HasPrefix(err.Error(), "feeds: encoding json: ") {
+		t.Errorf("expected a json encoding error and no output, got %q, %v", s, err)
+	}
+	if err := feed.WriteJSON(&bytes.Buffer{}); err == nil || !strings.HasPrefix(err.Error(), "feeds: encoding json: ") {
+		t.Errorf("expected a json encoding error writing the feed, got %v", err)
+	}
+	if m, err := feed.ToMap(); m != nil || err == nil || !strings.HasPrefix(err.Error(), "feeds: encoding json: ") {
+		t.Errorf("expected a json encoding error and no map, got %v, %v", m, err)
+	}
+	// the xml formats do not write the json extensions
+	for _, to := range []func() (string, error){feed.ToRss, feed.ToAtom, feed.ToAmazonRss} {
+		if s, err := to(); err != nil || s == "" {
+			t.Errorf("unexpected error encoding xml: %v", err)
+		}
+	}
+}
+
+func TestFormatOptions(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2013-01-16T21:52:35-05:00")
+	feed := &Feed{
+		Title:              "jmoiron.net blog",
+		Link:               &Link{Href: "http://jmoiron.net/blog"},
+		Description:        "discussion about tech, footie, photos",
+		Created:            now,
+		DefaultIsPermaLink: true,
+		SubtitleType:       "html",
+		CDATADescription:   true,
+		Items: []*Item{{
+			Id:          "http://jmoiron.net/blog/limiting-concurrency-in-go/",
+			Title:       "Limiting Concurrency in Go",
+			Description: "<p>A discussion on controlled parallelism in golang</p>",
+			Created:     now,
+		}},
+	}
+	// the options of the Feed apply to the wrappers without them
+	for name, test := range map[string]struct {
+		write func(io.Writer) error
+		want  string
+	}{
+		"rss":    {feed.WriteRss, `<guid isPermaLink="true">http://jmoiron.net/blog/limiting-concurrency-in-go/</guid>`},
+		"atom":   {feed.WriteAtom, `<subtitle type="html">discussion about tech, footie, photos</subtitle>`},
+		"amazon": {feed.WriteAmazonRss, "<![CDATA[<p>A discussion"},
+	} {
+		var buf bytes.Buffer
+		if err := test.write(&buf); err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+		}
+		if !strings.Contains(buf.String(), test.want) {
+			t.Errorf("%s missing %s.  Got:\n%s\n", name, test.want, buf.String())
+		}
+	}
+	if rss, err := ToXML(&Rss{feed}); err != nil || !strings.Contains(rss, `isPermaLink="true"`) {
+		t.Errorf("Rss{feed} should use the options of the feed, got %v:\n%s", err, rss)
+	}
+}
+
+// testCreated is when the feeds built by testFeed were created.
+var testCreated, _ = time.Parse(time.RFC3339, "2013-01-16T21:52:35-05:00")
+
+// testFeed returns the jmoiron.net blog with items, the feed the tests
+// build on; they set whatever other fields they exercise on the result.
+func testFeed(items ...*Item) *Feed {
+	return &Feed{
+		Title:       "jmoiron.net blog",
+		Link:        &Link{Href: "http://jmoiron.net/blog"},
+		Description: "discussion about tech, footie, photos",
+		Created:     testCreated,
+		Items:       items,
+	}
+}
@@ -0,0 +1,156 @@
+package feeds
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Fetcher makes the requests of the helpers which use the network, like
+// CheckImages, so they share their timeouts, User-Agent and limits. The
+// zero value of each field uses the one of DefaultFetcher. A Fetcher must
+// not be copied after its first request.
+type Fetcher struct {
+	// Client makes the requests, and its Timeout bounds each of them.
+	Client *http.Client
+
+	// UserAgent is sent with each request.
+	UserAgent string
+
+	// MaxConcurrentPerHost is how many requests to the same host can be
+	// in flight at once, each one until its body is closed.
+	MaxConcurrentPerHost int
+
+	// MaxBodyBytes is how much of a response body can be read before
+	// reading it fails.
+	MaxBodyBytes int64
+
+	mu    sync.Mutex
+	hosts map[string]chan struct{} // the slots of requests to each host
+}
+
+// DefaultFetcher is the Fetcher used by the network helpers without one.
+var DefaultFetcher = &Fetcher{
+	Client:               &http.Client{Timeout: 10 * time.Second},
+	UserAgent:            generatorName + "/" + Version,
+	MaxConcurrentPerHost: 2,
+	MaxBodyBytes:         1 << 20,
+}
+
+// the fetcher to use for f
+func fetcher(f *Fetcher) *Fetcher {
+	if f == nil {
+		return DefaultFetcher
+	}
+	return f
+}
+
+func (f *Fetcher) client() *http.Client {
+	if f.Client == nil {
+		return DefaultFetcher.Client
+	}
+	return f.Client
+}
+
+func (f *Fetcher) userAgent() string {
+	if f.UserAgent == "" {
+		return DefaultFetcher.UserAgent
+	}
+	return f.UserAgent
+}
+
+func (f *Fetcher) maxConcurrentPerHost() int {
+	if f.MaxConcurrentPerHost <= 0 {
+		return DefaultFetcher.MaxConcurrentPerHost
+	}
+	return f.MaxConcurrentPerHost
+}
+
+func (f *Fetcher) maxBodyBytes() int64 {
+	if f.MaxBodyBytes <= 0 {
+		return DefaultFetcher.MaxBodyBytes
+	}
+	return f.MaxBodyBytes
+}
+
+// the slots of the requests to host
+func (f *Fetcher) slots(host string) chan struct{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.hosts == nil {
+		f.hosts = map[string]chan struct{}{}
+	}
+	s, ok := f.hosts[host]
+	if !ok {
+		s = make(chan struct{}, f.maxConcurrentPerHost())
+		f.hosts[host] = s
+	}
+	return s
+}
+
+// Do sends a request with method to link with the User-Agent of the
+// fetcher, once there is a slot for its host or ctx is done. The body of
+// the response must be closed to free the slot, and fails to read past
+// MaxBodyBytes.
+func (f *Fetcher) Do(ctx context.Context, method, link string) (*http.Response, error) {
+	req, err := http.NewRequest(method, link, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("User-Agent", f.userAgent())
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	slots := f.slots(req.URL.Host)
+	select {
+	case slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	resp, err := f.client().Do(req)
+	if err != nil {
+		<-slots
+		return nil, err
+	}
+	resp.Body = &fetchedBody{body: resp.Body, left: f.maxBodyBytes(), slots: slots}
+	return resp, nil
+}
+
+var errBodyTooLarge = errors.New("feeds: response body is larger than MaxBodyBytes")
+
+// the body of a response, which holds a slot of its host until closed
+type fetchedBody struct {
+	body   io.ReadCloser
+	left   int64 // how many more bytes can be read
+	slots  chan struct{}
+	closed sync.Once
+}
+
+func (b *fetchedBody) Read(p []byte) (int, error) {
+	if b.left <= 0 {
+		// only fail when there is more to read than the limit
+		var one [1]byte
+		n, err := b.body.Read(one[:])
+		if n > 0 {
+			return 0, errBodyTooLarge
+		}
+		return 0, err
+	}
+	if int64(len(p)) > b.left {
+		p = p[:b.left]
+	}
+	n, err := b.body.Read(p)
+	b.left -= int64(n)
+	return n, err
+}
+
+func (b *fetchedBody) Close() error {
+	err := b.body.Close()
+	b.closed.Do(func() { <-b.slots })
+	return err
+}
@@ -0,0 +1,132 @@
+package feeds
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// a RoundTripper answering requests with a function, so no test needs the
+// network
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (fn roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return fn(r)
+}
+
+// a Fetcher whose requests are answered by fn
+func testFetcher(fn func(*http.Request) (*http.Response, error)) *Fetcher {
+	return &Fetcher{Client: &http.Client{Transport: roundTripFunc(fn)}}
+}
+
+// a response with status, content type and body
+func testResponse(r *http.Request, status int, typ, body string) *http.Response {
+	header := http.Header{}
+	if typ != "" {
+		header.Set("Content-Type", typ)
+	}
+	return &http.Response{
+		StatusCode: status,
+		Status:     strconv.Itoa(status) + " " + http.StatusText(status),
+		Header:     header,
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+		Request:    r,
+	}
+}
+
+func TestFetcherDo(t *testing.T) {
+	f := testFetcher(func(r *http.Request) (*http.Response, error) {
+		if got, want := r.Header.Get("User-Agent"), "feeds/"+Version; got != want {
+			t.Errorf("expected the User-Agent %q, got %q", want, got)
+		}
+		return testResponse(r, 200, "text/plain", "discussion about tech, footie, photos"), nil
+	})
+	f.MaxBodyBytes = 10
+	resp, err := f.Do(context.Background(), "GET", "http://jmoiron.net/blog")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != errBodyTooLarge || string(body) != "discussion" {
+		t.Errorf("expected the body to stop at 10 bytes, got %q, %v", body, err)
+	}
+
+	f.MaxBodyBytes = 37
+	f.UserAgent = "jmoiron.net/1.0"
+	f.Client.Transport = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if got := r.Header.Get("User-Agent"); got != "jmoiron.net/1.0" {
+			t.Errorf("expected the User-Agent jmoiron.net/1.0, got %q", got)
+		}
+		return testResponse(r, 200, "text/plain", "discussion about tech, footie, photos"), nil
+	})
+	resp, err = f.Do(context.Background(), "GET", "http://jmoiron.net/blog")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	body, err = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || len(body) != 37 {
+		t.Errorf("expected a body of exactly the limit to be read, got %q, %v", body, err)
+	}
+}
+
+func TestFetcherConcurrentPerHost(t *testing.T) {
+	var mu sync.Mutex
+	active, most := 0, 0
+	f := testFetcher(func(r *http.Request) (*http.Response, error) {
+		mu.Lock()
+		active++
+		if active > most {
+			most = active
+		}
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		active--
+		mu.Unlock()
+		return testResponse(r, 200, "", ""), nil
+	})
+	f.MaxConcurrentPerHost = 2
+	var wg sync.WaitGroup
+	for n := 0; n < 8; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := f.Do(context.Background(), "HEAD", "http://jmoiron.net/logo.png")
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			resp.Body.Close()
+		}()
+	}
+	wg.Wait()
+	if most > 2 {
+		t.Errorf("expected at most 2 requests to a host at once, got %d", most)
+	}
+
+	// a request waiting for a slot stops with its context
+	held, err := f.Do(context.Background(), "HEAD", "http://jmoiron.net/logo.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer held.Body.Close()
+	held2, _ := f.Do(context.Background(), "HEAD", "http://jmoiron.net/logo.png")
+	defer held2.Body.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := f.Do(ctx, "HEAD", "http://jmoiron.net/logo.png"); err != context.DeadlineExceeded {
+		t.Errorf("expected the request to wait for a slot until its deadline, got %v", err)
+	}
+	other, err := f.Do(context.Background(), "HEAD", "http://example.com/logo.png")
+	if err != nil {
+		t.Fatalf("expected other hosts to have their own slots, got %v", err)
+	}
+	other.Body.Close()
+}
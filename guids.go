@@ -0,0 +1,96 @@
+package feeds
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// the key items are matched by across versions of a feed when their ids
+// cannot be relied upon: the href of their link, with the scheme and host
+// lowercased and without a fragment or trailing slash. Items without a
+// link have no key.
+func itemLinkKey(i *Item) string {
+	if i == nil || i.Link == nil || strings.TrimSpace(i.Link.Href) == "" {
+		return ""
+	}
+	href := strings.TrimSpace(i.Link.Href)
+	u, err := url.Parse(href)
+	if err != nil {
+		return href
+	}
+	u.Scheme, u.Host, u.Fragment = strings.ToLower(u.Scheme), strings.ToLower(u.Host), ""
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	return u.String()
+}
+
+// CheckGuidStability returns an issue for each published item of current
+// whose link matches an item of previous but whose written id differs from
+// it, with the IDScheme of each feed applied, so readers would see it as a
+// new item.
+func CheckGuidStability(previous, current *Feed) []ValidationIssue {
+	issues, _ := checkGuidStability(previous, current)
+	return issues
+}
+
+// the guid issues of current, and the number of its items matched by link
+func checkGuidStability(previous, current *Feed) ([]ValidationIssue, int) {
+	ids := map[string]string{}
+	for _, i := range previous.Items {
+		if key := itemLinkKey(i); key != "" && i.published() {
+			if _, seen := ids[key]; !seen {
+				ids[key] = previous.itemId(i)
+			}
+		}
+	}
+	var issues []ValidationIssue
+	matched := 0
+	for n, i := range current.Items {
+		if !i.published() {
+			continue
+		}
+		id, ok := ids[itemLinkKey(i)]
+		if !ok {
+			continue
+		}
+		matched++
+		if now := current.itemId(i); now != id {
+			issues = append(issues, ValidationIssue{
+				Path:    fmt.Sprintf("item[%d]", n),
+				Message: fmt.Sprintf("guid of %s changed from %q to %q", i.Link.Href, id, now),
+			})
+		}
+	}
+	return issues, matched
+}
+
+// GuidStability guards a deploy against changing the guids of many items
+// at once, which readers would all show again as new.
+type GuidStability struct {
+	// MaxChangedPercent is the highest percentage of the items matched by
+	// link whose guid may change, none when 0.
+	MaxChangedPercent float64
+}
+
+// GuidStabilityError is returned by GuidStability.Check when more guids
+// changed than allowed.
+type GuidStabilityError struct {
+	Changed, Matched int
+	Issues           []ValidationIssue
+}
+
+func (e *GuidStabilityError) Error() string {
+	return fmt.Sprintf("feeds: the guids of %d of %d items changed (%.1f%%)", e.Changed, e.Matched,
+		100*float64(e.Changed)/float64(e.Matched))
+}
+
+// Check returns a GuidStabilityError when the guids of more than the
+// MaxChangedPercent of the items of current which match an item of
+// previous by link, as CheckGuidStability matches them, changed.
+func (g GuidStability) Check(previous, current *Feed) error {
+	issues, matched := checkGuidStability(previous, current)
+	if len(issues) == 0 || 100*float64(len(issues)) <= g.MaxChangedPercent*float64(matched) {
+		return nil
+	}
+	return &GuidStabilityError{Changed: len(issues), Matched: matched, Issues: issues}
+}
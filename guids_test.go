@@ -0,0 +1,72 @@
+package feeds
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func guidTestFeed(ids ...string) *Feed {
+	feed := testFeed()
+	for n, id := range ids {
+		feed.Add(&Item{Id: id, Link: &Link{Href: "http://jmoiron.net/blog/post-" + string(rune('a'+n)) + "/"}})
+	}
+	return feed
+}
+
+func TestCheckGuidStability(t *testing.T) {
+	previous := guidTestFeed("a", "b", "c", "d")
+	current := guidTestFeed("a", "b2", "c", "d")
+	// matched by link without the trailing slash, fragment or host case
+	current.Items[2].Link.Href = "http://JMOIRON.net/blog/post-c#comments"
+	current.Items[3].Link.Href = "http://jmoiron.net/blog/post-d/"
+	current.Items[3].Id = "d2"
+	current.Add(&Item{Id: "e", Link: &Link{Href: "http://jmoiron.net/blog/post-e/"}})
+
+	want := []string{
+		`item[1]: guid of http://jmoiron.net/blog/post-b/ changed from "b" to "b2"`,
+		`item[3]: guid of http://jmoiron.net/blog/post-d/ changed from "d" to "d2"`,
+	}
+	if got := issueStrings(CheckGuidStability(previous, current)); !reflect.DeepEqual(got, want) {
+		t.Errorf("got issues:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// a new id scheme changes every written guid
+	current = guidTestFeed("a", "b", "c", "d")
+	current.IDScheme = UUIDURNs
+	if got := CheckGuidStability(previous, current); len(got) != 4 {
+		t.Errorf("an id scheme change should change all 4 guids, got %v", got)
+	}
+	if got := CheckGuidStability(previous, guidTestFeed("a", "b", "c", "d")); len(got) != 0 {
+		t.Errorf("unchanged guids should have no issues, got %v", got)
+	}
+}
+
+func TestGuidStability(t *testing.T) {
+	previous := guidTestFeed("a", "b", "c", "d")
+	current := guidTestFeed("a", "b2", "c", "d")
+	for _, test := range []struct {
+		max float64
+		ok  bool
+	}{
+		{0, false},
+		{20, false},
+		{25, true},
+		{100, true},
+	} {
+		err := GuidStability{MaxChangedPercent: test.max}.Check(previous, current)
+		if (err == nil) != test.ok {
+			t.Errorf("max %v%%: got %v", test.max, err)
+		}
+		stability, _ := err.(*GuidStabilityError)
+		if err != nil && (stability == nil || stability.Changed != 1 || stability.Matched != 4 || len(stability.Issues) != 1) {
+			t.Errorf("max %v%%: got %#v", test.max, err)
+		}
+		if err != nil && err.Error() != "feeds: the guids of 1 of 4 items changed (25.0%)" {
+			t.Errorf("max %v%%: got message %q", test.max, err)
+		}
+	}
+	if err := (GuidStability{}).Check(previous, guidTestFeed("a", "b", "c", "d")); err != nil {
+		t.Errorf("unchanged guids should pass, got %v", err)
+	}
+}
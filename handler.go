@@ -0,0 +1,163 @@
+package feeds
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Handler is an http.Handler serving a Feed in the format selected by Type.
+// Responses have an ETag from the feed's ContentHash and a Last-Modified
+// date from its Updated date, and conditional GET and HEAD requests get a
+// 304 Not Modified while those do not change. The ContentHash covers the
+// fields and options of the feed, so changing how it is written, like its
+// Preview, changes the ETag too; see ContentHash for what it leaves out.
+type Handler struct {
+	Feed *Feed
+	Type FeedType
+
+	// DeriveCacheControl sets a Cache-Control max-age from the feed's TTL,
+	// or from its Syndication update period when it has none. A
+	// Cache-Control header already set by a wrapping handler is never
+	// overridden, and no header is set for a feed without a TTL.
+	DeriveCacheControl bool
+
+	// CDNMaxAge, when non-zero, adds an s-maxage directive to the derived
+	// Cache-Control header for shared caches.
+	CDNMaxAge time.Duration
+
+	// CapMaxAgeAtExpiry caps the derived ages at the time left until the
+	// next Expires date of the feed's items, judged with the feed's clock,
+	// so clients poll again right after an item expires. A feed without a
+	// TTL then gets a max-age from it alone. Expires dates in the past are
+	// ignored.
+	CapMaxAgeAtExpiry bool
+
+	// MinMaxAge is the shortest age the expiry cap sets, to keep clients
+	// from all polling at once; a minute when zero.
+	MinMaxAge time.Duration
+}
+
+// Handler returns a Handler serving this feed in the format selected by t.
+func (f *Feed) Handler(t FeedType) *Handler {
+	return &Handler{Feed: f, Type: t}
+}
+
+// the Cache-Control value derived from the feed's TTL, or ""
+func (h *Handler) cacheControl() string {
+	ttl := h.Feed.TTL
+	if ttl == 0 {
+		ttl = h.Feed.Syndication.ttl()
+	}
+	maxAge, cdnMaxAge := time.Duration(ttl)*time.Minute, h.CDNMaxAge
+	if until, ok := h.untilExpiry(); ok && h.CapMaxAgeAtExpiry {
+		floor := h.MinMaxAge
+		if floor <= 0 {
+			floor = time.Minute
+		}
+		if until < floor {
+			until = floor
+		}
+		if maxAge <= 0 || until < maxAge {
+			maxAge = until
+		}
+		if cdnMaxAge > 0 && until < cdnMaxAge {
+			cdnMaxAge = until
+		}
+	}
+	if maxAge <= 0 {
+		return ""
+	}
+	directives := []string{fmt.Sprintf("max-age=%d", int64(maxAge/time.Second))}
+	if cdnMaxAge > 0 {
+		directives = append(directives, fmt.Sprintf("s-maxage=%d", int64(cdnMaxAge/time.Second)))
+	}
+	return strings.Join(directives, ", ")
+}
+
+// the weak entity tag of the feed in the handler's format, which changes
+// with the content and options of the feed but not with its Updated date
+func (h *Handler) etag() string {
+	return `W/"` + h.Feed.ContentHash() + "-" + h.Type.String() + `"`
+}
+
+// the Last-Modified date of the feed: its Updated or Created date, else the
+// most recent one of its items
+func (h *Handler) lastModified() time.Time {
+	return FirstNonZeroTime(h.Feed.lastModified(), h.Feed.latestItem())
+}
+
+// whether the conditional headers of r match the etag and modification date
+// of the response; If-None-Match takes precedence over If-Modified-Since
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if r.Method != "GET" && r.Method != "HEAD" {
+		return false
+	}
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, tag := range strings.Split(match, ",") {
+			tag = strings.TrimSpace(tag)
+			// the weak comparison of rfc 7232
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	return err == nil && !modified.IsZero() && !modified.Truncate(time.Second).After(since)
+}
+
+// the time left until the next Expires date of the items, if any
+func (h *Handler) untilExpiry() (time.Duration, bool) {
+	now := h.Feed.now()
+	var next time.Time
+	for _, i := range h.Feed.Items {
+		if i.published() && i.Expires.After(now) && (next.IsZero() || i.Expires.Before(next)) {
+			next = i.Expires
+		}
+	}
+	return next.Sub(now), !next.IsZero()
+}
+
+// ServeHTTP writes the feed as the response, or a 304 Not Modified for a
+// conditional request matching it. The feed is encoded before anything is
+// written so encoding errors can be reported with a 500. The ETag and
+// Last-Modified headers of a wrapping handler are kept, and used for the
+// conditional requests.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	header := w.Header()
+	var set []string // the headers set here, removed from error responses
+	setHeader := func(key, value string) {
+		if header.Get(key) == "" && value != "" {
+			header.Set(key, value)
+			set = append(set, key)
+		}
+	}
+	setHeader("ETag", h.etag())
+	if modified := h.lastModified(); !modified.IsZero() {
+		setHeader("Last-Modified", modified.UTC().Format(http.TimeFormat))
+	}
+	if h.DeriveCacheControl {
+		setHeader("Cache-Control", h.cacheControl())
+	}
+	modified, _ := http.ParseTime(header.Get("Last-Modified"))
+	if notModified(r, header.Get("ETag"), modified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := h.Feed.write(&buf, h.Type); err != nil {
+		for _, key := range set {
+			header.Del(key)
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", h.Type.MIMEType()+"; charset=utf-8")
+	}
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,252 @@
+package feeds
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandlerCacheControl(t *testing.T) {
+	tests := []struct {
+		ttl       int
+		cdnMaxAge time.Duration
+		want      string
+	}{
+		{0, 0, ""},
+		{0, time.Hour, ""},
+		{1, 0, "max-age=60"},
+		{60, 0, "max-age=3600"},
+		{1440, 0, "max-age=86400"},
+		{15, 5 * time.Minute, "max-age=900, s-maxage=300"},
+	}
+	for _, test := range tests {
+		feed := &Feed{Title: "jmoiron.net blog", Link: &Link{Href: "http://jmoiron.net/blog"}, TTL: test.ttl}
+		h := &Handler{Feed: feed, DeriveCacheControl: true, CDNMaxAge: test.cdnMaxAge}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/feed.xml", nil))
+
+		if got := rec.Header().Get("Cache-Control"); got != test.want {
+			t.Errorf("ttl %d, cdn %v: Cache-Control = %q, want %q", test.ttl, test.cdnMaxAge, got, test.want)
+		}
+		if _, ok := rec.Header()["Cache-Control"]; test.want == "" && ok {
+			t.Errorf("ttl %d: Cache-Control header should not be set", test.ttl)
+		}
+	}
+}
+
+func TestHandlerCacheControlFromSyndication(t *testing.T) {
+	feed := &Feed{
+		Title:       "jmoiron.net blog",
+		Link:        &Link{Href: "http://jmoiron.net/blog"},
+		Syndication: &Syndication{UpdatePeriod: "hourly", UpdateFrequency: 4},
+	}
+	rec := httptest.NewRecorder()
+	h := &Handler{Feed: feed, DeriveCacheControl: true}
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/feed.xml", nil))
+
+	if got := rec.Header().Get("Cache-Control"); got != "max-age=900" {
+		t.Errorf("Cache-Control = %q, want %q", got, "max-age=900")
+	}
+}
+
+func TestHandlerCacheControlDisabled(t *testing.T) {
+	feed := &Feed{Title: "jmoiron.net blog", Link: &Link{Href: "http://jmoiron.net/blog"}, TTL: 60, Created: time.Now()}
+	rec := httptest.NewRecorder()
+	feed.Handler(FeedTypeAtom).ServeHTTP(rec, httptest.NewRequest("GET", "/feed.atom", nil))
+
+	if got := rec.Header().Get("Cache-Control"); got != "" {
+		t.Errorf("Cache-Control = %q, want no header", got)
+	}
+	if got := rec.Header().Get("Content-Type"); got != "application/atom+xml; charset=utf-8" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if !strings.Contains(rec.Body.String(), "<feed") {
+		t.Errorf("expected an atom feed, got:\n%s", rec.Body.String())
+	}
+}
+
+func TestHandlerJSONContentType(t *testing.T) {
+	feed := &Feed{Title: "jmoiron.net blog", Link: &Link{Href: "http://jmoiron.net/blog"}, Created: time.Now()}
+	rec := httptest.NewRecorder()
+	feed.Handler(FeedTypeJSON).ServeHTTP(rec, httptest.NewRequest("GET", "/feed.json", nil))
+
+	// the same media type the other formats advertise json alternates with
+	if got := rec.Header().Get("Content-Type"); got != "application/feed+json; charset=utf-8" {
+		t.Errorf("Content-Type = %q", got)
+	}
+}
+
+func TestHandlerKeepsCallerHeaders(t *testing.T) {
+	feed := &Feed{Title: "jmoiron.net blog", Link: &Link{Href: "http://jmoiron.net/blog"}, TTL: 60}
+	h := &Handler{Feed: feed, Type: FeedTypeJSON, DeriveCacheControl: true}
+	middleware := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+		h.ServeHTTP(w, r)
+	})
+	rec := httptest.NewRecorder()
+	middleware.ServeHTTP(rec, httptest.NewRequest("GET", "/feed.json", nil))
+
+	if got := rec.Header().Get("Cache-Control"); got != "no-store" {
+		t.Errorf("Cache-Control = %q, want the caller's %q", got, "no-store")
+	}
+}
+
+func TestHandlerCacheControlExpiry(t *testing.T) {
+	now := testCreated
+	tests := []struct {
+		name    string
+		ttl     int
+		expires []time.Duration // from now
+		min     time.Duration
+		want    string
+	}{
+		{"no expiries", 60, nil, 0, "max-age=3600, s-maxage=7200"},
+		{"later expiry", 60, []time.Duration{3 * time.Hour}, 0, "max-age=3600, s-maxage=7200"},
+		{"upcoming expiry", 60, []time.Duration{3 * time.Hour, 90 * time.Minute, 10 * time.Minute}, 0, "max-age=600, s-maxage=600"},
+		{"imminent expiry", 60, []time.Duration{10 * time.Second}, 0, "max-age=60, s-maxage=60"},
+		{"imminent expiry with a floor", 60, []time.Duration{10 * time.Second}, 30 * time.Second, "max-age=30, s-maxage=30"},
+		{"passed expiry", 60, []time.Duration{-time.Minute}, 0, "max-age=3600, s-maxage=7200"},
+		{"expiry without a ttl", 0, []time.Duration{20 * time.Minute}, 0, "max-age=1200, s-maxage=1200"},
+	}
+	for _, test := range tests {
+		feed := &Feed{
+			Title: "jmoiron.net blog",
+			Link:  &Link{Href: "http://jmoiron.net/blog"},
+			TTL:   test.ttl,
+			Clock: func() time.Time { return now },
+		}
+		for _, e := range test.expires {
+			feed.Add(&Item{Title: "deal", Expires: now.Add(e)})
+		}
+		h := &Handler{Feed: feed, DeriveCacheControl: true, CDNMaxAge: 2 * time.Hour, CapMaxAgeAtExpiry: true, MinMaxAge: test.min}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/feed.xml", nil))
+
+		if got := rec.Header().Get("Cache-Control"); got != test.want {
+			t.Errorf("%s: Cache-Control = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestHandlerConditional(t *testing.T) {
+	updated := testCreated
+	feed := &Feed{
+		Title:   "jmoiron.net blog",
+		Link:    &Link{Href: "http://jmoiron.net/blog"},
+		TTL:     60,
+		Updated: updated,
+		Items:   []*Item{{Title: "Limiting Concurrency in Go", Id: "limiting-concurrency-in-go", Created: updated}},
+	}
+	h := &Handler{Feed: feed, DeriveCacheControl: true}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/feed.xml", nil))
+	etag := rec.Header().Get("ETag")
+	if want := `W/"` + feed.ContentHash() + `-rss"`; etag != want {
+		t.Errorf("ETag = %q, want %q", etag, want)
+	}
+	if got := rec.Header().Get("Last-Modified"); got != "Thu, 17 Jan 2013 02:52:35 GMT" {
+		t.Errorf("Last-Modified = %q", got)
+	}
+
+	// a regenerated feed with the same content has the same etag
+	feed.Updated = updated.Add(time.Hour)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest("GET", "/feed.xml", nil))
+	if got := rec.Header().Get("ETag"); got != etag {
+		t.Errorf("ETag changed without the content from %q to %q", etag, got)
+	}
+
+	tests := []struct {
+		name    string
+		method  string
+		headers map[string]string
+		want    int
+	}{
+		{"matching etag", "GET", map[string]string{"If-None-Match": etag}, http.StatusNotModified},
+		{"strong form of the etag", "HEAD", map[string]string{"If-None-Match": `"other", ` + strings.TrimPrefix(etag, "W/")}, http.StatusNotModified},
+		{"any etag", "GET", map[string]string{"If-None-Match": "*"}, http.StatusNotModified},
+		{"other etag", "GET", map[string]string{"If-None-Match": `W/"other"`}, http.StatusOK},
+		{"etag over date", "GET", map[string]string{"If-None-Match": `W/"other"`, "If-Modified-Since": "Fri, 18 Jan 2013 00:00:00 GMT"}, http.StatusOK},
+		{"not modified since", "GET", map[string]string{"If-Modified-Since": "Thu, 17 Jan 2013 03:52:35 GMT"}, http.StatusNotModified},
+		{"modified since", "GET", map[string]string{"If-Modified-Since": "Thu, 17 Jan 2013 03:52:34 GMT"}, http.StatusOK},
+		{"bad date", "GET", map[string]string{"If-Modified-Since": "yesterday"}, http.StatusOK},
+		{"post", "POST", map[string]string{"If-None-Match": etag}, http.StatusOK},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, "/feed.xml", nil)
+		for k, v := range test.headers {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != test.want {
+			t.Errorf("%s: status %d, want %d", test.name, rec.Code, test.want)
+		}
+		if test.want == http.StatusNotModified && (rec.Body.Len() != 0 || rec.Header().Get("Cache-Control") != "max-age=3600" || rec.Header().Get("ETag") != etag) {
+			t.Errorf("%s: 304 should have the cache headers and no body, got %v:\n%s", test.name, rec.Header(), rec.Body.String())
+		}
+	}
+
+	feed.Items[0].Title = "Limiting Concurrency in Go, revised"
+	req := httptest.NewRequest("GET", "/feed.xml", nil)
+	req.Header.Set("If-None-Match", etag)
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Errorf("changed content should get a new etag and a 200, got %d %q", rec.Code, rec.Header().Get("ETag"))
+	}
+}
+
+func TestHandlerChannelChanges(t *testing.T) {
+	for name, change := range map[string]func(*Feed){
+		"image": func(f *Feed) {
+			f.Image = &Image{Url: "http://jmoiron.net/logo.png", Title: "jmoiron.net", Link: "http://jmoiron.net/blog"}
+		},
+		"language":     func(f *Feed) { f.Language = "en-us" },
+		"copyright":    func(f *Feed) { f.Copyright = "This work is copyright © Benjamin Button" },
+		"preview":      func(f *Feed) { f.Preview = &PreviewMode{} },
+		"id scheme":    func(f *Feed) { f.IDScheme = UUIDURNs },
+		"url rewriter": func(f *Feed) { f.URLRewriter = HostSwapRewriter(map[string]string{"jmoiron.net": "www.jmoiron.net"}) },
+	} {
+		updated := testCreated
+		feed := &Feed{
+			Title:   "jmoiron.net blog",
+			Link:    &Link{Href: "http://jmoiron.net/blog"},
+			Updated: updated,
+			Items:   []*Item{{Title: "Limiting Concurrency in Go", Id: "limiting-concurrency-in-go", Created: updated}},
+		}
+		h := &Handler{Feed: feed}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", "/feed.xml", nil))
+		etag, body := rec.Header().Get("ETag"), rec.Body.String()
+
+		change(feed)
+		req := httptest.NewRequest("GET", "/feed.xml", nil)
+		req.Header.Set("If-None-Match", etag)
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+			t.Errorf("%s: got %d %q, want a new etag and a 200", name, rec.Code, rec.Header().Get("ETag"))
+		}
+		if rec.Body.String() == body {
+			t.Errorf("%s: changing the feed did not change the document", name)
+		}
+	}
+}
+
+func TestHandlerErrorHeaders(t *testing.T) {
+	feed := &Feed{Title: "jmoiron.net blog", Link: &Link{Href: "http://jmoiron.net/blog"}, TTL: 60}
+	feed.Add(&Item{Title: "Limiting Concurrency in Go", Created: time.Now(), ViaURL: "%zz"})
+	rec := httptest.NewRecorder()
+	(&Handler{Feed: feed, DeriveCacheControl: true}).ServeHTTP(rec, httptest.NewRequest("GET", "/feed.xml", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status %d, want a 500", rec.Code)
+	}
+	for _, key := range []string{"ETag", "Last-Modified", "Cache-Control"} {
+		if got := rec.Header().Get(key); got != "" {
+			t.Errorf("error responses should have no %s, got %q", key, got)
+		}
+	}
+}
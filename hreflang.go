@@ -0,0 +1,71 @@
+package feeds
+
+import (
+	"strings"
+)
+
+// JSONAlternateLanguage is a link to the feed or an item in another
+// language, written in the _alternate_languages extension.
+type JSONAlternateLanguage struct {
+	Url      string `json:"url"`
+	Language string `json:"language"`
+	Title    string `json:"title,omitempty"`
+}
+
+// the json alternate languages of the alternate links with a HrefLang
+func jsonAlternateLanguages(links []*Link) []*JSONAlternateLanguage {
+	var alternates []*JSONAlternateLanguage
+	for _, l := range links {
+		if l != nil && l.HrefLang != "" && l.Href != "" && (l.Rel == "" || l.Rel == "alternate") {
+			alternates = append(alternates, &JSONAlternateLanguage{Url: l.Href, Language: l.HrefLang, Title: l.Title})
+		}
+	}
+	return alternates
+}
+
+// whether tag looks like a bcp 47 language tag: a primary language of 2
+// to 8 letters, or a private use x or grandfathered i, followed by subtags
+// of 1 to 8 letters and digits. The subtags are not checked against the
+// registry.
+func validHrefLang(tag string) bool {
+	subtags := strings.Split(tag, "-")
+	primary := subtags[0]
+	if len(primary) < 2 || len(primary) > 8 || !isAlpha(primary) {
+		if p := strings.ToLower(primary); (p != "x" && p != "i") || len(subtags) < 2 {
+			return false
+		}
+	}
+	for _, s := range subtags[1:] {
+		if len(s) < 1 || len(s) > 8 || !isAlphanumeric(s) {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlpha(s string) bool {
+	for _, c := range s {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlphanumeric(s string) bool {
+	for _, c := range s {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// add a violation for each link with an implausible hreflang
+func (v *validator) checkHrefLangs(path string, links ...*Link) {
+	for _, l := range links {
+		if l != nil && l.HrefLang != "" {
+			v.check(validHrefLang(l.HrefLang), "%s link %s hreflang %q is not a language tag", path, l.Href, l.HrefLang)
+		}
+	}
+}
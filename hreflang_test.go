@@ -0,0 +1,148 @@
+package feeds
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidHrefLang(t *testing.T) {
+	for tag, want := range map[string]bool{
+		"es":           true,
+		"en-US":        true,
+		"zh-Hant-TW":   true,
+		"sr-Latn-RS":   true,
+		"x-klingon":    true,
+		"i-navajo":     true,
+		"de-CH-1996":   true,
+		"":             false,
+		"e":            false,
+		"x":            false,
+		"en_US":        false,
+		"en-":          false,
+		"español":      false,
+		"en-toolongxx": false,
+	} {
+		if got := validHrefLang(tag); got != want {
+			t.Errorf("validHrefLang(%q) should be %v", tag, want)
+		}
+	}
+}
+
+func TestHrefLang(t *testing.T) {
+	now := testCreated
+	feed := &Feed{
+		Title:       "jmoiron.net blog",
+		Link:        &Link{Href: "http://jmoiron.net/blog", HrefLang: "en"},
+		Links:       []*Link{{Href: "http://jmoiron.net/es/blog", Rel: "alternate", HrefLang: "es", Title: "Blog en español"}},
+		Description: "discussion about tech",
+		Created:     now,
+		Items: []*Item{{
+			Title:       "Limiting Concurrency in Go",
+			Link:        &Link{Href: "http://jmoiron.net/blog/limiting-concurrency-in-go/"},
+			Description: "A discussion on controlled parallelism in golang",
+			Created:     now,
+			Links: []*Link{
+				{Href: "http://jmoiron.net/es/blog/limitar-la-concurrencia-en-go/", Rel: "alternate", HrefLang: "es"},
+				{Href: "http://jmoiron.net/blog/limiting-concurrency-in-go/comments", Rel: "replies"},
+			},
+		}},
+	}
+
+	atom, err := feed.ToAtom()
+	if err != nil {
+		t.Errorf("unexpected error encoding Atom: %v", err)
+	}
+	for _, want := range []string{
+		`<link href="http://jmoiron.net/blog" hreflang="en"></link>`,
+		`<link href="http://jmoiron.net/es/blog" rel="alternate" hreflang="es" title="Blog en español"></link>`,
+		`<link href="http://jmoiron.net/es/blog/limitar-la-concurrencia-en-go/" rel="alternate" hreflang="es"></link>`,
+		`<link href="http://jmoiron.net/blog/limiting-concurrency-in-go/comments" rel="replies"></link>`,
+	} {
+		if !strings.Contains(atom, want) {
+			t.Errorf("Atom missing %s.  Got:\n%s\n", want, atom)
+		}
+	}
+
+	rss, err := feed.ToRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding RSS: %v", err)
+	}
+	if strings.Contains(rss, "atom:link") {
+		t.Errorf("Rss should only write the item links with AtomItemLinks.  Got:\n%s\n", rss)
+	}
+	feed.AtomItemLinks = true
+	rss, err = ToXML(&Rss{Feed: feed})
+	if err != nil {
+		t.Errorf("unexpected error encoding RSS: %v", err)
+	}
+	want := `<atom:link href="http://jmoiron.net/es/blog/limitar-la-concurrencia-en-go/" rel="alternate" hreflang="es"></atom:link>`
+	if !strings.Contains(rss, want) || !strings.Contains(rss, `xmlns:atom="http://www.w3.org/2005/Atom"`) {
+		t.Errorf("Rss missing %s.  Got:\n%s\n", want, rss)
+	}
+
+	json, err := feed.ToJSON()
+	if err != nil {
+		t.Errorf("unexpected error encoding JSON: %v", err)
+	}
+	for _, want := range []string{`"_alternate_languages": [
+    {
+      "url": "http://jmoiron.net/es/blog",
+      "language": "es",
+      "title": "Blog en español"
+    }
+  ]`, `"_alternate_languages": [
+        {
+          "url": "http://jmoiron.net/es/blog/limitar-la-concurrencia-en-go/",
+          "language": "es"
+        }
+      ]`} {
+		if !strings.Contains(json, want) {
+			t.Errorf("JSON missing %s.  Got:\n%s\n", want, json)
+		}
+	}
+	if strings.Contains(json, "comments") {
+		t.Errorf("JSON should only list the language alternates.  Got:\n%s\n", json)
+	}
+
+	if err := feed.ValidateAtom(); err != nil {
+		t.Errorf("unexpected error validating Atom: %v", err)
+	}
+	feed.Items[0].Links[0].HrefLang = "Spanish (Spain)"
+	for name, validate := range map[string]func() error{"ValidateRSS": feed.ValidateRSS, "ValidateAtom": feed.ValidateAtom, "ValidateJSON": feed.ValidateJSON} {
+		err := validate()
+		if err == nil || !strings.Contains(err.Error(), `item 0 link http://jmoiron.net/es/blog/limitar-la-concurrencia-en-go/ hreflang "Spanish (Spain)" is not a language tag`) {
+			t.Errorf("%s should fail for the hreflang, got %v", name, err)
+		}
+	}
+}
+
+func TestAtomLinkLength(t *testing.T) {
+	now := testCreated
+	feed := &Feed{
+		Title:   "jmoiron.net blog",
+		Link:    &Link{Href: "http://jmoiron.net/blog"},
+		Links:   []*Link{{Href: "http://jmoiron.net/blog?page=2", Rel: "next", Length: "1024"}},
+		Created: now,
+		Items: []*Item{{
+			Title:   "Limiting Concurrency in Go",
+			Created: now,
+			Links: []*Link{
+				{Href: "http://jmoiron.net/es/blog/limitar-la-concurrencia-en-go/", Rel: "alternate", HrefLang: "es", Length: "2048"},
+				{Href: "http://jmoiron.net/episode.mp3", Rel: "enclosure", Type: "audio/mpeg", Length: "123456"},
+			},
+		}},
+	}
+	atom, err := feed.ToAtom()
+	if err != nil {
+		t.Errorf("unexpected error encoding Atom: %v", err)
+	}
+	for _, want := range []string{
+		`<link href="http://jmoiron.net/blog?page=2" rel="next" type="application/atom+xml"></link>`,
+		`<link href="http://jmoiron.net/es/blog/limitar-la-concurrencia-en-go/" rel="alternate" hreflang="es"></link>`,
+		`<link href="http://jmoiron.net/episode.mp3" rel="enclosure" type="audio/mpeg" length="123456"></link>`,
+	} {
+		if !strings.Contains(atom, want) {
+			t.Errorf("Atom missing %s.  Got:\n%s\n", want, atom)
+		}
+	}
+}
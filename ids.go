@@ -0,0 +1,57 @@
+package feeds
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// An IDScheme formats the Id of an item as the id written to a feed, like
+// an urn or a tag uri which atom and its validators prefer to bare strings.
+// It is not called for items without an Id.
+type IDScheme func(id string) string
+
+// RawIDs writes the ids of the items as they are, as feeds without an
+// IDScheme do.
+func RawIDs(id string) string {
+	return id
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// UUIDURNs writes the ids of the items as urn:uuid: urns. Ids which are
+// uuids are prefixed, other ids are replaced with the name based uuid of
+// the id, which is the same each time, and urn:uuid: ids are left as they
+// are.
+func UUIDURNs(id string) string {
+	if strings.HasPrefix(strings.ToLower(id), "urn:uuid:") {
+		return id
+	}
+	if uuidPattern.MatchString(id) {
+		return "urn:uuid:" + strings.ToLower(id)
+	}
+	return "urn:uuid:" + newNameUUID(id).String()
+}
+
+// TagURIs returns an IDScheme writing the ids of the items as rfc 4151 tag
+// uris of the authority, a domain or an email address it has owned since
+// the date, like tag:example.com,2024-01-02:id. Ids which are tag uris
+// already are left as they are.
+func TagURIs(authority string, date time.Time) IDScheme {
+	prefix := fmt.Sprintf("tag:%s,%s:", strings.ToLower(authority), date.Format("2006-01-02"))
+	return func(id string) string {
+		if strings.HasPrefix(id, "tag:") {
+			return id
+		}
+		return prefix + id
+	}
+}
+
+// the id written for an item with an Id
+func (f *Feed) itemId(i *Item) string {
+	if f.IDScheme == nil || i.Id == "" {
+		return i.Id
+	}
+	return f.IDScheme(i.Id)
+}
@@ -0,0 +1,72 @@
+package feeds
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIDSchemes(t *testing.T) {
+	date, _ := time.Parse("2006-01-02", "2013-01-16")
+	tags := TagURIs("JMoiron.net", date)
+	for _, test := range []struct {
+		scheme   IDScheme
+		id, want string
+	}{
+		{RawIDs, "limiting-concurrency-in-go", "limiting-concurrency-in-go"},
+		{UUIDURNs, "limiting-concurrency-in-go", "urn:uuid:6e830bdc-736c-59ab-9074-0a3afe451c9c"},
+		{UUIDURNs, "6E830BDC-736C-59AB-9074-0A3AFE451C9C", "urn:uuid:6e830bdc-736c-59ab-9074-0a3afe451c9c"},
+		{UUIDURNs, "urn:uuid:6e830bdc-736c-59ab-9074-0a3afe451c9c", "urn:uuid:6e830bdc-736c-59ab-9074-0a3afe451c9c"},
+		{tags, "limiting-concurrency-in-go", "tag:jmoiron.net,2013-01-16:limiting-concurrency-in-go"},
+		{tags, "tag:example.com,2024:1", "tag:example.com,2024:1"},
+	} {
+		if got := test.scheme(test.id); got != test.want {
+			t.Errorf("got id %q for %q, want %q", got, test.id, test.want)
+		}
+	}
+}
+
+func TestFeedIDScheme(t *testing.T) {
+	now := testCreated
+	feed := &Feed{
+		Title:       "jmoiron.net blog",
+		Link:        &Link{Href: "http://jmoiron.net/blog"},
+		Description: "discussion about tech",
+		Created:     now,
+		Items: []*Item{
+			{Title: "Limiting Concurrency in Go", Id: "limiting-concurrency-in-go", Created: now},
+			{Title: "Logic-less Template Redux", Link: &Link{Href: "http://jmoiron.net/blog/logicless-template-redux/"}, Created: now},
+		},
+	}
+	rss, _ := feed.ToRss()
+	if !strings.Contains(rss, `<guid isPermaLink="false">limiting-concurrency-in-go</guid>`) {
+		t.Errorf("Rss should leave ids as they are without an IDScheme.  Got:\n%s\n", rss)
+	}
+
+	feed.IDScheme = TagURIs("jmoiron.net", now)
+	want := "tag:jmoiron.net,2013-01-16:limiting-concurrency-in-go"
+	rss, _ = feed.ToRss()
+	atom, _ := feed.ToAtom()
+	json, _ := feed.ToJSON()
+	amazon, _ := feed.ToAmazonRss()
+	for _, test := range []struct{ name, out, want string }{
+		{"Rss", rss, `<guid isPermaLink="false">` + want + "</guid>"},
+		{"Atom", atom, "<id>" + want + "</id>"},
+		{"JSON", json, `"id": "` + want + `"`},
+		{"AmazonRss", amazon, "<guid>" + want + "</guid>"},
+	} {
+		if !strings.Contains(test.out, test.want) {
+			t.Errorf("%s missing %s.  Got:\n%s\n", test.name, test.want, test.out)
+		}
+	}
+	// items without an id keep the id atom makes for them
+	if !strings.Contains(atom, "<id>tag:jmoiron.net,2013-01-16:/blog/logicless-template-redux/</id>") {
+		t.Errorf("Atom should make ids for items without one.  Got:\n%s\n", atom)
+	}
+	if strings.Count(rss, "<guid") != 1 {
+		t.Errorf("Rss should not write a guid for items without an id.  Got:\n%s\n", rss)
+	}
+	if feed.Items[0].Id != "limiting-concurrency-in-go" {
+		t.Errorf("the IDScheme changed the item's Id to %q", feed.Items[0].Id)
+	}
+}
@@ -0,0 +1,136 @@
+package feeds
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// the extensions of the image files readers show
+var imageExtensions = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true,
+	".webp": true, ".avif": true, ".svg": true, ".ico": true,
+}
+
+// an image url written by a format, with the field it is from
+type imageRef struct {
+	path string // like "feed image" or "item 2 hero image"
+	url  string
+	typ  string // the mime type given with the url, if any
+}
+
+// the image urls of the feed written as t, with the thumbnails and hero
+// images items get from their lead images and the AmazonDefaults
+func (f *Feed) imageRefs(t FeedType) []imageRef {
+	var refs []imageRef
+	add := func(path, url, typ string) {
+		if url != "" {
+			refs = append(refs, imageRef{path, url, typ})
+		}
+	}
+	if f.Image != nil {
+		add("feed image", f.Image.Url, f.Image.Type)
+	}
+	if t == FeedTypeAtom || t == FeedTypeJSON {
+		add("feed icon", f.Icon, "")
+		add("feed favicon", f.Favicon, "")
+	}
+	defaults := f.AmazonDefaults
+	if defaults == nil {
+		defaults = &AmazonItem{}
+	}
+	for n, i := range f.Items {
+		if !i.published() {
+			continue
+		}
+		thumbnail := f.thumbnail(i)
+		if t == FeedTypeJSON || t == FeedTypeAmazonRss {
+			add(fmt.Sprintf("item %d thumbnail", n), thumbnail, "")
+		}
+		if t != FeedTypeAmazonRss {
+			continue
+		}
+		a := i.Amazon
+		if a == nil {
+			a = &AmazonItem{}
+		}
+		// the thumbnail is only checked once when it is the hero image
+		if hero := amazonValue(a.HeroImage, a.Suppress&AmazonHeroImage != 0, defaults.HeroImage, thumbnail); hero != thumbnail {
+			add(fmt.Sprintf("item %d hero image", n), hero, "")
+		}
+		for k, p := range a.Products {
+			add(fmt.Sprintf("item %d product %d image", n, k), p.ImageURL, "")
+		}
+	}
+	return refs
+}
+
+// check an image url is an http or https url, and that its path ends in an
+// image extension or typ, the mime type given with it, is an image type
+func validateImage(link, typ string) error {
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q is not an http or https url", link)
+	}
+	if typ != "" {
+		if !strings.HasPrefix(typ, "image/") {
+			return fmt.Errorf("type %q of %q is not an image type", typ, link)
+		}
+		return nil
+	}
+	if !imageExtensions[strings.ToLower(path.Ext(u.Path))] {
+		return fmt.Errorf("%q does not end in an image extension like .jpg or .png, and has no type", link)
+	}
+	return nil
+}
+
+// add a violation for each image url of the feed written as t which is not
+// valid
+func (v *validator) checkImages(f *Feed) {
+	for _, ref := range f.imageRefs(v.t) {
+		if err := validateImage(ref.url, ref.typ); err != nil {
+			v.check(false, "%s %v", ref.path, err)
+		}
+	}
+}
+
+// CheckImages requests each image url of the feed written as t with a HEAD
+// request, and returns an issue for each one which fails or is not served
+// as an image/* type. Nothing is requested by the Validate methods, which
+// only check the urls themselves. The requests are made with fetcher, or
+// the DefaultFetcher when it is nil, and stop when ctx is done.
+func (f *Feed) CheckImages(ctx context.Context, fetcher *Fetcher, t FeedType) []ValidationIssue {
+	var issues []ValidationIssue
+	checked := map[string]string{}
+	for _, ref := range f.imageRefs(t) {
+		problem, seen := checked[ref.url]
+		if !seen {
+			problem = checkImage(ctx, fetcher, ref.url)
+			checked[ref.url] = problem
+		}
+		if problem != "" {
+			issues = append(issues, ValidationIssue{Path: ref.path, Message: problem})
+		}
+	}
+	return issues
+}
+
+// the problem of the image at link, or "" when it is served as an image
+func checkImage(ctx context.Context, f *Fetcher, link string) string {
+	resp, err := fetcher(f).Do(ctx, "HEAD", link)
+	if err != nil {
+		return err.Error()
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Sprintf("%s is %s", link, resp.Status)
+	}
+	typ, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !strings.HasPrefix(typ, "image/") {
+		return fmt.Sprintf("%s is served as %q rather than an image", link, resp.Header.Get("Content-Type"))
+	}
+	return ""
+}
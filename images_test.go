@@ -0,0 +1,108 @@
+package feeds
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func imagesTestFeed(hero string) *Feed {
+	feed := testFeed(&Item{
+		Title:     "Limiting Concurrency in Go",
+		Thumbnail: "https://jmoiron.net/concurrency.JPG",
+		Amazon:    &AmazonItem{HeroImage: hero},
+	})
+	feed.Image = &Image{Url: "http://jmoiron.net/logo.png"}
+	return feed
+}
+
+func TestValidateImages(t *testing.T) {
+	if err := imagesTestFeed("http://jmoiron.net/hero.webp?w=600").ValidateAmazonRss(); err != nil {
+		t.Errorf("unexpected error validating images: %v", err)
+	}
+	for _, hero := range []string{
+		"http://jmoiron.net/blog/limiting-concurrency-in-go/",
+		"ftp://jmoiron.net/hero.jpg",
+		"/hero.jpg",
+		"hero.jpg",
+	} {
+		err := imagesTestFeed(hero).ValidateAmazonRss()
+		if err == nil || !strings.Contains(err.Error(), "item 0 hero image") {
+			t.Errorf("expected the hero image %q to be rejected, got %v", hero, err)
+		}
+	}
+
+	// rss does not write hero images
+	if err := imagesTestFeed("http://jmoiron.net/blog/").ValidateRSS(); err != nil {
+		t.Errorf("unexpected error validating rss: %v", err)
+	}
+
+	feed := imagesTestFeed("")
+	feed.Image = &Image{Url: "http://cdn.jmoiron.net/images/1234"}
+	if err := feed.ValidateRSS(); err == nil || !strings.Contains(err.Error(), "feed image") {
+		t.Errorf("expected the image without an extension to be rejected, got %v", err)
+	}
+	feed.Image.Type = "image/png"
+	if err := feed.ValidateRSS(); err != nil {
+		t.Errorf("unexpected error for an image with a type: %v", err)
+	}
+	feed.Image.Type = "text/html"
+	if err := feed.ValidateRSS(); err == nil || !strings.Contains(err.Error(), `type "text/html"`) {
+		t.Errorf("expected the html type to be rejected, got %v", err)
+	}
+
+	// the thumbnails and hero images items are written with are checked
+	feed = imagesTestFeed("")
+	feed.Items[0].Thumbnail = ""
+	feed.Items[0].Content = `<p><img src="http://jmoiron.net/lead"></p>`
+	feed.LeadImageThumbnails = true
+	for _, validate := range []func() error{feed.ValidateJSON, feed.ValidateAmazonRss} {
+		if err := validate(); err == nil || !strings.Contains(err.Error(), `item 0 thumbnail "http://jmoiron.net/lead" does not end`) {
+			t.Errorf("expected the lead image to be rejected, got %v", err)
+		}
+	}
+	feed = imagesTestFeed("")
+	feed.Items = append(feed.Items, &Item{Title: "Logic-less Template Redux"})
+	feed.AmazonDefaults = &AmazonItem{HeroImage: "hero.jpg"}
+	err := feed.ValidateAmazonRss()
+	if err == nil || !strings.Contains(err.Error(), `item 0 hero image "hero.jpg"`) || !strings.Contains(err.Error(), `item 1 hero image "hero.jpg"`) {
+		t.Errorf("expected the default hero image to be rejected, got %v", err)
+	}
+}
+
+func TestCheckImages(t *testing.T) {
+	fetcher := testFetcher(func(r *http.Request) (*http.Response, error) {
+		if r.Method != "HEAD" {
+			t.Errorf("expected a HEAD request, got %s", r.Method)
+		}
+		switch r.URL.Path {
+		case "/logo.png":
+			return testResponse(r, 200, "image/png", ""), nil
+		case "/hero.jpg":
+			return testResponse(r, 200, "text/html; charset=utf-8", ""), nil
+		}
+		return testResponse(r, 404, "", ""), nil
+	})
+
+	feed := imagesTestFeed("http://jmoiron.net/hero.jpg")
+	feed.Items[0].Thumbnail = "http://jmoiron.net/missing.jpg"
+	feed.Items = append(feed.Items, &Item{Title: "Logic-less Template Redux", Thumbnail: "http://jmoiron.net/missing.jpg"})
+
+	issues := issueStrings(feed.CheckImages(context.Background(), fetcher, FeedTypeAmazonRss))
+	if len(issues) != 3 ||
+		!strings.HasPrefix(issues[0], "item 0 thumbnail: ") || !strings.Contains(issues[0], "404") ||
+		!strings.HasPrefix(issues[1], "item 0 hero image: ") || !strings.Contains(issues[1], `served as "text/html; charset=utf-8"`) ||
+		!strings.HasPrefix(issues[2], "item 1 thumbnail: ") {
+		t.Errorf("unexpected issues checking images: %q", issues)
+	}
+	if issues := feed.CheckImages(context.Background(), fetcher, FeedTypeRss); len(issues) != 0 {
+		t.Errorf("expected only the rss image to be checked, got %v", issues)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if issues := feed.CheckImages(ctx, fetcher, FeedTypeRss); len(issues) != 1 {
+		t.Errorf("expected the image to fail with a done context, got %v", issues)
+	}
+}
@@ -0,0 +1,22 @@
+//go:build go1.18
+// +build go1.18
+
+package feeds
+
+// ItemsFrom converts each of values to an Item with conv, leaving out the
+// values conv returns nil for.
+func ItemsFrom[T any](values []T, conv func(T) *Item) []*Item {
+	var items []*Item
+	for _, v := range values {
+		if i := conv(v); i != nil {
+			items = append(items, i)
+		}
+	}
+	return items
+}
+
+// FeedFromItems returns a Feed with the items ItemsFrom converts from
+// values; its channel data can be set afterwards.
+func FeedFromItems[T any](values []T, conv func(T) *Item) *Feed {
+	return &Feed{Items: ItemsFrom(values, conv)}
+}
@@ -0,0 +1,34 @@
+//go:build go1.18
+// +build go1.18
+
+package feeds
+
+import "testing"
+
+func TestFeedFromItems(t *testing.T) {
+	type post struct {
+		slug  string
+		title string
+		draft bool
+	}
+	posts := []post{
+		{"limiting-concurrency-in-go", "Limiting Concurrency in Go", false},
+		{"unfinished", "Unfinished", true},
+		{"logicless-template-redux", "Logic-less Template Redux", false},
+	}
+	feed := FeedFromItems(posts, func(p post) *Item {
+		if p.draft {
+			return nil
+		}
+		return &Item{Id: p.slug, Title: p.title, Link: &Link{Href: "http://jmoiron.net/blog/" + p.slug + "/"}}
+	})
+	if len(feed.Items) != 2 {
+		t.Fatalf("got %d items, want the 2 published posts", len(feed.Items))
+	}
+	if feed.Items[0].Id != "limiting-concurrency-in-go" || feed.Items[1].Title != "Logic-less Template Redux" {
+		t.Errorf("got items %+v and %+v", feed.Items[0], feed.Items[1])
+	}
+	if items := ItemsFrom([]post(nil), func(post) *Item { return &Item{} }); items != nil {
+		t.Errorf("got items %v from no values", items)
+	}
+}
@@ -0,0 +1,37 @@
+package feeds
+
+// itunes podcast namespace support
+// spec here:
+//    https://help.apple.com/itc/podcasts_connect/#/itcb54353390
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+)
+
+const itunesNamespace = "http://www.itunes.com/dtds/podcast-1.0.dtd"
+
+// RssITunesCategory is the itunes:category of a podcast, one of the Apple
+// Podcasts categories
+type RssITunesCategory struct {
+	XMLName xml.Name `xml:"itunes:category"`
+	Text    string   `xml:"text,attr"`
+}
+
+// check the new feed url of a podcast is an absolute http or https url
+func validateITunesNewFeedURL(f *Feed) error {
+	if f.ITunesNewFeedURL == "" {
+		return nil
+	}
+	u, err := url.Parse(f.ITunesNewFeedURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("itunes:new-feed-url %q is not an http or https url", f.ITunesNewFeedURL)
+	}
+	return nil
+}
+
+// whether the channel uses itunes namespace elements
+func (r *RssFeed) usesITunes() bool {
+	return r.ITunesNewFeedURL != "" || r.ITunesExplicit != "" || r.ITunesCategory != nil
+}
@@ -0,0 +1,93 @@
+package feeds
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestITunesNewFeedURL(t *testing.T) {
+	feed := mediaTestFeed(&Item{})
+	rss, err := feed.ToRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding RSS: %v", err)
+	}
+	if strings.Contains(rss, "itunes") {
+		t.Errorf("Rss should not use the itunes namespace.  Got:\n%s\n", rss)
+	}
+
+	feed.ITunesNewFeedURL = "https://podcasts.example.com/jmoiron/feed.xml"
+	rss, err = feed.ToRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding RSS: %v", err)
+	}
+	for _, want := range []string{
+		`xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd"`,
+		"<itunes:new-feed-url>https://podcasts.example.com/jmoiron/feed.xml</itunes:new-feed-url>\n    <item>",
+	} {
+		if !strings.Contains(rss, want) {
+			t.Errorf("Rss missing %s.  Got:\n%s\n", want, rss)
+		}
+	}
+	if strings.Index(rss, "itunes:new-feed-url") > strings.Index(rss, "<item>") {
+		t.Errorf("itunes:new-feed-url should be in the channel.  Got:\n%s\n", rss)
+	}
+}
+
+func TestITunesNewFeedURLInvalid(t *testing.T) {
+	for _, u := range []string{"podcasts.example.com/feed.xml", "ftp://example.com/feed.xml", "https://", "http://exa mple.com"} {
+		feed := mediaTestFeed(&Item{})
+		feed.ITunesNewFeedURL = u
+		if rss, err := feed.ToRss(); err == nil {
+			t.Errorf("expected an error for %q, got:\n%s", u, rss)
+		}
+		if err := feed.ValidateRSS(); err == nil {
+			t.Errorf("expected a validation error for %q", u)
+		}
+	}
+}
+
+func TestExplicit(t *testing.T) {
+	feed := mediaTestFeed(&Item{})
+	for _, to := range []func() (string, error){feed.ToRss, feed.ToAmazonRss, feed.ToJSON} {
+		if s, _ := to(); strings.Contains(s, "explicit") || strings.Contains(s, "rating") {
+			t.Errorf("expected no explicit or rating when unsaid.  Got:\n%s\n", s)
+		}
+	}
+
+	explicit := false
+	feed.Explicit = &explicit
+	feed.AudienceRating = `(PICS-1.1 "http://www.icra.org/ratingsv02.html" l r (nz 1 vz 1 lz 1 oz 1 cz 1))`
+	rss, _ := feed.ToRss()
+	if strings.Contains(rss, "itunes:explicit") {
+		t.Errorf("Rss should only say itunes:explicit of podcasts.  Got:\n%s\n", rss)
+	}
+	for _, to := range []func() (string, error){feed.ToRss, feed.ToAmazonRss} {
+		if s, _ := to(); !strings.Contains(s, `<rating>(PICS-1.1 &#34;http://www.icra.org/ratingsv02.html&#34; l r (nz 1 vz 1 lz 1 oz 1 cz 1))</rating>`) {
+			t.Errorf("expected the rating.  Got:\n%s\n", s)
+		}
+	}
+	if json, _ := feed.ToJSON(); !strings.Contains(json, `"_explicit": false`) {
+		t.Errorf("JSON should say _explicit when it is false.  Got:\n%s\n", json)
+	}
+	if err := feed.ValidateAmazonRss(); err != nil {
+		t.Errorf("unexpected error validating a feed which is not explicit: %v", err)
+	}
+
+	explicit = true
+	feed.Items[0].PodcastEpisode = &PodcastEpisode{Number: 1}
+	rss, _ = feed.ToRss()
+	for _, want := range []string{
+		`xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd"`,
+		"<itunes:explicit>true</itunes:explicit>",
+	} {
+		if !strings.Contains(rss, want) {
+			t.Errorf("Rss missing %s.  Got:\n%s\n", want, rss)
+		}
+	}
+	if json, _ := feed.ToJSON(); !strings.Contains(json, `"_explicit": true`) {
+		t.Errorf("JSON missing _explicit.  Got:\n%s\n", json)
+	}
+	if err := feed.ValidateAmazonRss(); err == nil || !strings.Contains(err.Error(), "feed is explicit") {
+		t.Errorf("expected amazon rss to reject an explicit feed, got %v", err)
+	}
+}
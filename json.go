@@ -1,12 +1,17 @@
 package feeds
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
 
-const jsonFeedVersion = "https://jsonfeed.org/version/1"
+const jsonFeedVersion = "https://jsonfeed.org/version/1.1"
 
 // JSONAuthor represents the author of the feed or of an individual item
 // in the feed
@@ -14,6 +19,89 @@ type JSONAuthor struct {
 	Name   string `json:"name,omitempty"`
 	Url    string `json:"url,omitempty"`
 	Avatar string `json:"avatar,omitempty"`
+	Role   string `json:"_role,omitempty"` // the Role of the Author
+
+	// Extensions are merged into the author object; their keys must start
+	// with an underscore, as the JSON Feed spec requires of extensions.
+	// json.RawMessage values are written as they are, so pre-rendered json
+	// keeps its key order and number precision, and must be valid json.
+	Extensions map[string]interface{} `json:"-"`
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+// The Extensions are added after the other fields in the order of their
+// keys, and an error naming the key is returned for keys without a leading
+// underscore and for values which cannot be encoded.
+func (a *JSONAuthor) MarshalJSON() ([]byte, error) {
+	type EmbeddedJSONAuthor JSONAuthor
+	data, err := json.Marshal((*EmbeddedJSONAuthor)(a))
+	if err != nil || len(a.Extensions) == 0 {
+		return data, err
+	}
+
+	keys := make([]string, 0, len(a.Extensions))
+	for k := range a.Extensions {
+		if err := validateJSONExtension(k, a.Extensions[k]); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	buf := bytes.NewBuffer(data[:len(data)-1])
+	for n, k := range keys {
+		if n > 0 || len(data) > 2 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(k)
+		value, ok := a.Extensions[k].(json.RawMessage)
+		if !ok {
+			var err error
+			if value, err = json.Marshal(a.Extensions[k]); err != nil {
+				return nil, fmt.Errorf("feeds: json author extension %q: %v", k, err)
+			}
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// check the key of a json author extension starts with an underscore, and
+// that a raw value is valid json
+func validateJSONExtension(key string, value interface{}) error {
+	if !strings.HasPrefix(key, "_") {
+		return fmt.Errorf("feeds: json author extension %q does not start with an underscore", key)
+	}
+	if raw, ok := value.(json.RawMessage); ok && !json.Valid(raw) {
+		return fmt.Errorf("feeds: json author extension %q is not valid json", key)
+	}
+	return nil
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+// Keys starting with an underscore are collected in the Extensions.
+func (a *JSONAuthor) UnmarshalJSON(data []byte) error {
+	type EmbeddedJSONAuthor JSONAuthor
+	if err := json.Unmarshal(data, (*EmbeddedJSONAuthor)(a)); err != nil {
+		return err
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	for k, v := range raw {
+		if strings.HasPrefix(k, "_") {
+			if a.Extensions == nil {
+				a.Extensions = make(map[string]interface{})
+			}
+			a.Extensions[k] = v
+		}
+	}
+	return nil
 }
 
 // JSONAttachment represents a related resource. Podcasts, for instance, would
@@ -24,6 +112,8 @@ type JSONAttachment struct {
 	Title    string        `json:"title,omitempty"`
 	Size     int32         `json:"size,omitempty"`
 	Duration time.Duration `json:"duration_in_seconds,omitempty"`
+	Bitrate  int           `json:"_bitrate,omitempty"` // kilobits per second
+	Ranges   *bool         `json:"_ranges,omitempty"`  // whether byte ranges are served
 }
 
 // MarshalJSON implements the json.Marshaler interface.
@@ -79,7 +169,22 @@ type JSONItem struct {
 	ModifiedDate  *time.Time       `json:"date_modified,omitempty"`
 	Author        *JSONAuthor      `json:"author,omitempty"`
 	Tags          []string         `json:"tags,omitempty"`
+	Language      string           `json:"language,omitempty"`
 	Attachments   []JSONAttachment `json:"attachments,omitempty"`
+
+	// AlternateLanguages are the Links of the item to it in other languages.
+	AlternateLanguages []*JSONAlternateLanguage `json:"_alternate_languages,omitempty"`
+
+	// Source is the feed the item came from, which json feed has no field
+	// for, written in the _source extension.
+	Source *JSONSource `json:"_source,omitempty"`
+}
+
+// JSONSource is the feed an item was republished from, the Source of the
+// Item, like the source of rss items.
+type JSONSource struct {
+	Url   string `json:"url"`
+	Title string `json:"title,omitempty"`
 }
 
 // JSONHub describes an endpoint that can be used to subscribe to real-time
@@ -89,8 +194,8 @@ type JSONHub struct {
 	Url  string `json:"url"`
 }
 
-// JSONFeed represents a syndication feed in the JSON Feed Version 1 format.
-// Matching the specification found here: https://jsonfeed.org/version/1.
+// JSONFeed represents a syndication feed in the JSON Feed Version 1.1 format.
+// Matching the specification found here: https://jsonfeed.org/version/1.1.
 type JSONFeed struct {
 	Version     string      `json:"version"`
 	Title       string      `json:"title"`
@@ -102,9 +207,26 @@ type JSONFeed struct {
 	Icon        string      `json:"icon,omitempty"`
 	Favicon     string      `json:"favicon,omitempty"`
 	Author      *JSONAuthor `json:"author,omitempty"`
+	Language    string      `json:"language,omitempty"`
 	Expired     *bool       `json:"expired,omitempty"`
 	Hubs        []*JSONItem `json:"hubs,omitempty"`
-	Items       []*JSONItem `json:"items,omitempty"`
+	Items       []*JSONItem `json:"items"` // required, even when empty
+	Preview     bool        `json:"_preview,omitempty"`
+	Generator   string      `json:"_generator,omitempty"`
+	Explicit    *bool       `json:"_explicit,omitempty"` // the Explicit of the Feed
+
+	// Alternates are the urls of the feed in the other formats.
+	Alternates []*JSONAlternate `json:"_alternates,omitempty"`
+
+	// AlternateLanguages are the Links of the feed to it in other languages.
+	AlternateLanguages []*JSONAlternateLanguage `json:"_alternate_languages,omitempty"`
+}
+
+// JSONAlternate is the url and media type of the feed in another format,
+// written in the _alternates extension.
+type JSONAlternate struct {
+	Url  string `json:"url"`
+	Type string `json:"type"`
 }
 
 // JSON is used to convert a generic Feed to a JSONFeed.
@@ -117,45 +239,104 @@ func (f *JSON) ToJSON() (string, error) {
 	return f.JSONFeed().ToJSON()
 }
 
-// ToJSON encodes f into a JSON string. Returns an error if marshalling fails.
+// ToJSON encodes f into a JSON string. Returns an error if marshalling fails,
+// and never a partial document.
 func (f *JSONFeed) ToJSON() (string, error) {
 	data, err := json.MarshalIndent(f, "", "  ")
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("feeds: encoding json: %v", err)
 	}
 
 	return string(data), nil
 }
 
+// ToMap returns f as the generic map decoding its json gives, for templates
+// and merging with other data: numbers are json.Numbers, the items are a
+// []map[string]interface{} and the dates of the items RFC3339 strings.
+func (f *JSONFeed) ToMap() (map[string]interface{}, error) {
+	data, err := json.Marshal(f)
+	if err != nil {
+		return nil, fmt.Errorf("feeds: encoding json: %v", err)
+	}
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	var m map[string]interface{}
+	if err := d.Decode(&m); err != nil {
+		return nil, err
+	}
+	items := make([]map[string]interface{}, len(f.Items))
+	for n, i := range m["items"].([]interface{}) {
+		items[n] = i.(map[string]interface{})
+		for key, date := range map[string]*time.Time{"date_published": f.Items[n].PublishedDate, "date_modified": f.Items[n].ModifiedDate} {
+			if date != nil {
+				items[n][key] = date.Format(time.RFC3339)
+			}
+		}
+	}
+	m["items"] = items
+	return m, nil
+}
+
 // JSONFeed creates a new JSONFeed with a generic Feed struct's data.
 func (f *JSON) JSONFeed() *JSONFeed {
+	f = &JSON{f.splitOversized()}
 	feed := &JSONFeed{
 		Version:     jsonFeedVersion,
-		Title:       f.Title,
+		Title:       f.title(),
 		Description: f.Description,
+		Language:    f.Language,
+		Icon:        f.Icon,
+		Favicon:     f.Favicon,
+		Items:       []*JSONItem{},
+		Preview:     f.Preview != nil,
+		FeedUrl:     f.AlternateFeeds[FeedTypeJSON],
+		Generator:   f.generator(),
+		Explicit:    f.Explicit,
+
+		AlternateLanguages: jsonAlternateLanguages(f.Links),
 	}
+	for _, a := range f.alternates(FeedTypeJSON) {
+		feed.Alternates = append(feed.Alternates, &JSONAlternate{Url: a.Url, Type: a.Type.MIMEType()})
+	}
+	f.checkIcons()
 
 	if f.Link != nil {
 		feed.HomePageUrl = f.Link.Href
 	}
 	if f.Author != nil {
 		feed.Author = &JSONAuthor{
-			Name: f.Author.Name,
+			Name:       f.Author.Name,
+			Extensions: f.Author.JSONExtensions,
 		}
 	}
-	for _, e := range f.Items {
-		feed.Items = append(feed.Items, newJSONItem(e))
+	ids := map[string]int{}
+	for n, e := range f.Items {
+		e = f.enclosureType(f.sanitize(e))
+		if !e.published() || f.scrubItem(e) || f.duplicateItem(ids, n, e) {
+			continue
+		}
+		item := f.newJSONItem(e)
+		item.Id = f.itemId(e)
+		if item.Image == "" {
+			item.Image = f.thumbnail(e)
+		}
+		feed.Items = append(feed.Items, item)
+		f.wrote(e)
 	}
+	f.rewriteJSONURLs(feed)
 	return feed
 }
 
-func newJSONItem(i *Item) *JSONItem {
+func (f *JSON) newJSONItem(i *Item) *JSONItem {
 	item := &JSONItem{
 		Id:      i.Id,
 		Title:   i.Title,
 		Summary: i.Description,
 
 		ContentHTML: i.Content,
+		Language:    i.Language,
+
+		AlternateLanguages: jsonAlternateLanguages(i.Links),
 	}
 
 	if i.Link != nil {
@@ -166,7 +347,9 @@ func newJSONItem(i *Item) *JSONItem {
 	}
 	if i.Author != nil {
 		item.Author = &JSONAuthor{
-			Name: i.Author.Name,
+			Name:       i.Author.Name,
+			Role:       i.Author.Role,
+			Extensions: i.Author.JSONExtensions,
 		}
 	}
 	if !i.Created.IsZero() {
@@ -175,8 +358,29 @@ func newJSONItem(i *Item) *JSONItem {
 	if !i.Updated.IsZero() {
 		item.ModifiedDate = &i.Updated
 	}
-	if i.Enclosure != nil && strings.HasPrefix(i.Enclosure.Type, "image/") {
-		item.Image = i.Enclosure.Url
+	if i.Source != nil && i.Source.Href != "" {
+		item.Source = &JSONSource{Url: i.Source.Href, Title: i.Source.Title}
+	}
+	// image enclosures are the image of the item, the others its attachment
+	if e := i.Enclosure; e != nil && strings.HasPrefix(e.Type, "image/") {
+		item.Image = e.Url
+	} else if e != nil && e.Url != "" {
+		item.Attachments = []JSONAttachment{{
+			Url:      e.Url,
+			MIMEType: e.Type,
+			Title:    e.Title,
+			Duration: e.Duration,
+			Bitrate:  e.Bitrate,
+			Ranges:   e.SupportsRanges,
+		}}
+		// the size is left out of files too large for it rather than
+		// written truncated
+		size, err := strconv.ParseInt(e.Length, 10, 64)
+		if err == nil && size <= math.MaxInt32 {
+			item.Attachments[0].Size = int32(size)
+		} else if err == nil {
+			f.log(EventWarning, "reason", fmt.Sprintf("enclosure %s is %d bytes, too large for the json attachment size", e.Url, size))
+		}
 	}
 
 	return item
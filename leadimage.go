@@ -0,0 +1,133 @@
+package feeds
+
+import (
+	"html"
+	"strconv"
+	"strings"
+)
+
+// ExtractLeadImage returns the src and alt of the first img of an html
+// fragment with a usable src, skipping data: urls and tracking pixels
+// whose width or height attribute is 1 or less. Nothing is fetched, and
+// malformed html only ends the search early.
+func ExtractLeadImage(content string) (url, alt string, ok bool) {
+	for n := 0; n < len(content); {
+		start := strings.IndexByte(content[n:], '<')
+		if start < 0 {
+			break
+		}
+		n += start + 1
+		rest := content[n:]
+		if strings.HasPrefix(rest, "!--") {
+			end := strings.Index(rest, "-->")
+			if end < 0 {
+				break
+			}
+			n += end + 3
+			continue
+		}
+		name, attrs, size := scanTag(rest)
+		n += size
+		switch name {
+		case "script", "style":
+			// skip the raw text of the element, which can hold a "<img"
+			end := strings.Index(strings.ToLower(content[n:]), "</"+name)
+			if end < 0 {
+				return "", "", false
+			}
+			n += end
+		case "img":
+			src := strings.TrimSpace(attrs["src"])
+			if src == "" || strings.HasPrefix(strings.ToLower(src), "data:") || trackingPixel(attrs) {
+				continue
+			}
+			return src, attrs["alt"], true
+		}
+	}
+	return "", "", false
+}
+
+// the lowercased name and the unescaped attributes of the start tag at the
+// beginning of s, just after its <, and the length of the tag in s
+func scanTag(s string) (name string, attrs map[string]string, size int) {
+	isSpace := func(c byte) bool { return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' }
+	n := 0
+	for n < len(s) && !isSpace(s[n]) && s[n] != '>' && s[n] != '/' {
+		n++
+	}
+	name = strings.ToLower(s[:n])
+	attrs = map[string]string{}
+	for n < len(s) {
+		for n < len(s) && (isSpace(s[n]) || s[n] == '/') {
+			n++
+		}
+		if n == len(s) || s[n] == '>' {
+			break
+		}
+		start := n
+		for n < len(s) && !isSpace(s[n]) && s[n] != '>' && s[n] != '/' && s[n] != '=' {
+			n++
+		}
+		key := strings.ToLower(s[start:n])
+		for n < len(s) && isSpace(s[n]) {
+			n++
+		}
+		if n == len(s) || s[n] != '=' {
+			if key != "" {
+				attrs[key] = ""
+			} else {
+				n++ // a stray character, like the quote of a broken value
+			}
+			continue
+		}
+		for n++; n < len(s) && isSpace(s[n]); n++ {
+		}
+		var value string
+		if n < len(s) && (s[n] == '"' || s[n] == '\'') {
+			end := strings.IndexByte(s[n+1:], s[n])
+			if end < 0 {
+				end = len(s) - n - 1
+			}
+			value = s[n+1 : n+1+end]
+			n += end + 2
+		} else {
+			start := n
+			for n < len(s) && !isSpace(s[n]) && s[n] != '>' {
+				n++
+			}
+			value = s[start:n]
+		}
+		if _, seen := attrs[key]; !seen {
+			attrs[key] = html.UnescapeString(value)
+		}
+	}
+	if n > len(s) {
+		n = len(s)
+	}
+	return name, attrs, n
+}
+
+// whether the size attributes of an img make it a tracking pixel
+func trackingPixel(attrs map[string]string) bool {
+	for _, a := range []string{"width", "height"} {
+		v, ok := attrs[a]
+		if !ok {
+			continue
+		}
+		size, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(v), "px"))
+		if err == nil && size <= 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// the thumbnail of an item, taken from the lead image of its content when
+// the feed has LeadImageThumbnails
+func (f *Feed) thumbnail(i *Item) string {
+	if i.Thumbnail != "" || !f.LeadImageThumbnails {
+		return i.Thumbnail
+	}
+	url, _, _ := ExtractLeadImage(i.Content)
+	return url
+}
@@ -0,0 +1,84 @@
+package feeds
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtractLeadImage(t *testing.T) {
+	for _, test := range []struct {
+		content  string
+		url, alt string
+		ok       bool
+	}{
+		{`<p>no images</p>`, "", "", false},
+		{`<p>intro</p><img src="http://example.com/lead.jpg" alt="the lead"><img src="http://example.com/second.jpg">`, "http://example.com/lead.jpg", "the lead", true},
+		{`<IMG SRC='http://example.com/a.jpg?w=1&amp;h=2' ALT=caps>`, "http://example.com/a.jpg?w=1&h=2", "caps", true},
+		{`<img src=http://example.com/unquoted.jpg/>`, "http://example.com/unquoted.jpg/", "", true},
+		{`<img src="data:image/gif;base64,R0lGOD"><img src="http://example.com/b.jpg">`, "http://example.com/b.jpg", "", true},
+		{`<img src="http://example.com/pixel.gif" width="1" height="1"><img src="http://example.com/c.jpg" width="640">`, "http://example.com/c.jpg", "", true},
+		{`<img src="http://example.com/pixel.gif" height="0px"><img src="">`, "", "", false},
+		{`<!-- <img src="http://example.com/commented.jpg"> --><img src="http://example.com/d.jpg">`, "http://example.com/d.jpg", "", true},
+		{`<script>var s = "<img src='http://example.com/script.jpg'>"</script><img src="http://example.com/e.jpg">`, "http://example.com/e.jpg", "", true},
+		// malformed html
+		{`<img src="http://example.com/unterminated.jpg`, "http://example.com/unterminated.jpg", "", true},
+		{`<img src=`, "", "", false},
+		{`<img "broken" src="http://example.com/f.jpg">`, "http://example.com/f.jpg", "", true},
+		{`<!-- unterminated <img src="http://example.com/g.jpg">`, "", "", false},
+		{`<script><img src="http://example.com/h.jpg">`, "", "", false},
+		{`<`, "", "", false},
+		{`<img`, "", "", false},
+	} {
+		url, alt, ok := ExtractLeadImage(test.content)
+		if url != test.url || alt != test.alt || ok != test.ok {
+			t.Errorf("ExtractLeadImage(%q) = %q, %q, %v, expected %q, %q, %v", test.content, url, alt, ok, test.url, test.alt, test.ok)
+		}
+	}
+}
+
+func TestLeadImageThumbnails(t *testing.T) {
+	feed := &Feed{
+		Title: "jmoiron.net blog",
+		Link:  &Link{Href: "http://jmoiron.net/blog"},
+		Items: []*Item{
+			{Title: "lead image", Link: &Link{Href: "http://jmoiron.net/blog/a"}, Content: `<p><img src="http://jmoiron.net/a.jpg"></p>`},
+			{Title: "thumbnail", Link: &Link{Href: "http://jmoiron.net/blog/b"}, Content: `<img src="http://jmoiron.net/unused.jpg">`, Thumbnail: "http://jmoiron.net/b.jpg"},
+		},
+	}
+	amazon, err := ToXML(&AmazonRss{Feed: feed})
+	if err != nil {
+		t.Fatalf("unexpected error encoding Amazon RSS: %v", err)
+	}
+	if strings.Contains(amazon, "<amzn:heroImage>http://jmoiron.net/a.jpg") || !strings.Contains(amazon, "<amzn:heroImage>http://jmoiron.net/b.jpg</amzn:heroImage>") {
+		t.Errorf("only the Thumbnail should be used without LeadImageThumbnails.  Got:\n%s\n", amazon)
+	}
+
+	feed.LeadImageThumbnails = true
+	amazon, err = ToXML(&AmazonRss{Feed: feed})
+	if err != nil {
+		t.Fatalf("unexpected error encoding Amazon RSS: %v", err)
+	}
+	json, err := feed.ToJSON()
+	if err != nil {
+		t.Fatalf("unexpected error encoding JSON: %v", err)
+	}
+	for _, want := range []string{
+		"<amzn:heroImage>http://jmoiron.net/a.jpg</amzn:heroImage>",
+		"<amzn:heroImage>http://jmoiron.net/b.jpg</amzn:heroImage>",
+	} {
+		if !strings.Contains(amazon, want) {
+			t.Errorf("Amazon RSS missing %s.  Got:\n%s\n", want, amazon)
+		}
+	}
+	for _, want := range []string{
+		`"image": "http://jmoiron.net/a.jpg"`,
+		`"image": "http://jmoiron.net/b.jpg"`,
+	} {
+		if !strings.Contains(json, want) {
+			t.Errorf("JSON missing %s.  Got:\n%s\n", want, json)
+		}
+	}
+	if strings.Contains(amazon, "<amzn:heroImage>http://jmoiron.net/unused.jpg") || strings.Contains(json, `"image": "http://jmoiron.net/unused.jpg"`) {
+		t.Errorf("the content image should not replace a Thumbnail")
+	}
+}
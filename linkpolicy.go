@@ -0,0 +1,162 @@
+package feeds
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// LinkPolicy restricts the hosts the items of a feed link to, checked
+// against their links, enclosures and amazon product urls. Relative urls
+// are on the feed's own site and always allowed.
+//
+// Hosts are matched case-insensitively, without their port, by patterns
+// which are one of:
+//
+//	example.com      the host example.com only
+//	.example.com     example.com and its subdomains, like a.example.com, but
+//	                 not badexample.com
+//	*.example.com    a glob as matched by path.Match, here the subdomains of
+//	                 example.com but not example.com itself
+type LinkPolicy struct {
+	Allow []string // the hosts links must match one of, any host when empty
+	Deny  []string // the hosts links must not match, even when allowed
+
+	// Scrub leaves the items with a link which is not allowed out of the
+	// feed when it is written, logging them as skipped and reporting them
+	// to WriteWithReport. Otherwise writing or validating the feed fails.
+	Scrub bool
+}
+
+// whether a host matches the pattern
+func matchHost(pattern, host string) bool {
+	pattern = strings.ToLower(pattern)
+	switch {
+	case strings.HasPrefix(pattern, "."):
+		return host == pattern[1:] || strings.HasSuffix(host, pattern)
+	case strings.ContainsAny(pattern, "*?["):
+		ok, _ := path.Match(pattern, host)
+		return ok
+	}
+	return host == pattern
+}
+
+// the lowercased host of a link, without its port. It is taken from the
+// authority by hand for links url.Parse rejects, like those with a bad
+// escape, so they are checked like any other.
+func linkHost(link string) string {
+	if u, err := url.Parse(link); err == nil {
+		return strings.ToLower(u.Hostname())
+	}
+	n := strings.Index(link, "//")
+	if n < 0 || n > 0 && (link[n-1] != ':' || strings.ContainsAny(link[:n-1], "/?#")) {
+		return ""
+	}
+	authority := link[n+2:]
+	if end := strings.IndexAny(authority, "/?#"); end >= 0 {
+		authority = authority[:end]
+	}
+	if at := strings.LastIndexByte(authority, '@'); at >= 0 {
+		authority = authority[at+1:]
+	}
+	if host, _, err := net.SplitHostPort(authority); err == nil {
+		authority = host
+	}
+	return strings.ToLower(strings.Trim(authority, "[]"))
+}
+
+// why the url is not allowed by the policy, or "" when it is
+func (p *LinkPolicy) disallow(link string) string {
+	host := linkHost(link)
+	if host == "" {
+		return ""
+	}
+	for _, pattern := range p.Deny {
+		if matchHost(pattern, host) {
+			return fmt.Sprintf("%q links to the denied host %s", link, host)
+		}
+	}
+	if len(p.Allow) == 0 {
+		return ""
+	}
+	for _, pattern := range p.Allow {
+		if matchHost(pattern, host) {
+			return ""
+		}
+	}
+	return fmt.Sprintf("%q links to %s, which is not an allowed host", link, host)
+}
+
+// why the first url of the item the policy does not allow is not allowed,
+// or "" when it allows all of them
+func (p *LinkPolicy) check(i *Item) string {
+	var links []string
+	for _, l := range append([]*Link{i.Link}, i.Links...) {
+		if l != nil {
+			links = append(links, l.Href)
+		}
+	}
+	if i.Enclosure != nil {
+		links = append(links, i.Enclosure.Url)
+	}
+	if i.Amazon != nil {
+		for _, product := range i.Amazon.Products {
+			links = append(links, product.URL)
+		}
+	}
+	for _, link := range links {
+		if reason := p.disallow(link); reason != "" {
+			return reason
+		}
+	}
+	return ""
+}
+
+// leave out an item with a link the Scrub LinkPolicy of the feed does not
+// allow, recording and logging it
+func (f *Feed) scrubItem(i *Item) bool {
+	if f.LinkPolicy == nil || !f.LinkPolicy.Scrub {
+		return false
+	}
+	reason := f.LinkPolicy.check(i)
+	if reason == "" {
+		return false
+	}
+	f.dropItem(i, reason)
+	return true
+}
+
+// the error of the first published item with a link a LinkPolicy without
+// Scrub does not allow
+func (f *Feed) checkLinkPolicy() error {
+	if f.LinkPolicy == nil || f.LinkPolicy.Scrub {
+		return nil
+	}
+	for n, i := range f.Items {
+		if i == nil || !i.published() {
+			continue
+		}
+		if reason := f.LinkPolicy.check(i); reason != "" {
+			return fmt.Errorf("feeds: item %d: %s", n, reason)
+		}
+	}
+	return nil
+}
+
+// add a violation for each published item with a link a LinkPolicy without
+// Scrub does not allow
+func (v *validator) checkLinkPolicy(f *Feed) {
+	if f.LinkPolicy == nil || f.LinkPolicy.Scrub {
+		return
+	}
+	for n, i := range f.Items {
+		if i == nil || !i.published() {
+			continue
+		}
+		if reason := f.LinkPolicy.check(i); reason != "" {
+			v.check(false, "item %d: %s", n, reason)
+		}
+	}
+}
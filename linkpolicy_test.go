@@ -0,0 +1,99 @@
+package feeds
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatchHost(t *testing.T) {
+	for _, test := range []struct {
+		pattern, host string
+		want          bool
+	}{
+		{"example.com", "example.com", true},
+		{"example.com", "a.example.com", false},
+		{"Example.COM", "example.com", true},
+		{".example.com", "example.com", true},
+		{".example.com", "a.example.com", true},
+		{".example.com", "a.b.example.com", true},
+		{".example.com", "badexample.com", false},
+		{".example.com", "example.com.evil.net", false},
+		{"*.example.com", "a.example.com", true},
+		{"*.example.com", "a.b.example.com", true},
+		{"*.example.com", "example.com", false},
+		{"*.example.com", "badexample.com", false},
+		{"staging-*.jmoiron.net", "staging-2.jmoiron.net", true},
+		{"staging-*.jmoiron.net", "jmoiron.net", false},
+	} {
+		if got := matchHost(test.pattern, test.host); got != test.want {
+			t.Errorf("matchHost(%q, %q) = %v, want %v", test.pattern, test.host, got, test.want)
+		}
+	}
+}
+
+func TestLinkPolicy(t *testing.T) {
+	policy := &LinkPolicy{Allow: []string{".jmoiron.net", "amazon.com"}, Deny: []string{".staging.jmoiron.net"}}
+	for _, test := range []struct {
+		item *Item
+		want string
+	}{
+		{&Item{Link: &Link{Href: "http://jmoiron.net/blog/"}}, ""},
+		{&Item{Link: &Link{Href: "/blog/relative/"}}, ""},
+		{&Item{Link: &Link{Href: "http://www.jmoiron.net:8080/blog/"}}, ""},
+		{&Item{Link: &Link{Href: "http://a.staging.jmoiron.net/blog/"}}, "denied host a.staging.jmoiron.net"},
+		{&Item{Link: &Link{Href: "http://a.staging.jmoiron.net:8080/blog/%zz"}}, "denied host a.staging.jmoiron.net"},
+		{&Item{Link: &Link{Href: "http://user@competitor.com/%zz"}}, "competitor.com, which is not an allowed host"},
+		{&Item{Link: &Link{Href: "/blog/a//b/%zz"}}, ""},
+		{&Item{Links: []*Link{{Href: "http://competitor.com/"}}}, "competitor.com, which is not an allowed host"},
+		{&Item{Enclosure: &Enclosure{Url: "http://cdn.example.com/a.mp3"}}, "cdn.example.com, which is not an allowed host"},
+		{&Item{Amazon: &AmazonItem{Products: []*AmazonProduct{{URL: "https://www.amazon.com/dp/B01"}}}}, "www.amazon.com, which is not an allowed host"},
+		{&Item{Amazon: &AmazonItem{Products: []*AmazonProduct{{URL: "https://amazon.com/dp/B01"}}}}, ""},
+	} {
+		got := policy.check(test.item)
+		if (test.want == "") != (got == "") || !strings.Contains(got, test.want) {
+			t.Errorf("check(%+v) = %q, want %q", test.item, got, test.want)
+		}
+	}
+}
+
+func TestLinkPolicyModes(t *testing.T) {
+	feed := &Feed{
+		Title:       "jmoiron.net blog",
+		Link:        &Link{Href: "http://jmoiron.net/blog"},
+		Description: "discussion about tech, footie, photos",
+		Created:     time.Date(2013, time.January, 16, 21, 52, 35, 0, time.UTC),
+		Items: []*Item{
+			{Id: "ok", Title: "Limiting Concurrency in Go", Link: &Link{Href: "http://jmoiron.net/blog/limiting-concurrency-in-go/"}},
+			{Id: "staging", Title: "Logic-less Template Redux", Link: &Link{Href: "http://staging.jmoiron.net/blog/logicless-template-redux/"}},
+		},
+		LinkPolicy: &LinkPolicy{Deny: []string{"staging.jmoiron.net"}},
+	}
+	if err := feed.ValidateRSS(); err == nil || !strings.Contains(err.Error(), "item 1: ") {
+		t.Errorf("expected ValidateRSS to reject the staging link, got %v", err)
+	}
+	for _, encode := range []func() (string, error){feed.ToRss, feed.ToAtom, feed.ToJSON, feed.ToAmazonRss} {
+		if out, err := encode(); err == nil || !strings.Contains(err.Error(), "denied host staging.jmoiron.net") {
+			t.Errorf("expected an error writing the staging link, got %v:\n%s", err, out)
+		}
+	}
+
+	feed.LinkPolicy.Scrub = true
+	if err := feed.ValidateRSS(); err != nil {
+		t.Errorf("unexpected error validating a scrubbed feed: %v", err)
+	}
+	for _, format := range []FeedType{FeedTypeRss, FeedTypeAtom, FeedTypeJSON, FeedTypeAmazonRss} {
+		var buf bytes.Buffer
+		report, err := feed.WriteWithReport(&buf, format)
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", format, err)
+		}
+		if len(report.Dropped) != 1 || report.Dropped[0].Id != "staging" {
+			t.Errorf("%v: expected the staging item to be dropped, got %+v", format, report.Dropped)
+		}
+		if out := buf.String(); strings.Contains(out, "staging") || !strings.Contains(out, "limiting-concurrency-in-go") {
+			t.Errorf("%v: expected only the staging item to be left out.  Got:\n%s\n", format, out)
+		}
+	}
+}
@@ -0,0 +1,553 @@
+package feeds
+
+// linting of feed documents written by anything, not only this package
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ValidationIssue is a problem found in a feed document by one of the Lint
+// functions, at the path of the element or field it was found at, like
+// rss/channel/item[2]/pubDate or items[2].date_published. Items are
+// numbered from 0.
+type ValidationIssue struct {
+	Path    string
+	Message string
+}
+
+func (i ValidationIssue) String() string {
+	return i.Path + ": " + i.Message
+}
+
+// collects the issues of a document
+type linter struct {
+	issues []ValidationIssue
+}
+
+func (l *linter) add(path, format string, args ...interface{}) {
+	l.issues = append(l.issues, ValidationIssue{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// add an issue unless the url is absolute, or relative when that is allowed
+func (l *linter) checkURL(path, value string, relative bool) {
+	u, err := url.Parse(strings.TrimSpace(value))
+	switch {
+	case err != nil:
+		l.add(path, "%q is not a valid url", value)
+	case !relative && (u.Scheme == "" || u.Host == "" && u.Opaque == ""):
+		l.add(path, "%q is not an absolute url", value)
+	}
+}
+
+// the layouts of rfc 822 dates, as rss requires them
+var rfc822Layouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	time.RFC822Z,
+	time.RFC822,
+}
+
+// add an issue unless the rss date is an rfc 822 date
+func (l *linter) checkRFC822(path, value string) {
+	value = strings.TrimSpace(value)
+	for _, layout := range rfc822Layouts {
+		if _, err := time.Parse(layout, value); err == nil {
+			return
+		}
+	}
+	if parseFeedTime(value).IsZero() {
+		l.add(path, "%q cannot be parsed as a date", value)
+	} else {
+		l.add(path, "%q is not an rfc 822 date", value)
+	}
+}
+
+// add an issue unless the date is an rfc 3339 date, as atom and json need
+func (l *linter) checkRFC3339(path, value string) {
+	value = strings.TrimSpace(value)
+	if _, err := time.Parse(time.RFC3339, value); err == nil {
+		return
+	}
+	if parseFeedTime(value).IsZero() {
+		l.add(path, "%q cannot be parsed as a date", value)
+	} else {
+		l.add(path, "%q is not an rfc 3339 date", value)
+	}
+}
+
+// an element of a linted xml document
+type lintNode struct {
+	name     xml.Name
+	attrs    []xml.Attr
+	text     string
+	children []*lintNode
+}
+
+// the first child in one of the namespaces with the local name, or nil
+func (n *lintNode) child(local string, spaces ...string) *lintNode {
+	if c := n.all(local, spaces...); len(c) > 0 {
+		return c[0]
+	}
+	return nil
+}
+
+// the children in one of the namespaces with the local name
+func (n *lintNode) all(local string, spaces ...string) []*lintNode {
+	if len(spaces) == 0 {
+		spaces = []string{""}
+	}
+	var found []*lintNode
+	for _, c := range n.children {
+		for _, s := range spaces {
+			if c.name.Local == local && c.name.Space == s {
+				found = append(found, c)
+				break
+			}
+		}
+	}
+	return found
+}
+
+// the trimmed text of the first child with the local name, with that of
+// its descendants like the xhtml of atom text, and whether there is one
+func (n *lintNode) childText(local string, spaces ...string) (string, bool) {
+	c := n.child(local, spaces...)
+	if c == nil {
+		return "", false
+	}
+	var text bytes.Buffer
+	c.writeText(&text)
+	return strings.TrimSpace(text.String()), true
+}
+
+func (n *lintNode) writeText(w *bytes.Buffer) {
+	w.WriteString(n.text)
+	for _, c := range n.children {
+		c.writeText(w)
+	}
+}
+
+func (n *lintNode) attr(local string) (string, bool) {
+	for _, a := range n.attrs {
+		if a.Name.Local == local && a.Name.Space == "" {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// read an xml document leniently: html entities are accepted, unclosed
+// elements end with their parent and latin-1 documents are decoded
+func parseLintXML(r io.Reader) (*lintNode, error) {
+	d := xml.NewDecoder(r)
+	d.Strict = false
+	d.Entity = xml.HTMLEntity
+	d.CharsetReader = lintCharsetReader
+	root := &lintNode{}
+	stack := []*lintNode{root}
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		parent := stack[len(stack)-1]
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			node := &lintNode{name: tok.Name, attrs: tok.Attr}
+			parent.children = append(parent.children, node)
+			stack = append(stack, node)
+		case xml.EndElement:
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			parent.text += string(tok)
+		}
+	}
+	if len(root.children) == 0 {
+		return nil, fmt.Errorf("feeds: document has no root element")
+	}
+	return root.children[0], nil
+}
+
+// decode the latin-1 and ascii documents the xml decoder does not read
+func lintCharsetReader(charset string, input io.Reader) (io.Reader, error) {
+	switch strings.ToLower(charset) {
+	case "iso-8859-1", "latin1", "latin-1", "us-ascii", "ascii":
+		var out bytes.Buffer
+		in := bufio.NewReader(input)
+		for {
+			b, err := in.ReadByte()
+			if err == io.EOF {
+				return &out, nil
+			}
+			if err != nil {
+				return nil, err
+			}
+			out.WriteRune(rune(b))
+		}
+	}
+	return nil, fmt.Errorf("feeds: unsupported charset %q", charset)
+}
+
+// LintRSS reads an rss 2.0 document leniently and returns the problems
+// found in it: the violations ValidateRSS finds in the feed read from it,
+// and of the document itself a root element other than rss 2.0, invalid
+// urls, dates which are not rfc 822 dates, ttls and enclosure lengths which
+// are not numbers and images without a url, title and link. The error is
+// only set when the document cannot be read at all.
+func LintRSS(r io.Reader) ([]ValidationIssue, error) {
+	doc, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	root, err := parseLintXML(bytes.NewReader(doc))
+	if err != nil {
+		return nil, err
+	}
+	l := &linter{}
+	if l.lintRSS(root) != nil {
+		if feed := l.rssFeed(doc); feed != nil {
+			l.addViolations(feed.ValidateRSS())
+		}
+	}
+	return l.issues, nil
+}
+
+// the feed read from an rss document, or nil with an issue when it cannot
+// be read
+func (l *linter) rssFeed(doc []byte) *Feed {
+	p := newRssParser(ParseOptions{MaxTextLength: -1})
+	p.lenient = true
+	var items []*Item
+	feed, err := p.parse(bytes.NewReader(doc), func(i *Item) error {
+		items = append(items, i)
+		return nil
+	})
+	if err != nil {
+		l.add("rss", "cannot be read as a feed: %v", err)
+		return nil
+	}
+	feed.Items = items
+	return feed
+}
+
+// add the violations of the Validate methods of the feed read from an rss
+// document as issues of its channel or of the items they name, once each
+func (l *linter) addViolations(errs ...error) {
+	seen := map[ValidationIssue]bool{}
+	for _, err := range errs {
+		verr, ok := err.(*ValidationError)
+		if !ok {
+			continue
+		}
+		for _, violation := range verr.Violations {
+			issue := ValidationIssue{Path: "rss/channel", Message: strings.TrimPrefix(violation, "feed ")}
+			var n int
+			if _, err := fmt.Sscanf(violation, "item %d", &n); err == nil {
+				rest := strings.TrimPrefix(violation, fmt.Sprintf("item %d", n))
+				issue = ValidationIssue{Path: fmt.Sprintf("rss/channel/item[%d]", n), Message: strings.TrimLeft(rest, ": ")}
+			}
+			if !seen[issue] {
+				seen[issue] = true
+				l.issues = append(l.issues, issue)
+			}
+		}
+	}
+}
+
+// the checks of an rss document which are not made of the feed read from
+// it, returning its channel
+func (l *linter) lintRSS(root *lintNode) *lintNode {
+	if root.name.Local != "rss" {
+		l.add(root.name.Local, "root element is not rss")
+		return nil
+	} else if v, _ := root.attr("version"); v != "2.0" {
+		l.add("rss", "version is %q, not 2.0", v)
+	}
+	channel := root.child("channel")
+	if channel == nil {
+		l.add("rss", "has no channel")
+		return nil
+	}
+	path := "rss/channel"
+	if v, ok := channel.childText("link"); ok && v != "" {
+		l.checkURL(path+"/link", v, false)
+	}
+	for _, date := range []string{"pubDate", "lastBuildDate"} {
+		if v, ok := channel.childText(date); ok {
+			l.checkRFC822(path+"/"+date, v)
+		}
+	}
+	if v, ok := channel.childText("ttl"); ok {
+		if n, err := strconv.Atoi(v); err != nil || n < 0 {
+			l.add(path+"/ttl", "%q is not a number of minutes", v)
+		}
+	}
+	if image := channel.child("image"); image != nil {
+		for _, required := range []string{"url", "title", "link"} {
+			if v, _ := image.childText(required); v == "" {
+				l.add(path+"/image", "has no %s", required)
+			}
+		}
+	}
+	for n, item := range channel.all("item") {
+		path := fmt.Sprintf("rss/channel/item[%d]", n)
+		for _, link := range []string{"link", "comments"} {
+			if v, ok := item.childText(link); ok {
+				l.checkURL(path+"/"+link, v, false)
+			}
+		}
+		if source := item.child("source"); source != nil {
+			if v, ok := source.attr("url"); ok {
+				l.checkURL(path+"/source", v, false)
+			}
+		}
+		if v, ok := item.childText("pubDate"); ok {
+			l.checkRFC822(path+"/pubDate", v)
+		}
+		if guid := item.child("guid"); guid != nil {
+			if p, _ := guid.attr("isPermaLink"); p != "false" {
+				l.checkURL(path+"/guid", guid.text, false)
+			}
+		}
+		for _, e := range item.all("enclosure") {
+			if url, _ := e.attr("url"); url != "" {
+				l.checkURL(path+"/enclosure", url, false)
+			}
+			length, _ := e.attr("length")
+			if n, err := strconv.ParseInt(length, 10, 64); length != "" && (err != nil || n < 0) {
+				l.add(path+"/enclosure", "length %q is not a number of bytes", length)
+			}
+		}
+	}
+	return channel
+}
+
+// LintAmazonRss reads an Amazon rss document leniently and returns the
+// problems LintRSS finds in it, those ValidateAmazonRss finds in the feed
+// read from it, and those of its Amazon elements: items need the title,
+// link, guid, date and hero image the AmazonStrict profile requires,
+// amzn:indexContent must be True or False, amzn:status deleted, positions
+// positive numbers and product urls absolute.
+func LintAmazonRss(r io.Reader) ([]ValidationIssue, error) {
+	doc, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	root, err := parseLintXML(bytes.NewReader(doc))
+	if err != nil {
+		return nil, err
+	}
+	l := &linter{}
+	channel := l.lintRSS(root)
+	if channel == nil {
+		return l.issues, nil
+	}
+	for n, item := range channel.all("item") {
+		path := fmt.Sprintf("rss/channel/item[%d]", n)
+		for _, required := range []string{"title", "link", "guid", "pubDate"} {
+			if v, _ := item.childText(required); v == "" {
+				l.add(path, "has no %s", required)
+			}
+		}
+		if v, _ := item.childText("heroImage", amazonNamespace); v == "" {
+			l.add(path, "has no amzn:heroImage")
+		}
+		if v, ok := item.childText("indexContent", amazonNamespace); ok && v != "True" && v != "False" {
+			l.add(path+"/amzn:indexContent", "%q is neither True nor False", v)
+		}
+		if v, ok := item.childText("status", amazonNamespace); ok && v != "deleted" {
+			l.add(path+"/amzn:status", "%q is not deleted", v)
+		}
+		if v, ok := item.childText("position", amazonNamespace); ok {
+			if position, err := strconv.Atoi(v); err != nil || position <= 0 {
+				l.add(path+"/amzn:position", "%q is not a positive number", v)
+			}
+		}
+		if products := item.child("products", amazonNamespace); products != nil {
+			for p, product := range products.all("product", amazonNamespace) {
+				path := fmt.Sprintf("%s/amzn:products/amzn:product[%d]", path, p)
+				if v, _ := product.childText("productURL", amazonNamespace); v == "" {
+					l.add(path, "has no amzn:productURL")
+				} else {
+					l.checkURL(path+"/amzn:productURL", v, false)
+				}
+			}
+		}
+	}
+	if feed := l.rssFeed(doc); feed != nil {
+		l.addViolations(feed.ValidateRSS(), feed.ValidateAmazonRss())
+	}
+	return l.issues, nil
+}
+
+// LintAtom reads an atom document leniently and returns the problems
+// found in it: a root element outside the atom namespace, feeds and
+// entries without an id, title or updated date, dates which are not rfc
+// 3339 dates, invalid links, entries without an author when the feed has
+// none and entries with neither content nor an alternate link. The error
+// is only set when the document cannot be read at all.
+func LintAtom(r io.Reader) ([]ValidationIssue, error) {
+	root, err := parseLintXML(r)
+	if err != nil {
+		return nil, err
+	}
+	l := &linter{}
+	if root.name.Local != "feed" {
+		l.add(root.name.Local, "root element is not feed")
+		return l.issues, nil
+	}
+	if root.name.Space != ns {
+		l.add("feed", "is not in the atom namespace %s", ns)
+	}
+	// elements without the namespace are still checked
+	spaces := []string{ns, ""}
+	l.lintAtomCommon("feed", root, spaces)
+	feedAuthor := root.child("author", spaces...) != nil
+	for n, entry := range root.all("entry", spaces...) {
+		path := fmt.Sprintf("feed/entry[%d]", n)
+		l.lintAtomCommon(path, entry, spaces)
+		if !feedAuthor && entry.child("author", spaces...) == nil {
+			l.add(path, "has no author, and neither has the feed")
+		}
+		if v, ok := entry.childText("published", spaces...); ok {
+			l.checkRFC3339(path+"/published", v)
+		}
+		alternate := false
+		for _, link := range entry.all("link", spaces...) {
+			if rel, _ := link.attr("rel"); rel == "" || rel == "alternate" {
+				alternate = true
+			}
+		}
+		if entry.child("content", spaces...) == nil && !alternate {
+			l.add(path, "has neither content nor an alternate link")
+		}
+	}
+	return l.issues, nil
+}
+
+// the checks of the elements atom feeds and entries share
+func (l *linter) lintAtomCommon(path string, n *lintNode, spaces []string) {
+	for _, required := range []string{"id", "title", "updated"} {
+		if v, _ := n.childText(required, spaces...); v == "" {
+			l.add(path, "has no %s", required)
+		}
+	}
+	if v, _ := n.childText("id", spaces...); v != "" {
+		l.checkURL(path+"/id", v, false)
+	}
+	if v, ok := n.childText("updated", spaces...); ok && v != "" {
+		l.checkRFC3339(path+"/updated", v)
+	}
+	for _, link := range n.all("link", spaces...) {
+		if href, ok := link.attr("href"); !ok || strings.TrimSpace(href) == "" {
+			l.add(path+"/link", "has no href")
+		} else {
+			l.checkURL(path+"/link", href, true)
+		}
+	}
+}
+
+// LintJSONFeed reads a json feed and returns the problems found in it: a
+// version which is not a json feed version, a missing title or items,
+// items without an id or any content, invalid urls, dates which are not
+// rfc 3339 dates and attachments without a url and type. The error is
+// only set when the document is not a json object.
+func LintJSONFeed(r io.Reader) ([]ValidationIssue, error) {
+	var feed map[string]interface{}
+	if err := json.NewDecoder(r).Decode(&feed); err != nil {
+		return nil, err
+	}
+	l := &linter{}
+	str := func(path string, obj map[string]interface{}, key string) (string, bool) {
+		v, ok := obj[key]
+		if !ok || v == nil {
+			return "", false
+		}
+		s, isString := v.(string)
+		if !isString {
+			l.add(path+key, "is not a string")
+		}
+		return s, isString
+	}
+	urls := func(path string, obj map[string]interface{}, keys ...string) {
+		for _, k := range keys {
+			if v, ok := str(path, obj, k); ok {
+				l.checkURL(path+k, v, false)
+			}
+		}
+	}
+
+	if v, _ := str("", feed, "version"); !strings.HasPrefix(v, "https://jsonfeed.org/version/") {
+		l.add("version", "%q is not a json feed version", v)
+	}
+	if v, _ := str("", feed, "title"); v == "" {
+		l.add("title", "is required")
+	}
+	urls("", feed, "home_page_url", "feed_url", "next_url", "icon", "favicon")
+	items, ok := feed["items"].([]interface{})
+	if !ok {
+		l.add("items", "is required to be an array")
+	}
+	for n, v := range items {
+		path := fmt.Sprintf("items[%d].", n)
+		item, ok := v.(map[string]interface{})
+		if !ok {
+			l.add(path[:len(path)-1], "is not an object")
+			continue
+		}
+		switch id := item["id"].(type) {
+		case string:
+			if id == "" {
+				l.add(path+"id", "is required")
+			}
+		case float64:
+			// numbers are accepted and read as strings
+		default:
+			l.add(path+"id", "is required")
+		}
+		html, _ := str(path, item, "content_html")
+		text, _ := str(path, item, "content_text")
+		if html == "" && text == "" {
+			l.add(path[:len(path)-1], "has neither content_html nor content_text")
+		}
+		urls(path, item, "url", "external_url", "image", "banner_image")
+		for _, date := range []string{"date_published", "date_modified"} {
+			if v, ok := str(path, item, date); ok {
+				l.checkRFC3339(path+date, v)
+			}
+		}
+		attachments, _ := item["attachments"].([]interface{})
+		for a, v := range attachments {
+			path := fmt.Sprintf("%sattachments[%d].", path, a)
+			attachment, _ := v.(map[string]interface{})
+			if url, _ := str(path, attachment, "url"); url == "" {
+				l.add(path+"url", "is required")
+			} else {
+				l.checkURL(path+"url", url, false)
+			}
+			if typ, _ := str(path, attachment, "mime_type"); typ == "" {
+				l.add(path+"mime_type", "is required")
+			}
+		}
+	}
+	return l.issues, nil
+}
@@ -0,0 +1,310 @@
+package feeds
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func lintTestFeed() *Feed {
+	feed := testFeed(&Item{
+		Title:       "Limiting Concurrency in Go",
+		Link:        &Link{Href: "http://jmoiron.net/blog/limiting-concurrency-in-go/"},
+		Id:          "http://jmoiron.net/blog/limiting-concurrency-in-go/",
+		Description: "A discussion on controlled parallelism in golang",
+		Content:     "<p>A discussion on controlled parallelism in golang</p>",
+		Created:     testCreated,
+		Enclosure:   &Enclosure{Url: "http://jmoiron.net/episode.mp3", Length: "123456", Type: "audio/mpeg"},
+		Amazon:      &AmazonItem{HeroImage: "http://jmoiron.net/hero.jpg", Position: 1},
+	})
+	feed.Author = &Author{Name: "Jason Moiron", Email: "jmoiron@jmoiron.net"}
+	return feed
+}
+
+func TestLintGeneratedFeeds(t *testing.T) {
+	feed := lintTestFeed()
+	for _, test := range []struct {
+		name string
+		lint func(r io.Reader) ([]ValidationIssue, error)
+		gen  func() (string, error)
+	}{
+		{"rss", LintRSS, feed.ToRss},
+		{"amazon", LintAmazonRss, feed.ToAmazonRss},
+		{"atom", LintAtom, feed.ToAtom},
+		{"json", LintJSONFeed, feed.ToJSON},
+	} {
+		doc, err := test.gen()
+		if err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		issues, err := test.lint(strings.NewReader(doc))
+		if err != nil || len(issues) != 0 {
+			t.Errorf("%s: got issues %v, %v for a valid feed:\n%s", test.name, issues, err, doc)
+		}
+	}
+}
+
+func TestLintRSS(t *testing.T) {
+	doc := `<?xml version="1.0" encoding="ISO-8859-1"?>
+<rss version="0.91">
+  <channel>
+    <title>jmoiron.net blog &mdash; caf` + "\xe9" + `</title>
+    <link>jmoiron.net/blog</link>
+    <pubDate>2013-01-16T21:52:35-05:00</pubDate>
+    <lastBuildDate>yesterday</lastBuildDate>
+    <ttl>an hour</ttl>
+    <item>
+      <link>http://jmoiron.net/blog/limiting-concurrency-in-go/</link>
+      <guid>limiting-concurrency-in-go</guid>
+      <enclosure url="http://jmoiron.net/episode.mp3" length="big"/>
+    </item>
+    <item>
+      <title>Logic-less Template Redux</title>
+      <guid isPermaLink="false">logicless-template-redux</guid>
+      <pubDate>Wed, 16 Jan 2013 21:52:35 -0500</pubDate>
+    </item>
+  </channel>
+</rss>`
+	issues, err := LintRSS(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`rss: version is "0.91", not 2.0`,
+		`rss/channel/link: "jmoiron.net/blog" is not an absolute url`,
+		`rss/channel/pubDate: "2013-01-16T21:52:35-05:00" is not an rfc 822 date`,
+		`rss/channel/lastBuildDate: "yesterday" cannot be parsed as a date`,
+		`rss/channel/ttl: "an hour" is not a number of minutes`,
+		`rss/channel/item[0]/guid: "limiting-concurrency-in-go" is not an absolute url`,
+		`rss/channel/item[0]/enclosure: length "big" is not a number of bytes`,
+		"rss/channel: has no description",
+		"rss/channel/item[0]: has neither a title nor a description",
+		"rss/channel/item[0]: enclosure needs a url, type and length",
+	}
+	if got := issueStrings(issues); !reflect.DeepEqual(got, want) {
+		t.Errorf("got issues:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if _, err := LintRSS(strings.NewReader("<rss><channel>")); err == nil {
+		t.Errorf("expected an error for a truncated document")
+	}
+	if _, err := LintRSS(strings.NewReader("not a feed")); err == nil {
+		t.Errorf("expected an error for a document without elements")
+	}
+}
+
+func TestLintAmazonRss(t *testing.T) {
+	doc := `<rss version="2.0" xmlns:amzn="https://amazon.com/ospublishing/1.0/">
+  <channel>
+    <title>jmoiron.net blog</title>
+    <link>http://jmoiron.net/blog</link>
+    <description>discussion about tech</description>
+    <item>
+      <title>Best Headphones of 2019</title>
+      <link>http://jmoiron.net/blog/best-headphones/</link>
+      <guid>http://jmoiron.net/blog/best-headphones/</guid>
+      <pubDate>Wed, 16 Jan 2013 21:52:35 -0500</pubDate>
+      <amzn:heroImage>http://jmoiron.net/hero.jpg</amzn:heroImage>
+      <amzn:indexContent>yes</amzn:indexContent>
+      <amzn:position>1</amzn:position>
+      <amzn:products><amzn:product><amzn:productURL>/dp/B01</amzn:productURL></amzn:product></amzn:products>
+    </item>
+    <item>
+      <title>Best Headphones of 2020</title>
+      <amzn:position>1</amzn:position>
+    </item>
+  </channel>
+</rss>`
+	issues, err := LintAmazonRss(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`rss/channel/item[0]/amzn:indexContent: "yes" is neither True nor False`,
+		`rss/channel/item[0]/amzn:products/amzn:product[0]/amzn:productURL: "/dp/B01" is not an absolute url`,
+		"rss/channel/item[1]: has no link",
+		"rss/channel/item[1]: has no guid",
+		"rss/channel/item[1]: has no pubDate",
+		"rss/channel/item[1]: has no amzn:heroImage",
+		"rss/channel/item[1]: amzn:position 1 is already used by item 0",
+	}
+	if got := issueStrings(issues); !reflect.DeepEqual(got, want) {
+		t.Errorf("got issues:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestLintValidateRules(t *testing.T) {
+	rss := `<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd"
+    xmlns:webfeeds="http://webfeeds.org/rss/1.0" xmlns:media="http://search.yahoo.com/mrss/" xmlns:podcast="https://podcastindex.org/namespace/1.0">
+  <channel>
+    <title>jmoiron.net blog</title>
+    <link>http://jmoiron.net/blog</link>
+    <description>discussion about tech</description>
+    <atom:link href="http://jmoiron.net/blog/fr" rel="alternate" hreflang="en_US"/>
+    <itunes:new-feed-url>ftp://jmoiron.net/feed</itunes:new-feed-url>
+    <webfeeds:accentColor>#00FF00</webfeeds:accentColor>
+    <item>
+      <title>Limiting Concurrency in Go</title>
+      <link>http://jmoiron.net/blog/limiting-concurrency-in-go/</link>
+      <guid>http://jmoiron.net/blog/limiting-concurrency-in-go/</guid>
+      <atom:link href="http://example.com/%zz" rel="via"/>
+      <media:player width="640"/>
+      <podcast:episode display="` + strings.Repeat("x", 33) + `">1</podcast:episode>
+    </item>
+  </channel>
+</rss>`
+	issues, err := LintRSS(strings.NewReader(rss))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`rss/channel: link http://jmoiron.net/blog/fr hreflang "en_US" is not a language tag`,
+		`rss/channel: webfeeds:accentColor "#00FF00" is not six hex digits without a #`,
+		`rss/channel: itunes:new-feed-url "ftp://jmoiron.net/feed" is not an http or https url`,
+		"rss/channel/item[0]: media:player url is required",
+		"rss/channel/item[0]: podcast:episode display is 33 characters, more than 32",
+		`rss/channel/item[0]: via url is invalid: parse "http://example.com/%zz": invalid URL escape "%zz"`,
+	}
+	if got := issueStrings(issues); !reflect.DeepEqual(got, want) {
+		t.Errorf("got issues:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	amazon := `<rss version="2.0" xmlns:amzn="https://amazon.com/ospublishing/1.0/" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd">
+  <channel>
+    <title>jmoiron.net blog</title>
+    <link>http://jmoiron.net/blog</link>
+    <description>discussion about tech</description>
+    <itunes:explicit>true</itunes:explicit>
+    <item>
+      <title>Best Headphones of 2019</title>
+      <link>http://jmoiron.net/blog/best-headphones/</link>
+      <guid>http://jmoiron.net/blog/best-headphones/</guid>
+      <pubDate>Wed, 16 Jan 2013 21:52:35 -0500</pubDate>
+      <amzn:heroImage>http://jmoiron.net/hero</amzn:heroImage>
+      <amzn:videoPosterImage>http://jmoiron.net/poster.jpg</amzn:videoPosterImage>
+      <amzn:status>removed</amzn:status>
+    </item>
+  </channel>
+</rss>`
+	issues, err = LintAmazonRss(strings.NewReader(amazon))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{
+		`rss/channel/item[0]/amzn:status: "removed" is not deleted`,
+		"rss/channel: is explicit, which amazon onsite does not accept",
+		"rss/channel/item[0]: amazon video item has no video enclosure",
+		`rss/channel/item[0]: hero image "http://jmoiron.net/hero" does not end in an image extension like .jpg or .png, and has no type`,
+	}
+	if got := issueStrings(issues); !reflect.DeepEqual(got, want) {
+		t.Errorf("got issues:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestLintAtom(t *testing.T) {
+	doc := `<feed xmlns="http://www.w3.org/2005/Atom">
+  <id>http://jmoiron.net/blog</id>
+  <title type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml">jmoiron.net <b>blog</b></div></title>
+  <updated>Wed, 16 Jan 2013 21:52:35 -0500</updated>
+  <link href="/blog" rel="self"/>
+  <entry>
+    <id>limiting-concurrency-in-go</id>
+    <title>Limiting Concurrency in Go</title>
+    <updated>2013-01-16T21:52:35-05:00</updated>
+    <author><name>Jason Moiron</name></author>
+    <content>A discussion on controlled parallelism in golang</content>
+  </entry>
+  <entry>
+    <id>http://jmoiron.net/blog/logicless-template-redux/</id>
+    <title>Logic-less Template Redux</title>
+    <updated>2013-01-16T21:52:35-05:00</updated>
+    <published>2013-01-16</published>
+    <link/>
+  </entry>
+</feed>`
+	issues, err := LintAtom(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`feed/updated: "Wed, 16 Jan 2013 21:52:35 -0500" is not an rfc 3339 date`,
+		`feed/entry[0]/id: "limiting-concurrency-in-go" is not an absolute url`,
+		"feed/entry[1]/link: has no href",
+		"feed/entry[1]: has no author, and neither has the feed",
+		`feed/entry[1]/published: "2013-01-16" is not an rfc 3339 date`,
+	}
+	if got := issueStrings(issues); !reflect.DeepEqual(got, want) {
+		t.Errorf("got issues:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestLintJSONFeed(t *testing.T) {
+	doc := `{
+  "version": "1",
+  "home_page_url": "jmoiron.net",
+  "items": [
+    {"id": 1, "content_text": "A discussion", "date_published": "2013-01-16T21:52:35-05:00"},
+    {"url": "http://jmoiron.net/blog/logicless-template-redux/", "date_modified": "yesterday",
+     "attachments": [{"url": "http://jmoiron.net/episode.mp3"}]},
+    "not an item"
+  ]
+}`
+	issues, err := LintJSONFeed(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		`version: "1" is not a json feed version`,
+		"title: is required",
+		`home_page_url: "jmoiron.net" is not an absolute url`,
+		"items[1].id: is required",
+		"items[1]: has neither content_html nor content_text",
+		`items[1].date_modified: "yesterday" cannot be parsed as a date`,
+		"items[1].attachments[0].mime_type: is required",
+		"items[2]: is not an object",
+	}
+	if got := issueStrings(issues); !reflect.DeepEqual(got, want) {
+		t.Errorf("got issues:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if _, err := LintJSONFeed(strings.NewReader("[]")); err == nil {
+		t.Errorf("expected an error for a document which is not an object")
+	}
+}
+
+func issueStrings(issues []ValidationIssue) []string {
+	var s []string
+	for _, i := range issues {
+		s = append(s, i.String())
+	}
+	return s
+}
+
+// Lint a feed file written by another program and print what is wrong
+// with it.
+func ExampleLintAtom() {
+	f, err := os.Open("test.atom")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer f.Close()
+	issues, err := LintAtom(f)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf("%d issues, the first of them:\n", len(issues))
+	for _, issue := range issues[:4] {
+		fmt.Println(issue)
+	}
+	// Output:
+	// 34 issues, the first of them:
+	// feed: is not in the atom namespace http://www.w3.org/2005/Atom
+	// feed: has no id
+	// feed: has no updated
+	// feed/link: has no href
+}
@@ -0,0 +1,69 @@
+package feeds
+
+import (
+	"time"
+)
+
+// Logger receives structured events about the generation of a feed. Each
+// event has a name and a list of alternating field names and values; the
+// events and their fields are:
+//
+//	feeds.generate.start   format, items
+//	feeds.generate.finish  format, items, duration, and error if it failed
+//	feeds.item.skipped     id, reason
+//	feeds.warning          reason
+//
+// format is the FeedType's String, items the number of items in the feed and
+// duration a time.Duration. id is the item's Id, or its link when it has
+// none. Warnings are logged for feeds which can be written but are likely
+// to be shown wrongly by readers. These names are stable and can be relied
+// upon by dashboards.
+type Logger interface {
+	Log(event string, keyvals ...interface{})
+}
+
+// The names of the events sent to a Logger.
+const (
+	EventGenerateStart  = "feeds.generate.start"
+	EventGenerateFinish = "feeds.generate.finish"
+	EventItemSkipped    = "feeds.item.skipped"
+	EventWarning        = "feeds.warning"
+)
+
+// LoggerFunc adapts a function to the Logger interface.
+type LoggerFunc func(event string, keyvals ...interface{})
+
+// Log calls l(event, keyvals...).
+func (l LoggerFunc) Log(event string, keyvals ...interface{}) {
+	l(event, keyvals...)
+}
+
+// log an event to the feed's Logger, if any
+func (f *Feed) log(event string, keyvals ...interface{}) {
+	if f.Logger != nil {
+		f.Logger.Log(event, keyvals...)
+	}
+}
+
+// run gen after checking the strings of the feed, logging when generating
+// the feed as t starts and finishes
+func (f *Feed) generate(t FeedType, gen func() error) error {
+	start := time.Now()
+	f.log(EventGenerateStart, "format", t.String(), "items", len(f.Items))
+	err := f.checkUTF8()
+	if err == nil {
+		err = f.checkLinkPolicy()
+	}
+	if err == nil {
+		err = f.checkDuplicateIDs()
+	}
+	if err == nil {
+		err = gen()
+	}
+	keyvals := []interface{}{"format", t.String(), "items", len(f.Items), "duration", time.Since(start)}
+	if err != nil {
+		keyvals = append(keyvals, "error", err)
+	}
+	f.log(EventGenerateFinish, keyvals...)
+	return err
+}
@@ -0,0 +1,99 @@
+package feeds
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type logEvent struct {
+	event  string
+	fields map[string]interface{}
+}
+
+// a Logger recording the events it receives
+type testLogger struct {
+	events []logEvent
+}
+
+func (l *testLogger) Log(event string, keyvals ...interface{}) {
+	fields := make(map[string]interface{})
+	for n := 0; n+1 < len(keyvals); n += 2 {
+		fields[keyvals[n].(string)] = keyvals[n+1]
+	}
+	l.events = append(l.events, logEvent{event, fields})
+}
+
+func TestLoggerGenerate(t *testing.T) {
+	logger := &testLogger{}
+	feed := &Feed{
+		Title:   "jmoiron.net blog",
+		Link:    &Link{Href: "http://jmoiron.net/blog"},
+		Created: time.Now(),
+		Logger:  logger,
+		Items: []*Item{
+			{Title: "Limiting Concurrency in Go", Link: &Link{Href: "http://jmoiron.net/blog/limiting-concurrency-in-go/"}},
+			{Title: "Logic-less Template Redux", Link: &Link{Href: "http://jmoiron.net/blog/logicless-template-redux/"}},
+		},
+	}
+	if _, err := feed.ToRss(); err != nil {
+		t.Fatalf("unexpected error encoding RSS: %v", err)
+	}
+	if len(logger.events) != 2 {
+		t.Fatalf("expected 2 events, got %+v", logger.events)
+	}
+	start, finish := logger.events[0], logger.events[1]
+	if start.event != EventGenerateStart || start.fields["format"] != "rss" || start.fields["items"] != 2 {
+		t.Errorf("unexpected start event %+v", start)
+	}
+	if finish.event != EventGenerateFinish || finish.fields["format"] != "rss" || finish.fields["items"] != 2 {
+		t.Errorf("unexpected finish event %+v", finish)
+	}
+	if _, ok := finish.fields["duration"].(time.Duration); !ok {
+		t.Errorf("finish event has no duration: %+v", finish)
+	}
+	if _, ok := finish.fields["error"]; ok {
+		t.Errorf("finish event has an error: %+v", finish)
+	}
+
+	logger.events = nil
+	feed.Created = time.Time{}
+	if _, err := feed.ToAtom(); err == nil {
+		t.Fatal("expected an error encoding an undated Atom feed")
+	}
+	if len(logger.events) != 2 || logger.events[1].fields["error"] == nil {
+		t.Errorf("expected the finish event to hold the error, got %+v", logger.events)
+	}
+}
+
+func TestLoggerSkippedItems(t *testing.T) {
+	logger := &testLogger{}
+	feed := sizeTestFeed()
+	full, _ := encodedSize(feed, FeedTypeRss)
+	feed.Logger = logger
+
+	_, report, err := FitToSize(feed, FeedTypeRss, full/2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(logger.events) != len(report.Dropped) {
+		t.Fatalf("expected %d events, got %+v", len(report.Dropped), logger.events)
+	}
+	for n, e := range logger.events {
+		if e.event != EventItemSkipped || e.fields["id"] != report.Dropped[n].Id || e.fields["reason"] != report.Dropped[n].Reason {
+			t.Errorf("unexpected event %+v for %+v", e, report.Dropped[n])
+		}
+	}
+}
+
+func TestLoggerFunc(t *testing.T) {
+	var got error
+	l := LoggerFunc(func(event string, keyvals ...interface{}) {
+		got = keyvals[1].(error)
+	})
+	want := errors.New("boom")
+	l.Log(EventGenerateFinish, "error", want)
+	if got != want {
+		t.Errorf("LoggerFunc did not pass the fields through")
+	}
+}
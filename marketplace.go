@@ -0,0 +1,84 @@
+package feeds
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// the domains of the amazon marketplaces, by their country codes
+var amazonMarketplaces = map[string]string{
+	"US": "www.amazon.com",
+	"UK": "www.amazon.co.uk",
+	"GB": "www.amazon.co.uk",
+	"CA": "www.amazon.ca",
+	"DE": "www.amazon.de",
+	"FR": "www.amazon.fr",
+	"IT": "www.amazon.it",
+	"ES": "www.amazon.es",
+	"NL": "www.amazon.nl",
+	"JP": "www.amazon.co.jp",
+	"IN": "www.amazon.in",
+	"AU": "www.amazon.com.au",
+	"MX": "www.amazon.com.mx",
+	"BR": "www.amazon.com.br",
+}
+
+// the domain of a marketplace, or an error for an unknown one
+func amazonMarketplaceDomain(marketplace string) (string, error) {
+	domain, ok := amazonMarketplaces[strings.ToUpper(marketplace)]
+	if !ok {
+		return "", fmt.Errorf("feeds: unknown amazon marketplace %q", marketplace)
+	}
+	return domain, nil
+}
+
+// whether the item is shown in the marketplace, which all items without
+// Marketplaces are, as are all items when no marketplace is targeted
+func (a *AmazonItem) inMarketplace(marketplace string) bool {
+	if marketplace == "" || a == nil || len(a.Marketplaces) == 0 {
+		return true
+	}
+	for _, m := range a.Marketplaces {
+		if strings.EqualFold(m, marketplace) {
+			return true
+		}
+	}
+	return false
+}
+
+// whether the host is the domain of one of the amazon marketplaces
+func isAmazonHost(host string) bool {
+	host = strings.TrimPrefix(strings.ToLower(host), "www.")
+	for _, domain := range amazonMarketplaces {
+		if host == strings.TrimPrefix(domain, "www.") {
+			return true
+		}
+	}
+	return false
+}
+
+// copies of the products with the urls of other amazon marketplaces
+// moved to the marketplace's domain
+func marketplaceProducts(products []*AmazonProduct, domain string) []*AmazonProduct {
+	moved := make([]*AmazonProduct, len(products))
+	for n, p := range products {
+		product := *p
+		if u, err := url.Parse(p.URL); err == nil && isAmazonHost(u.Host) {
+			u.Host = domain
+			product.URL = u.String()
+		}
+		moved[n] = &product
+	}
+	return moved
+}
+
+// whether the item is left out of the amazon rss feed because its
+// Marketplaces do not include the targeted Marketplace, dropping it
+func (f *Feed) outsideMarketplace(i *Item) bool {
+	if i.Amazon.inMarketplace(f.Marketplace) {
+		return false
+	}
+	f.dropItem(i, fmt.Sprintf("not shown in the %s marketplace", strings.ToUpper(f.Marketplace)))
+	return true
+}
@@ -0,0 +1,237 @@
+package feeds
+
+// media rss support
+// spec here:
+//    https://www.rssboard.org/media-rss
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+)
+
+const mediaNamespace = "http://search.yahoo.com/mrss/"
+
+// MediaPlayer is a web page playing an item's media in an embedded player,
+// used as media:player.
+type MediaPlayer struct {
+	URL           string // required
+	Width, Height int
+}
+
+// MediaHash is a hash of an enclosure's file for checking a download of
+// it, used as the media:hash of its media:content.
+type MediaHash struct {
+	Algo  string // md5 or sha-1
+	Value string // the hash in hex
+}
+
+// MediaEmbed is a player embedding an enclosure in a page, with the
+// parameters it is configured with, used as the media:embed of its
+// media:content.
+type MediaEmbed struct {
+	URL           string // required, an absolute url
+	Width, Height int
+	Params        map[string]string // media:param values by name
+}
+
+// MediaTitle is the title of an item's media, like the title of a video
+// rather than of the post it is in, used as media:title.
+type MediaTitle struct {
+	Text string
+	Type string // plain or html, plain when empty
+}
+
+type RssMediaContent struct {
+	XMLName  xml.Name `xml:"media:content"`
+	Url      string   `xml:"url,attr"`
+	Type     string   `xml:"type,attr,omitempty"`
+	FileSize int64    `xml:"fileSize,attr,omitempty"`
+	Bitrate  int      `xml:"bitrate,attr,omitempty"`
+	Hash     *RssMediaHash
+	Embed    *RssMediaEmbed
+}
+
+type RssMediaEmbed struct {
+	XMLName xml.Name         `xml:"media:embed"`
+	Url     string           `xml:"url,attr"`
+	Width   int              `xml:"width,attr,omitempty"`
+	Height  int              `xml:"height,attr,omitempty"`
+	Params  []*RssMediaParam `xml:"media:param"`
+}
+
+type RssMediaParam struct {
+	XMLName xml.Name `xml:"media:param"`
+	Name    string   `xml:"name,attr"`
+	Value   string   `xml:",chardata"`
+}
+
+type RssMediaHash struct {
+	XMLName xml.Name `xml:"media:hash"`
+	Algo    string   `xml:"algo,attr"`
+	Value   string   `xml:",chardata"`
+}
+
+type RssMediaTitle struct {
+	XMLName xml.Name `xml:"media:title"`
+	Type    string   `xml:"type,attr,omitempty"`
+	Text    string   `xml:",chardata"`
+}
+
+type RssMediaBackLinks struct {
+	XMLName   xml.Name `xml:"media:backLinks"`
+	BackLinks []string `xml:"media:backLink"`
+}
+
+type RssMediaPlayer struct {
+	XMLName xml.Name `xml:"media:player"`
+	Url     string   `xml:"url,attr"`
+	Width   int      `xml:"width,attr,omitempty"`
+	Height  int      `xml:"height,attr,omitempty"`
+}
+
+func (p *MediaPlayer) validate() error {
+	if p.URL == "" {
+		return errors.New("media:player url is required")
+	}
+	if p.Width < 0 || p.Height < 0 {
+		return fmt.Errorf("media:player size %dx%d is negative", p.Width, p.Height)
+	}
+	return nil
+}
+
+func (t *MediaTitle) validate() error {
+	if t.Type != "" && t.Type != "plain" && t.Type != "html" {
+		return fmt.Errorf("media:title type %q is not plain or html", t.Type)
+	}
+	return nil
+}
+
+// check a media:backLink is an absolute url
+func validateBackLink(link string) error {
+	if u, err := url.Parse(link); err != nil || !u.IsAbs() || u.Host == "" {
+		return fmt.Errorf("media:backLink %q is not an absolute url", link)
+	}
+	return nil
+}
+
+func (e *MediaEmbed) validate() error {
+	if u, err := url.Parse(e.URL); err != nil || !u.IsAbs() || u.Host == "" {
+		return fmt.Errorf("media:embed url %q is not an absolute url", e.URL)
+	}
+	if e.Width < 0 || e.Height < 0 {
+		return fmt.Errorf("media:embed size %dx%d is negative", e.Width, e.Height)
+	}
+	for name := range e.Params {
+		if name == "" {
+			return errors.New("media:embed has a media:param without a name")
+		}
+	}
+	return nil
+}
+
+// the media:embed of an embed, with its params in the order of their names
+func newRssMediaEmbed(e *MediaEmbed) *RssMediaEmbed {
+	embed := &RssMediaEmbed{Url: e.URL, Width: e.Width, Height: e.Height}
+	names := make([]string, 0, len(e.Params))
+	for name := range e.Params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		embed.Params = append(embed.Params, &RssMediaParam{Name: name, Value: e.Params[name]})
+	}
+	return embed
+}
+
+func (h *MediaHash) validate() error {
+	if h.Algo != "md5" && h.Algo != "sha-1" {
+		return fmt.Errorf("media:hash algo %q is not md5 or sha-1", h.Algo)
+	}
+	if h.Value == "" {
+		return errors.New("media:hash has no value")
+	}
+	return nil
+}
+
+// set the media rss elements of an RssItem from a generic Item; only
+// enclosures with a hash, a bitrate or an embed are repeated as
+// media:content, and
+// items with media but no MediaTitle use their title as the media:title
+func setRssMedia(item *RssItem, i *Item) {
+	if e := i.Enclosure; e != nil && (e.Hash != nil || e.Bitrate > 0 || e.Embed != nil) {
+		size, _ := strconv.ParseInt(e.Length, 10, 64)
+		item.MediaContent = &RssMediaContent{
+			Url:      e.Url,
+			Type:     e.Type,
+			FileSize: size,
+			Bitrate:  e.Bitrate,
+		}
+		if e.Hash != nil {
+			item.MediaContent.Hash = &RssMediaHash{Algo: e.Hash.Algo, Value: e.Hash.Value}
+		}
+		if e.Embed != nil {
+			item.MediaContent.Embed = newRssMediaEmbed(e.Embed)
+		}
+	}
+	if p := i.MediaPlayer; p != nil {
+		item.MediaPlayer = &RssMediaPlayer{Url: p.URL, Width: p.Width, Height: p.Height}
+	}
+	if t := i.MediaTitle; t != nil {
+		item.MediaTitle = &RssMediaTitle{Type: t.Type, Text: t.Text}
+		if item.MediaTitle.Type == "" {
+			item.MediaTitle.Type = "plain"
+		}
+	} else if (item.MediaContent != nil || item.MediaPlayer != nil) && i.Title != "" {
+		item.MediaTitle = &RssMediaTitle{Type: "plain", Text: i.Title}
+	}
+	if len(i.MediaBackLinks) > 0 {
+		item.BackLinks = &RssMediaBackLinks{BackLinks: i.MediaBackLinks}
+	}
+}
+
+// check the media rss fields of an Item
+func validateMedia(i *Item) error {
+	if i.Enclosure != nil && i.Enclosure.Hash != nil {
+		if err := i.Enclosure.Hash.validate(); err != nil {
+			return err
+		}
+	}
+	if i.Enclosure != nil && i.Enclosure.Bitrate < 0 {
+		return fmt.Errorf("media:content bitrate %d is negative", i.Enclosure.Bitrate)
+	}
+	if i.Enclosure != nil && i.Enclosure.Embed != nil {
+		if err := i.Enclosure.Embed.validate(); err != nil {
+			return err
+		}
+	}
+	if i.MediaPlayer != nil {
+		if err := i.MediaPlayer.validate(); err != nil {
+			return err
+		}
+	}
+	if i.MediaTitle != nil {
+		if err := i.MediaTitle.validate(); err != nil {
+			return err
+		}
+	}
+	for _, link := range i.MediaBackLinks {
+		if err := validateBackLink(link); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// whether any of the items use media rss elements
+func (r *RssFeed) usesMedia() bool {
+	for _, i := range r.Items {
+		if i.MediaContent != nil || i.MediaPlayer != nil || i.MediaTitle != nil || i.BackLinks != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,313 @@
+package feeds
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func mediaTestFeed(i *Item) *Feed {
+	i.Title = "Never Gonna Give You Up"
+	i.Link = &Link{Href: "http://example.com/RickRoll"}
+	return testFeed(i)
+}
+
+func TestMediaPlayer(t *testing.T) {
+	rss, err := mediaTestFeed(&Item{}).ToRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding RSS: %v", err)
+	}
+	if strings.Contains(rss, "media") {
+		t.Errorf("Rss should not use media rss.  Got:\n%s\n", rss)
+	}
+
+	feed := mediaTestFeed(&Item{MediaPlayer: &MediaPlayer{URL: "http://example.com/player?id=1&autoplay=0", Width: 640, Height: 360}})
+	rss, err = feed.ToRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding RSS: %v", err)
+	}
+	for _, want := range []string{
+		`xmlns:media="http://search.yahoo.com/mrss/"`,
+		`<media:player url="http://example.com/player?id=1&amp;autoplay=0" width="640" height="360"></media:player>`,
+	} {
+		if !strings.Contains(rss, want) {
+			t.Errorf("Rss missing %s.  Got:\n%s\n", want, rss)
+		}
+	}
+}
+
+func TestMediaPlayerInvalid(t *testing.T) {
+	for _, p := range []*MediaPlayer{
+		{Width: 640, Height: 360},
+		{URL: "http://example.com/player", Width: -1},
+		{URL: "http://example.com/player", Height: -1},
+	} {
+		if rss, err := mediaTestFeed(&Item{MediaPlayer: p}).ToRss(); err == nil {
+			t.Errorf("expected an error for %+v, got:\n%s", p, rss)
+		}
+	}
+}
+
+func TestMediaHash(t *testing.T) {
+	enclosure := &Enclosure{Url: "http://example.com/RickRoll.mp4", Type: "video/mp4", Length: "123456789"}
+	rss, err := mediaTestFeed(&Item{Enclosure: enclosure}).ToRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding RSS: %v", err)
+	}
+	if strings.Contains(rss, "media") {
+		t.Errorf("Rss should not use media rss without a hash.  Got:\n%s\n", rss)
+	}
+
+	enclosure.Hash = &MediaHash{Algo: "md5", Value: "dc86f0fee1f2d6163a295ba4fcbf4d26"}
+	rss, err = mediaTestFeed(&Item{Enclosure: enclosure}).ToRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding RSS: %v", err)
+	}
+	for _, want := range []string{
+		`xmlns:media="http://search.yahoo.com/mrss/"`,
+		`<enclosure url="http://example.com/RickRoll.mp4" length="123456789" type="video/mp4"></enclosure>`,
+		`<media:content url="http://example.com/RickRoll.mp4" type="video/mp4" fileSize="123456789">`,
+		`<media:hash algo="md5">dc86f0fee1f2d6163a295ba4fcbf4d26</media:hash>`,
+	} {
+		if !strings.Contains(rss, want) {
+			t.Errorf("Rss missing %s.  Got:\n%s\n", want, rss)
+		}
+	}
+
+	for _, h := range []*MediaHash{
+		{Algo: "sha-256", Value: "e3b0c44298fc1c149afbf4c8996fb924"},
+		{Algo: "SHA1", Value: "da39a3ee5e6b4b0d3255bfef95601890afd80709"},
+		{Algo: "sha-1"},
+	} {
+		enclosure.Hash = h
+		feed := mediaTestFeed(&Item{Enclosure: enclosure})
+		if rss, err := feed.ToRss(); err == nil {
+			t.Errorf("expected an error for %+v, got:\n%s", h, rss)
+		}
+		feed.Description = "never gonna"
+		if err := feed.ValidateRSS(); err == nil {
+			t.Errorf("expected a validation error for %+v", h)
+		}
+	}
+}
+
+func TestMediaTitle(t *testing.T) {
+	player := &MediaPlayer{URL: "http://example.com/player?id=1"}
+	rss, err := mediaTestFeed(&Item{MediaPlayer: player}).ToRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding RSS: %v", err)
+	}
+	want := `<media:title type="plain">Never Gonna Give You Up</media:title>`
+	if !strings.Contains(rss, want) {
+		t.Errorf("Rss missing %s.  Got:\n%s\n", want, rss)
+	}
+
+	title := &MediaTitle{Text: "Rick Astley - <b>Official</b> Video", Type: "html"}
+	rss, err = mediaTestFeed(&Item{MediaPlayer: player, MediaTitle: title}).ToRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding RSS: %v", err)
+	}
+	for _, want := range []string{
+		`<title>Never Gonna Give You Up</title>`,
+		`<media:title type="html">Rick Astley - &lt;b&gt;Official&lt;/b&gt; Video</media:title>`,
+	} {
+		if !strings.Contains(rss, want) {
+			t.Errorf("Rss missing %s.  Got:\n%s\n", want, rss)
+		}
+	}
+
+	rss, err = mediaTestFeed(&Item{MediaTitle: &MediaTitle{Text: "Official Video"}}).ToRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding RSS: %v", err)
+	}
+	for _, want := range []string{
+		`xmlns:media="http://search.yahoo.com/mrss/"`,
+		`<media:title type="plain">Official Video</media:title>`,
+	} {
+		if !strings.Contains(rss, want) {
+			t.Errorf("Rss missing %s.  Got:\n%s\n", want, rss)
+		}
+	}
+
+	if rss, err := mediaTestFeed(&Item{MediaTitle: &MediaTitle{Text: "Official Video", Type: "text"}}).ToRss(); err == nil {
+		t.Errorf("expected an error for the media:title type, got:\n%s", rss)
+	}
+}
+
+func TestEnclosureStreamingHints(t *testing.T) {
+	ranges := true
+	enclosure := &Enclosure{Url: "http://example.com/episode.mp3", Type: "audio/mpeg", Length: "123456", Bitrate: 128, SupportsRanges: &ranges}
+	feed := mediaTestFeed(&Item{Enclosure: enclosure})
+	rss, err := feed.ToRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding RSS: %v", err)
+	}
+	want := `<media:content url="http://example.com/episode.mp3" type="audio/mpeg" fileSize="123456" bitrate="128"></media:content>`
+	if !strings.Contains(rss, want) {
+		t.Errorf("Rss missing %s.  Got:\n%s\n", want, rss)
+	}
+
+	json, err := feed.ToJSON()
+	if err != nil {
+		t.Errorf("unexpected error encoding JSON: %v", err)
+	}
+	want = `"attachments": [
+        {
+          "url": "http://example.com/episode.mp3",
+          "mime_type": "audio/mpeg",
+          "size": 123456,
+          "_bitrate": 128,
+          "_ranges": true
+        }
+      ]`
+	if !strings.Contains(json, want) {
+		t.Errorf("JSON missing %s.  Got:\n%s\n", want, json)
+	}
+
+	amazon, err := feed.ToAmazonRss()
+	if err != nil || strings.Contains(amazon, "bitrate") {
+		t.Errorf("AmazonRss should ignore the hints, got %v:\n%s", err, amazon)
+	}
+
+	enclosure.Bitrate = -1
+	if rss, err := feed.ToRss(); err == nil {
+		t.Errorf("expected an error for a negative bitrate, got:\n%s", rss)
+	}
+}
+
+func TestMediaBackLinks(t *testing.T) {
+	rss, err := mediaTestFeed(&Item{MediaBackLinks: []string{}}).ToRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding RSS: %v", err)
+	}
+	if strings.Contains(rss, "media") {
+		t.Errorf("Rss should not use media rss without back links.  Got:\n%s\n", rss)
+	}
+
+	feed := mediaTestFeed(&Item{MediaBackLinks: []string{"http://jmoiron.net/blog/rickroll/", "http://example.com/?q=rick&roll"}})
+	rss, err = feed.ToRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding RSS: %v", err)
+	}
+	for _, want := range []string{
+		`xmlns:media="http://search.yahoo.com/mrss/"`,
+		"<media:backLinks>\n        <media:backLink>http://jmoiron.net/blog/rickroll/</media:backLink>\n" +
+			"        <media:backLink>http://example.com/?q=rick&amp;roll</media:backLink>\n      </media:backLinks>",
+	} {
+		if !strings.Contains(rss, want) {
+			t.Errorf("Rss missing %s.  Got:\n%s\n", want, rss)
+		}
+	}
+
+	for _, link := range []string{"", "/blog/rickroll/", "jmoiron.net", "http://%zz"} {
+		feed := mediaTestFeed(&Item{MediaBackLinks: []string{"http://jmoiron.net/", link}})
+		feed.Description = "discussion about tech, footie, photos"
+		if rss, err := feed.ToRss(); err == nil {
+			t.Errorf("expected an error for the back link %q, got:\n%s", link, rss)
+		}
+		if err := feed.ValidateRSS(); err == nil || !strings.Contains(err.Error(), "media:backLink") {
+			t.Errorf("expected ValidateRSS to reject the back link %q, got %v", link, err)
+		}
+	}
+}
+
+func TestJSONAttachmentDuration(t *testing.T) {
+	enclosure := &Enclosure{Url: "http://example.com/episode.mp3", Type: "audio/mpeg", Length: "123456", Duration: 1805 * time.Second, Title: "Episode 1 (mp3)"}
+	json, err := mediaTestFeed(&Item{Enclosure: enclosure}).ToJSON()
+	if err != nil {
+		t.Errorf("unexpected error encoding JSON: %v", err)
+	}
+	want := `"attachments": [
+        {
+          "duration_in_seconds": 1805,
+          "url": "http://example.com/episode.mp3",
+          "mime_type": "audio/mpeg",
+          "title": "Episode 1 (mp3)",
+          "size": 123456
+        }
+      ]`
+	if !strings.Contains(json, want) {
+		t.Errorf("JSON missing %s.  Got:\n%s\n", want, json)
+	}
+
+	enclosure.Title = ""
+	json, _ = mediaTestFeed(&Item{Enclosure: enclosure}).ToJSON()
+	if !strings.Contains(json, `"duration_in_seconds": 1805`) || strings.Contains(json, `"title": "Episode`) {
+		t.Errorf("JSON should only write the duration.  Got:\n%s\n", json)
+	}
+	// plain enclosures are attachments too
+	enclosure.Duration = 0
+	json, _ = mediaTestFeed(&Item{Enclosure: enclosure}).ToJSON()
+	want = `"attachments": [
+        {
+          "url": "http://example.com/episode.mp3",
+          "mime_type": "audio/mpeg",
+          "size": 123456
+        }
+      ]`
+	if !strings.Contains(json, want) {
+		t.Errorf("JSON missing %s.  Got:\n%s\n", want, json)
+	}
+
+	// the size of files over 2 GiB is left out rather than truncated
+	enclosure.Length = "3000000000"
+	var warnings []interface{}
+	feed := mediaTestFeed(&Item{Enclosure: enclosure})
+	feed.Logger = LoggerFunc(func(event string, keyvals ...interface{}) {
+		if event == EventWarning {
+			warnings = append(warnings, keyvals[1])
+		}
+	})
+	json, _ = feed.ToJSON()
+	if !strings.Contains(json, `"url": "http://example.com/episode.mp3"`) || strings.Contains(json, `"size"`) {
+		t.Errorf("JSON should write the attachment without its size.  Got:\n%s\n", json)
+	}
+	if len(warnings) != 1 || !strings.Contains(fmt.Sprint(warnings[0]), "3000000000 bytes") {
+		t.Errorf("expected a warning about the size, got %v", warnings)
+	}
+}
+
+func TestMediaEmbed(t *testing.T) {
+	feed := mediaTestFeed(&Item{Enclosure: &Enclosure{
+		Url:  "http://example.com/RickRoll.flv",
+		Type: "video/x-flv",
+		Embed: &MediaEmbed{
+			URL:    "http://example.com/player.swf",
+			Width:  512,
+			Height: 323,
+			Params: map[string]string{"type": "application/x-shockwave-flash", "allowFullScreen": "true", "flashVars": "id=7&b=1"},
+		},
+	}})
+	rss, err := feed.ToRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding RSS: %v", err)
+	}
+	want := `<media:embed url="http://example.com/player.swf" width="512" height="323">
+          <media:param name="allowFullScreen">true</media:param>
+          <media:param name="flashVars">id=7&amp;b=1</media:param>
+          <media:param name="type">application/x-shockwave-flash</media:param>
+        </media:embed>`
+	if !strings.Contains(rss, want) || !strings.Contains(rss, `<media:content url="http://example.com/RickRoll.flv" type="video/x-flv">`) {
+		t.Errorf("Rss missing %s.  Got:\n%s\n", want, rss)
+	}
+
+	feed.Items[0].Enclosure.Embed.Params = nil
+	if rss, _ = feed.ToRss(); !strings.Contains(rss, `<media:embed url="http://example.com/player.swf" width="512" height="323"></media:embed>`) {
+		t.Errorf("Rss should write an embed without params.  Got:\n%s\n", rss)
+	}
+
+	for _, embed := range []*MediaEmbed{
+		{URL: "/player.swf"},
+		{URL: "http://example.com/player.swf", Width: -1},
+		{URL: "http://example.com/player.swf", Params: map[string]string{"": "true"}},
+	} {
+		feed := mediaTestFeed(&Item{Enclosure: &Enclosure{Url: "http://example.com/RickRoll.flv", Type: "video/x-flv", Embed: embed}})
+		if rss, err := feed.ToRss(); err == nil {
+			t.Errorf("expected an error for %+v, got:\n%s", embed, rss)
+		}
+		if err := feed.ValidateRSS(); err == nil {
+			t.Errorf("expected a validation error for %+v", embed)
+		}
+	}
+}
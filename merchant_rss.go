@@ -0,0 +1,166 @@
+package feeds
+
+// google merchant center product feed support
+// spec here:
+//    https://support.google.com/merchants/answer/7052112
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const googleNamespace = "http://base.google.com/ns/1.0"
+
+// MerchantRssFeedXml is the <rss>..</rss> wrapper of a MerchantRssFeed
+type MerchantRssFeedXml struct {
+	XMLName         xml.Name `xml:"rss"`
+	Version         string   `xml:"version,attr"`
+	GoogleNamespace string   `xml:"xmlns:g,attr"`
+	Channel         *MerchantRssFeed
+}
+
+// MerchantRssFeed is a Google Merchant Center product feed
+type MerchantRssFeed struct {
+	XMLName     xml.Name           `xml:"channel"`
+	Title       string             `xml:"title"`
+	Link        string             `xml:"link"`
+	Description string             `xml:"description"`
+	Items       []*MerchantRssItem `xml:"item"`
+}
+
+// MerchantRssItem is a single product of a MerchantRssFeed
+type MerchantRssItem struct {
+	XMLName     xml.Name `xml:"item"`
+	Id          string   `xml:"g:id"`          // required
+	Title       string   `xml:"g:title"`       // required
+	Description string   `xml:"g:description"` // required
+	Link        string   `xml:"g:link"`        // required
+	ImageLink   string   `xml:"g:image_link"`  // required
+	Price       string   `xml:"g:price,omitempty"`
+}
+
+// MerchantRss creates a Google Merchant Center product feed from the
+// AmazonProducts of a Feed's items.
+type MerchantRss struct {
+	*Feed
+}
+
+// the ASIN of a product, or the one in its amazon.com/dp/ASIN link
+func productASIN(p *AmazonProduct) string {
+	if p.ASIN != "" {
+		return p.ASIN
+	}
+	u, err := url.Parse(p.URL)
+	if err != nil {
+		return ""
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for n := 0; n+1 < len(parts); n++ {
+		if parts[n] == "dp" {
+			return parts[n+1]
+		}
+	}
+	return ""
+}
+
+// create a new MerchantRssItem from an AmazonProduct, or return the names
+// of the required fields it lacks
+func newMerchantRssItem(p *AmazonProduct) (*MerchantRssItem, []string) {
+	item := &MerchantRssItem{
+		Id:          productASIN(p),
+		Title:       p.Headline,
+		Description: p.Summary,
+		Link:        p.URL,
+		ImageLink:   p.ImageURL,
+	}
+	if p.Price != "" && p.Currency != "" {
+		item.Price = p.Price + " " + p.Currency
+	}
+
+	var missing []string
+	for _, f := range []struct{ name, value string }{
+		{"g:id", item.Id},
+		{"g:title", item.Title},
+		{"g:description", item.Description},
+		{"g:link", item.Link},
+		{"g:image_link", item.ImageLink},
+	} {
+		if f.value == "" {
+			missing = append(missing, f.name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, missing
+	}
+	return item, nil
+}
+
+// MerchantRssFeed creates a MerchantRssFeed with one item for each of the
+// products of the Feed's items. Products missing any of the fields Google
+// requires are left out, and logged as skipped and reported by
+// WriteWithReport with the item they belong to. The g:price is only
+// written for products with a Currency, and writing the feed fails for
+// those without one.
+func (m *MerchantRss) MerchantRssFeed() *MerchantRssFeed {
+	feed, _ := m.merchantRssFeed()
+	return feed
+}
+
+// create a new MerchantRssFeed, returning an error along with it if a
+// product has a price without a currency
+func (m *MerchantRss) merchantRssFeed() (*MerchantRssFeed, error) {
+	var err error
+	channel := &MerchantRssFeed{
+		Title:       m.Title,
+		Description: m.Description,
+	}
+	if m.Link != nil {
+		channel.Link = m.Link.Href
+	}
+	for n, i := range m.Items {
+		if i.Amazon == nil || !i.published() {
+			continue
+		}
+		for _, p := range i.Amazon.Products {
+			id := productASIN(p)
+			if id == "" {
+				id = p.URL
+			}
+			if p.Price != "" && p.Currency == "" && err == nil {
+				err = fmt.Errorf("feeds: item %d: product %s: price %q has no currency", n, id, p.Price)
+			}
+			item, missing := newMerchantRssItem(p)
+			if item == nil {
+				m.dropItem(i, "product "+id+": missing "+strings.Join(missing, ", "))
+				continue
+			}
+			channel.Items = append(channel.Items, item)
+			m.wrote(i)
+		}
+	}
+	return channel, err
+}
+
+// FeedXml returns an XML-Ready object for a MerchantRss object
+func (m *MerchantRss) FeedXml() interface{} {
+	return m.MerchantRssFeed().FeedXml()
+}
+
+func (m *MerchantRss) buildFeedXml() (interface{}, error) {
+	feed, err := m.merchantRssFeed()
+	if err != nil {
+		return nil, err
+	}
+	return feed.FeedXml(), nil
+}
+
+// FeedXml returns an XML-ready object for a MerchantRssFeed object
+func (m *MerchantRssFeed) FeedXml() interface{} {
+	return &MerchantRssFeedXml{
+		Version:         "2.0",
+		GoogleNamespace: googleNamespace,
+		Channel:         m,
+	}
+}
@@ -0,0 +1,123 @@
+package feeds
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// the structure of the example feed in Google's documentation, limited to
+// the attributes taken from AmazonProduct
+var merchantRssOutput = `<?xml version="1.0" encoding="UTF-8"?><rss version="2.0" xmlns:g="http://base.google.com/ns/1.0">
+  <channel>
+    <title>Example - Online Store</title>
+    <link>http://www.example.com</link>
+    <description>This is a sample feed containing the required and recommended attributes for a variety of different products</description>
+    <item>
+      <g:id>B00KQRJ6CM</g:id>
+      <g:title>LG 22LB4510 - 22&#34; LED TV - 1080p (FullHD)</g:title>
+      <g:description>Attractively styled and boasting stunning picture quality, the LG 22LB4510 - 22&#34; LED TV - 1080p (FullHD) is an excellent television/monitor.</g:description>
+      <g:link>https://www.amazon.com/dp/B00KQRJ6CM</g:link>
+      <g:image_link>http://images.example.com/TV_123456.png</g:image_link>
+      <g:price>159.00 USD</g:price>
+    </item>
+    <item>
+      <g:id>TV_654321</g:id>
+      <g:title>LG 32LB5610 - 32&#34; LED TV</g:title>
+      <g:description>A larger television for the living room.</g:description>
+      <g:link>http://www.example.com/electronics/tv/32LB5610.html</g:link>
+      <g:image_link>http://images.example.com/TV_654321.png</g:image_link>
+      <g:price>249.00 USD</g:price>
+    </item>
+  </channel>
+</rss>`
+
+func TestMerchantRss(t *testing.T) {
+	feed := &Feed{
+		Title:       "Example - Online Store",
+		Link:        &Link{Href: "http://www.example.com"},
+		Description: "This is a sample feed containing the required and recommended attributes for a variety of different products",
+	}
+	feed.Items = []*Item{
+		{
+			Title: "Best TVs",
+			Link:  &Link{Href: "http://www.example.com/best-tvs"},
+			Amazon: &AmazonItem{Products: []*AmazonProduct{
+				{
+					URL:      "https://www.amazon.com/dp/B00KQRJ6CM",
+					Headline: `LG 22LB4510 - 22" LED TV - 1080p (FullHD)`,
+					Summary:  `Attractively styled and boasting stunning picture quality, the LG 22LB4510 - 22" LED TV - 1080p (FullHD) is an excellent television/monitor.`,
+					ImageURL: "http://images.example.com/TV_123456.png",
+					Price:    "159.00",
+					Currency: "USD",
+				},
+				{
+					URL:      "https://www.amazon.com/dp/B00NOIMAGE",
+					Headline: "A television without a picture",
+					Summary:  "Not listed",
+				},
+			}},
+		},
+		{Title: "A post without products", Link: &Link{Href: "http://www.example.com/news"}},
+		{
+			Title: "Bigger TVs",
+			Link:  &Link{Href: "http://www.example.com/bigger-tvs"},
+			Amazon: &AmazonItem{Products: []*AmazonProduct{
+				{
+					URL:      "http://www.example.com/electronics/tv/32LB5610.html",
+					ASIN:     "TV_654321",
+					Headline: `LG 32LB5610 - 32" LED TV`,
+					Summary:  "A larger television for the living room.",
+					ImageURL: "http://images.example.com/TV_654321.png",
+					Price:    "249.00",
+					Currency: "USD",
+				},
+				{URL: "http://www.example.com/electronics/tv/unknown.html"},
+			}},
+		},
+	}
+
+	merchant, err := feed.ToMerchantRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding Merchant RSS: %v", err)
+	}
+	if merchant != merchantRssOutput {
+		t.Errorf("Merchant RSS not what was expected.  Got:\n%s\n\nExpected:\n%s\n", merchant, merchantRssOutput)
+	}
+
+	// the products left out are reported and logged with their items
+	var events []string
+	feed.Logger = LoggerFunc(func(event string, keyvals ...interface{}) {
+		events = append(events, event)
+	})
+	var buf bytes.Buffer
+	report, err := feed.WriteWithReport(&buf, FeedTypeMerchantRss)
+	if err != nil {
+		t.Errorf("unexpected error writing Merchant RSS: %v", err)
+	}
+	if buf.String() != merchantRssOutput {
+		t.Errorf("WriteWithReport wrote a different document.  Got:\n%s\n", buf.String())
+	}
+	want := []DroppedItem{
+		{Id: "http://www.example.com/best-tvs", Reason: "product B00NOIMAGE: missing g:image_link"},
+		{Id: "http://www.example.com/bigger-tvs", Reason: "product http://www.example.com/electronics/tv/unknown.html: missing g:id, g:title, g:description, g:image_link"},
+	}
+	if len(report.Dropped) != len(want) {
+		t.Fatalf("expected %d dropped products, got %+v", len(want), report.Dropped)
+	}
+	for n, d := range report.Dropped {
+		if d != want[n] {
+			t.Errorf("dropped product %d = %+v, want %+v", n, d, want[n])
+		}
+	}
+	wantEvents := []string{EventGenerateStart, EventItemSkipped, EventItemSkipped, EventGenerateFinish}
+	if !reflect.DeepEqual(events, wantEvents) {
+		t.Errorf("got events %v, want %v", events, wantEvents)
+	}
+	feed.Logger = nil
+
+	feed.Items[2].Amazon.Products[0].Currency = ""
+	if out, err := feed.ToMerchantRss(); err == nil || err.Error() != `feeds: item 2: product TV_654321: price "249.00" has no currency` {
+		t.Errorf("expected an error for a price without a currency, got %v:\n%s", err, out)
+	}
+}
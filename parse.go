@@ -0,0 +1,656 @@
+package feeds
+
+// parsing of rss documents back into the generic Feed
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// layouts accepted by parseFeedTime, most common first
+var feedTimeLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	time.RFC3339,
+	time.RFC3339Nano,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 2 Jan 2006 15:04:05 -07:00",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	time.RFC822Z,
+	time.RFC822,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05Z0700",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// parse a date as found in real world feeds, trying each of the known
+// layouts in turn. returns the zero time if none of them match.
+func parseFeedTime(value string) time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}
+	}
+	for _, layout := range feedTimeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// the encoder uses prefixed names like "amzn:heroImage", which the decoder
+// does not match against namespaced elements, so parsing uses its own
+// namespace-aware mirror of the rss types.
+type rssParseChannel struct {
+	Title          string
+	Link           string
+	Description    string
+	Language       string
+	Copyright      string
+	ManagingEditor string
+	PubDate        string
+	LastBuildDate  string
+	TTL            string
+	Image          *rssParseImage
+	PublisherId    string
+	ProgramId      string
+	Links          []*rssParseLink
+	NewFeedURL     string
+	Explicit       string
+	Webfeeds       *Webfeeds
+}
+
+// an atom:link of a channel or item
+type rssParseLink struct {
+	Href     string `xml:"href,attr"`
+	Rel      string `xml:"rel,attr"`
+	Type     string `xml:"type,attr"`
+	HrefLang string `xml:"hreflang,attr"`
+	Title    string `xml:"title,attr"`
+}
+
+func (l *rssParseLink) link() *Link {
+	return &Link{Href: l.Href, Rel: l.Rel, Type: l.Type, HrefLang: l.HrefLang, Title: l.Title}
+}
+
+// numbers are parsed from strings, so a hostile value cannot fail the
+// whole document
+type rssParseImage struct {
+	Url    string `xml:"url"`
+	Title  string `xml:"title"`
+	Link   string `xml:"link"`
+	Width  string `xml:"width"`
+	Height string `xml:"height"`
+}
+
+type rssParseItem struct {
+	// the decoder matches the fields without a namespace against elements
+	// in any namespace, so the atom:link and media:title come first
+	Links      []*rssParseLink     `xml:"http://www.w3.org/2005/Atom link"`
+	MediaTitle *rssParseMediaTitle `xml:"http://search.yahoo.com/mrss/ title"`
+
+	Title        string             `xml:"title"`
+	Link         string             `xml:"link"`
+	Description  string             `xml:"description"`
+	Content      string             `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Author       string             `xml:"author"`
+	Enclosure    *RssEnclosure      `xml:"enclosure"`
+	Guid         string             `xml:"guid"`
+	PubDate      string             `xml:"pubDate"`
+	Source       string             `xml:"source"`
+	Creator      string             `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	HeroImage    *string            `xml:"https://amazon.com/ospublishing/1.0/ heroImage"`
+	IntroText    *string            `xml:"https://amazon.com/ospublishing/1.0/ introText"`
+	IndexContent *string            `xml:"https://amazon.com/ospublishing/1.0/ indexContent"`
+	Section      string             `xml:"https://amazon.com/ospublishing/1.0/ section"`
+	Position     string             `xml:"https://amazon.com/ospublishing/1.0/ position"`
+	Products     []*rssParseProduct `xml:"https://amazon.com/ospublishing/1.0/ products>product"`
+	VideoPoster  *string            `xml:"https://amazon.com/ospublishing/1.0/ videoPosterImage"`
+	Status       string             `xml:"https://amazon.com/ospublishing/1.0/ status"`
+
+	MediaContent   *rssParseMediaContent `xml:"http://search.yahoo.com/mrss/ content"`
+	MediaPlayer    *rssParseMediaPlayer  `xml:"http://search.yahoo.com/mrss/ player"`
+	BackLinks      []string              `xml:"http://search.yahoo.com/mrss/ backLinks>backLink"`
+	PodcastSeason  *rssParsePodcast      `xml:"https://podcastindex.org/namespace/1.0 season"`
+	PodcastEpisode *rssParsePodcast      `xml:"https://podcastindex.org/namespace/1.0 episode"`
+}
+
+type rssParseMediaContent struct {
+	Url      string `xml:"url,attr"`
+	Type     string `xml:"type,attr"`
+	FileSize string `xml:"fileSize,attr"`
+	Bitrate  string `xml:"bitrate,attr"`
+	Hash     *struct {
+		Algo  string `xml:"algo,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"http://search.yahoo.com/mrss/ hash"`
+	Embed *struct {
+		Url    string `xml:"url,attr"`
+		Width  string `xml:"width,attr"`
+		Height string `xml:"height,attr"`
+		Params []struct {
+			Name  string `xml:"name,attr"`
+			Value string `xml:",chardata"`
+		} `xml:"http://search.yahoo.com/mrss/ param"`
+	} `xml:"http://search.yahoo.com/mrss/ embed"`
+}
+
+type rssParseMediaPlayer struct {
+	Url    string `xml:"url,attr"`
+	Width  string `xml:"width,attr"`
+	Height string `xml:"height,attr"`
+}
+
+type rssParseMediaTitle struct {
+	Type string `xml:"type,attr"`
+	Text string `xml:",chardata"`
+}
+
+// a podcast:season, with its name, or a podcast:episode, with its display
+type rssParsePodcast struct {
+	Name    string `xml:"name,attr"`
+	Display string `xml:"display,attr"`
+	Number  string `xml:",chardata"`
+}
+
+type rssParseProduct struct {
+	URL      string `xml:"https://amazon.com/ospublishing/1.0/ productURL"`
+	Headline string `xml:"https://amazon.com/ospublishing/1.0/ productHeadline"`
+	Award    string `xml:"https://amazon.com/ospublishing/1.0/ award"`
+	Summary  string `xml:"https://amazon.com/ospublishing/1.0/ productSummary"`
+}
+
+// DefaultMaxTextLength is the number of bytes the text of the parsed
+// elements is cut to by default.
+const DefaultMaxTextLength = 1 << 20
+
+// ParseOptions are the limits of ParseRssWithWarnings and ParseJSONWithWarnings.
+type ParseOptions struct {
+	// MaxTextLength is the number of bytes the text of each element is cut
+	// to, DefaultMaxTextLength when 0 and unlimited when negative.
+	MaxTextLength int
+}
+
+// the state of parsing an rss or json document
+type rssParser struct {
+	max      int
+	items    int  // the number of items decoded so far
+	lenient  bool // read documents like the Lint functions do
+	warnings []ValidationIssue
+}
+
+func newRssParser(opts ParseOptions) *rssParser {
+	p := &rssParser{max: opts.MaxTextLength}
+	if p.max == 0 {
+		p.max = DefaultMaxTextLength
+	}
+	return p
+}
+
+func (p *rssParser) warn(path, format string, args ...interface{}) {
+	p.warnings = append(p.warnings, ValidationIssue{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// a text of a parsed element, named by the element below the path passed
+// to text
+type parseText struct {
+	name string
+	s    *string
+}
+
+// cut the texts to the maximum length on a character boundary
+func (p *rssParser) text(path string, texts []parseText) {
+	for _, t := range texts {
+		if p.max < 0 || len(*t.s) <= p.max {
+			continue
+		}
+		n := p.max
+		for n > 0 && !utf8.RuneStart((*t.s)[n]) {
+			n--
+		}
+		name := t.name
+		if path != "" {
+			name = path + "/" + name
+		}
+		p.warn(name, "cut from %d to %d bytes", len(*t.s), n)
+		*t.s = (*t.s)[:n]
+	}
+}
+
+// the non-negative number of an element, or 0 with a warning when it is
+// not a number or does not fit in an int
+func (p *rssParser) number(path, value string) int {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		p.warn(path, "%q is not a number, left at 0", value)
+		return 0
+	}
+	return n
+}
+
+// split a managingEditor value in the "email (name)" form written by RssFeed
+func parseRssPerson(value string) *Author {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	if i := strings.Index(value, " ("); i >= 0 && strings.HasSuffix(value, ")") {
+		return &Author{Email: value[:i], Name: value[i+2 : len(value)-1]}
+	}
+	return &Author{Email: value}
+}
+
+// decode a webfeeds: element of the channel into its Webfeeds
+func (c *rssParseChannel) decodeWebfeeds(d *xml.Decoder, start *xml.StartElement) error {
+	var e struct {
+		Text  string `xml:",chardata"`
+		Image string `xml:"image,attr"`
+		Id    string `xml:"id,attr"`
+	}
+	if err := d.DecodeElement(&e, start); err != nil {
+		return err
+	}
+	if c.Webfeeds == nil {
+		c.Webfeeds = &Webfeeds{}
+	}
+	w, text := c.Webfeeds, strings.TrimSpace(e.Text)
+	switch start.Name.Local {
+	case "icon":
+		w.Icon = text
+	case "logo":
+		w.Logo = text
+	case "accentColor":
+		w.AccentColor = text
+	case "cover":
+		w.CoverImage = e.Image
+	case "analytics":
+		w.Analytics = e.Id
+	}
+	return nil
+}
+
+// create a generic Feed from the channel level data of a parsed rss document
+func (c *rssParseChannel) feed(p *rssParser) *Feed {
+	p.text("rss/channel", []parseText{
+		{"title", &c.Title}, {"link", &c.Link}, {"description", &c.Description}, {"language", &c.Language},
+		{"copyright", &c.Copyright}, {"managingEditor", &c.ManagingEditor},
+	})
+	feed := &Feed{
+		Title:       c.Title,
+		Link:        &Link{Href: c.Link},
+		Description: c.Description,
+		Language:    c.Language,
+		Copyright:   c.Copyright,
+		Author:      parseRssPerson(c.ManagingEditor),
+		Created:     parseFeedTime(c.PubDate),
+		Updated:     parseFeedTime(c.LastBuildDate),
+		TTL:         p.number("rss/channel/ttl", c.TTL),
+	}
+	if c.PublisherId != "" || c.ProgramId != "" {
+		p.text("rss/channel", []parseText{{"amzn:publisherId", &c.PublisherId}, {"amzn:programId", &c.ProgramId}})
+		feed.Amazon = &AmazonChannel{PublisherID: strings.TrimSpace(c.PublisherId), ProgramID: strings.TrimSpace(c.ProgramId)}
+	}
+	for _, l := range c.Links {
+		feed.Links = append(feed.Links, l.link())
+	}
+	feed.ITunesNewFeedURL = strings.TrimSpace(c.NewFeedURL)
+	switch explicit := strings.ToLower(strings.TrimSpace(c.Explicit)); explicit {
+	case "":
+	case "true", "yes", "false", "no", "clean":
+		b := explicit == "true" || explicit == "yes"
+		feed.Explicit = &b
+	default:
+		p.warn("rss/channel/itunes:explicit", "%q is neither true nor false, left unset", c.Explicit)
+	}
+	feed.Webfeeds = c.Webfeeds
+	if i := c.Image; i != nil {
+		p.text("rss/channel/image", []parseText{{"url", &i.Url}, {"title", &i.Title}, {"link", &i.Link}})
+		feed.Image = &Image{
+			Url:    i.Url,
+			Title:  i.Title,
+			Link:   i.Link,
+			Width:  p.number("rss/channel/image/width", i.Width),
+			Height: p.number("rss/channel/image/height", i.Height),
+		}
+	}
+	return feed
+}
+
+// create a generic Item from a parsed rss item, the one at path
+func (i *rssParseItem) item(p *rssParser, path string) *Item {
+	p.text(path, []parseText{
+		{"title", &i.Title}, {"link", &i.Link}, {"description", &i.Description}, {"content:encoded", &i.Content},
+		{"author", &i.Author}, {"guid", &i.Guid}, {"source", &i.Source}, {"dc:creator", &i.Creator},
+		{"amzn:section", &i.Section}, {"amzn:status", &i.Status},
+	})
+	item := &Item{
+		Title:       i.Title,
+		Description: i.Description,
+		Id:          i.Guid,
+		Created:     parseFeedTime(i.PubDate),
+		Content:     i.Content,
+		Amazon:      i.amazonItem(p, path),
+	}
+	if i.Link != "" {
+		item.Link = &Link{Href: i.Link}
+	}
+	// video posts use their Thumbnail as the poster
+	if i.VideoPoster != nil {
+		p.text(path, []parseText{{"amzn:videoPosterImage", i.VideoPoster}})
+		item.Thumbnail = *i.VideoPoster
+	}
+	switch status := strings.TrimSpace(i.Status); {
+	case strings.EqualFold(status, "deleted"):
+		item.Status = ItemUnpublished
+	case status != "":
+		p.warn(path+"/amzn:status", "%q is not deleted, left published", i.Status)
+	}
+	if i.Source != "" {
+		item.Source = &Link{Href: i.Source}
+	}
+	for _, l := range i.Links {
+		if l.Rel == "via" && item.ViaURL == "" {
+			item.ViaURL = l.Href
+		} else {
+			item.Links = append(item.Links, l.link())
+		}
+	}
+	if e := i.Enclosure; e != nil {
+		p.text(path+"/enclosure", []parseText{{"url", &e.Url}, {"type", &e.Type}})
+		length := strings.TrimSpace(e.Length)
+		if n, err := strconv.ParseInt(length, 10, 64); length != "" && (err != nil || n < 0) {
+			p.warn(path+"/enclosure/length", "%q is not a number of bytes, left out", e.Length)
+			length = ""
+		}
+		item.Enclosure = &Enclosure{Url: e.Url, Length: length, Type: e.Type}
+	}
+	i.media(p, path, item)
+	i.podcast(p, path, item)
+	if name := i.Creator; name != "" {
+		item.Author = &Author{Name: name}
+	} else if name := i.Author; name != "" {
+		item.Author = &Author{Name: name}
+	}
+	return item
+}
+
+// set the media rss fields of item from the media: elements of a parsed
+// item. a media:content without an enclosure is taken as the enclosure,
+// and a plain media:title of the item's own title is left unset, as it is
+// written for all items with media.
+func (i *rssParseItem) media(p *rssParser, path string, item *Item) {
+	if c := i.MediaContent; c != nil {
+		path := path + "/media:content"
+		if item.Enclosure == nil {
+			item.Enclosure = &Enclosure{Url: c.Url, Type: c.Type}
+			if size := p.number(path+"/fileSize", c.FileSize); size > 0 {
+				item.Enclosure.Length = strconv.Itoa(size)
+			}
+		}
+		item.Enclosure.Bitrate = p.number(path+"/bitrate", c.Bitrate)
+		if h := c.Hash; h != nil {
+			item.Enclosure.Hash = &MediaHash{Algo: h.Algo, Value: strings.TrimSpace(h.Value)}
+		}
+		if e := c.Embed; e != nil {
+			embed := &MediaEmbed{
+				URL:    e.Url,
+				Width:  p.number(path+"/media:embed/width", e.Width),
+				Height: p.number(path+"/media:embed/height", e.Height),
+			}
+			for _, param := range e.Params {
+				if embed.Params == nil {
+					embed.Params = map[string]string{}
+				}
+				embed.Params[param.Name] = param.Value
+			}
+			item.Enclosure.Embed = embed
+		}
+	}
+	if m := i.MediaPlayer; m != nil {
+		item.MediaPlayer = &MediaPlayer{
+			URL:    m.Url,
+			Width:  p.number(path+"/media:player/width", m.Width),
+			Height: p.number(path+"/media:player/height", m.Height),
+		}
+	}
+	if t := i.MediaTitle; t != nil {
+		p.text(path, []parseText{{"media:title", &t.Text}})
+		if t.Type != "plain" || t.Text != item.Title {
+			item.MediaTitle = &MediaTitle{Text: t.Text, Type: t.Type}
+		}
+	}
+	item.MediaBackLinks = i.BackLinks
+}
+
+// set the podcast namespace fields of item from the podcast: elements of
+// a parsed item
+func (i *rssParseItem) podcast(p *rssParser, path string, item *Item) {
+	if s := i.PodcastSeason; s != nil {
+		p.text(path+"/podcast:season", []parseText{{"name", &s.Name}})
+		item.PodcastSeason = &PodcastSeason{Number: p.number(path+"/podcast:season", s.Number), Name: s.Name}
+	}
+	if e := i.PodcastEpisode; e != nil {
+		p.text(path+"/podcast:episode", []parseText{{"display", &e.Display}})
+		number, err := strconv.ParseFloat(strings.TrimSpace(e.Number), 64)
+		if err != nil || number < 0 {
+			p.warn(path+"/podcast:episode", "%q is not a number, left at 0", e.Number)
+			number = 0
+		}
+		item.PodcastEpisode = &PodcastEpisode{Number: number, Display: e.Display}
+	}
+}
+
+// create the AmazonItem options from the amzn: elements of a parsed item,
+// or nil if it has none
+func (i *rssParseItem) amazonItem(p *rssParser, path string) *AmazonItem {
+	if i.HeroImage == nil && i.IntroText == nil && i.IndexContent == nil && i.Section == "" && i.Position == "" && len(i.Products) == 0 && i.VideoPoster == nil {
+		return nil
+	}
+	a := &AmazonItem{Section: i.Section}
+	if i.VideoPoster != nil {
+		a.ContentKind = AmazonVideo
+	}
+	// a position which is not a number is left unset
+	a.Position = p.number(path+"/amzn:position", i.Position)
+	for n, product := range i.Products {
+		p.text(fmt.Sprintf("%s/amzn:products/amzn:product[%d]", path, n), []parseText{
+			{"amzn:productURL", &product.URL}, {"amzn:productHeadline", &product.Headline},
+			{"amzn:award", &product.Award}, {"amzn:productSummary", &product.Summary},
+		})
+		a.Products = append(a.Products, &AmazonProduct{URL: product.URL, Headline: product.Headline, Award: product.Award, Summary: product.Summary})
+	}
+	if i.HeroImage != nil {
+		p.text(path, []parseText{{"amzn:heroImage", i.HeroImage}})
+		a.HeroImage = *i.HeroImage
+	}
+	if i.IntroText != nil {
+		p.text(path, []parseText{{"amzn:introText", i.IntroText}})
+		a.IntroText = *i.IntroText
+	}
+	// a nil IndexContent already means True
+	if i.IndexContent != nil && strings.EqualFold(strings.TrimSpace(*i.IndexContent), "false") {
+		index := false
+		a.IndexContent = &index
+	}
+	return a
+}
+
+// ParseRss reads an RSS 2.0 document into a generic Feed, with the atom
+// links, media rss, podcast, itunes and webfeeds elements written by Rss.
+// Dates that cannot be parsed are left as the zero time, and the values
+// ParseRssWithWarnings warns about are handled as it does. Documents whose
+// root element is not rss, like atom feeds, fail.
+func ParseRss(r io.Reader) (*Feed, error) {
+	feed, _, err := ParseRssWithWarnings(r, ParseOptions{})
+	return feed, err
+}
+
+// ParseRssWithWarnings reads an RSS 2.0 document into a generic Feed like
+// ParseRss, along with warnings for the values which could not be used as
+// they are: numbers which do not parse or overflow are left at 0, or left
+// out for enclosure lengths, items with an amzn:status other than deleted
+// are left published, and texts longer than the MaxTextLength of opts are
+// cut to it.
+func ParseRssWithWarnings(r io.Reader, opts ParseOptions) (*Feed, []ValidationIssue, error) {
+	var items []*Item
+	p := newRssParser(opts)
+	feed, err := p.parse(r, func(item *Item) error {
+		items = append(items, item)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	feed.Items = items
+	return feed, p.warnings, nil
+}
+
+// ParseAmazonRss reads an Amazon RSS document, as written by AmazonRss, back
+// into a generic Feed. The amzn: elements of each item are returned in its
+// Amazon options, with the amzn:videoPosterImage of video posts as their
+// Thumbnail and deleted items as unpublished. The Marketplaces and Suppress
+// of the items only change what is written, so they are left unset. Dates
+// that cannot be parsed are left as the zero time.
+func ParseAmazonRss(r io.Reader) (*Feed, error) {
+	return ParseRss(r)
+}
+
+// ParseRSSStream reads an RSS 2.0 or Amazon RSS document without keeping its
+// items in memory: onItem is called with each item as it is decoded, and the
+// returned Feed only holds the channel level data. Parsing stops at the first
+// error returned by onItem, which is returned as is.
+func ParseRSSStream(r io.Reader, onItem func(*Item) error) (*Feed, error) {
+	return newRssParser(ParseOptions{}).parse(r, onItem)
+}
+
+func (p *rssParser) parse(r io.Reader, onItem func(*Item) error) (*Feed, error) {
+	d := xml.NewDecoder(r)
+	if p.lenient {
+		d.Strict = false
+		d.Entity = xml.HTMLEntity
+		d.CharsetReader = lintCharsetReader
+	}
+	var c rssParseChannel
+	depth := 0 // 1 inside <rss>, 2 inside <channel>
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch tok := tok.(type) {
+		case xml.EndElement:
+			depth--
+		case xml.StartElement:
+			if depth == 0 && tok.Name.Local != "rss" {
+				return nil, fmt.Errorf("feeds: root element is <%s>, not <rss>", tok.Name.Local)
+			}
+			if depth < 2 {
+				if depth == 1 && tok.Name.Local != "channel" {
+					if err := d.Skip(); err != nil {
+						return nil, err
+					}
+					continue
+				}
+				depth++
+				continue
+			}
+			if err := c.decodeElement(p, d, &tok, onItem); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return c.feed(p), nil
+}
+
+// decode one child element of the channel into c, or into an Item passed to
+// onItem. elements in other namespaces and unknown elements are skipped.
+func (c *rssParseChannel) decodeElement(p *rssParser, d *xml.Decoder, start *xml.StartElement, onItem func(*Item) error) error {
+	var field interface{}
+	switch start.Name.Space {
+	case "":
+	case amazonNamespace:
+		switch start.Name.Local {
+		case "publisherId":
+			field = &c.PublisherId
+		case "programId":
+			field = &c.ProgramId
+		default:
+			return d.Skip()
+		}
+		return d.DecodeElement(field, start)
+	case ns:
+		if start.Name.Local != "link" {
+			return d.Skip()
+		}
+		var l rssParseLink
+		if err := d.DecodeElement(&l, start); err != nil {
+			return err
+		}
+		c.Links = append(c.Links, &l)
+		return nil
+	case itunesNamespace:
+		switch start.Name.Local {
+		case "new-feed-url":
+			field = &c.NewFeedURL
+		case "explicit":
+			field = &c.Explicit
+		default:
+			return d.Skip()
+		}
+		return d.DecodeElement(field, start)
+	case webfeedsNamespace:
+		return c.decodeWebfeeds(d, start)
+	default:
+		return d.Skip()
+	}
+	switch start.Name.Local {
+	case "item":
+		var i rssParseItem
+		if err := d.DecodeElement(&i, start); err != nil {
+			return err
+		}
+		item := i.item(p, fmt.Sprintf("rss/channel/item[%d]", p.items))
+		p.items++
+		return onItem(item)
+	case "title":
+		field = &c.Title
+	case "link":
+		field = &c.Link
+	case "description":
+		field = &c.Description
+	case "language":
+		field = &c.Language
+	case "copyright":
+		field = &c.Copyright
+	case "managingEditor":
+		field = &c.ManagingEditor
+	case "pubDate":
+		field = &c.PubDate
+	case "lastBuildDate":
+		field = &c.LastBuildDate
+	case "ttl":
+		field = &c.TTL
+	case "image":
+		field = &c.Image
+	default:
+		return d.Skip()
+	}
+	return d.DecodeElement(field, start)
+}
@@ -0,0 +1,45 @@
+//go:build go1.18
+// +build go1.18
+
+package feeds
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func FuzzParseRss(f *testing.F) {
+	seed, err := ioutil.ReadFile("test.rss")
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(string(seed))
+	f.Add(hostileRss)
+	for _, seed := range []string{
+		`<rss><channel><ttl>99999999999999999999</ttl></channel></rss>`,
+		`<rss><channel><ttl>-60</ttl><image><width>1e400</width></image></channel></rss>`,
+		`<rss><channel><item><enclosure length="-1"/></item></channel></rss>`,
+		`<rss><channel><item><enclosure length="18446744073709551616"/></item></channel></rss>`,
+		`<rss><channel><title>` + strings.Repeat("é", 1<<19+1) + `</title></channel></rss>`,
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, doc string) {
+		feed, _, err := ParseRssWithWarnings(strings.NewReader(doc), ParseOptions{MaxTextLength: 1 << 10})
+		if err != nil {
+			return
+		}
+		if feed.TTL < 0 || feed.Image != nil && (feed.Image.Width < 0 || feed.Image.Height < 0) {
+			t.Errorf("parsed negative numbers: %+v", feed)
+		}
+		if len(feed.Title) > 1<<10 || len(feed.Description) > 1<<10 {
+			t.Errorf("parsed texts longer than the limit")
+		}
+		for _, i := range feed.Items {
+			if len(i.Description) > 1<<10 || len(i.Content) > 1<<10 {
+				t.Errorf("parsed texts longer than the limit")
+			}
+		}
+	})
+}
@@ -0,0 +1,263 @@
+package feeds
+
+// parsing of json feed documents back into the generic Feed
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the members of a json object, decoded as they are so parsing can warn
+// about a member of the wrong type and leave it unset, rather than failing
+// the document the way decoding it into the json types would.
+type jsonObject map[string]json.RawMessage
+
+// ParseJSON reads a JSON Feed document into a generic Feed. Dates are parsed
+// with the same tolerance as the rss ones, and the dates that cannot be
+// parsed are left as the zero time.
+func ParseJSON(r io.Reader) (*Feed, error) {
+	feed, _, err := ParseJSONWithWarnings(r, ParseOptions{})
+	return feed, err
+}
+
+// ParseJSONWithWarnings reads a JSON Feed document into a generic Feed like
+// ParseJSON, along with warnings for the dates which could not be parsed,
+// the members of the wrong type, which are left unset, and the texts cut to
+// the MaxTextLength of opts. Only documents which are not json objects
+// fail.
+func ParseJSONWithWarnings(r io.Reader, opts ParseOptions) (*Feed, []ValidationIssue, error) {
+	var doc json.RawMessage
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, nil, err
+	}
+	var f jsonObject
+	if err := json.Unmarshal(doc, &f); err != nil || f == nil {
+		return nil, nil, fmt.Errorf("feeds: json feed is not an object")
+	}
+	p := newRssParser(opts)
+	feed := p.jsonFeed(f)
+	for n, raw := range p.jsonArray("", f, "items") {
+		path := fmt.Sprintf("items[%d]", n)
+		if i := p.jsonObject(path, raw); i != nil {
+			feed.Items = append(feed.Items, p.jsonItem(path, i))
+		}
+	}
+	return feed, p.warnings, nil
+}
+
+// the path of the member name of the object at path
+func jsonPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "/" + name
+}
+
+// the object at path, or nil with a warning when it is another type
+func (p *rssParser) jsonObject(path string, value json.RawMessage) jsonObject {
+	var o jsonObject
+	if err := json.Unmarshal(value, &o); err != nil {
+		p.warn(path, "%s is not an object, left unset", value)
+		return nil
+	}
+	return o
+}
+
+// the elements of the array member name of o, or none with a warning when
+// it is another type
+func (p *rssParser) jsonArray(path string, o jsonObject, name string) []json.RawMessage {
+	var a []json.RawMessage
+	if value, ok := o[name]; ok && json.Unmarshal(value, &a) != nil {
+		p.warn(jsonPath(path, name), "%s is not an array, left unset", value)
+	}
+	return a
+}
+
+// the string member name of o, or "" with a warning when it is another type
+func (p *rssParser) jsonString(path string, o jsonObject, name string) string {
+	var s string
+	if value, ok := o[name]; ok && json.Unmarshal(value, &s) != nil {
+		p.warn(jsonPath(path, name), "%s is not a string, left unset", value)
+	}
+	return s
+}
+
+// the text of the number member name of o, or "" with a warning when it is
+// another type, or when integer is set and it is not a whole number of at
+// least 0
+func (p *rssParser) jsonNumber(path string, o jsonObject, name string, integer bool) string {
+	value, ok := o[name]
+	if !ok || string(value) == "null" {
+		return ""
+	}
+	n := string(value)
+	if _, err := strconv.ParseFloat(n, 64); err != nil || strings.HasPrefix(n, `"`) {
+		p.warn(jsonPath(path, name), "%s is not a number, left unset", value)
+		return ""
+	}
+	if _, err := strconv.ParseUint(n, 10, 64); err != nil && integer {
+		p.warn(jsonPath(path, name), "%s is not a whole number, left unset", value)
+		return ""
+	}
+	return n
+}
+
+// the boolean member name of o, or nil with a warning when it is another
+// type
+func (p *rssParser) jsonBool(path string, o jsonObject, name string) *bool {
+	var b *bool
+	if value, ok := o[name]; ok && json.Unmarshal(value, &b) != nil {
+		p.warn(jsonPath(path, name), "%s is not a boolean, left unset", value)
+		return nil
+	}
+	return b
+}
+
+// the date member name of o, or the zero time with a warning when it is
+// neither RFC3339 nor one of the deviations parseFeedTime accepts
+func (p *rssParser) jsonDate(path string, o jsonObject, name string) time.Time {
+	if value, ok := o[name]; ok && !strings.HasPrefix(string(value), `"`) && string(value) != "null" {
+		p.warn(jsonPath(path, name), "%s is not a date, left unset", value)
+		return time.Time{}
+	}
+	return p.date(jsonPath(path, name), p.jsonString(path, o, name))
+}
+
+// the date of a json feed, or the zero time with a warning when it is
+// neither RFC3339 nor one of the deviations parseFeedTime accepts
+func (p *rssParser) date(path, value string) time.Time {
+	t := parseFeedTime(value)
+	if t.IsZero() && value != "" {
+		p.warn(path, "%q is not a date, left unset", value)
+	}
+	return t
+}
+
+// the generic Author of the json author member name of o, whose members
+// starting with an underscore other than _role are its JSONExtensions
+func (p *rssParser) jsonAuthor(path string, o jsonObject, name string) *Author {
+	value, ok := o[name]
+	if !ok {
+		return nil
+	}
+	path = jsonPath(path, name)
+	a := p.jsonObject(path, value)
+	if a == nil {
+		return nil
+	}
+	author := &Author{Name: p.jsonString(path, a, "name"), Role: p.jsonString(path, a, "_role")}
+	for k, v := range a {
+		if !strings.HasPrefix(k, "_") || k == "_role" {
+			continue
+		}
+		var ext interface{}
+		json.Unmarshal(v, &ext)
+		if author.JSONExtensions == nil {
+			author.JSONExtensions = make(map[string]interface{})
+		}
+		author.JSONExtensions[k] = ext
+	}
+	return author
+}
+
+// create a generic Feed from the top level members of a json feed
+func (p *rssParser) jsonFeed(f jsonObject) *Feed {
+	feed := &Feed{
+		Title:       p.jsonString("", f, "title"),
+		Description: p.jsonString("", f, "description"),
+		Icon:        p.jsonString("", f, "icon"),
+		Favicon:     p.jsonString("", f, "favicon"),
+		Language:    p.jsonString("", f, "language"),
+		Author:      p.jsonAuthor("", f, "author"),
+	}
+	home := p.jsonString("", f, "home_page_url")
+	p.text("", []parseText{
+		{"title", &feed.Title}, {"home_page_url", &home}, {"description", &feed.Description},
+		{"icon", &feed.Icon}, {"favicon", &feed.Favicon}, {"language", &feed.Language},
+	})
+	if home != "" {
+		feed.Link = &Link{Href: home}
+	}
+	return feed
+}
+
+// create a generic Item from the json item i at path
+func (p *rssParser) jsonItem(path string, i jsonObject) *Item {
+	str := func(name string) string {
+		return p.jsonString(path, i, name)
+	}
+	item := &Item{
+		Id:          str("id"),
+		Title:       str("title"),
+		Description: str("summary"),
+		Content:     str("content_html"),
+		Thumbnail:   str("image"),
+		Language:    str("language"),
+		Author:      p.jsonAuthor(path, i, "author"),
+		Created:     p.jsonDate(path, i, "date_published"),
+		Updated:     p.jsonDate(path, i, "date_modified"),
+	}
+	url, externalUrl, text := str("url"), str("external_url"), str("content_text")
+	p.text(path, []parseText{
+		{"id", &item.Id}, {"url", &url}, {"external_url", &externalUrl}, {"title", &item.Title},
+		{"content_html", &item.Content}, {"content_text", &text}, {"summary", &item.Description},
+		{"image", &item.Thumbnail}, {"language", &item.Language},
+	})
+	if item.Content == "" {
+		item.Content = text
+	}
+	if url != "" {
+		item.Link = &Link{Href: url}
+	}
+	if externalUrl != "" {
+		item.Source = &Link{Href: externalUrl}
+	}
+	if value, ok := i["_source"]; ok {
+		if s := p.jsonObject(jsonPath(path, "_source"), value); s != nil {
+			sourcePath := jsonPath(path, "_source")
+			if url := p.jsonString(sourcePath, s, "url"); url != "" {
+				item.Source = &Link{Href: url, Title: p.jsonString(sourcePath, s, "title")}
+			}
+		}
+	}
+	if attachments := p.jsonArray(path, i, "attachments"); len(attachments) > 0 {
+		item.Enclosure = p.jsonEnclosure(jsonPath(path, "attachments[0]"), attachments[0])
+	}
+	return item
+}
+
+// the generic Enclosure of the json attachment at path, or nil when it is
+// not an object
+func (p *rssParser) jsonEnclosure(path string, value json.RawMessage) *Enclosure {
+	a := p.jsonObject(path, value)
+	if a == nil {
+		return nil
+	}
+	e := &Enclosure{
+		Url:            p.jsonString(path, a, "url"),
+		Type:           p.jsonString(path, a, "mime_type"),
+		Title:          p.jsonString(path, a, "title"),
+		SupportsRanges: p.jsonBool(path, a, "_ranges"),
+	}
+	// json feed names it size_in_bytes, which JSONAttachment writes as size
+	e.Length = p.jsonNumber(path, a, "size_in_bytes", true)
+	if e.Length == "" {
+		e.Length = p.jsonNumber(path, a, "size", true)
+	}
+	if e.Length == "0" {
+		e.Length = ""
+	}
+	if d := p.jsonNumber(path, a, "duration_in_seconds", false); d != "" {
+		if seconds, _ := strconv.ParseFloat(d, 64); seconds > 0 {
+			e.Duration = time.Duration(seconds * float64(time.Second))
+		}
+	}
+	if b := p.jsonNumber(path, a, "_bitrate", true); b != "" {
+		e.Bitrate, _ = strconv.Atoi(b)
+	}
+	return e
+}
@@ -0,0 +1,154 @@
+package feeds
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var offDateJSON = `{
+  "version": "https://jsonfeed.org/version/1",
+  "title": "jmoiron.net blog",
+  "home_page_url": "http://jmoiron.net/blog",
+  "description": "discussion about tech, footie, photos",
+  "author": {"name": "Jason Moiron", "_role": "writer"},
+  "items": [
+    {"id": "rfc3339", "title": "Limiting Concurrency in Go", "url": "http://jmoiron.net/blog/limiting-concurrency-in-go/",
+     "date_published": "2013-01-16T21:52:35-05:00", "date_modified": "2013-01-17T21:52:35.5-05:00"},
+    {"id": "no-colon", "title": "Logic-less Template Redux", "date_published": "2013-01-16T21:52:35-0500"},
+    {"id": "space", "title": "Idiomatic Code Reuse in Go", "date_published": "2013-01-16 21:52:35-05:00",
+     "date_modified": "2013-01-16 21:52:35-0500"},
+    {"id": "bad", "title": "Never Gonna Give You Up Mp3", "content_text": "never gonna", "date_published": "last tuesday",
+     "attachments": [{"url": "http://example.com/RickRoll.mp3", "mime_type": "audio/mpeg", "size_in_bytes": 123456, "duration_in_seconds": 213}]}
+  ]
+}`
+
+func TestParseJSONDates(t *testing.T) {
+	feed, warnings, err := ParseJSONWithWarnings(strings.NewReader(offDateJSON), ParseOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error parsing json: %v", err)
+	}
+	want, _ := time.Parse(time.RFC3339, "2013-01-16T21:52:35-05:00")
+	if feed.Title != "jmoiron.net blog" || feed.Link.Href != "http://jmoiron.net/blog" || feed.Author.Role != "writer" {
+		t.Errorf("unexpected feed %+v", feed)
+	}
+	if len(feed.Items) != 4 {
+		t.Fatalf("expected 4 items, got %d", len(feed.Items))
+	}
+	for _, i := range feed.Items[:3] {
+		if !i.Created.Equal(want) {
+			t.Errorf("item %s: expected the date %v, got %v", i.Id, want, i.Created)
+		}
+	}
+	if got := feed.Items[0].Updated; !got.Equal(want.Add(24*time.Hour + time.Second/2)) {
+		t.Errorf("expected the fractional date_modified to parse, got %v", got)
+	}
+	if !feed.Items[2].Updated.Equal(want) {
+		t.Errorf("expected a date with a space and no colon to parse, got %v", feed.Items[2].Updated)
+	}
+
+	bad := feed.Items[3]
+	if !bad.Created.IsZero() || bad.Content != "never gonna" {
+		t.Errorf("expected the bad date to be left unset, got %+v", bad)
+	}
+	if e := bad.Enclosure; e == nil || e.Url != "http://example.com/RickRoll.mp3" || e.Duration != 213*time.Second {
+		t.Errorf("unexpected enclosure %+v", e)
+	}
+	if got := issueStrings(warnings); len(got) != 1 || got[0] != `items[3]/date_published: "last tuesday" is not a date, left unset` {
+		t.Errorf("unexpected warnings %q", got)
+	}
+
+	if _, err := ParseJSON(strings.NewReader(`{"items": [`)); err == nil {
+		t.Errorf("expected an error parsing a truncated document")
+	}
+}
+
+func TestParseJSONWrongTypes(t *testing.T) {
+	doc := `{
+  "version": "https://jsonfeed.org/version/1.1",
+  "title": "jmoiron.net blog",
+  "description": 42,
+  "author": "Jason Moiron",
+  "items": [
+    {"id": "big", "title": "Never Gonna Give You Up Mp3", "date_published": 1358391155,
+     "attachments": [{"url": "http://example.com/RickRoll.mp3", "mime_type": "audio/mpeg", "size_in_bytes": 3000000000,
+       "duration_in_seconds": "213", "_ranges": "yes"}]},
+    {"id": ["typed"], "title": "Limiting Concurrency in Go", "date_modified": "2013-01-16T21:52:35-05:00",
+     "attachments": {"url": "http://example.com/RickRoll.mp3"}},
+    "an item",
+    {"id": "sized", "attachments": [{"url": "http://example.com/RickRoll.mp3", "size_in_bytes": -1, "_bitrate": 1.5}]}
+  ]
+}`
+	feed, warnings, err := ParseJSONWithWarnings(strings.NewReader(doc), ParseOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error parsing json: %v", err)
+	}
+	if feed.Title != "jmoiron.net blog" || feed.Description != "" || feed.Author != nil {
+		t.Errorf("unexpected feed %+v", feed)
+	}
+	if len(feed.Items) != 3 {
+		t.Fatalf("expected 3 items, got %d", len(feed.Items))
+	}
+	big := feed.Items[0]
+	if e := big.Enclosure; e == nil || e.Length != "3000000000" || e.Duration != 0 || e.SupportsRanges != nil {
+		t.Errorf("unexpected enclosure %+v", e)
+	}
+	if !big.Created.IsZero() {
+		t.Errorf("expected the numeric date to be left unset, got %v", big.Created)
+	}
+	if typed := feed.Items[1]; typed.Id != "" || typed.Title != "Limiting Concurrency in Go" || typed.Updated.IsZero() || typed.Enclosure != nil {
+		t.Errorf("unexpected item %+v", typed)
+	}
+	if e := feed.Items[2].Enclosure; e == nil || e.Length != "" || e.Bitrate != 0 {
+		t.Errorf("unexpected enclosure %+v", e)
+	}
+	want := []string{
+		"description: 42 is not a string, left unset",
+		`author: "Jason Moiron" is not an object, left unset`,
+		"items[0]/date_published: 1358391155 is not a date, left unset",
+		`items[0]/attachments[0]/_ranges: "yes" is not a boolean, left unset`,
+		`items[0]/attachments[0]/duration_in_seconds: "213" is not a number, left unset`,
+		`items[1]/id: ["typed"] is not a string, left unset`,
+		`items[1]/attachments: {"url": "http://example.com/RickRoll.mp3"} is not an array, left unset`,
+		`items[2]: "an item" is not an object, left unset`,
+		"items[3]/attachments[0]/size_in_bytes: -1 is not a whole number, left unset",
+		"items[3]/attachments[0]/_bitrate: 1.5 is not a whole number, left unset",
+	}
+	if got := issueStrings(warnings); !reflect.DeepEqual(got, want) {
+		t.Errorf("got warnings\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if _, err := ParseJSON(strings.NewReader(`["items"]`)); err == nil {
+		t.Errorf("expected an error parsing a document which is not an object")
+	}
+}
+
+func TestParseJSONRoundTrip(t *testing.T) {
+	now := testCreated
+	feed := &Feed{
+		Title:       "jmoiron.net blog",
+		Link:        &Link{Href: "http://jmoiron.net/blog"},
+		Description: "discussion about tech, footie, photos",
+		Items: []*Item{{
+			Id:          "limiting-concurrency-in-go",
+			Title:       "Limiting Concurrency in Go",
+			Link:        &Link{Href: "http://jmoiron.net/blog/limiting-concurrency-in-go/"},
+			Description: "A discussion on controlled parallelism in golang",
+			Author:      &Author{Name: "Jason Moiron"},
+			Created:     now,
+		}},
+	}
+	json, err := feed.ToJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseJSON(strings.NewReader(json))
+	if err != nil {
+		t.Fatalf("unexpected error parsing json: %v", err)
+	}
+	again, err := parsed.ToJSON()
+	if err != nil || again != json {
+		t.Errorf("expected the parsed feed to write the same json.\nGot:\n%s\nWant:\n%s\n", again, json)
+	}
+}
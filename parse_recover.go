@@ -0,0 +1,143 @@
+package feeds
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// ParseIssue is an item a recovering parse skipped, and why.
+type ParseIssue struct {
+	Path   string // the item, like rss/channel/item[3]
+	Offset int64  // the byte offset of the item in the document
+	Length int64  // the length of the item in bytes
+	Err    error
+}
+
+func (i ParseIssue) String() string {
+	return fmt.Sprintf("%s at byte %d: %v", i.Path, i.Offset, i.Err)
+}
+
+var errItemUnclosed = errors.New("item has no end tag before the next item or the end of the channel")
+
+// the bytes of an item of a document being recovered
+type rssItemChunk struct {
+	start, end int
+	closed     bool // whether the item ends at its own end tag
+}
+
+// ParseRssRecover reads an RSS 2.0 document like ParseRss, with the limits
+// of opts, but decodes each of its items on its own, so the items which are
+// not well-formed are skipped rather than failing the whole document. The
+// skipped items are returned as ParseIssues in document order.
+//
+// An item ends at its end tag, or without one where the next item or the
+// end of the channel starts, so the content of a broken item is never read
+// into another one. The document is read into memory, and everything but
+// its items still has to be well-formed.
+func ParseRssRecover(r io.Reader, opts ParseOptions) (*Feed, []ParseIssue, error) {
+	doc, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	chunks, prefix, skeleton := splitRssItems(doc)
+	p := newRssParser(opts)
+	feed, err := p.parse(bytes.NewReader(skeleton), func(*Item) error { return nil })
+	if err != nil {
+		return nil, nil, err
+	}
+	var skipped []ParseIssue
+	for n, c := range chunks {
+		path := fmt.Sprintf("rss/channel/item[%d]", n)
+		i, err := decodeRssItem(doc, prefix, c)
+		if err != nil {
+			skipped = append(skipped, ParseIssue{Path: path, Offset: int64(c.start), Length: int64(c.end - c.start), Err: err})
+			continue
+		}
+		feed.Items = append(feed.Items, i.item(p, path))
+	}
+	return feed, skipped, nil
+}
+
+// ParseAmazonRssRecover reads an Amazon RSS document like ParseAmazonRss,
+// skipping the items which are not well-formed as ParseRssRecover does.
+func ParseAmazonRssRecover(r io.Reader, opts ParseOptions) (*Feed, []ParseIssue, error) {
+	return ParseRssRecover(r, opts)
+}
+
+// split the items out of an rss document, returning them along with the
+// rss and channel start tags declaring the namespaces they use, and the
+// rest of the document
+func splitRssItems(doc []byte) (items []rssItemChunk, prefix, skeleton []byte) {
+	var rest, start bytes.Buffer
+	seen := map[string]bool{}
+	n := 0
+	item := -1 // the start of the current item
+	end := func(at int, closed bool) {
+		items = append(items, rssItemChunk{start: item, end: at, closed: closed})
+		item = -1
+	}
+	scanXML(doc, func(chunk []byte, tag bool) {
+		offset := n
+		n += len(chunk)
+		if tag {
+			name, endTag := tagName(chunk), bytes.HasPrefix(chunk, []byte("</"))
+			switch {
+			case (name == "rss" || name == "channel") && !endTag && !seen[name]:
+				seen[name] = true
+				start.Write(chunk)
+			case item >= 0 && ((name == "item" && !endTag) || ((name == "channel" || name == "rss") && endTag)):
+				end(offset, false)
+			}
+			switch {
+			case name == "item" && !endTag && item < 0:
+				item = offset
+				if bytes.HasSuffix(chunk, []byte("/>")) {
+					end(n, true)
+				}
+				return
+			case name == "item" && endTag && item >= 0:
+				end(n, true)
+				return
+			}
+		}
+		if item < 0 {
+			rest.Write(chunk)
+		}
+	})
+	if item >= 0 {
+		end(len(doc), false)
+	}
+	return items, start.Bytes(), rest.Bytes()
+}
+
+// decode the item c of doc after the start tags of prefix, with the lines
+// of syntax errors counted in doc
+func decodeRssItem(doc, prefix []byte, c rssItemChunk) (*rssParseItem, error) {
+	if !c.closed {
+		return nil, errItemUnclosed
+	}
+	d := xml.NewDecoder(io.MultiReader(bytes.NewReader(prefix), bytes.NewReader(doc[c.start:c.end])))
+	var i rssParseItem
+	for {
+		tok, err := d.Token()
+		if err == nil {
+			start, ok := tok.(xml.StartElement)
+			if !ok || start.Name.Local != "item" || start.Name.Space != "" {
+				continue
+			}
+			err = d.DecodeElement(&i, &start)
+		}
+		if e, ok := err.(*xml.SyntaxError); ok {
+			line := e.Line - bytes.Count(prefix, []byte("\n")) + bytes.Count(doc[:c.start], []byte("\n"))
+			return nil, &xml.SyntaxError{Msg: e.Msg, Line: line}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &i, nil
+	}
+}
@@ -0,0 +1,155 @@
+package feeds
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// A Stage is a step of a Pipeline, returning the feed it made from the one
+// it is given and any issues it found. The built-in stages change the feed
+// and items they are given in place, which Run gives them copies of.
+type Stage func(*Feed) (*Feed, []ValidationIssue, error)
+
+// Pipeline runs a list of stages over a feed, like sorting, deduplicating
+// and limiting its items before it is written.
+type Pipeline struct {
+	stages []Stage
+}
+
+// NewPipeline returns a Pipeline running the stages in order.
+func NewPipeline(stages ...Stage) *Pipeline {
+	return &Pipeline{stages: stages}
+}
+
+// Run passes a copy of the feed and of its items through the stages in
+// order, and returns the feed made by the last one with the issues of all
+// of them. The feed and items given are not changed. It stops at the first
+// stage failing, returning the issues found until then.
+func (p *Pipeline) Run(feed *Feed) (*Feed, []ValidationIssue, error) {
+	f := *feed
+	f.Items = make([]*Item, len(feed.Items))
+	for n, i := range feed.Items {
+		if i != nil {
+			item := *i
+			i = &item
+		}
+		f.Items[n] = i
+	}
+	var issues []ValidationIssue
+	out := &f
+	for n, stage := range p.stages {
+		next, found, err := stage(out)
+		issues = append(issues, found...)
+		if err != nil {
+			return nil, issues, fmt.Errorf("feeds: stage %d: %v", n, err)
+		}
+		out = next
+	}
+	return out, issues, nil
+}
+
+// SortStage sorts the items with the less function, like Feed.Sort.
+func SortStage(less func(a, b *Item) bool) Stage {
+	return func(f *Feed) (*Feed, []ValidationIssue, error) {
+		f.Sort(less)
+		return f, nil, nil
+	}
+}
+
+// DedupeStage drops the items with the Id of an earlier item, with an
+// issue for each of them. Items without an Id are always kept.
+func DedupeStage() Stage {
+	return func(f *Feed) (*Feed, []ValidationIssue, error) {
+		var issues []ValidationIssue
+		first := map[string]int{}
+		kept := f.Items[:0]
+		for n, i := range f.Items {
+			if i != nil && i.Id != "" {
+				if m, seen := first[i.Id]; seen {
+					issues = append(issues, ValidationIssue{
+						Path:    fmt.Sprintf("item[%d]", n),
+						Message: fmt.Sprintf("dropped as a duplicate of item[%d] with the id %q", m, i.Id),
+					})
+					continue
+				}
+				first[i.Id] = n
+			}
+			kept = append(kept, i)
+		}
+		f.Items = kept
+		return f, issues, nil
+	}
+}
+
+// LimitStage keeps the first max items.
+func LimitStage(max int) Stage {
+	return func(f *Feed) (*Feed, []ValidationIssue, error) {
+		if max < 0 {
+			return nil, nil, fmt.Errorf("limit %d is negative", max)
+		}
+		if len(f.Items) > max {
+			f.Items = f.Items[:max]
+		}
+		return f, nil, nil
+	}
+}
+
+// SanitizeStage passes the description and content of the items through
+// the sanitizer, except for those with SkipSanitize, like the Sanitizer of
+// a Feed does when it is written.
+func SanitizeStage(s Sanitizer) Stage {
+	return func(f *Feed) (*Feed, []ValidationIssue, error) {
+		sanitizing := &Feed{Sanitizer: s}
+		for n, i := range f.Items {
+			if i != nil {
+				f.Items[n] = sanitizing.sanitize(i)
+			}
+		}
+		return f, nil, nil
+	}
+}
+
+// ResolveURLsStage makes the relative links, enclosure urls and thumbnails
+// of the items absolute, resolving them against the link of the feed. The
+// urls which cannot be parsed are left as they are with an issue.
+func ResolveURLsStage() Stage {
+	return func(f *Feed) (*Feed, []ValidationIssue, error) {
+		if f.Link == nil || f.Link.Href == "" {
+			return f, []ValidationIssue{{Path: "feed", Message: "has no link to resolve urls against"}}, nil
+		}
+		base, err := url.Parse(f.Link.Href)
+		if err != nil || !base.IsAbs() {
+			return nil, nil, fmt.Errorf("feed link %q is not an absolute url", f.Link.Href)
+		}
+		var issues []ValidationIssue
+		resolve := func(path string, s *string) {
+			if *s == "" {
+				return
+			}
+			u, err := url.Parse(*s)
+			if err != nil {
+				issues = append(issues, ValidationIssue{Path: path, Message: fmt.Sprintf("%q is not a url", *s)})
+				return
+			}
+			*s = base.ResolveReference(u).String()
+		}
+		for n, i := range f.Items {
+			if i == nil {
+				continue
+			}
+			path := fmt.Sprintf("item[%d]", n)
+			if i.Link != nil {
+				l := *i.Link
+				resolve(path+"/link", &l.Href)
+				i.Link = &l
+			}
+			if i.Enclosure != nil {
+				e := *i.Enclosure
+				resolve(path+"/enclosure", &e.Url)
+				i.Enclosure = &e
+			}
+			resolve(path+"/thumbnail", &i.Thumbnail)
+		}
+		return f, issues, nil
+	}
+}
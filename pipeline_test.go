@@ -0,0 +1,80 @@
+package feeds
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func pipelineTestFeed() *Feed {
+	feed := testFeed(
+		&Item{Id: "c", Title: "Go Tip: Structs", Link: &Link{Href: "go-tip-structs/"}},
+		&Item{Id: "a", Title: "Limiting Concurrency in Go", Description: "<script>x</script>ok"},
+		&Item{Id: "b", Title: "Logic-less Template Redux", Thumbnail: "/images/redux.png"},
+		&Item{Id: "a", Title: "Limiting Concurrency in Go, again"},
+	)
+	feed.Link = &Link{Href: "http://jmoiron.net/blog/"}
+	return feed
+}
+
+func pipelineIds(f *Feed) []string {
+	var ids []string
+	for _, i := range f.Items {
+		ids = append(ids, i.Id)
+	}
+	return ids
+}
+
+func TestPipelineOrder(t *testing.T) {
+	byId := func(a, b *Item) bool { return a.Id < b.Id }
+	for _, test := range []struct {
+		stages []Stage
+		want   []string
+	}{
+		{[]Stage{SortStage(byId), LimitStage(2)}, []string{"a", "a"}},
+		{[]Stage{LimitStage(2), SortStage(byId)}, []string{"a", "c"}},
+		{[]Stage{DedupeStage(), SortStage(byId), LimitStage(2)}, []string{"a", "b"}},
+		{[]Stage{SortStage(byId), LimitStage(2), DedupeStage()}, []string{"a"}},
+	} {
+		feed := pipelineTestFeed()
+		out, _, err := NewPipeline(test.stages...).Run(feed)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := pipelineIds(out); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("expected the items %v, got %v", test.want, got)
+		}
+		if got := pipelineIds(feed); !reflect.DeepEqual(got, []string{"c", "a", "b", "a"}) {
+			t.Errorf("expected the input feed to be left alone, got %v", got)
+		}
+	}
+}
+
+func TestPipelineStages(t *testing.T) {
+	feed := pipelineTestFeed()
+	strip := func(html string) string { return strings.Replace(html, "<script>x</script>", "", -1) }
+	out, issues, err := NewPipeline(DedupeStage(), SanitizeStage(strip), ResolveURLsStage()).Run(feed)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := issueStrings(issues); len(got) != 1 || got[0] != `item[3]: dropped as a duplicate of item[1] with the id "a"` {
+		t.Errorf("unexpected issues: %q", got)
+	}
+	if got := out.Items[0].Link.Href; got != "http://jmoiron.net/blog/go-tip-structs/" {
+		t.Errorf("expected the link to be resolved, got %s", got)
+	}
+	if got := out.Items[2].Thumbnail; got != "http://jmoiron.net/images/redux.png" {
+		t.Errorf("expected the thumbnail to be resolved, got %s", got)
+	}
+	if got := out.Items[1].Description; got != "ok" {
+		t.Errorf("expected the description to be sanitized, got %s", got)
+	}
+	if feed.Items[0].Link.Href != "go-tip-structs/" || feed.Items[1].Description != "<script>x</script>ok" {
+		t.Errorf("expected the input items to be left alone, got %+v", feed.Items)
+	}
+
+	_, issues, err = NewPipeline(DedupeStage(), LimitStage(-1), SortStage(nil)).Run(feed)
+	if err == nil || err.Error() != "feeds: stage 1: limit -1 is negative" || len(issues) != 1 {
+		t.Errorf("expected the limit stage to fail after the dedupe issue, got %v %v", err, issues)
+	}
+}
@@ -0,0 +1,97 @@
+package feeds
+
+// podcasting 2.0 namespace support
+// spec here:
+//    https://github.com/Podcastindex-org/podcast-namespace/blob/main/docs/1.0.md
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"unicode/utf8"
+)
+
+const podcastNamespace = "https://podcastindex.org/namespace/1.0"
+
+// PodcastSeason is the season of an episode, used as podcast:season. Apps
+// show the Name, when there is one, over the number.
+type PodcastSeason struct {
+	Number int
+	Name   string // at most 128 characters
+}
+
+// PodcastEpisode is the number of an episode in its season, or in the
+// podcast, used as podcast:episode. Apps show the Display text, when
+// there is one, over the number.
+type PodcastEpisode struct {
+	Number  float64
+	Display string // at most 32 characters
+}
+
+type RssPodcastSeason struct {
+	XMLName xml.Name `xml:"podcast:season"`
+	Name    string   `xml:"name,attr,omitempty"`
+	Number  int      `xml:",chardata"`
+}
+
+type RssPodcastEpisode struct {
+	XMLName xml.Name `xml:"podcast:episode"`
+	Display string   `xml:"display,attr,omitempty"`
+	Number  string   `xml:",chardata"`
+}
+
+func (s *PodcastSeason) validate() error {
+	if s.Number < 0 {
+		return fmt.Errorf("podcast:season number %d is negative", s.Number)
+	}
+	if n := utf8.RuneCountInString(s.Name); n > 128 {
+		return fmt.Errorf("podcast:season name is %d characters, more than 128", n)
+	}
+	return nil
+}
+
+func (e *PodcastEpisode) validate() error {
+	if e.Number < 0 {
+		return fmt.Errorf("podcast:episode number %v is negative", e.Number)
+	}
+	if n := utf8.RuneCountInString(e.Display); n > 32 {
+		return fmt.Errorf("podcast:episode display is %d characters, more than 32", n)
+	}
+	return nil
+}
+
+// set the podcast namespace elements of an RssItem from a generic Item
+func setRssPodcast(item *RssItem, i *Item) {
+	if s := i.PodcastSeason; s != nil {
+		item.PodcastSeason = &RssPodcastSeason{Name: s.Name, Number: s.Number}
+	}
+	if e := i.PodcastEpisode; e != nil {
+		number := strconv.FormatFloat(e.Number, 'f', -1, 64)
+		item.PodcastEpisode = &RssPodcastEpisode{Display: e.Display, Number: number}
+	}
+}
+
+// check the podcast namespace fields of an Item
+func validatePodcast(i *Item) error {
+	if i.PodcastSeason != nil {
+		if err := i.PodcastSeason.validate(); err != nil {
+			return err
+		}
+	}
+	if i.PodcastEpisode != nil {
+		if err := i.PodcastEpisode.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// whether any of the items use podcast namespace elements
+func (r *RssFeed) usesPodcast() bool {
+	for _, i := range r.Items {
+		if i.PodcastSeason != nil || i.PodcastEpisode != nil {
+			return true
+		}
+	}
+	return false
+}
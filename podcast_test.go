@@ -0,0 +1,62 @@
+package feeds
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPodcastSeasonEpisode(t *testing.T) {
+	rss, err := mediaTestFeed(&Item{}).ToRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding RSS: %v", err)
+	}
+	if strings.Contains(rss, "podcast") {
+		t.Errorf("Rss should not use the podcast namespace.  Got:\n%s\n", rss)
+	}
+
+	feed := mediaTestFeed(&Item{
+		PodcastSeason:  &PodcastSeason{Number: 2, Name: "Volume 2: The Return"},
+		PodcastEpisode: &PodcastEpisode{Number: 5.5, Display: "Bonus & Extras"},
+	})
+	feed.Items = append(feed.Items, &Item{
+		Title:          "Numbered",
+		PodcastSeason:  &PodcastSeason{Number: 3},
+		PodcastEpisode: &PodcastEpisode{Number: 12},
+	})
+	rss, err = feed.ToRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding RSS: %v", err)
+	}
+	for _, want := range []string{
+		`xmlns:podcast="https://podcastindex.org/namespace/1.0"`,
+		`<podcast:season name="Volume 2: The Return">2</podcast:season>`,
+		`<podcast:episode display="Bonus &amp; Extras">5.5</podcast:episode>`,
+		`<podcast:season>3</podcast:season>`,
+		`<podcast:episode>12</podcast:episode>`,
+	} {
+		if !strings.Contains(rss, want) {
+			t.Errorf("Rss missing %s.  Got:\n%s\n", want, rss)
+		}
+	}
+}
+
+func TestPodcastSeasonEpisodeInvalid(t *testing.T) {
+	for _, i := range []*Item{
+		{PodcastSeason: &PodcastSeason{Number: -1}},
+		{PodcastSeason: &PodcastSeason{Number: 1, Name: strings.Repeat("s", 129)}},
+		{PodcastEpisode: &PodcastEpisode{Number: -1}},
+		{PodcastEpisode: &PodcastEpisode{Number: 1, Display: strings.Repeat("é", 33)}},
+	} {
+		feed := mediaTestFeed(i)
+		if rss, err := feed.ToRss(); err == nil {
+			t.Errorf("expected an error for %+v %+v, got:\n%s", i.PodcastSeason, i.PodcastEpisode, rss)
+		}
+		if err := feed.ValidateRSS(); err == nil {
+			t.Errorf("expected a validation error for %+v %+v", i.PodcastSeason, i.PodcastEpisode)
+		}
+	}
+	feed := mediaTestFeed(&Item{PodcastEpisode: &PodcastEpisode{Number: 1, Display: strings.Repeat("é", 32)}})
+	if _, err := feed.ToRss(); err != nil {
+		t.Errorf("unexpected error for a 32 character display: %v", err)
+	}
+}
@@ -0,0 +1,46 @@
+package feeds
+
+import (
+	"errors"
+	"time"
+)
+
+const defaultPreviewMarker = "[PREVIEW] "
+
+// PreviewMode writes a feed for trying it in real readers before it is
+// published: the title is marked, Amazon is told not to index any item,
+// json feeds get a "_preview": true extension and the xml formats start
+// with a comment holding the time the preview was built.
+type PreviewMode struct {
+	// Marker is put before the title of the feed, "[PREVIEW] " when empty.
+	Marker string
+
+	// AllowStrictProfile lets the feed be written with the AmazonStrict
+	// profile, which refuses previews so they are not published by mistake.
+	AllowStrictProfile bool
+}
+
+// the title of the feed, marked when it is a preview
+func (f *Feed) title() string {
+	if f.Preview == nil {
+		return f.Title
+	}
+	marker := f.Preview.Marker
+	if marker == "" {
+		marker = defaultPreviewMarker
+	}
+	return marker + f.Title
+}
+
+// the comment written before the root element of a preview xml feed
+func (f *Feed) previewComment() []byte {
+	return []byte("\n<!-- preview built " + FormatTime(time.RFC3339, nil, f.now()) + " -->\n")
+}
+
+// a Rule refusing previews unless they allow strict profiles
+func notPreview(f *Feed) error {
+	if f.Preview != nil && !f.Preview.AllowStrictProfile {
+		return errors.New("feed is a preview, set Preview.AllowStrictProfile to write it anyway")
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package feeds
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPreview(t *testing.T) {
+	now := testCreated
+	index := true
+	feed := profileTestFeed()
+	feed.Clock = func() time.Time { return now }
+	feed.Items[0].Amazon.IndexContent = &index
+	feed.Preview = &PreviewMode{}
+
+	rss, err := feed.ToRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding RSS: %v", err)
+	}
+	atom, err := feed.ToAtom()
+	if err != nil {
+		t.Errorf("unexpected error encoding Atom: %v", err)
+	}
+	amazon, err := feed.ToAmazonRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding Amazon RSS: %v", err)
+	}
+	comment := `<?xml version="1.0" encoding="UTF-8"?>` + "\n<!-- preview built 2013-01-16T21:52:35-05:00 -->\n<"
+	for name, out := range map[string]string{"Rss": rss, "Atom": atom, "AmazonRss": amazon} {
+		if !strings.HasPrefix(out, comment) {
+			t.Errorf("%s missing the preview comment.  Got:\n%s\n", name, out)
+		}
+		if !strings.Contains(out, "<title>[PREVIEW] jmoiron.net blog</title>") {
+			t.Errorf("%s missing the preview title.  Got:\n%s\n", name, out)
+		}
+	}
+	if !strings.Contains(amazon, "<amzn:indexContent>False</amzn:indexContent>") {
+		t.Errorf("AmazonRss should not index previews.  Got:\n%s\n", amazon)
+	}
+
+	var buf bytes.Buffer
+	if err := feed.WriteRss(&buf); err != nil || buf.String() != rss {
+		t.Errorf("WriteRss wrote %q, %v, want %q", buf.String(), err, rss)
+	}
+
+	feed.Preview.Marker = "DRAFT: "
+	json, err := feed.ToJSON()
+	if err != nil {
+		t.Errorf("unexpected error encoding JSON: %v", err)
+	}
+	for _, want := range []string{`"title": "DRAFT: jmoiron.net blog"`, `"_preview": true`} {
+		if !strings.Contains(json, want) {
+			t.Errorf("JSON missing %s.  Got:\n%s\n", want, json)
+		}
+	}
+
+	feed.Preview = nil
+	if json, _ = feed.ToJSON(); strings.Contains(json, "_preview") {
+		t.Errorf("JSON should only be marked as a preview in preview mode.  Got:\n%s\n", json)
+	}
+	if rss, _ = feed.ToRss(); strings.Contains(rss, "<!--") {
+		t.Errorf("Rss should only have the comment in preview mode.  Got:\n%s\n", rss)
+	}
+}
+
+func TestPreviewAmazonStrict(t *testing.T) {
+	feed := profileTestFeed()
+	feed.Preview = &PreviewMode{}
+	var buf bytes.Buffer
+	if err := AmazonStrict().Write(feed, &buf); err == nil || buf.Len() != 0 {
+		t.Errorf("AmazonStrict wrote a preview feed, error %v", err)
+	}
+
+	feed.Preview.AllowStrictProfile = true
+	if err := AmazonStrict().Write(feed, &buf); err != nil {
+		t.Errorf("unexpected error writing an allowed preview: %v", err)
+	}
+	if !strings.Contains(buf.String(), "<amzn:indexContent>False</amzn:indexContent>") {
+		t.Errorf("AmazonRss should not index previews.  Got:\n%s\n", buf.String())
+	}
+}
@@ -0,0 +1,179 @@
+package feeds
+
+import (
+	"fmt"
+	"io"
+	"unicode/utf8"
+)
+
+// A Rule checks a feed for something a destination requires of it.
+type Rule func(f *Feed) error
+
+// Profile bundles the format a destination reads, the options it needs
+// and the rules its feeds must pass. AmazonStrict, GoogleNews and
+// ApplePodcasts return the presets for the common destinations; a Profile
+// can also be built by hand.
+type Profile struct {
+	Name string
+	Type FeedType
+
+	// Options sets the options of the destination on the copy of the feed
+	// Write validates and writes, the feed passed to Write is not changed.
+	Options func(f *Feed)
+
+	Rules []Rule
+}
+
+// Validate returns the first error of the profile's rules for f, if any.
+func (p *Profile) Validate(f *Feed) error {
+	for _, rule := range p.Rules {
+		if err := rule(f); err != nil {
+			return fmt.Errorf("feeds: %s: %v", p.Name, err)
+		}
+	}
+	return nil
+}
+
+// Write validates f with the profile's options and writes it to w in the
+// profile's format.
+func (p *Profile) Write(f *Feed, w io.Writer) error {
+	feed := *f
+	if p.Options != nil {
+		p.Options(&feed)
+	}
+	if err := p.Validate(&feed); err != nil {
+		return err
+	}
+	return feed.write(w, p.Type)
+}
+
+// AmazonStrict is the profile of Amazon rss feeds, requiring valid utf-8,
+// the publisher id of the feed and the dates, guids and hero images of the
+// items, and an amazon value for the Category of the feed when its
+// TaxonomyMap has amazon values. Previews are refused unless they set
+// AllowStrictProfile. Descriptions are written in cdata and as the content
+// of items without one, and items with the id of an earlier one fail.
+func AmazonStrict() *Profile {
+	return &Profile{
+		Name: "amazon",
+		Type: FeedTypeAmazonRss,
+		Options: func(f *Feed) {
+			f.CDATADescription = true
+			f.ContentFromDescription = true
+			f.OnDuplicateID = DuplicateIDError
+		},
+		Rules: []Rule{
+			notPreview,
+			validUTF8,
+			requireFeed("title", func(f *Feed) bool { return f.Title != "" }),
+			requireFeed("link", func(f *Feed) bool { return f.Link != nil && f.Link.Href != "" }),
+			requireFeed("description", func(f *Feed) bool { return f.Description != "" }),
+			requireFeed("publisher id", func(f *Feed) bool { return f.Amazon != nil && f.Amazon.PublisherID != "" }),
+			func(f *Feed) error { return f.Amazon.validate() },
+			requireItems("title", func(i *Item) bool { return i.Title != "" }),
+			requireItems("link", func(i *Item) bool { return i.Link != nil && i.Link.Href != "" }),
+			requireItems("id", func(i *Item) bool { return i.Id != "" }),
+			requireItems("date", func(i *Item) bool { return !i.pubDate().IsZero() }),
+			requireItems("hero image", func(i *Item) bool { return i.Amazon != nil && i.Amazon.HeroImage != "" }),
+			mappedCategory("amazon"),
+		},
+	}
+}
+
+// GoogleNews is the profile of rss feeds read by Google News, which relies
+// on the language of the feed and the link and date of every article, and
+// takes articles with the id of an earlier one for the same article, so
+// they fail. The Category of the feed needs a googlenews value when its
+// TaxonomyMap has googlenews values.
+func GoogleNews() *Profile {
+	return &Profile{
+		Name:    "google news",
+		Type:    FeedTypeRss,
+		Options: func(f *Feed) { f.OnDuplicateID = DuplicateIDError },
+		Rules: []Rule{
+			requireFeed("title", func(f *Feed) bool { return f.Title != "" }),
+			requireFeed("link", func(f *Feed) bool { return f.Link != nil && f.Link.Href != "" }),
+			requireFeed("description", func(f *Feed) bool { return f.Description != "" }),
+			requireFeed("language", func(f *Feed) bool { return f.Language != "" }),
+			requireItems("title", func(i *Item) bool { return i.Title != "" }),
+			requireItems("link", func(i *Item) bool { return i.Link != nil && i.Link.Href != "" }),
+			requireItems("date", func(i *Item) bool { return !i.pubDate().IsZero() }),
+			mappedCategory("googlenews"),
+		},
+	}
+}
+
+// ApplePodcasts is the profile of podcast rss feeds read by Apple Podcasts,
+// which needs an artwork image for the show and an audio or video
+// enclosure for every episode, and shows at most 4000 characters of a
+// description. The Category of the feed needs an itunes value when its
+// TaxonomyMap has itunes values, which is written as its itunes:category.
+// Episodes with the id of an earlier one fail, as Apple takes them for the
+// same episode.
+func ApplePodcasts() *Profile {
+	return &Profile{
+		Name:    "apple podcasts",
+		Type:    FeedTypeRss,
+		Options: func(f *Feed) { f.OnDuplicateID = DuplicateIDError },
+		Rules: []Rule{
+			requireFeed("title", func(f *Feed) bool { return f.Title != "" }),
+			requireFeed("description", func(f *Feed) bool { return f.Description != "" }),
+			requireFeed("language", func(f *Feed) bool { return f.Language != "" }),
+			requireFeed("image", func(f *Feed) bool { return f.Image != nil && f.Image.Url != "" }),
+			maxFeedLength("description", 4000, func(f *Feed) string { return f.Description }),
+			requireItems("title", func(i *Item) bool { return i.Title != "" }),
+			requireItems("id", func(i *Item) bool { return i.Id != "" }),
+			// rss only writes enclosures with a type and a length
+			requireItems("enclosure", func(i *Item) bool {
+				e := i.Enclosure
+				return e != nil && e.Url != "" && e.Type != "" && e.Length != ""
+			}),
+			maxItemLength("description", 4000, func(i *Item) string { return i.Description }),
+			mappedCategory("itunes"),
+		},
+	}
+}
+
+// a Rule failing when has is false for the feed
+func requireFeed(what string, has func(f *Feed) bool) Rule {
+	return func(f *Feed) error {
+		if !has(f) {
+			return fmt.Errorf("feed has no %s", what)
+		}
+		return nil
+	}
+}
+
+// a Rule failing for the first item has is false for
+func requireItems(what string, has func(i *Item) bool) Rule {
+	return func(f *Feed) error {
+		for n, i := range f.Items {
+			if !has(i) {
+				return fmt.Errorf("item %d has no %s", n, what)
+			}
+		}
+		return nil
+	}
+}
+
+// a Rule failing when a field of the feed is longer than max characters
+func maxFeedLength(what string, max int, field func(f *Feed) string) Rule {
+	return func(f *Feed) error {
+		if n := utf8.RuneCountInString(field(f)); n > max {
+			return fmt.Errorf("feed %s has %d characters, more than %d", what, n, max)
+		}
+		return nil
+	}
+}
+
+// a Rule failing for the first item with a field longer than max characters
+func maxItemLength(what string, max int, field func(i *Item) string) Rule {
+	return func(f *Feed) error {
+		for n, i := range f.Items {
+			if c := utf8.RuneCountInString(field(i)); c > max {
+				return fmt.Errorf("item %d %s has %d characters, more than %d", n, what, c, max)
+			}
+		}
+		return nil
+	}
+}
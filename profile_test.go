@@ -0,0 +1,82 @@
+package feeds
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func profileTestFeed() *Feed {
+	feed := testFeed(&Item{
+		Title:       "Limiting Concurrency in Go",
+		Link:        &Link{Href: "http://jmoiron.net/blog/limiting-concurrency-in-go/"},
+		Id:          "limiting-concurrency-in-go",
+		Description: "A discussion on controlled parallelism in golang",
+		Created:     testCreated,
+		Enclosure:   &Enclosure{Url: "http://jmoiron.net/episode.mp3", Length: "123456", Type: "audio/mpeg"},
+		Amazon:      &AmazonItem{HeroImage: "http://jmoiron.net/hero.jpg"},
+	})
+	feed.Language = "en-us"
+	feed.Image = &Image{Url: "http://jmoiron.net/cover.jpg"}
+	feed.Amazon = &AmazonChannel{PublisherID: "jmoiron-blog"}
+	return feed
+}
+
+func TestProfiles(t *testing.T) {
+	for _, test := range []struct {
+		profile *Profile
+		want    string // in the written feed
+		breaks  func(f *Feed)
+	}{
+		{AmazonStrict(), "<amzn:heroImage>http://jmoiron.net/hero.jpg</amzn:heroImage>", func(f *Feed) { f.Items[0].Amazon = nil }},
+		{GoogleNews(), "<language>en-us</language>", func(f *Feed) { f.Items[0].Created = time.Time{} }},
+		{ApplePodcasts(), `<enclosure url="http://jmoiron.net/episode.mp3"`, func(f *Feed) { f.Items[0].Enclosure.Length = "" }},
+		{ApplePodcasts(), `<enclosure url="http://jmoiron.net/episode.mp3"`, func(f *Feed) { f.Description = strings.Repeat("é", 4001) }},
+	} {
+		feed := profileTestFeed()
+		var buf bytes.Buffer
+		if err := test.profile.Write(feed, &buf); err != nil {
+			t.Errorf("%s: unexpected error: %v", test.profile.Name, err)
+		}
+		if !strings.Contains(buf.String(), test.want) {
+			t.Errorf("%s: missing %q.  Got:\n%s\n", test.profile.Name, test.want, buf.String())
+		}
+
+		test.breaks(feed)
+		buf.Reset()
+		if err := test.profile.Write(feed, &buf); err == nil || !strings.HasPrefix(err.Error(), "feeds: "+test.profile.Name) {
+			t.Errorf("%s: got error %v for an invalid feed", test.profile.Name, err)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("%s: wrote an invalid feed", test.profile.Name)
+		}
+	}
+}
+
+func TestProfileOptions(t *testing.T) {
+	feed := profileTestFeed()
+	var buf bytes.Buffer
+	if err := AmazonStrict().Write(feed, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		"<description><![CDATA[A discussion on controlled parallelism in golang]]></description>",
+		"<content:encoded><![CDATA[A discussion on controlled parallelism in golang]]></content:encoded>",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("amazon: missing %q.  Got:\n%s\n", want, buf.String())
+		}
+	}
+	if feed.CDATADescription || feed.ContentFromDescription || feed.OnDuplicateID != DuplicateIDAllow {
+		t.Errorf("writing with a profile changed the options of the feed")
+	}
+
+	feed.Items = append(feed.Items, feed.Items[0])
+	for _, profile := range []*Profile{AmazonStrict(), GoogleNews(), ApplePodcasts()} {
+		buf.Reset()
+		if err := profile.Write(feed, &buf); err == nil || !strings.Contains(err.Error(), "have the same id") {
+			t.Errorf("%s: got error %v for items with the same id", profile.Name, err)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package feeds
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// the extension of the files of a format written by WriteAll
+func (t FeedType) extension() string {
+	switch t {
+	case FeedTypeAtom:
+		return ".atom"
+	case FeedTypeJSON:
+		return ".json"
+	case FeedTypeAmazonRss:
+		return ".amazon.xml"
+	case FeedTypeMerchantRss:
+		return ".merchant.xml"
+	}
+	return ".xml"
+}
+
+// WriteAll writes the feed into dir in each of formats, or as rss, atom and
+// json without any, to basename.xml, basename.atom and basename.json, with
+// amazon rss as basename.amazon.xml and merchant rss as
+// basename.merchant.xml. Each file is replaced atomically, so
+// readers see either the old or the new feed, and the first error stops
+// writing the rest.
+func (f *Feed) WriteAll(dir, basename string, formats ...FeedType) error {
+	if len(formats) == 0 {
+		formats = []FeedType{FeedTypeRss, FeedTypeAtom, FeedTypeJSON}
+	}
+	for _, t := range formats {
+		path := filepath.Join(dir, basename+t.extension())
+		err := writeFileAtomic(path, func(w io.Writer) error {
+			return f.write(w, t)
+		})
+		if err != nil {
+			return fmt.Errorf("feeds: writing %s: %v", path, err)
+		}
+	}
+	return nil
+}
+
+// write a file with a temporary file in its directory renamed over it, so
+// it is never seen half written
+func writeFileAtomic(path string, write func(io.Writer) error) (err error) {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+	if err = write(tmp); err != nil {
+		return err
+	}
+	if err = tmp.Chmod(0644); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
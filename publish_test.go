@@ -0,0 +1,54 @@
+package feeds
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestWriteAll(t *testing.T) {
+	dir, err := ioutil.TempDir("", "feeds")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	feed := channelTestFeed()
+	if err := feed.WriteAll(dir, "feed"); err != nil {
+		t.Fatalf("unexpected error writing all formats: %v", err)
+	}
+	for name, want := range map[string]func() (string, error){
+		"feed.xml":  feed.ToRss,
+		"feed.atom": feed.ToAtom,
+		"feed.json": feed.ToJSON,
+	} {
+		got, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		w, _ := want()
+		if strings.TrimSpace(string(got)) != strings.TrimSpace(w) {
+			t.Errorf("%s not as expected.\nGot:\n%s\nWant:\n%s\n", name, got, w)
+		}
+	}
+
+	if err := feed.WriteAll(dir, "amazon", FeedTypeAmazonRss); err != nil {
+		t.Fatalf("unexpected error writing amazon rss: %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	sort.Strings(files)
+	var names []string
+	for _, f := range files {
+		names = append(names, filepath.Base(f))
+	}
+	if got, want := strings.Join(names, " "), "amazon.amazon.xml feed.atom feed.json feed.xml"; got != want {
+		t.Errorf("expected the files %s, got %s", want, got)
+	}
+
+	if err := feed.WriteAll(filepath.Join(dir, "missing"), "feed"); err == nil {
+		t.Errorf("expected an error writing into a missing directory")
+	}
+}
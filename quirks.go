@@ -0,0 +1,123 @@
+package feeds
+
+import (
+	"bytes"
+)
+
+// Quirks work around consumers which fail on valid xml, applied to the
+// output of the xml formats in the order of their fields. The zero value
+// changes nothing.
+type Quirks struct {
+	// ExpandSelfClosing writes self-closed elements, like those of xhtml
+	// content or SelfCloseEmpty, as <foo></foo> for parsers which only know
+	// elements with an end tag.
+	ExpandSelfClosing bool
+
+	// AlwaysEmitGuid adds an empty guid to the rss items without an Id, for
+	// readers which drop items without a guid element.
+	AlwaysEmitGuid bool
+}
+
+func (q Quirks) any() bool {
+	return q.ExpandSelfClosing || q.AlwaysEmitGuid
+}
+
+// apply the quirks to the marshaled xml doc
+func (q Quirks) apply(doc []byte) []byte {
+	if q.ExpandSelfClosing {
+		doc = expandSelfClosing(doc)
+	}
+	if q.AlwaysEmitGuid {
+		doc = emitGuids(doc)
+	}
+	return doc
+}
+
+// call visit with the text and the tags of doc in order; cdata sections,
+// comments and processing instructions are passed on as text
+func scanXML(doc []byte, visit func(chunk []byte, tag bool)) {
+	for n := 0; n < len(doc); {
+		rest := doc[n:]
+		skip := 0
+		for _, s := range [][2]string{{"<![CDATA[", "]]>"}, {"<!--", "-->"}, {"<?", "?>"}} {
+			if bytes.HasPrefix(rest, []byte(s[0])) {
+				if skip = bytes.Index(rest, []byte(s[1])) + len(s[1]); skip < len(s[1]) {
+					skip = len(rest)
+				}
+				break
+			}
+		}
+		switch end := bytes.IndexByte(rest, '>'); {
+		case skip > 0:
+			visit(rest[:skip], false)
+			n += skip
+		case rest[0] == '<' && end > 0:
+			visit(rest[:end+1], true)
+			n += end + 1
+		default:
+			text := bytes.IndexByte(rest[1:], '<') + 1
+			if text == 0 {
+				text = len(rest)
+			}
+			visit(rest[:text], false)
+			n += text
+		}
+	}
+}
+
+// the name of the element of a start or end tag
+func tagName(tag []byte) string {
+	name := bytes.TrimLeft(tag[1:len(tag)-1], "/")
+	if n := bytes.IndexAny(name, " \t\r\n/"); n >= 0 {
+		name = name[:n]
+	}
+	return string(name)
+}
+
+// rewrite the self-closed elements of doc, like <link/>, as <link></link>
+func expandSelfClosing(doc []byte) []byte {
+	var out bytes.Buffer
+	scanXML(doc, func(chunk []byte, tag bool) {
+		if !tag || !bytes.HasSuffix(chunk, []byte("/>")) {
+			out.Write(chunk)
+			return
+		}
+		out.Write(bytes.TrimRight(chunk[:len(chunk)-2], " \t\r\n"))
+		out.WriteString("></" + tagName(chunk) + ">")
+	})
+	return out.Bytes()
+}
+
+// add an empty guid to the items of doc without one, indented like the
+// other children when doc is indented
+func emitGuids(doc []byte) []byte {
+	var out bytes.Buffer
+	inItem, hasGuid := false, false
+	var indent []byte // the line break and indent before the current tag
+	scanXML(doc, func(chunk []byte, tag bool) {
+		if !tag {
+			indent = nil
+			if len(bytes.TrimSpace(chunk)) == 0 && bytes.HasPrefix(chunk, []byte("\n")) {
+				indent = chunk
+			}
+		} else {
+			switch name, end := tagName(chunk), bytes.HasPrefix(chunk, []byte("</")); {
+			case name == "item" && !end:
+				inItem, hasGuid = true, false
+			case name == "guid" && inItem:
+				hasGuid = true
+			case name == "item" && end:
+				if !hasGuid && indent != nil {
+					out.WriteString("  <guid></guid>")
+					out.Write(indent)
+				} else if !hasGuid {
+					out.WriteString("<guid></guid>")
+				}
+				inItem = false
+			}
+			indent = nil
+		}
+		out.Write(chunk)
+	})
+	return out.Bytes()
+}
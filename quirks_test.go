@@ -0,0 +1,94 @@
+package feeds
+
+import "testing"
+
+func quirksTestFeed() *Feed {
+	return testFeed(
+		&Item{Title: "Limiting Concurrency in Go", Link: &Link{Href: "http://jmoiron.net/blog/limiting-concurrency-in-go/"}, Created: testCreated},
+		&Item{Title: "Logic-less Template Redux", Id: "logicless-template-redux", Created: testCreated},
+	)
+}
+
+var quirksGuidOutput = `<?xml version="1.0" encoding="UTF-8"?><rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/">
+  <channel>
+    <title>jmoiron.net blog</title>
+    <link>http://jmoiron.net/blog</link>
+    <description>discussion about tech, footie, photos</description>
+    <pubDate>Wed, 16 Jan 2013 21:52:35 -0500</pubDate>
+    <item>
+      <title>Limiting Concurrency in Go</title>
+      <link>http://jmoiron.net/blog/limiting-concurrency-in-go/</link>
+      <description></description>
+      <pubDate>Wed, 16 Jan 2013 21:52:35 -0500</pubDate>
+      <guid></guid>
+    </item>
+    <item>
+      <title>Logic-less Template Redux</title>
+      <description></description>
+      <guid isPermaLink="false">logicless-template-redux</guid>
+      <pubDate>Wed, 16 Jan 2013 21:52:35 -0500</pubDate>
+    </item>
+  </channel>
+</rss>`
+
+func TestQuirksAlwaysEmitGuid(t *testing.T) {
+	feed := quirksTestFeed()
+	feed.Quirks.AlwaysEmitGuid = true
+	rss, err := feed.ToRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding RSS: %v", err)
+	}
+	if rss != quirksGuidOutput {
+		t.Errorf("Rss not what was expected.  Got:\n%s\n\nExpected:\n%s\n", rss, quirksGuidOutput)
+	}
+}
+
+var quirksExpandOutput = `<?xml version="1.0" encoding="UTF-8"?><feed xmlns="http://www.w3.org/2005/Atom">
+  <title>jmoiron.net blog</title>
+  <id>http://jmoiron.net/blog</id>
+  <updated>2013-01-16T21:52:35-05:00</updated>
+  <subtitle>discussion about tech, footie, photos</subtitle>
+  <link href="http://jmoiron.net/blog"></link>
+  <entry>
+    <title>Limiting Concurrency in Go</title>
+    <updated>2013-01-16T21:52:35-05:00</updated>
+    <id>tag:jmoiron.net,2013-01-16:/blog/limiting-concurrency-in-go/</id>
+    <content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"><p>a<br></br>b<img src="http://jmoiron.net/a.png"></img></p></div></content>
+    <link href="http://jmoiron.net/blog/limiting-concurrency-in-go/" rel="alternate"></link>
+    <summary type="html"></summary>
+  </entry>
+  <entry>
+    <title>Logic-less Template Redux</title>
+    <updated>2013-01-16T21:52:35-05:00</updated>
+    <id>logicless-template-redux</id>
+    <summary type="html"></summary>
+  </entry>
+</feed>`
+
+func TestQuirksExpandSelfClosing(t *testing.T) {
+	feed := quirksTestFeed()
+	feed.Items[0].Content = `<p>a<br/>b<img src="http://jmoiron.net/a.png" /></p>`
+	// expanding wins over self-closing the empty elements
+	feed.SelfCloseEmpty = true
+	feed.Quirks.ExpandSelfClosing = true
+	feed.XHTMLContent = true
+	atom, err := ToXML(&Atom{Feed: feed})
+	if err != nil {
+		t.Errorf("unexpected error encoding Atom: %v", err)
+	}
+	if atom != quirksExpandOutput {
+		t.Errorf("Atom not what was expected.  Got:\n%s\n\nExpected:\n%s\n", atom, quirksExpandOutput)
+	}
+}
+
+func TestQuirksDefault(t *testing.T) {
+	feed := quirksTestFeed()
+	want, err := feed.ToRss()
+	if err != nil {
+		t.Fatal(err)
+	}
+	feed.Quirks = Quirks{}
+	if got := string(feed.Quirks.apply([]byte(want))); got != want {
+		t.Errorf("the zero Quirks changed the output.  Got:\n%s\n", got)
+	}
+}
@@ -0,0 +1,51 @@
+package feeds
+
+import (
+	"fmt"
+	"strings"
+)
+
+// whether the author is credited as an author rather than as a contributor
+func (a *Author) isAuthor() bool {
+	return a.Role == "" || strings.EqualFold(a.Role, "author")
+}
+
+// the byline of the author as a dc:creator, with its role in parentheses
+func (a *Author) creator() string {
+	if a.Role == "" {
+		return a.Name
+	}
+	return fmt.Sprintf("%s (%s)", a.Name, a.Role)
+}
+
+// the dc:creator bylines of the Author and Contributors of an item
+func (i *Item) roleCreators() []string {
+	var creators []string
+	for _, a := range append([]*Author{i.Author}, i.Contributors...) {
+		if a != nil && a.Name != "" {
+			creators = append(creators, a.creator())
+		}
+	}
+	return creators
+}
+
+// add the Author and Contributors of an item to its atom entry, as authors
+// or contributors by their Role
+func setAtomPeople(x *AtomEntry, i *Item) {
+	for _, a := range append([]*Author{i.Author}, i.Contributors...) {
+		if a == nil || (a.Name == "" && a.Email == "") {
+			continue
+		}
+		person := AtomPerson{Name: a.Name, Email: a.Email}
+		switch {
+		case a.isAuthor() && x.Author == nil:
+			x.Author = &AtomAuthor{AtomPerson: person}
+		case a.isAuthor():
+			x.Authors = append(x.Authors, &AtomAuthor{AtomPerson: person})
+		case x.Contributor == nil:
+			x.Contributor = &AtomContributor{AtomPerson: person}
+		default:
+			x.Contributors = append(x.Contributors, &AtomContributor{AtomPerson: person})
+		}
+	}
+}
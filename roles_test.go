@@ -0,0 +1,114 @@
+package feeds
+
+import (
+	"strings"
+	"testing"
+)
+
+func rolesTestFeed() *Feed {
+	return testFeed(&Item{
+		Id:     "footie-season",
+		Title:  "Footie Season",
+		Author: &Author{Name: "Jason Moiron", Email: "jmoiron@jmoiron.net", Role: "writer"},
+		Contributors: []*Author{
+			{Name: "Ann Photo", Role: "photographer"},
+			{Name: "Ed Itor", Role: "editor"},
+			{Name: "Co Author", Role: "author"},
+		},
+	})
+}
+
+func TestRoleCreators(t *testing.T) {
+	feed := rolesTestFeed()
+	rss, err := feed.ToRss()
+	if err != nil {
+		t.Fatalf("unexpected error encoding RSS: %v", err)
+	}
+	if strings.Contains(rss, "dc:creator") || strings.Contains(rss, "xmlns:dc") {
+		t.Errorf("Rss should not write creators by default.  Got:\n%s\n", rss)
+	}
+	amazon, err := feed.ToAmazonRss()
+	if err != nil {
+		t.Fatalf("unexpected error encoding AmazonRss: %v", err)
+	}
+	if strings.Count(amazon, "<dc:creator>") != 1 || !strings.Contains(amazon, "<dc:creator>Jason Moiron</dc:creator>") {
+		t.Errorf("AmazonRss should only write the author's name by default.  Got:\n%s\n", amazon)
+	}
+	if item := (&AmazonRss{Feed: feed}).AmazonRssFeed().Items[0]; item.Creator != "Jason Moiron" || item.Creators != nil {
+		t.Errorf("AmazonRss should set the Creator of the item by default, got %q and %q", item.Creator, item.Creators)
+	}
+
+	feed.RoleCreators = true
+	rss, _ = ToXML(&Rss{Feed: feed})
+	amazon, _ = ToXML(&AmazonRss{Feed: feed})
+	for name, out := range map[string]string{"Rss": rss, "AmazonRss": amazon} {
+		for _, want := range []string{
+			`xmlns:dc="http://purl.org/dc/elements/1.1/"`,
+			"<dc:creator>Jason Moiron (writer)</dc:creator>",
+			"<dc:creator>Ann Photo (photographer)</dc:creator>",
+			"<dc:creator>Ed Itor (editor)</dc:creator>",
+			"<dc:creator>Co Author (author)</dc:creator>",
+		} {
+			if !strings.Contains(out, want) {
+				t.Errorf("%s missing %s.  Got:\n%s\n", name, want, out)
+			}
+		}
+	}
+
+	// the creators written by the cdata description path too
+	feed.Items[0].Description = "<p>footie</p>"
+	feed.CDATADescription = true
+	amazon, _ = ToXML(&AmazonRss{Feed: feed})
+	if strings.Count(amazon, "<dc:creator>") != 4 || !strings.Contains(amazon, "<description><![CDATA[<p>footie</p>]]></description>") {
+		t.Errorf("AmazonRss with CDATADescription missing the creators.  Got:\n%s\n", amazon)
+	}
+
+	feed.Items[0].Author.Role = ""
+	rss, _ = ToXML(&Rss{Feed: feed})
+	if !strings.Contains(rss, "<dc:creator>Jason Moiron</dc:creator>") {
+		t.Errorf("Rss should write a role-less author by name.  Got:\n%s\n", rss)
+	}
+}
+
+func TestAtomContributors(t *testing.T) {
+	feed := rolesTestFeed()
+	atom, err := feed.ToAtom()
+	if err != nil {
+		t.Fatalf("unexpected error encoding Atom: %v", err)
+	}
+	for _, want := range []string{
+		"<contributor>\n      <name>Jason Moiron</name>\n      <email>jmoiron@jmoiron.net</email>\n    </contributor>",
+		"<contributor>\n      <name>Ann Photo</name>\n    </contributor>",
+		"<contributor>\n      <name>Ed Itor</name>\n    </contributor>",
+		"<author>\n      <name>Co Author</name>\n    </author>",
+	} {
+		if !strings.Contains(atom, want) {
+			t.Errorf("Atom missing %s.  Got:\n%s\n", want, atom)
+		}
+	}
+	if strings.Count(atom, "<author>") != 1 {
+		t.Errorf("Atom should only have the one author.  Got:\n%s\n", atom)
+	}
+
+	feed.Items[0].Author.Role = ""
+	feed.Items[0].Contributors = nil
+	atom, _ = feed.ToAtom()
+	if !strings.Contains(atom, "<author>\n      <name>Jason Moiron</name>") || strings.Contains(atom, "contributor") {
+		t.Errorf("Atom should write a role-less author as the author.  Got:\n%s\n", atom)
+	}
+}
+
+func TestJSONAuthorRole(t *testing.T) {
+	feed := rolesTestFeed()
+	json, err := feed.ToJSON()
+	if err != nil {
+		t.Fatalf("unexpected error encoding JSON: %v", err)
+	}
+	if want := `"_role": "writer"`; !strings.Contains(json, want) {
+		t.Errorf("JSON missing %s.  Got:\n%s\n", want, json)
+	}
+	feed.Items[0].Author.Role = ""
+	if json, _ = feed.ToJSON(); strings.Contains(json, "_role") {
+		t.Errorf("JSON should not write an empty role.  Got:\n%s\n", json)
+	}
+}
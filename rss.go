@@ -7,15 +7,33 @@ package feeds
 import (
 	"encoding/xml"
 	"fmt"
+	"net/url"
+	"strconv"
 	"time"
 )
 
+const (
+	contentNamespace     = "http://purl.org/rss/1.0/modules/content/"
+	dublinCoreNamespace  = "http://purl.org/dc/elements/1.1/"
+	amazonNamespace      = "https://amazon.com/ospublishing/1.0/"
+	syndicationNamespace = "http://purl.org/rss/1.0/modules/syndication/"
+	dcTermsNamespace     = "http://purl.org/dc/terms/"
+)
+
 // private wrapper around the RssFeed which gives us the <rss>..</rss> xml
 type RssFeedXml struct {
-	XMLName          xml.Name `xml:"rss"`
-	Version          string   `xml:"version,attr"`
-	ContentNamespace string   `xml:"xmlns:content,attr"`
-	Channel          *RssFeed
+	XMLName              xml.Name `xml:"rss"`
+	Version              string   `xml:"version,attr"`
+	ContentNamespace     string   `xml:"xmlns:content,attr"`
+	DublinCoreNamespace  string   `xml:"xmlns:dc,attr,omitempty"`
+	SyndicationNamespace string   `xml:"xmlns:sy,attr,omitempty"`
+	MediaNamespace       string   `xml:"xmlns:media,attr,omitempty"`
+	DCTermsNamespace     string   `xml:"xmlns:dcterms,attr,omitempty"`
+	WebfeedsNamespace    string   `xml:"xmlns:webfeeds,attr,omitempty"`
+	PodcastNamespace     string   `xml:"xmlns:podcast,attr,omitempty"`
+	ITunesNamespace      string   `xml:"xmlns:itunes,attr,omitempty"`
+	AtomNamespace        string   `xml:"xmlns:atom,attr,omitempty"`
+	Channel              *RssFeed
 }
 
 type RssContent struct {
@@ -41,64 +59,208 @@ type RssTextInput struct {
 }
 
 type RssFeed struct {
-	XMLName        xml.Name `xml:"channel"`
-	Title          string   `xml:"title"`       // required
-	Link           string   `xml:"link"`        // required
-	Description    string   `xml:"description"` // required
-	Language       string   `xml:"language,omitempty"`
-	Copyright      string   `xml:"copyright,omitempty"`
-	ManagingEditor string   `xml:"managingEditor,omitempty"` // Author used
-	WebMaster      string   `xml:"webMaster,omitempty"`
-	PubDate        string   `xml:"pubDate,omitempty"`       // created or updated
-	LastBuildDate  string   `xml:"lastBuildDate,omitempty"` // updated used
-	Category       string   `xml:"category,omitempty"`
-	Generator      string   `xml:"generator,omitempty"`
-	Docs           string   `xml:"docs,omitempty"`
-	Cloud          string   `xml:"cloud,omitempty"`
-	Ttl            int      `xml:"ttl,omitempty"`
-	Rating         string   `xml:"rating,omitempty"`
-	SkipHours      string   `xml:"skipHours,omitempty"`
-	SkipDays       string   `xml:"skipDays,omitempty"`
-	Image          *RssImage
-	TextInput      *RssTextInput
-	Items          []*RssItem `xml:"item"`
+	XMLName          xml.Name `xml:"channel"`
+	Title            string   `xml:"title"`       // required
+	Link             string   `xml:"link"`        // required
+	Description      string   `xml:"description"` // required
+	Language         string   `xml:"language,omitempty"`
+	Copyright        string   `xml:"copyright,omitempty"`
+	ManagingEditor   string   `xml:"managingEditor,omitempty"` // Author used
+	WebMaster        string   `xml:"webMaster,omitempty"`
+	PubDate          string   `xml:"pubDate,omitempty"`       // created or updated
+	LastBuildDate    string   `xml:"lastBuildDate,omitempty"` // updated used
+	Category         string   `xml:"category,omitempty"`
+	Generator        string   `xml:"generator,omitempty"`
+	Docs             string   `xml:"docs,omitempty"`
+	Cloud            string   `xml:"cloud,omitempty"`
+	Ttl              int      `xml:"ttl,omitempty"`
+	Rating           string   `xml:"rating,omitempty"`
+	SkipHours        string   `xml:"skipHours,omitempty"`
+	SkipDays         string   `xml:"skipDays,omitempty"`
+	Image            *RssImage
+	TextInput        *RssTextInput
+	UpdatePeriod     string         `xml:"sy:updatePeriod,omitempty"`
+	UpdateFrequency  int            `xml:"sy:updateFrequency,omitempty"`
+	UpdateBase       string         `xml:"sy:updateBase,omitempty"`
+	AtomLinks        []*RssAtomLink `xml:"atom:link"` // alternate and search links
+	ITunesNewFeedURL string         `xml:"itunes:new-feed-url,omitempty"`
+	ITunesExplicit   string         `xml:"itunes:explicit,omitempty"` // true or false
+	ITunesCategory   *RssITunesCategory
+	Items            []*RssItem `xml:"item"`
+	*RssWebfeeds
+}
+
+// the guid element of an RssItem
+type rssGuid struct {
+	XMLName     xml.Name `xml:"guid"`
+	IsPermaLink string   `xml:"isPermaLink,attr,omitempty"`
+	Id          string   `xml:",chardata"`
 }
 
 type RssItem struct {
-	XMLName     xml.Name `xml:"item"`
-	Title       string   `xml:"title"`       // required
-	Link        string   `xml:"link"`        // required
-	Description string   `xml:"description"` // required
-	Content     *RssContent
-	Author      string `xml:"author,omitempty"`
-	Category    string `xml:"category,omitempty"`
-	Comments    string `xml:"comments,omitempty"`
-	Enclosure   *RssEnclosure
-	Guid        string `xml:"guid,omitempty"`    // Id used
-	PubDate     string `xml:"pubDate,omitempty"` // created or updated
-	Source      string `xml:"source,omitempty"`
+	XMLName      xml.Name `xml:"item"`
+	Title        string   `xml:"title,omitempty"` // required without a description
+	Link         string   `xml:"link,omitempty"`
+	Description  string   `xml:"description"` // required without a title
+	Content      *RssContent
+	Author       string   `xml:"author,omitempty"`
+	Creators     []string `xml:"dc:creator,omitempty"`
+	Category     string   `xml:"category,omitempty"`
+	Comments     string   `xml:"comments,omitempty"`
+	Enclosure    *RssEnclosure
+	Guid         string `xml:"guid,omitempty"`    // Id used
+	PubDate      string `xml:"pubDate,omitempty"` // created or updated
+	Source       string `xml:"source,omitempty"`
+	MediaContent *RssMediaContent
+	MediaPlayer  *RssMediaPlayer
+	MediaTitle   *RssMediaTitle
+	BackLinks    *RssMediaBackLinks
+	Created      string `xml:"dcterms:created,omitempty"`  // created used
+	Modified     string `xml:"dcterms:modified,omitempty"` // updated used
+
+	PodcastSeason  *RssPodcastSeason
+	PodcastEpisode *RssPodcastEpisode
+	AtomLinks      []*RssAtomLink `xml:"atom:link"` // via and AtomItemLinks links
+
+	// IsPermaLink is the isPermaLink attribute of the guid, true or false
+	IsPermaLink string `xml:"-"`
+}
+
+// MarshalXML implements the xml.Marshaler interface.
+// It writes the Guid with the IsPermaLink attribute, which a string field
+// cannot have.
+func (i *RssItem) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	var guid *rssGuid
+	if i.Guid != "" {
+		guid = &rssGuid{Id: i.Guid, IsPermaLink: i.IsPermaLink}
+	}
+	// the start element is named after the type rather than the XMLName
+	start.Name = xml.Name{Local: "item"}
+	return e.EncodeElement(struct {
+		XMLName        xml.Name `xml:"item"`
+		Title          string   `xml:"title,omitempty"`
+		Link           string   `xml:"link,omitempty"`
+		Description    string   `xml:"description"`
+		Content        *RssContent
+		Author         string   `xml:"author,omitempty"`
+		Creators       []string `xml:"dc:creator,omitempty"`
+		Category       string   `xml:"category,omitempty"`
+		Comments       string   `xml:"comments,omitempty"`
+		Enclosure      *RssEnclosure
+		Guid           *rssGuid
+		PubDate        string `xml:"pubDate,omitempty"`
+		Source         string `xml:"source,omitempty"`
+		MediaContent   *RssMediaContent
+		MediaPlayer    *RssMediaPlayer
+		MediaTitle     *RssMediaTitle
+		BackLinks      *RssMediaBackLinks
+		Created        string `xml:"dcterms:created,omitempty"`
+		Modified       string `xml:"dcterms:modified,omitempty"`
+		PodcastSeason  *RssPodcastSeason
+		PodcastEpisode *RssPodcastEpisode
+		AtomLinks      []*RssAtomLink `xml:"atom:link"`
+	}{
+		i.XMLName, i.Title, i.Link, i.Description, i.Content, i.Author, i.Creators, i.Category, i.Comments,
+		i.Enclosure, guid, i.PubDate, i.Source, i.MediaContent, i.MediaPlayer, i.MediaTitle, i.BackLinks,
+		i.Created, i.Modified, i.PodcastSeason, i.PodcastEpisode, i.AtomLinks,
+	}, start)
+}
+
+// RssAtomLink is an atom link in an rss document
+type RssAtomLink struct {
+	XMLName  xml.Name `xml:"atom:link"`
+	Href     string   `xml:"href,attr"`
+	Rel      string   `xml:"rel,attr,omitempty"`
+	Type     string   `xml:"type,attr,omitempty"`
+	HrefLang string   `xml:"hreflang,attr,omitempty"`
+	Title    string   `xml:"title,attr,omitempty"`
 }
 
 type RssEnclosure struct {
 	//RSS 2.0 <enclosure url="http://example.com/file.mp3" length="123456789" type="audio/mpeg" />
 	XMLName xml.Name `xml:"enclosure"`
 	Url     string   `xml:"url,attr"`
-	Length  string   `xml:"length,attr"`
+	Length  string   `xml:"length,attr,omitempty"`
 	Type    string   `xml:"type,attr"`
 }
 
+// Syndication holds the RSS syndication module (sy:) hints telling
+// aggregators how often a feed is updated.
+// See http://web.resource.org/rss/1.0/modules/syndication/
+type Syndication struct {
+	UpdatePeriod    string // hourly, daily, weekly, monthly or yearly
+	UpdateFrequency int    // updates per period, 1 when unset
+	UpdateBase      time.Time
+}
+
+// minutes in each of the syndication module's update periods
+var syndicationPeriodMinutes = map[string]int{
+	"hourly":  60,
+	"daily":   24 * 60,
+	"weekly":  7 * 24 * 60,
+	"monthly": 30 * 24 * 60,
+	"yearly":  365 * 24 * 60,
+}
+
+// the number of minutes between updates described by the module, or 0 if
+// the period is unknown
+func (s *Syndication) ttl() int {
+	if s == nil {
+		return 0
+	}
+	frequency := s.UpdateFrequency
+	if frequency < 1 {
+		frequency = 1
+	}
+	return syndicationPeriodMinutes[s.UpdatePeriod] / frequency
+}
+
 type Rss struct {
 	*Feed
 }
 
+// the docs url of DefaultDocs
+const rssSpecification = "https://www.rssboard.org/rss-specification"
+
+// create an RssAtomLink from a generic Link
+func newRssAtomLink(l *Link) *RssAtomLink {
+	return &RssAtomLink{Href: l.Href, Rel: l.Rel, Type: l.Type, HrefLang: l.HrefLang, Title: l.Title}
+}
+
+// the guid of an item and its isPermaLink, without them when it has no Id
+func (r *Rss) guid(i *Item) (id, isPermaLink string) {
+	id = r.itemId(i)
+	if id == "" {
+		return "", ""
+	}
+	permalink := r.DefaultIsPermaLink
+	if i.IsPermaLink != nil {
+		permalink = *i.IsPermaLink
+	} else if i.Link != nil && i.Link.Href == id {
+		permalink = true
+	} else if !permaLinkable(id) {
+		permalink = false
+	}
+	return id, strconv.FormatBool(permalink)
+}
+
+// whether the guid id could be a permalink, an http or https url readers
+// can open rather than a tag uri or urn
+func permaLinkable(id string) bool {
+	u, err := url.Parse(id)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
 // create a new RssItem with a generic Item struct's data
 func newRssItem(i *Item) *RssItem {
 	item := &RssItem{
 		Title:       i.Title,
-		Link:        i.Link.Href,
 		Description: i.Description,
-		Guid:        i.Id,
-		PubDate:     anyTimeFormat(time.RFC1123Z, i.Created, i.Updated),
+		PubDate:     FormatTime(time.RFC1123Z, nil, i.pubDate()),
+	}
+	// items without a link are identified by their guid alone
+	if i.Link != nil {
+		item.Link = i.Link.Href
 	}
 	if len(i.Content) > 0 {
 		item.Content = &RssContent{Content: i.Content}
@@ -108,20 +270,38 @@ func newRssItem(i *Item) *RssItem {
 	}
 
 	// Define a closure
-	if i.Enclosure != nil && i.Enclosure.Type != "" && i.Enclosure.Length != "" {
+	if i.Enclosure != nil && i.Enclosure.Type != "" {
 		item.Enclosure = &RssEnclosure{Url: i.Enclosure.Url, Type: i.Enclosure.Type, Length: i.Enclosure.Length}
 	}
 
 	if i.Author != nil {
 		item.Author = i.Author.Name
 	}
+	if i.ViaURL != "" {
+		item.AtomLinks = append(item.AtomLinks, &RssAtomLink{Href: i.ViaURL, Rel: "via"})
+	}
+	setRssMedia(item, i)
+	setRssPodcast(item, i)
 	return item
 }
 
 // create a new RssFeed with a generic Feed struct's data
 func (r *Rss) RssFeed() *RssFeed {
-	pub := anyTimeFormat(time.RFC1123Z, r.Created, r.Updated)
-	build := anyTimeFormat(time.RFC1123Z, r.Updated)
+	feed, _ := r.rssFeed()
+	return feed
+}
+
+// create a new RssFeed, returning an error along with it if any of the
+// items have invalid values
+func (r *Rss) rssFeed() (*RssFeed, error) {
+	if split := r.splitOversized().jitterDates(); split != r.Feed {
+		rss := *r
+		rss.Feed = split
+		r = &rss
+	}
+	pubDate, buildDate := r.channelDates()
+	pub := FormatTime(time.RFC1123Z, nil, pubDate)
+	build := FormatTime(time.RFC1123Z, nil, buildDate)
 	author := ""
 	if r.Author != nil {
 		author = r.Author.Email
@@ -136,19 +316,89 @@ func (r *Rss) RssFeed() *RssFeed {
 	}
 
 	channel := &RssFeed{
-		Title:          r.Title,
-		Link:           r.Link.Href,
+		Title:          r.title(),
 		Description:    r.Description,
+		Language:       r.Language,
 		ManagingEditor: author,
 		PubDate:        pub,
 		LastBuildDate:  build,
-		Copyright:      r.Copyright,
+		Copyright:      r.copyright(),
+		Docs:           r.Docs,
+		Generator:      r.generator(),
+		Ttl:            r.TTL,
+		Rating:         r.AudienceRating,
 		Image:          image,
+		RssWebfeeds:    newRssWebfeeds(r.Webfeeds),
+		AtomLinks:      r.rssAtomLinks(FeedTypeRss),
 	}
-	for _, i := range r.Items {
-		channel.Items = append(channel.Items, newRssItem(i))
+	if r.Link != nil {
+		channel.Link = r.Link.Href
+	}
+	if channel.Docs == "" && r.DefaultDocs {
+		channel.Docs = rssSpecification
+	}
+	if s := r.Syndication; s != nil {
+		channel.UpdatePeriod = s.UpdatePeriod
+		channel.UpdateFrequency = s.UpdateFrequency
+		channel.UpdateBase = FormatTime(time.RFC3339, nil, s.UpdateBase)
+	}
+	if channel.Ttl == 0 && r.DeriveTTLFromSyndication {
+		channel.Ttl = r.Syndication.ttl()
+	}
+	channel.ITunesNewFeedURL = r.ITunesNewFeedURL
+	if category, ok := r.taxonomy("itunes"); ok {
+		channel.ITunesCategory = &RssITunesCategory{Text: category}
 	}
-	return channel
+	err := validateWebfeeds(r.Feed)
+	if e := validateITunesNewFeedURL(r.Feed); e != nil && err == nil {
+		err = fmt.Errorf("feeds: %v", e)
+	}
+	ids := map[string]int{}
+	for n, i := range r.Items {
+		i = r.enclosureType(r.sanitize(i))
+		if !i.published() || r.scrubItem(i) || r.duplicateItem(ids, n, i) {
+			continue
+		}
+		e := validateMedia(i)
+		if e == nil {
+			e = validatePodcast(i)
+		}
+		if e == nil {
+			e = i.validateVia()
+		}
+		if e != nil {
+			if r.skipItem(i, e) {
+				continue
+			}
+			if err == nil {
+				err = fmt.Errorf("feeds: item %d: %v", n, e)
+			}
+		}
+		item := newRssItem(i)
+		item.Guid, item.IsPermaLink = r.guid(i)
+		if r.AtomItemLinks {
+			for _, l := range i.Links {
+				if l != nil {
+					item.AtomLinks = append(item.AtomLinks, newRssAtomLink(l))
+				}
+			}
+		}
+		if r.RoleCreators {
+			item.Creators = i.roleCreators()
+		}
+		if r.DCTermsDates {
+			item.Created = FormatTime(time.RFC3339, nil, i.Created)
+			item.Modified = FormatTime(time.RFC3339, nil, i.Updated)
+		}
+		channel.Items = append(channel.Items, item)
+		r.wrote(i)
+	}
+	// itunes:explicit is only said of podcasts
+	if r.Explicit != nil && (channel.usesITunes() || channel.usesPodcast()) {
+		channel.ITunesExplicit = strconv.FormatBool(*r.Explicit)
+	}
+	r.rewriteRssURLs(channel)
+	return channel, err
 }
 
 // FeedXml returns an XML-Ready object for an Rss object
@@ -158,11 +408,56 @@ func (r *Rss) FeedXml() interface{} {
 
 }
 
+func (r *Rss) buildFeedXml() (interface{}, error) {
+	feed, err := r.rssFeed()
+	if err != nil {
+		return nil, err
+	}
+	return feed.FeedXml(), nil
+}
+
 // FeedXml returns an XML-ready object for an RssFeed object
 func (r *RssFeed) FeedXml() interface{} {
-	return &RssFeedXml{
+	x := &RssFeedXml{
 		Version:          "2.0",
 		Channel:          r,
-		ContentNamespace: "http://purl.org/rss/1.0/modules/content/",
+		ContentNamespace: contentNamespace,
+	}
+	if r.UpdatePeriod != "" || r.UpdateFrequency != 0 || r.UpdateBase != "" {
+		x.SyndicationNamespace = syndicationNamespace
+	}
+	if r.usesMedia() {
+		x.MediaNamespace = mediaNamespace
+	}
+	if r.RssWebfeeds != nil {
+		x.WebfeedsNamespace = webfeedsNamespace
+	}
+	if r.usesPodcast() {
+		x.PodcastNamespace = podcastNamespace
+	}
+	if r.usesITunes() {
+		x.ITunesNamespace = itunesNamespace
+	}
+	if len(r.AtomLinks) > 0 {
+		x.AtomNamespace = ns
+	}
+	for _, i := range r.Items {
+		if len(i.AtomLinks) > 0 {
+			x.AtomNamespace = ns
+			break
+		}
+	}
+	for _, i := range r.Items {
+		if i.Created != "" || i.Modified != "" {
+			x.DCTermsNamespace = dcTermsNamespace
+			break
+		}
+	}
+	for _, i := range r.Items {
+		if len(i.Creators) > 0 {
+			x.DublinCoreNamespace = dublinCoreNamespace
+			break
+		}
 	}
+	return x
 }
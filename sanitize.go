@@ -0,0 +1,21 @@
+package feeds
+
+// A Sanitizer returns a safe version of the html of an item, like one
+// without the scripts of content written by untrusted users.
+type Sanitizer func(html string) string
+
+// the item as it is written, with its description and content passed
+// through the feed's Sanitizer unless it is trusted to skip it
+func (f *Feed) sanitize(i *Item) *Item {
+	if f.Sanitizer == nil || i.SkipSanitize {
+		return i
+	}
+	item := *i
+	if item.Description != "" {
+		item.Description = f.Sanitizer(item.Description)
+	}
+	if item.Content != "" {
+		item.Content = f.Sanitizer(item.Content)
+	}
+	return &item
+}
@@ -0,0 +1,40 @@
+package feeds
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSanitizer(t *testing.T) {
+	now := testCreated
+	script := `<script>alert("hi")</script>`
+	feed := &Feed{
+		Title:       "jmoiron.net blog",
+		Link:        &Link{Href: "http://jmoiron.net/blog"},
+		Description: "discussion about tech",
+		Created:     now,
+		Items: []*Item{
+			{Title: "A comment", Id: "comment", Created: now, Description: "nice post" + script, Content: "<p>nice post</p>" + script},
+			{Title: "Limiting Concurrency in Go", Id: "limiting-concurrency-in-go", Created: now,
+				Content: `<p>An embedded demo</p><script src="http://jmoiron.net/demo.js"></script>`, SkipSanitize: true},
+		},
+		Sanitizer: func(html string) string {
+			return strings.Replace(html, script, "", -1)
+		},
+	}
+	rss, _ := feed.ToRss()
+	atom, _ := feed.ToAtom()
+	json, _ := feed.ToJSON()
+	amazon, _ := feed.ToAmazonRss()
+	for _, test := range []struct{ name, out string }{{"Rss", rss}, {"Atom", atom}, {"JSON", json}, {"AmazonRss", amazon}} {
+		if strings.Contains(test.out, "alert") {
+			t.Errorf("%s should be sanitized.  Got:\n%s\n", test.name, test.out)
+		}
+		if !strings.Contains(test.out, "demo.js") {
+			t.Errorf("%s should not sanitize items which skip it.  Got:\n%s\n", test.name, test.out)
+		}
+	}
+	if !strings.HasSuffix(feed.Items[0].Content, script) {
+		t.Errorf("the sanitizer changed the item's Content to %q", feed.Items[0].Content)
+	}
+}
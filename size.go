@@ -0,0 +1,160 @@
+package feeds
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Report lists the items a helper left out of the feed it returned.
+type Report struct {
+	Dropped []DroppedItem
+}
+
+// DroppedItem identifies an item left out of a feed and why.
+type DroppedItem struct {
+	Id     string // the item's Id, or its link when it has none
+	Reason string
+}
+
+// the identity of an item in a Report
+func reportId(i *Item) string {
+	if i.Id == "" && i.Link != nil {
+		return i.Link.Href
+	}
+	return i.Id
+}
+
+// an io.Writer which only counts what is written to it
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// the number of bytes f is encoded to as format
+func encodedSize(f *Feed, format FeedType) (int64, error) {
+	w := &countingWriter{}
+	if err := f.write(w, format); err != nil {
+		return 0, err
+	}
+	return w.n, nil
+}
+
+// FitToSize returns a copy of feed holding as many of its newest items as
+// fit in a document of at most maxBytes when encoded as format, along with a
+// Report of the items that were dropped. Items are kept in their original
+// order and feed itself is not modified. FitToSize returns an error if not
+// even the channel data fits in maxBytes.
+//
+// The size of each item is measured by encoding it on its own, and the
+// result is encoded once more to make sure it is within maxBytes.
+func FitToSize(feed *Feed, format FeedType, maxBytes int64) (*Feed, Report, error) {
+	var report Report
+
+	// the dry runs are not logged as generating the feed
+	logger := feed.Logger
+	quiet := *feed
+	quiet.Logger = nil
+	feed = &quiet
+
+	// the channel data without any items; an atom feed takes its updated
+	// date from its items when it has none, so keep the newest one
+	channel := *feed
+	channel.Items = nil
+	if format == FeedTypeAtom && channel.Updated.IsZero() && channel.Created.IsZero() {
+		for _, i := range feed.Items {
+			if t := i.lastModified(); t.After(channel.Updated) {
+				channel.Updated = t
+			}
+		}
+	}
+	overhead, err := encodedSize(&channel, format)
+	if err != nil {
+		return nil, report, err
+	}
+	if overhead > maxBytes {
+		return nil, report, fmt.Errorf("feeds: channel data needs %d bytes, more than the maximum of %d", overhead, maxBytes)
+	}
+
+	sizes := make([]int64, len(feed.Items))
+	for n, i := range feed.Items {
+		single := channel
+		single.Items = []*Item{i}
+		size, err := encodedSize(&single, format)
+		if err != nil {
+			return nil, report, err
+		}
+		sizes[n] = size - overhead
+	}
+
+	// indexes of the items, newest first
+	newest := make([]int, len(feed.Items))
+	for n := range newest {
+		newest[n] = n
+	}
+	sort.SliceStable(newest, func(a, b int) bool {
+		return feed.Items[newest[a]].lastModified().After(feed.Items[newest[b]].lastModified())
+	})
+
+	kept := 0
+	for total := overhead; kept < len(newest); kept++ {
+		total += sizes[newest[kept]]
+		if total > maxBytes {
+			break
+		}
+	}
+
+	// the estimate can be off by the separators between items and the
+	// wrapping of the item list, so correct it by encoding the result
+	fitted, size, err := fitNewest(feed, format, newest[:kept])
+	if err != nil {
+		return nil, report, err
+	}
+	for size <= maxBytes && kept < len(newest) {
+		more, moreSize, err := fitNewest(feed, format, newest[:kept+1])
+		if err != nil {
+			return nil, report, err
+		}
+		if moreSize > maxBytes {
+			break
+		}
+		fitted, size, kept = more, moreSize, kept+1
+	}
+	for size > maxBytes && kept > 0 {
+		kept--
+		if fitted, size, err = fitNewest(feed, format, newest[:kept]); err != nil {
+			return nil, report, err
+		}
+	}
+
+	for _, n := range newest[kept:] {
+		id, reason := reportId(feed.Items[n]), fmt.Sprintf("does not fit in %d bytes", maxBytes)
+		report.Dropped = append(report.Dropped, DroppedItem{Id: id, Reason: reason})
+		if logger != nil {
+			logger.Log(EventItemSkipped, "id", id, "reason", reason)
+		}
+	}
+	fitted.Logger = logger
+	return fitted, report, nil
+}
+
+// a copy of feed with only the items at the keep indexes, in their original
+// order, and its size when encoded as format
+func fitNewest(feed *Feed, format FeedType, keep []int) (*Feed, int64, error) {
+	kept := make(map[int]bool, len(keep))
+	for _, n := range keep {
+		kept[n] = true
+	}
+	fitted := *feed
+	fitted.Items = nil
+	for n, i := range feed.Items {
+		if kept[n] {
+			fitted.Items = append(fitted.Items, i)
+		}
+	}
+	size, err := encodedSize(&fitted, format)
+	return &fitted, size, err
+}
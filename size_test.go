@@ -0,0 +1,80 @@
+package feeds
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func sizeTestFeed() *Feed {
+	feed := testFeed()
+	for n := 0; n < 20; n++ {
+		feed.Add(&Item{
+			Title:   fmt.Sprintf("Post %d", n),
+			Link:    &Link{Href: fmt.Sprintf("http://jmoiron.net/blog/%d/", n)},
+			Id:      fmt.Sprintf("post-%d", n),
+			Content: strings.Repeat("<p>footie</p>", n*n%7*10),
+			// every other item is newer than all of the ones before it
+			Created: testCreated.Add(time.Duration(n%2*100+n) * time.Hour),
+		})
+	}
+	return feed
+}
+
+func TestFitToSize(t *testing.T) {
+	for _, format := range []FeedType{FeedTypeRss, FeedTypeAtom, FeedTypeJSON, FeedTypeAmazonRss} {
+		feed := sizeTestFeed()
+		full, err := encodedSize(feed, format)
+		if err != nil {
+			t.Fatalf("format %d: unexpected error: %v", format, err)
+		}
+
+		for _, maxBytes := range []int64{full, full - 1, full / 2, full / 4} {
+			fitted, report, err := FitToSize(feed, format, maxBytes)
+			if err != nil {
+				t.Fatalf("format %d, max %d: unexpected error: %v", format, maxBytes, err)
+			}
+			var buf bytes.Buffer
+			if err := fitted.write(&buf, format); err != nil {
+				t.Fatalf("format %d, max %d: unexpected error: %v", format, maxBytes, err)
+			}
+			if int64(buf.Len()) > maxBytes {
+				t.Errorf("format %d: fitted feed is %d bytes, over the maximum of %d", format, buf.Len(), maxBytes)
+			}
+			if len(fitted.Items)+len(report.Dropped) != len(feed.Items) {
+				t.Errorf("format %d, max %d: kept %d and dropped %d of %d items", format, maxBytes, len(fitted.Items), len(report.Dropped), len(feed.Items))
+			}
+			if maxBytes == full && len(report.Dropped) != 0 {
+				t.Errorf("format %d: dropped %d items from a feed within its budget", format, len(report.Dropped))
+			}
+			if maxBytes < full && len(report.Dropped) == 0 {
+				t.Errorf("format %d, max %d: expected items to be dropped", format, maxBytes)
+			}
+
+			var oldestKept time.Time
+			for n, i := range fitted.Items {
+				if n == 0 || i.lastModified().Before(oldestKept) {
+					oldestKept = i.lastModified()
+				}
+			}
+			for _, d := range report.Dropped {
+				for _, i := range feed.Items {
+					if i.Id == d.Id && i.lastModified().After(oldestKept) {
+						t.Errorf("format %d, max %d: dropped %s which is newer than a kept item", format, maxBytes, d.Id)
+					}
+				}
+			}
+		}
+		if len(feed.Items) != 20 {
+			t.Errorf("format %d: FitToSize modified the feed", format)
+		}
+	}
+}
+
+func TestFitToSizeTooSmall(t *testing.T) {
+	if _, _, err := FitToSize(sizeTestFeed(), FeedTypeRss, 100); err == nil {
+		t.Error("expected an error when the channel does not fit")
+	}
+}
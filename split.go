@@ -0,0 +1,170 @@
+package feeds
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// SplitOversizedItems splits the Content of items longer than MaxBytes into
+// continuation items, for consumers which reject large items. The content
+// is only split between its top level elements, so each part is well
+// formed html, and content which cannot be split that way is left whole.
+// Amazon rss items are never split, and are invalid instead.
+type SplitOversizedItems struct {
+	MaxBytes int
+
+	// TitleFormat is the title of the continuations from the title of the
+	// item and their part number, "%s (part %d)" when empty.
+	TitleFormat string
+}
+
+// the default TitleFormat
+const splitTitleFormat = "%s (part %d)"
+
+// whether the content of i is longer than the MaxBytes of s
+func (s *SplitOversizedItems) oversized(i *Item) bool {
+	return s != nil && s.MaxBytes > 0 && len(i.Content) > s.MaxBytes
+}
+
+// the failure of an oversized amazon rss item, which is never split
+func errContentOversized(i *Item, s *SplitOversizedItems) error {
+	return fmt.Errorf("content is %d bytes, more than %d, and amazon rss items are not split", len(i.Content), s.MaxBytes)
+}
+
+// returns the feed with its oversized items split into their parts, the
+// feed itself when none are. continuations have the id of the item with
+// a "-partN" suffix and dates a second later than the part before them.
+func (f *Feed) splitOversized() *Feed {
+	s := f.SplitOversizedItems
+	if s == nil {
+		return f
+	}
+	var items []*Item
+	split := false
+	for _, i := range f.Items {
+		if i == nil || !s.oversized(i) {
+			items = append(items, i)
+			continue
+		}
+		parts := splitHTML(i.Content, s.MaxBytes)
+		if len(parts) < 2 {
+			f.log(EventWarning, "reason", fmt.Sprintf("item %s content of %d bytes cannot be split", reportId(i), len(i.Content)))
+			items = append(items, i)
+			continue
+		}
+		split = true
+		format := s.TitleFormat
+		if format == "" {
+			format = splitTitleFormat
+		}
+		for n, content := range parts {
+			part := *i
+			part.Content = content
+			if n > 0 {
+				after := time.Duration(n) * time.Second
+				part.Title = fmt.Sprintf(format, i.Title, n+1)
+				if i.Id != "" {
+					part.Id = fmt.Sprintf("%s-part%d", i.Id, n+1)
+				}
+				part.Enclosure = nil
+				if !i.Created.IsZero() {
+					part.Created = i.Created.Add(after)
+				}
+				if !i.Updated.IsZero() {
+					part.Updated = i.Updated.Add(after)
+				}
+			}
+			items = append(items, &part)
+		}
+	}
+	if !split {
+		return f
+	}
+	feed := *f
+	feed.Items = items
+	return &feed
+}
+
+// the void elements of html, which have no end tag
+var htmlVoid = map[string]bool{}
+
+func init() {
+	for _, name := range xml.HTMLAutoClose {
+		htmlVoid[name] = true
+	}
+}
+
+// the offsets in an html fragment where each of its top level elements,
+// texts and comments end, or false if it does not parse with all of its
+// elements closed
+func htmlBounds(fragment string) ([]int, bool) {
+	// the fragment is wrapped so void elements at its end are closed
+	const root = "<root>"
+	d := xml.NewDecoder(strings.NewReader(root + fragment + "</root>"))
+	d.Strict = false
+	d.AutoClose = xml.HTMLAutoClose
+	d.Entity = xml.HTMLEntity
+	var bounds []int
+	depth := 0
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return bounds, depth == 0
+		}
+		if err != nil {
+			return nil, false
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			if !htmlVoid[strings.ToLower(tok.Name.Local)] {
+				depth++
+				continue
+			}
+		case xml.EndElement:
+			if htmlVoid[strings.ToLower(tok.Name.Local)] {
+				continue
+			}
+			depth--
+		}
+		end := int(d.InputOffset()) - len(root)
+		if end > len(fragment) && depth > 0 {
+			// only the root is closed by its end tag, the others are not
+			// closed within the fragment
+			return nil, false
+		}
+		if depth == 1 {
+			bounds = append(bounds, end)
+		}
+	}
+}
+
+// split an html fragment into parts of at most max bytes between its top
+// level elements, or return nil when it cannot be split so that each part
+// is well formed. a single element longer than max is a part of its own.
+func splitHTML(content string, max int) []string {
+	bounds, ok := htmlBounds(content)
+	if !ok {
+		return nil
+	}
+	var parts []string
+	start, last := 0, 0
+	for _, end := range bounds {
+		if end-start > max && last > start {
+			parts = append(parts, content[start:last])
+			start = last
+		}
+		last = end
+	}
+	if start < len(content) {
+		parts = append(parts, content[start:])
+	}
+	for _, part := range parts {
+		if _, ok := htmlBounds(part); !ok {
+			return nil
+		}
+	}
+	return parts
+}
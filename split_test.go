@@ -0,0 +1,103 @@
+package feeds
+
+import (
+	"strings"
+	"testing"
+)
+
+func splitTestFeed(content string) *Feed {
+	feed := testFeed(&Item{
+		Id:        "guide",
+		Title:     "The Long Guide",
+		Link:      &Link{Href: "http://jmoiron.net/blog/guide/"},
+		Enclosure: &Enclosure{Url: "http://jmoiron.net/guide.mp3", Type: "audio/mpeg"},
+		Created:   testCreated,
+		Content:   content,
+	})
+	feed.SplitOversizedItems = &SplitOversizedItems{MaxBytes: 40}
+	return feed
+}
+
+func TestSplitHTML(t *testing.T) {
+	content := "<p>first paragraph</p><p>second <b>one</b></p><br><div><p>third paragraph</p></div>tail"
+	parts := splitHTML(content, 40)
+	want := []string{"<p>first paragraph</p>", "<p>second <b>one</b></p><br>", "<div><p>third paragraph</p></div>tail"}
+	if strings.Join(parts, "|") != strings.Join(want, "|") {
+		t.Errorf("expected the parts %q, got %q", want, parts)
+	}
+	if parts := splitHTML(strings.Repeat("<p>a paragraph</p>", 3), 1000); len(parts) != 1 {
+		t.Errorf("expected short content to be one part, got %q", parts)
+	}
+	for _, bad := range []string{"<p>unclosed <p>paragraphs", "<div><p>no end</p>"} {
+		if parts := splitHTML(bad, 10); parts != nil {
+			t.Errorf("expected %q not to be split, got %q", bad, parts)
+		}
+	}
+}
+
+func TestSplitOversizedItems(t *testing.T) {
+	feed := splitTestFeed("<p>Limiting concurrency in go</p><p>with buffered channels</p><p>and a wait group</p>")
+	rss, err := feed.ToRss()
+	if err != nil {
+		t.Fatalf("unexpected error encoding RSS: %v", err)
+	}
+	for _, want := range []string{
+		`<title>The Long Guide</title>`,
+		`<guid isPermaLink="false">guide</guid>`,
+		`<pubDate>Wed, 16 Jan 2013 21:52:35 -0500</pubDate>`,
+		`<content:encoded><![CDATA[<p>Limiting concurrency in go</p>]]></content:encoded>`,
+		`<title>The Long Guide (part 2)</title>`,
+		`<guid isPermaLink="false">guide-part2</guid>`,
+		`<pubDate>Wed, 16 Jan 2013 21:52:36 -0500</pubDate>`,
+		`<content:encoded><![CDATA[<p>with buffered channels</p>]]></content:encoded>`,
+		`<guid isPermaLink="false">guide-part3</guid>`,
+	} {
+		if !strings.Contains(rss, want) {
+			t.Errorf("Rss missing %s.  Got:\n%s\n", want, rss)
+		}
+	}
+	if strings.Count(rss, "<enclosure") != 1 || strings.Index(rss, "guide-part2") > strings.Index(rss, "guide-part3") {
+		t.Errorf("expected the parts in order, with the enclosure only on the first.  Got:\n%s\n", rss)
+	}
+	if len(feed.Items) != 1 {
+		t.Errorf("expected the items of the feed to be left as they are, got %d", len(feed.Items))
+	}
+
+	feed.SplitOversizedItems.TitleFormat = "%s, part %d"
+	atom, _ := feed.ToAtom()
+	json, _ := feed.ToJSON()
+	if !strings.Contains(atom, "<title>The Long Guide, part 3</title>") || !strings.Contains(json, `"id": "guide-part3"`) {
+		t.Errorf("expected atom and json to be split.  Got:\n%s\n%s\n", atom, json)
+	}
+
+	if _, err := feed.ToAmazonRss(); err == nil || !strings.Contains(err.Error(), "amazon rss items are not split") {
+		t.Errorf("expected amazon rss to fail, got %v", err)
+	}
+	if err := feed.ValidateAmazonRss(); err == nil || !strings.Contains(err.Error(), "item 0: content is 85 bytes, more than 40") {
+		t.Errorf("expected amazon rss validation to fail, got %v", err)
+	}
+	feed.SplitOversizedItems = nil
+	if err := feed.ValidateAmazonRss(); err != nil {
+		t.Errorf("unexpected error validating without splitting: %v", err)
+	}
+}
+
+func TestSplitOversizedItemsUnsplittable(t *testing.T) {
+	logger := &testLogger{}
+	feed := splitTestFeed("<div><p>Limiting concurrency in go</p><p>with buffered channels</p></div>")
+	feed.Logger = logger
+	rss, err := feed.ToRss()
+	if err != nil {
+		t.Fatalf("unexpected error encoding RSS: %v", err)
+	}
+	if strings.Contains(rss, "part2") {
+		t.Errorf("expected a single element not to be split.  Got:\n%s\n", rss)
+	}
+	warned := false
+	for _, e := range logger.events {
+		warned = warned || e.event == EventWarning
+	}
+	if !warned {
+		t.Errorf("expected a warning for the item which cannot be split, got %v", logger.events)
+	}
+}
@@ -0,0 +1,167 @@
+package feeds
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"sort"
+)
+
+// Stats describes a feed written by WriteStats.
+type Stats struct {
+	Bytes int64      // the size of the whole document
+	Items []ItemSize // the size of each item in feed order, when collected
+}
+
+// ItemSize is the number of bytes the element of an item, including its
+// children, took up in a written feed.
+type ItemSize struct {
+	Id    string // the item's Id, or its link when it has none
+	Bytes int64
+}
+
+// StatsOptions selects what WriteStats records.
+type StatsOptions struct {
+	// CollectItemSizes records the size of every item, which keeps a copy
+	// of the whole document while it is written.
+	CollectItemSizes bool
+}
+
+// Largest returns the n largest items of the stats, largest first.
+func (s Stats) Largest(n int) []ItemSize {
+	largest := append([]ItemSize(nil), s.Items...)
+	sort.SliceStable(largest, func(a, b int) bool {
+		return largest[a].Bytes > largest[b].Bytes
+	})
+	if n < len(largest) {
+		largest = largest[:n]
+	}
+	return largest
+}
+
+// WriteStats writes the representation of this feed selected by t to the
+// writer, like WriteRss and the others, and returns Stats about what was
+// written. The item sizes are those of the item elements found in the
+// output, so they add up to the document size along with the channel data
+// and the whitespace between the items.
+func (f *Feed) WriteStats(w io.Writer, t FeedType, opts StatsOptions) (Stats, error) {
+	var stats Stats
+	counter := &countingWriter{}
+	var buf bytes.Buffer
+	out := io.MultiWriter(w, counter)
+	if opts.CollectItemSizes {
+		out = io.MultiWriter(w, counter, &buf)
+	}
+	var written []*Item
+	feed := *f
+	feed.written = &written
+	if err := feed.write(out, t); err != nil {
+		return stats, err
+	}
+	stats.Bytes = counter.n
+	if !opts.CollectItemSizes {
+		return stats, nil
+	}
+
+	var spans [][2]int64
+	var err error
+	switch t {
+	case FeedTypeJSON:
+		spans = jsonItemSpans(buf.Bytes())
+	case FeedTypeAtom:
+		spans, err = xmlItemSpans(buf.Bytes(), "entry")
+	default:
+		spans, err = xmlItemSpans(buf.Bytes(), "item")
+	}
+	if err != nil {
+		return stats, err
+	}
+	// the spans are those of the items the encoder recorded as written
+	for n, span := range spans {
+		if n == len(written) {
+			break
+		}
+		stats.Items = append(stats.Items, ItemSize{Id: reportId(written[n]), Bytes: span[1] - span[0]})
+	}
+	return stats, nil
+}
+
+// record an item as written, in the order of the output, for WriteStats
+func (f *Feed) wrote(i *Item) {
+	if f.written != nil {
+		*f.written = append(*f.written, i)
+	}
+}
+
+// the start and end offsets of the top level elements named name in doc
+func xmlItemSpans(doc []byte, name string) ([][2]int64, error) {
+	var spans [][2]int64
+	d := xml.NewDecoder(bytes.NewReader(doc))
+	depth := 0 // inside an item
+	for {
+		start := d.InputOffset()
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			return spans, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			if depth > 0 {
+				depth++
+			} else if tok.Name.Local == name {
+				depth = 1
+				spans = append(spans, [2]int64{start, 0})
+			}
+		case xml.EndElement:
+			if depth > 0 {
+				if depth--; depth == 0 {
+					spans[len(spans)-1][1] = d.InputOffset()
+				}
+			}
+		}
+	}
+}
+
+// the start and end offsets of the objects in the items array of a json
+// feed document
+func jsonItemSpans(doc []byte) [][2]int64 {
+	var spans [][2]int64
+	depth, inItems := 0, false
+	var key, last []byte // the last key of the feed object, the last string
+	for n := 0; n < len(doc); n++ {
+		switch c := doc[n]; c {
+		case '"':
+			start := n + 1
+			for n++; n < len(doc) && doc[n] != '"'; n++ {
+				if doc[n] == '\\' {
+					n++
+				}
+			}
+			last = doc[start:n]
+		case ':':
+			if depth == 1 {
+				key = last
+			}
+		case '{', '[':
+			if inItems && depth == 2 {
+				spans = append(spans, [2]int64{int64(n), 0})
+			}
+			if c == '[' && depth == 1 && string(key) == "items" {
+				inItems = true
+			}
+			depth++
+		case '}', ']':
+			depth--
+			if inItems && depth == 2 {
+				spans[len(spans)-1][1] = int64(n + 1)
+			}
+			if depth == 1 {
+				inItems = false
+			}
+		}
+	}
+	return spans
+}
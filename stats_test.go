@@ -0,0 +1,177 @@
+package feeds
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteStats(t *testing.T) {
+	feed := sizeTestFeed()
+	// an empty feed falls back to its created date as its lastBuildDate
+	feed.Updated = feed.Created
+	empty := *feed
+	empty.Items = nil
+
+	// the whitespace written before each item
+	separators := map[FeedType]string{
+		FeedTypeRss:       "\n    ",
+		FeedTypeAtom:      "\n  ",
+		FeedTypeAmazonRss: "\n    ",
+	}
+	for format, separator := range separators {
+		var buf bytes.Buffer
+		stats, err := feed.WriteStats(&buf, format, StatsOptions{CollectItemSizes: true})
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if stats.Bytes != int64(buf.Len()) {
+			t.Errorf("%s: got %d bytes, wrote %d", format, stats.Bytes, buf.Len())
+		}
+		if len(stats.Items) != len(feed.Items) {
+			t.Fatalf("%s: got sizes of %d items, want %d", format, len(stats.Items), len(feed.Items))
+		}
+		envelope, err := encodedSize(&empty, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if format == FeedTypeAmazonRss {
+			// amazon rss only declares the content namespace with content
+			envelope += int64(len(` xmlns:content="` + contentNamespace + `"`))
+		}
+		total := envelope
+		for n, i := range stats.Items {
+			if i.Id != feed.Items[n].Id {
+				t.Errorf("%s: item %d has id %q, want %q", format, n, i.Id, feed.Items[n].Id)
+			}
+			total += i.Bytes + int64(len(separator))
+		}
+		if total != stats.Bytes {
+			t.Errorf("%s: items and envelope add up to %d bytes, want %d", format, total, stats.Bytes)
+		}
+	}
+
+	var buf bytes.Buffer
+	stats, err := feed.WriteStats(&buf, FeedTypeJSON, StatsOptions{CollectItemSizes: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := (&JSON{feed}).JSONFeed().Items
+	if len(stats.Items) != len(items) {
+		t.Fatalf("json: got sizes of %d items, want %d", len(stats.Items), len(items))
+	}
+	for n, i := range items {
+		data, err := json.MarshalIndent(i, "    ", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.Items[n].Bytes != int64(len(data)) {
+			t.Errorf("json: item %d has %d bytes, want %d", n, stats.Items[n].Bytes, len(data))
+		}
+	}
+
+	largest := stats.Largest(3)
+	if len(largest) != 3 {
+		t.Fatalf("got %d largest items, want 3", len(largest))
+	}
+	for _, i := range stats.Items {
+		if i.Bytes > largest[2].Bytes && i.Id != largest[0].Id && i.Id != largest[1].Id {
+			t.Errorf("%s with %d bytes is larger than the largest items %+v", i.Id, i.Bytes, largest)
+		}
+	}
+}
+
+func TestWriteStatsWithoutItemSizes(t *testing.T) {
+	var buf bytes.Buffer
+	stats, err := sizeTestFeed().WriteStats(&buf, FeedTypeRss, StatsOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Bytes != int64(buf.Len()) || stats.Items != nil {
+		t.Errorf("got %d bytes and items %v, want %d bytes and no items", stats.Bytes, stats.Items, buf.Len())
+	}
+}
+
+// a feed of three items of different sizes
+func statsTestFeed() *Feed {
+	feed := testFeed()
+	for n, id := range []string{"a", "b", "c"} {
+		feed.Add(&Item{
+			Title:   "Post " + id,
+			Link:    &Link{Href: "http://jmoiron.net/blog/" + id + "/"},
+			Id:      id,
+			Content: strings.Repeat("<p>footie</p>", 1+n*5),
+			Created: testCreated.Add(time.Duration(n) * time.Hour),
+		})
+	}
+	return feed
+}
+
+// the sizes of the items of the feed written as format, by id
+func itemSizes(t *testing.T, feed *Feed, format FeedType) ([]string, map[string]int64) {
+	stats, err := feed.WriteStats(ioutil.Discard, format, StatsOptions{CollectItemSizes: true})
+	if err != nil {
+		t.Fatalf("%s: %v", format, err)
+	}
+	var ids []string
+	sizes := map[string]int64{}
+	for _, i := range stats.Items {
+		ids = append(ids, i.Id)
+		sizes[i.Id] = i.Bytes
+	}
+	return ids, sizes
+}
+
+func TestWriteStatsDroppedItems(t *testing.T) {
+	all := []FeedType{FeedTypeRss, FeedTypeAtom, FeedTypeJSON, FeedTypeAmazonRss}
+	for _, test := range []struct {
+		name    string
+		formats []FeedType
+		drop    func(f *Feed) // makes writing the feed leave items out
+		want    []string
+	}{
+		{"duplicate", all, func(f *Feed) {
+			f.OnDuplicateID = DuplicateIDKeepFirst
+			f.Items[1].Id = "a"
+		}, []string{"a", "c"}},
+		// json has no items which fail to encode
+		{"failing", []FeedType{FeedTypeRss, FeedTypeAtom, FeedTypeAmazonRss}, func(f *Feed) {
+			f.FailureMode = SkipAndReport
+			f.Items[1].ViaURL = "http://jmoiron.net/%zz"
+			f.Items[1].Amazon = &AmazonItem{ContentKind: AmazonVideo}
+		}, []string{"a", "c"}},
+		{"scrubbed", all, func(f *Feed) {
+			f.LinkPolicy = &LinkPolicy{Deny: []string{"staging.jmoiron.net"}, Scrub: true}
+			f.Items[0].Link.Href = "http://staging.jmoiron.net/blog/a/"
+		}, []string{"b", "c"}},
+		// amazon rss items are not split
+		{"split", []FeedType{FeedTypeRss, FeedTypeAtom, FeedTypeJSON}, func(f *Feed) {
+			f.SplitOversizedItems = &SplitOversizedItems{MaxBytes: 200}
+			f.Items[0].Content = strings.Repeat("<p>footie</p>", 20)
+		}, []string{"a", "a-part2", "b", "c"}},
+	} {
+		for _, format := range test.formats {
+			_, full := itemSizes(t, statsTestFeed(), format)
+			feed := statsTestFeed()
+			test.drop(feed)
+			ids, sizes := itemSizes(t, feed, format)
+			if !reflect.DeepEqual(ids, test.want) {
+				t.Errorf("%s %s: got sizes of %v, want %v", test.name, format, ids, test.want)
+			}
+			// the other items are written as in the full feed, but for
+			// the first part of the split item
+			for id, size := range sizes {
+				if test.name == "split" && id == "a" {
+					continue
+				}
+				if want, ok := full[id]; ok && size != want {
+					t.Errorf("%s %s: item %s has %d bytes, want %d", test.name, format, id, size, want)
+				}
+			}
+		}
+	}
+}
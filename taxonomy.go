@@ -0,0 +1,35 @@
+package feeds
+
+import (
+	"fmt"
+)
+
+// the value of the feed's Category in the taxonomy of a destination of its
+// TaxonomyMap, or the Category as it is when the taxonomy has no value for
+// it. ok is false when the map has no such destination or there is no
+// Category to map.
+func (f *Feed) taxonomy(destination string) (value string, ok bool) {
+	values, has := f.TaxonomyMap[destination]
+	if !has || f.Category == "" {
+		return "", false
+	}
+	if v, mapped := values[f.Category]; mapped {
+		return v, true
+	}
+	return f.Category, true
+}
+
+// a Rule failing when the TaxonomyMap has the destination but no value for
+// the Category of the feed, which would be written as it is
+func mappedCategory(destination string) Rule {
+	return func(f *Feed) error {
+		values, has := f.TaxonomyMap[destination]
+		if !has || f.Category == "" {
+			return nil
+		}
+		if _, mapped := values[f.Category]; !mapped {
+			return fmt.Errorf("category %q has no %s value in the taxonomy map", f.Category, destination)
+		}
+		return nil
+	}
+}
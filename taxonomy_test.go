@@ -0,0 +1,76 @@
+package feeds
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTaxonomyMap(t *testing.T) {
+	feed := profileTestFeed()
+	feed.Category = "programming"
+	for _, to := range []func() (string, error){feed.ToRss, feed.ToAmazonRss} {
+		s, err := to()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if strings.Contains(s, "programming") || strings.Contains(s, "itunes") || strings.Contains(s, "amzn:section") {
+			t.Errorf("expected the category only through the taxonomy map.  Got:\n%s\n", s)
+		}
+	}
+
+	feed.TaxonomyMap = map[string]map[string]string{
+		"itunes":     {"programming": "Technology"},
+		"amazon":     {"programming": "tech"},
+		"googlenews": {"programming": "Technology"},
+	}
+	rss, err := feed.ToRss()
+	if err != nil {
+		t.Fatalf("unexpected error encoding RSS: %v", err)
+	}
+	for _, want := range []string{
+		`xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd"`,
+		`<itunes:category text="Technology"></itunes:category>`,
+	} {
+		if !strings.Contains(rss, want) {
+			t.Errorf("Rss missing %s.  Got:\n%s\n", want, rss)
+		}
+	}
+	amazon, err := feed.ToAmazonRss()
+	if err != nil {
+		t.Fatalf("unexpected error encoding Amazon Rss: %v", err)
+	}
+	if !strings.Contains(amazon, "<amzn:section>tech</amzn:section>") {
+		t.Errorf("Amazon Rss missing the mapped section.  Got:\n%s\n", amazon)
+	}
+
+	// the section of an item and its Suppress win over the taxonomy
+	feed.Items[0].Amazon.Section = "golang"
+	if amazon, _ = feed.ToAmazonRss(); !strings.Contains(amazon, "<amzn:section>golang</amzn:section>") {
+		t.Errorf("expected the item's own section.  Got:\n%s\n", amazon)
+	}
+	feed.Items[0].Amazon.Section = ""
+	feed.Items[0].Amazon.Suppress = AmazonSection
+	if amazon, _ = feed.ToAmazonRss(); strings.Contains(amazon, "amzn:section") {
+		t.Errorf("expected a suppressed section.  Got:\n%s\n", amazon)
+	}
+	feed.Items[0].Amazon.Suppress = 0
+
+	// unmapped categories are written as they are, but fail strict profiles
+	feed.Category = "footie"
+	if rss, _ = feed.ToRss(); !strings.Contains(rss, `<itunes:category text="footie"></itunes:category>`) {
+		t.Errorf("expected the unmapped category as it is.  Got:\n%s\n", rss)
+	}
+	for _, p := range []*Profile{AmazonStrict(), GoogleNews(), ApplePodcasts()} {
+		err := p.Write(feed, &bytes.Buffer{})
+		if err == nil || !strings.Contains(err.Error(), `category "footie" has no`) {
+			t.Errorf("%s: expected an unmapped category error, got %v", p.Name, err)
+		}
+	}
+	feed.TaxonomyMap = map[string]map[string]string{"itunes": {}}
+	for _, p := range []*Profile{AmazonStrict(), GoogleNews()} {
+		if err := p.Write(feed, &bytes.Buffer{}); err != nil {
+			t.Errorf("%s: expected no error without its taxonomy, got %v", p.Name, err)
+		}
+	}
+}
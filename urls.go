@@ -0,0 +1,226 @@
+package feeds
+
+import (
+	"net/url"
+)
+
+// URLKind is what a url written to a feed points at.
+type URLKind int
+
+const (
+	URLLink       URLKind = iota // the links of the feed and its items
+	URLEnclosure                 // the enclosures and media of the items
+	URLImage                     // the feed's image and icons, json item images
+	URLHeroImage                 // amazon hero images and video posters
+	URLProductURL                // amazon product urls
+)
+
+// A URLRewriter returns the url to write for rawurl when a feed is encoded
+// as format, like the url of another host for some of the formats. It is
+// called with the urls of the generated document, so the Feed is left as
+// it is. Every url attribute and element the encoders write is rewritten,
+// except the ids of the feed and its items, which name them rather than
+// link to them, and the urls inside item descriptions and content.
+type URLRewriter func(format FeedType, kind URLKind, rawurl string) string
+
+// HostSwapRewriter returns a URLRewriter replacing the hosts of the urls
+// which are keys of hosts with their values, for every format and kind.
+// Other urls, and those which do not parse, are left as they are.
+func HostSwapRewriter(hosts map[string]string) URLRewriter {
+	return func(format FeedType, kind URLKind, rawurl string) string {
+		u, err := url.Parse(rawurl)
+		if err != nil {
+			return rawurl
+		}
+		host, ok := hosts[u.Host]
+		if !ok {
+			return rawurl
+		}
+		u.Host = host
+		return u.String()
+	}
+}
+
+// rewrite the url in place with the feed's URLRewriter
+func (f *Feed) rewriteURL(format FeedType, kind URLKind, rawurl *string) {
+	if f.URLRewriter != nil && *rawurl != "" {
+		*rawurl = f.URLRewriter(format, kind, *rawurl)
+	}
+}
+
+func (f *Feed) rewriteRssURLs(r *RssFeed) {
+	if f.URLRewriter == nil {
+		return
+	}
+	f.rewriteURL(FeedTypeRss, URLLink, &r.Link)
+	if r.Image != nil {
+		f.rewriteURL(FeedTypeRss, URLImage, &r.Image.Url)
+		f.rewriteURL(FeedTypeRss, URLLink, &r.Image.Link)
+	}
+	for _, l := range r.AtomLinks {
+		f.rewriteURL(FeedTypeRss, URLLink, &l.Href)
+	}
+	f.rewriteWebfeedsURLs(FeedTypeRss, r.RssWebfeeds)
+	for _, i := range r.Items {
+		f.rewriteURL(FeedTypeRss, URLLink, &i.Link)
+		f.rewriteURL(FeedTypeRss, URLLink, &i.Source)
+		if i.Enclosure != nil {
+			f.rewriteURL(FeedTypeRss, URLEnclosure, &i.Enclosure.Url)
+		}
+		if i.MediaContent != nil {
+			f.rewriteURL(FeedTypeRss, URLEnclosure, &i.MediaContent.Url)
+			if i.MediaContent.Embed != nil {
+				f.rewriteURL(FeedTypeRss, URLEnclosure, &i.MediaContent.Embed.Url)
+			}
+		}
+		if i.MediaPlayer != nil {
+			f.rewriteURL(FeedTypeRss, URLEnclosure, &i.MediaPlayer.Url)
+		}
+		if i.BackLinks != nil {
+			// the back links are the item's own, so rewrite a copy of them
+			links := make([]string, len(i.BackLinks.BackLinks))
+			for n, l := range i.BackLinks.BackLinks {
+				links[n] = l
+				f.rewriteURL(FeedTypeRss, URLLink, &links[n])
+			}
+			i.BackLinks = &RssMediaBackLinks{BackLinks: links}
+		}
+		for _, l := range i.AtomLinks {
+			f.rewriteURL(FeedTypeRss, URLLink, &l.Href)
+		}
+	}
+}
+
+func (f *Feed) rewriteWebfeedsURLs(format FeedType, w *RssWebfeeds) {
+	if w == nil {
+		return
+	}
+	if w.Cover != nil {
+		f.rewriteURL(format, URLImage, &w.Cover.Image)
+	}
+	f.rewriteURL(format, URLImage, &w.Icon)
+	f.rewriteURL(format, URLImage, &w.Logo)
+}
+
+func (f *Feed) rewriteAtomURLs(a *AtomFeed) {
+	if f.URLRewriter == nil {
+		return
+	}
+	rewriteLinks := func(links []AtomLink) {
+		for n := range links {
+			kind := URLLink
+			switch links[n].Rel {
+			case "enclosure":
+				kind = URLEnclosure
+			case "icon", "logo":
+				kind = URLImage
+			}
+			f.rewriteURL(FeedTypeAtom, kind, &links[n].Href)
+		}
+	}
+	rewritePerson := func(p *AtomPerson) {
+		f.rewriteURL(FeedTypeAtom, URLLink, &p.Uri)
+	}
+	f.rewriteURL(FeedTypeAtom, URLImage, &a.Icon)
+	f.rewriteURL(FeedTypeAtom, URLImage, &a.Logo)
+	if a.Generator != nil {
+		f.rewriteURL(FeedTypeAtom, URLLink, &a.Generator.Uri)
+	}
+	if a.Link != nil {
+		f.rewriteURL(FeedTypeAtom, URLLink, &a.Link.Href)
+	}
+	rewriteLinks(a.Links)
+	if a.Author != nil {
+		rewritePerson(&a.Author.AtomPerson)
+	}
+	if a.Contributor != nil {
+		rewritePerson(&a.Contributor.AtomPerson)
+	}
+	for _, e := range a.Entries {
+		rewriteLinks(e.Links)
+		if e.Author != nil {
+			rewritePerson(&e.Author.AtomPerson)
+		}
+		if e.Contributor != nil {
+			rewritePerson(&e.Contributor.AtomPerson)
+		}
+		for _, p := range e.Authors {
+			rewritePerson(&p.AtomPerson)
+		}
+		for _, p := range e.Contributors {
+			rewritePerson(&p.AtomPerson)
+		}
+	}
+}
+
+func (f *Feed) rewriteJSONURLs(j *JSONFeed) {
+	if f.URLRewriter == nil {
+		return
+	}
+	f.rewriteURL(FeedTypeJSON, URLLink, &j.HomePageUrl)
+	f.rewriteURL(FeedTypeJSON, URLLink, &j.FeedUrl)
+	for _, a := range j.Alternates {
+		f.rewriteURL(FeedTypeJSON, URLLink, &a.Url)
+	}
+	for _, a := range j.AlternateLanguages {
+		f.rewriteURL(FeedTypeJSON, URLLink, &a.Url)
+	}
+	f.rewriteURL(FeedTypeJSON, URLImage, &j.Icon)
+	f.rewriteURL(FeedTypeJSON, URLImage, &j.Favicon)
+	rewriteAuthor := func(a *JSONAuthor) {
+		if a != nil {
+			f.rewriteURL(FeedTypeJSON, URLLink, &a.Url)
+			f.rewriteURL(FeedTypeJSON, URLImage, &a.Avatar)
+		}
+	}
+	rewriteAuthor(j.Author)
+	for _, i := range j.Items {
+		rewriteAuthor(i.Author)
+		f.rewriteURL(FeedTypeJSON, URLLink, &i.Url)
+		f.rewriteURL(FeedTypeJSON, URLLink, &i.ExternalUrl)
+		f.rewriteURL(FeedTypeJSON, URLImage, &i.Image)
+		for n := range i.Attachments {
+			f.rewriteURL(FeedTypeJSON, URLEnclosure, &i.Attachments[n].Url)
+		}
+		for _, a := range i.AlternateLanguages {
+			f.rewriteURL(FeedTypeJSON, URLLink, &a.Url)
+		}
+		if i.Source != nil {
+			f.rewriteURL(FeedTypeJSON, URLLink, &i.Source.Url)
+		}
+	}
+}
+
+func (f *Feed) rewriteAmazonRssURLs(r *AmazonRssFeed) {
+	if f.URLRewriter == nil {
+		return
+	}
+	f.rewriteURL(FeedTypeAmazonRss, URLLink, &r.Link)
+	if r.Image != nil {
+		f.rewriteURL(FeedTypeAmazonRss, URLImage, &r.Image.Url)
+		f.rewriteURL(FeedTypeAmazonRss, URLLink, &r.Image.Link)
+	}
+	for _, l := range r.AtomLinks {
+		f.rewriteURL(FeedTypeAmazonRss, URLLink, &l.Href)
+	}
+	f.rewriteWebfeedsURLs(FeedTypeAmazonRss, r.RssWebfeeds)
+	for _, i := range r.Items {
+		f.rewriteURL(FeedTypeAmazonRss, URLLink, &i.Link)
+		f.rewriteURL(FeedTypeAmazonRss, URLLink, &i.Source)
+		if i.Enclosure != nil {
+			f.rewriteURL(FeedTypeAmazonRss, URLEnclosure, &i.Enclosure.Url)
+		}
+		f.rewriteURL(FeedTypeAmazonRss, URLHeroImage, &i.HeroImage)
+		f.rewriteURL(FeedTypeAmazonRss, URLHeroImage, &i.VideoPoster)
+		if i.Products != nil {
+			// the products are the item's own, so rewrite copies of them
+			products := make([]*AmazonProduct, len(i.Products.Products))
+			for n, p := range i.Products.Products {
+				product := *p
+				f.rewriteURL(FeedTypeAmazonRss, URLProductURL, &product.URL)
+				products[n] = &product
+			}
+			i.Products = &AmazonProducts{Products: products}
+		}
+	}
+}
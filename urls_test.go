@@ -0,0 +1,156 @@
+package feeds
+
+import (
+	"strings"
+	"testing"
+)
+
+func urlsTestFeed() *Feed {
+	feed := testFeed(&Item{
+		Title:     "Best Headphones of 2019",
+		Link:      &Link{Href: "http://jmoiron.net/blog/best-headphones/"},
+		Id:        "best-headphones",
+		Thumbnail: "http://media.jmoiron.net/headphones.jpg",
+		Enclosure: &Enclosure{Url: "http://media.jmoiron.net/headphones.mp4", Type: "video/mp4", Length: "123"},
+		Amazon: &AmazonItem{
+			HeroImage: "http://media.jmoiron.net/hero.jpg",
+			Products:  []*AmazonProduct{{URL: "https://www.amazon.com/dp/B01"}},
+		},
+	})
+	feed.Image = &Image{Url: "http://media.jmoiron.net/logo.png", Title: "jmoiron.net", Link: "http://jmoiron.net/blog"}
+	return feed
+}
+
+func TestURLRewriter(t *testing.T) {
+	cdn := HostSwapRewriter(map[string]string{"media.jmoiron.net": "cdn.example.com"})
+	origin := HostSwapRewriter(map[string]string{"media.jmoiron.net": "origin.example.com"})
+	var kinds []URLKind
+	feed := urlsTestFeed()
+	feed.URLRewriter = func(format FeedType, kind URLKind, rawurl string) string {
+		if format != FeedTypeAmazonRss {
+			return cdn(format, kind, rawurl)
+		}
+		kinds = append(kinds, kind)
+		if kind == URLProductURL {
+			return rawurl + "?tag=jmoiron-20"
+		}
+		return origin(format, kind, rawurl)
+	}
+
+	rss, err := feed.ToRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding RSS: %v", err)
+	}
+	feed.AllowGenerationTimeFallback = true
+	atom, err := ToXML(&Atom{Feed: feed})
+	if err != nil {
+		t.Errorf("unexpected error encoding Atom: %v", err)
+	}
+	json, err := feed.ToJSON()
+	if err != nil {
+		t.Errorf("unexpected error encoding JSON: %v", err)
+	}
+	for name, out := range map[string]string{"Rss": rss, "Atom": atom, "JSON": json} {
+		if !strings.Contains(out, "http://cdn.example.com/") || strings.Contains(out, "media.jmoiron.net") {
+			t.Errorf("%s should use the cdn.  Got:\n%s\n", name, out)
+		}
+		if !strings.Contains(out, "http://jmoiron.net/blog/best-headphones/") {
+			t.Errorf("%s should keep the other links.  Got:\n%s\n", name, out)
+		}
+	}
+
+	amazon, err := feed.ToAmazonRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding Amazon RSS: %v", err)
+	}
+	for _, want := range []string{
+		"<url>http://origin.example.com/logo.png</url>",
+		`<enclosure url="http://origin.example.com/headphones.mp4"`,
+		"<amzn:heroImage>http://origin.example.com/hero.jpg</amzn:heroImage>",
+		"<amzn:productURL>https://www.amazon.com/dp/B01?tag=jmoiron-20</amzn:productURL>",
+	} {
+		if !strings.Contains(amazon, want) {
+			t.Errorf("AmazonRss missing %s.  Got:\n%s\n", want, amazon)
+		}
+	}
+	want := []URLKind{URLLink, URLImage, URLLink, URLLink, URLEnclosure, URLHeroImage, URLProductURL}
+	if len(kinds) != len(want) {
+		t.Fatalf("got url kinds %v, want %v", kinds, want)
+	}
+	for n := range want {
+		if kinds[n] != want[n] {
+			t.Errorf("got url kinds %v, want %v", kinds, want)
+			break
+		}
+	}
+
+	// the feed is left as it is
+	unchanged := urlsTestFeed()
+	if feed.Image.Url != unchanged.Image.Url || feed.Items[0].Enclosure.Url != unchanged.Items[0].Enclosure.Url ||
+		feed.Items[0].Amazon.HeroImage != unchanged.Items[0].Amazon.HeroImage ||
+		feed.Items[0].Amazon.Products[0].URL != unchanged.Items[0].Amazon.Products[0].URL {
+		t.Errorf("rewriting the urls changed the feed")
+	}
+}
+
+func TestURLRewriterMedia(t *testing.T) {
+	feed := urlsTestFeed()
+	feed.URLRewriter = HostSwapRewriter(map[string]string{"media.jmoiron.net": "cdn.example.com"})
+	feed.Generator = &Generator{Value: "jmoiron.net", URI: "http://media.jmoiron.net/generator"}
+	feed.Webfeeds = &Webfeeds{
+		CoverImage: "http://media.jmoiron.net/cover.jpg",
+		Icon:       "http://media.jmoiron.net/icon.png",
+		Logo:       "http://media.jmoiron.net/logo.svg",
+	}
+	item := feed.Items[0]
+	item.Enclosure.Embed = &MediaEmbed{URL: "http://media.jmoiron.net/embed"}
+	item.MediaPlayer = &MediaPlayer{URL: "http://media.jmoiron.net/player"}
+	item.MediaBackLinks = []string{"http://media.jmoiron.net/headphones"}
+
+	rss, err := feed.ToRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding RSS: %v", err)
+	}
+	feed.AllowGenerationTimeFallback = true
+	atom, err := ToXML(&Atom{Feed: feed})
+	if err != nil {
+		t.Errorf("unexpected error encoding Atom: %v", err)
+	}
+	for name, out := range map[string]string{"Rss": rss, "Atom": atom} {
+		if strings.Contains(out, "media.jmoiron.net") {
+			t.Errorf("%s should use the cdn.  Got:\n%s\n", name, out)
+		}
+	}
+	for _, want := range []string{
+		`<webfeeds:cover image="http://cdn.example.com/cover.jpg">`,
+		"<webfeeds:icon>http://cdn.example.com/icon.png</webfeeds:icon>",
+		`<media:embed url="http://cdn.example.com/embed">`,
+		`<media:player url="http://cdn.example.com/player">`,
+		"<media:backLink>http://cdn.example.com/headphones</media:backLink>",
+	} {
+		if !strings.Contains(rss, want) {
+			t.Errorf("Rss missing %s.  Got:\n%s\n", want, rss)
+		}
+	}
+	if want := `uri="http://cdn.example.com/generator"`; !strings.Contains(atom, want) {
+		t.Errorf("Atom missing %s.  Got:\n%s\n", want, atom)
+	}
+	if item.MediaBackLinks[0] != "http://media.jmoiron.net/headphones" {
+		t.Errorf("rewriting the urls changed the feed's back links")
+	}
+}
+
+func TestHostSwapRewriter(t *testing.T) {
+	swap := HostSwapRewriter(map[string]string{"a.example.com": "b.example.com", "c.example.com:8080": "d.example.com"})
+	for in, want := range map[string]string{
+		"https://a.example.com/x.mp3?y=1#z": "https://b.example.com/x.mp3?y=1#z",
+		"https://c.example.com:8080/x.mp3":  "https://d.example.com/x.mp3",
+		"https://c.example.com/x.mp3":       "https://c.example.com/x.mp3",
+		"https://e.example.com/x.mp3":       "https://e.example.com/x.mp3",
+		"http://a.example.com/%zz":          "http://a.example.com/%zz",
+	} {
+		if got := swap(FeedTypeRss, URLEnclosure, in); got != want {
+			t.Errorf("swap(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
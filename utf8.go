@@ -0,0 +1,135 @@
+package feeds
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"unicode/utf8"
+)
+
+// UTF8Mode is how the strings of a feed which are not valid utf-8, like
+// text with an unpaired surrogate from a bad conversion, are handled when
+// it is generated by its To and Write methods.
+type UTF8Mode int
+
+const (
+	// UTF8Unchecked leaves them to encoding/xml and encoding/json, which
+	// write U+FFFD for them except in cdata, like the rss content.
+	UTF8Unchecked UTF8Mode = iota
+	// UTF8Strict fails generating the feed with a *FieldError.
+	UTF8Strict
+	// UTF8Replace writes U+FFFD for every invalid sequence, logging an
+	// EventWarning for each field which has one.
+	UTF8Replace
+)
+
+var errInvalidUTF8 = errors.New("not valid utf-8")
+
+// FieldError is an error with a field of a Feed, named by its path, like
+// Title or Items[2].Author.Name.
+type FieldError struct {
+	Field string
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("feeds: %s: %v", e.Field, e.Err)
+}
+
+// the paths of the strings in v which are not valid utf-8, appended to
+// found; funcs and interface fields, like the Logger, are not looked into
+func invalidUTF8(path string, v reflect.Value, found []string) []string {
+	switch v.Kind() {
+	case reflect.String:
+		if !utf8.ValidString(v.String()) {
+			found = append(found, path)
+		}
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			found = invalidUTF8(path, v.Elem(), found)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for n := 0; n < t.NumField(); n++ {
+			field := t.Field(n)
+			if field.PkgPath != "" || field.Type.Kind() == reflect.Interface {
+				continue
+			}
+			name := field.Name
+			if path != "" {
+				name = path + "." + name
+			}
+			found = invalidUTF8(name, v.Field(n), found)
+		}
+	case reflect.Slice, reflect.Array:
+		for n := 0; n < v.Len(); n++ {
+			found = invalidUTF8(fmt.Sprintf("%s[%d]", path, n), v.Index(n), found)
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(a, b int) bool {
+			return fmt.Sprint(keys[a]) < fmt.Sprint(keys[b])
+		})
+		for _, key := range keys {
+			name := fmt.Sprintf("%s[%q]", path, key)
+			if key.Kind() == reflect.String && !utf8.ValidString(key.String()) {
+				found = append(found, name)
+			}
+			found = invalidUTF8(name, v.MapIndex(key), found)
+		}
+	}
+	return found
+}
+
+// the paths of the fields of the feed which are not valid utf-8, in the
+// order of the fields and of the map keys
+func (f *Feed) invalidUTF8() []string {
+	return invalidUTF8("", reflect.ValueOf(*f), nil)
+}
+
+// check the strings of the feed according to its CheckUTF8 mode
+func (f *Feed) checkUTF8() error {
+	if f.CheckUTF8 == UTF8Unchecked {
+		return nil
+	}
+	for _, field := range f.invalidUTF8() {
+		if f.CheckUTF8 == UTF8Strict {
+			return &FieldError{Field: field, Err: errInvalidUTF8}
+		}
+		f.log(EventWarning, "reason", field+" is not valid utf-8, written with U+FFFD")
+	}
+	return nil
+}
+
+// doc with every invalid utf-8 sequence replaced by U+FFFD
+func toValidUTF8(doc []byte) []byte {
+	if utf8.Valid(doc) {
+		return doc
+	}
+	var out bytes.Buffer
+	invalid := false
+	for len(doc) > 0 {
+		r, size := utf8.DecodeRune(doc)
+		if r == utf8.RuneError && size == 1 {
+			if !invalid {
+				out.WriteRune(utf8.RuneError)
+			}
+			invalid = true
+		} else {
+			out.Write(doc[:size])
+			invalid = false
+		}
+		doc = doc[size:]
+	}
+	return out.Bytes()
+}
+
+// a Rule failing for the first string of the feed which is not valid utf-8
+func validUTF8(f *Feed) error {
+	if found := f.invalidUTF8(); len(found) > 0 {
+		return &FieldError{Field: found[0], Err: errInvalidUTF8}
+	}
+	return nil
+}
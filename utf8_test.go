@@ -0,0 +1,113 @@
+package feeds
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// an unpaired surrogate as a bad conversion from utf-16 leaves it
+const unpairedSurrogate = "\xed\xa0\x80"
+
+func utf8TestFeed() *Feed {
+	feed := testFeed(
+		&Item{Title: "Limiting Concurrency in Go", Id: "limiting-concurrency"},
+		&Item{Title: "Logic-less Template Redux", Id: "logicless-template-redux", Content: "<p>bad \xff\xfe content</p>"},
+	)
+	feed.Title += " " + unpairedSurrogate
+	feed.Author = &Author{Name: "Jason Moiron", JSONExtensions: map[string]interface{}{"_b": "ok", "_a": "bad \xff"}}
+	return feed
+}
+
+func TestInvalidUTF8Fields(t *testing.T) {
+	want := []string{"Title", `Author.JSONExtensions["_a"]`, "Items[1].Content"}
+	if got := utf8TestFeed().invalidUTF8(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got invalid fields %q, want %q", got, want)
+	}
+	feed := utf8TestFeed()
+	feed.Title, feed.Author, feed.Items[1].Content = "jmoiron.net blog", nil, ""
+	if got := feed.invalidUTF8(); len(got) != 0 {
+		t.Errorf("got invalid fields %q for a valid feed", got)
+	}
+}
+
+func TestCheckUTF8Strict(t *testing.T) {
+	feed := utf8TestFeed()
+	if _, err := feed.ToAtom(); err != nil {
+		t.Errorf("unexpected error without CheckUTF8: %v", err)
+	}
+
+	feed.CheckUTF8 = UTF8Strict
+	for name, write := range map[string]func(*Feed) (string, error){
+		"Rss":       (*Feed).ToRss,
+		"Atom":      (*Feed).ToAtom,
+		"JSON":      (*Feed).ToJSON,
+		"AmazonRss": (*Feed).ToAmazonRss,
+	} {
+		out, err := write(feed)
+		ferr, ok := err.(*FieldError)
+		if !ok || ferr.Field != "Title" || out != "" {
+			t.Errorf("%s: expected a *FieldError for the title, got %v:\n%s", name, err, out)
+		}
+	}
+	if err := feed.WriteRss(&bytes.Buffer{}); err == nil {
+		t.Errorf("expected an error writing Rss")
+	}
+
+	verr, ok := feed.ValidateRSS().(*ValidationError)
+	if !ok {
+		t.Fatalf("expected a *ValidationError, got %v", feed.ValidateRSS())
+	}
+	want := []string{
+		"Title is not valid utf-8",
+		`Author.JSONExtensions["_a"] is not valid utf-8`,
+		"Items[1].Content is not valid utf-8",
+	}
+	if !reflect.DeepEqual(verr.Violations, want) {
+		t.Errorf("got violations %q, want %q", verr.Violations, want)
+	}
+
+	feed.CheckUTF8 = UTF8Unchecked
+	if err := AmazonStrict().Validate(feed); err == nil || !strings.Contains(err.Error(), "Title: not valid utf-8") {
+		t.Errorf("expected the amazon profile to fail on the title, got %v", err)
+	}
+}
+
+func TestCheckUTF8Replace(t *testing.T) {
+	feed := utf8TestFeed()
+	feed.CheckUTF8 = UTF8Replace
+	var warnings []string
+	feed.Logger = LoggerFunc(func(event string, keyvals ...interface{}) {
+		if event == EventWarning {
+			warnings = append(warnings, fmt.Sprint(keyvals...))
+		}
+	})
+	for name, write := range map[string]func(*Feed) (string, error){
+		"Rss":  (*Feed).ToRss,
+		"Atom": (*Feed).ToAtom,
+		"JSON": (*Feed).ToJSON,
+	} {
+		warnings = nil
+		out, err := write(feed)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+		}
+		if !utf8.ValidString(out) {
+			t.Errorf("%s: output is not valid utf-8:\n%q", name, out)
+		}
+		if len(warnings) != 3 || !strings.Contains(warnings[0], "Title is not valid utf-8") {
+			t.Errorf("%s: got warnings %q", name, warnings)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := feed.WriteRss(&buf); err != nil {
+		t.Errorf("unexpected error writing Rss: %v", err)
+	}
+	if want := "<![CDATA[<p>bad \uFFFD content</p>]]>"; !strings.Contains(buf.String(), want) {
+		t.Errorf("Rss missing %s.  Got:\n%s\n", want, buf.String())
+	}
+}
@@ -4,6 +4,7 @@ package feeds
 
 import (
 	"crypto/rand"
+	"crypto/sha1"
 	"fmt"
 )
 
@@ -22,6 +23,23 @@ func NewUUID() *UUID {
 	return u
 }
 
+// the rfc 4122 namespace of uuids made from urls
+var urlNamespace = UUID{0x6b, 0xa7, 0xb8, 0x11, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}
+
+// create the uuid v5 of a name in the url namespace, the same for the
+// same name
+func newNameUUID(name string) *UUID {
+	h := sha1.New()
+	h.Write(urlNamespace[:])
+	h.Write([]byte(name))
+	u := &UUID{}
+	copy(u[:], h.Sum(nil))
+
+	u[8] = (u[8] | 0x80) & 0xBf
+	u[6] = (u[6] & 0x0f) | 0x50
+	return u
+}
+
 func (u *UUID) String() string {
 	return fmt.Sprintf("%x-%x-%x-%x-%x", u[:4], u[4:6], u[6:8], u[8:10], u[10:])
 }
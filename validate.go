@@ -0,0 +1,251 @@
+package feeds
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ValidationError is returned by the Validate methods of a Feed with all
+// the problems found in it for a format. Each of them also reports the
+// strings of the feed which are not valid utf-8.
+type ValidationError struct {
+	Type       FeedType
+	Violations []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("feeds: invalid %s feed: %s", e.Type, strings.Join(e.Violations, "; "))
+}
+
+// the failure of an atom entry without an updated date
+var errEntryUpdated = errors.New("updated is required")
+
+// the violation of an atom entry without an updated date
+func entryUpdatedRequired(n int, i *Item) string {
+	return fmt.Sprintf("item %d: %q: %v", n, reportId(i), errEntryUpdated)
+}
+
+// collects the violations of a feed
+type validator struct {
+	t          FeedType
+	violations []string
+}
+
+func (v *validator) check(ok bool, format string, args ...interface{}) {
+	if !ok {
+		v.violations = append(v.violations, fmt.Sprintf(format, args...))
+	}
+}
+
+// add a violation for each string of the feed which is not valid utf-8
+func (v *validator) checkUTF8(f *Feed) {
+	for _, field := range f.invalidUTF8() {
+		v.check(false, "%s is not valid utf-8", field)
+	}
+}
+
+// add a violation for each invalid json extension of the author of path
+func (v *validator) checkJSONExtensions(path string, a *Author) {
+	if a == nil {
+		return
+	}
+	keys := make([]string, 0, len(a.JSONExtensions))
+	for k := range a.JSONExtensions {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if err := validateJSONExtension(k, a.JSONExtensions[k]); err != nil {
+			v.check(false, "%s author: %v", path, err)
+		}
+	}
+}
+
+func (v *validator) err() error {
+	if len(v.violations) == 0 {
+		return nil
+	}
+	return &ValidationError{Type: v.t, Violations: v.violations}
+}
+
+// ValidateRSS checks the feed has the title, link and description rss
+// requires, that each of its items has a title or a description and valid
+// enclosures and media, that the hreflang of the links are language tags,
+// that the image is an http or https url of an image, that the
+// ITunesNewFeedURL is an http or https url and that no item without an Id
+// has the date and title of an earlier one, unless JitterIdenticalDates
+// moves it.
+func (f *Feed) ValidateRSS() error {
+	v := &validator{t: FeedTypeRss}
+	v.check(f.Title != "", "feed has no title")
+	v.check(f.Link != nil && f.Link.Href != "", "feed has no link")
+	v.check(f.Description != "", "feed has no description")
+	v.checkHrefLangs("feed", append([]*Link{f.Link}, f.Links...)...)
+	if f.Webfeeds != nil {
+		if err := f.Webfeeds.validate(); err != nil {
+			v.check(false, "%v", err)
+		}
+	}
+	if err := validateITunesNewFeedURL(f); err != nil {
+		v.check(false, "%v", err)
+	}
+	for n, i := range f.Items {
+		if !i.published() {
+			continue
+		}
+		v.check(i.Title != "" || i.Description != "", "item %d has neither a title nor a description", n)
+		if e := f.enclosureType(i).Enclosure; e != nil {
+			v.check(e.Url != "" && e.Type != "" && e.Length != "", "item %d enclosure needs a url, type and length", n)
+		}
+		if err := validateMedia(i); err != nil {
+			v.check(false, "item %d: %v", n, err)
+		}
+		if err := validatePodcast(i); err != nil {
+			v.check(false, "item %d: %v", n, err)
+		}
+		if err := i.validateVia(); err != nil {
+			v.check(false, "item %d: %v", n, err)
+		}
+		v.checkHrefLangs(fmt.Sprintf("item %d", n), append([]*Link{i.Link}, i.Links...)...)
+	}
+	v.checkIdenticalDates(f)
+	v.checkImages(f)
+	v.checkLinkPolicy(f)
+	v.checkUTF8(f)
+	return v.err()
+}
+
+// ValidateAmazonRss checks the feed has the title, link and description rss
+// requires, that its amazon ids have no surrounding whitespace, that each of
+// its items has a title or a description, that their explicit positions are
+// unique, that the video items have a video enclosure, that no item without
+// an Id has the date and title of an earlier one as ValidateRSS does, and
+// that the channel, hero, thumbnail and product images are http or https
+// urls of images. Amazon onsite does not accept explicit feeds.
+func (f *Feed) ValidateAmazonRss() error {
+	v := &validator{t: FeedTypeAmazonRss}
+	v.check(f.Title != "", "feed has no title")
+	v.check(f.Link != nil && f.Link.Href != "", "feed has no link")
+	v.check(f.Description != "", "feed has no description")
+	if err := f.Amazon.validate(); err != nil {
+		v.check(false, "%v", err)
+	}
+	v.check(f.Explicit == nil || !*f.Explicit, "feed is explicit, which amazon onsite does not accept")
+	positions := map[int]int{}
+	for n, i := range f.Items {
+		if !i.published() {
+			continue
+		}
+		v.check(i.Title != "" || i.Description != "", "item %d has neither a title nor a description", n)
+		if f.SplitOversizedItems.oversized(i) {
+			v.check(false, "item %d: %v", n, errContentOversized(i, f.SplitOversizedItems))
+		}
+		if err := validateAmazonVideo(f.enclosureType(i)); err != nil {
+			v.check(false, "item %d: %v", n, err)
+		}
+		if i.Amazon == nil || i.Amazon.Position == 0 {
+			continue
+		}
+		other, used := positions[i.Amazon.Position]
+		v.check(!used, "item %d: amzn:position %d is already used by item %d", n, i.Amazon.Position, other)
+		v.check(i.Amazon.Position > 0, "item %d: amzn:position %d is negative", n, i.Amazon.Position)
+		positions[i.Amazon.Position] = n
+	}
+	v.checkIdenticalDates(f)
+	v.checkImages(f)
+	v.checkLinkPolicy(f)
+	v.checkUTF8(f)
+	return v.err()
+}
+
+// ValidateAtom checks the feed has the id, title and updated date atom
+// requires, and that each of its entries has a title, a stable id (its Id,
+// or one made from its link and date) and an updated date of its own, that
+// the hreflang of the links are language tags, that the uri of the
+// generator is an absolute iri and that the image and icons are http or
+// https urls of images.
+func (f *Feed) ValidateAtom() error {
+	v := &validator{t: FeedTypeAtom}
+	v.check(f.Link != nil && f.Link.Href != "", "feed has no id, which is taken from its link")
+	v.check(f.Title != "", "feed has no title")
+	v.check((&Atom{Feed: f}).updated() != "", "feed has no updated date")
+	v.checkHrefLangs("feed", append([]*Link{f.Link}, f.Links...)...)
+	if err := f.Generator.validate(); err != nil {
+		v.check(false, "%v", err)
+	}
+	for n, i := range f.Items {
+		if !i.published() {
+			continue
+		}
+		v.check(i.Title != "", "item %d has no title", n)
+		v.check(i.Id != "" || (i.Link != nil && i.Link.Href != "" && !i.lastModified().IsZero()),
+			"item %d has no id, nor a link and date to make one from", n)
+		if err := i.validateVia(); err != nil {
+			v.check(false, "item %d: %v", n, err)
+		}
+		v.checkHrefLangs(fmt.Sprintf("item %d", n), append([]*Link{i.Link}, i.Links...)...)
+		v.check(!i.lastModified().IsZero(), "%s", entryUpdatedRequired(n, i))
+	}
+	v.checkImages(f)
+	v.checkLinkPolicy(f)
+	v.checkUTF8(f)
+	return v.err()
+}
+
+// ValidateJSON checks the feed has the title json feed requires and that
+// each of its items has an id, that the hreflang of the links are language
+// tags, that the icons and item images are http or https urls of images and
+// that the author extensions have valid keys and raw values.
+// The version is always written.
+func (f *Feed) ValidateJSON() error {
+	v := &validator{t: FeedTypeJSON}
+	v.check(f.Title != "", "feed has no title")
+	v.checkHrefLangs("feed", f.Links...)
+	v.checkJSONExtensions("feed", f.Author)
+	for n, i := range f.Items {
+		if !i.published() {
+			continue
+		}
+		v.check(i.Id != "", "item %d has no id", n)
+		v.checkJSONExtensions(fmt.Sprintf("item %d", n), i.Author)
+		v.checkHrefLangs(fmt.Sprintf("item %d", n), i.Links...)
+	}
+	v.checkImages(f)
+	v.checkLinkPolicy(f)
+	v.checkUTF8(f)
+	return v.err()
+}
+
+// validate checks the feed with the Validate method of format
+func (f *Feed) validate(format FeedType) error {
+	switch format {
+	case FeedTypeRss:
+		return f.ValidateRSS()
+	case FeedTypeAtom:
+		return f.ValidateAtom()
+	case FeedTypeJSON:
+		return f.ValidateJSON()
+	case FeedTypeAmazonRss:
+		return f.ValidateAmazonRss()
+	}
+	return fmt.Errorf("feeds: unknown feed type %v", format)
+}
+
+// ValidateAll validates each of the feeds for format, and returns the
+// errors of the invalid ones keyed by their names in feeds. It is empty
+// when all of them are valid.
+func ValidateAll(feeds map[string]*Feed, format FeedType) map[string]error {
+	errs := map[string]error{}
+	for name, f := range feeds {
+		if f == nil {
+			errs[name] = errors.New("feeds: feed is nil")
+			continue
+		}
+		if err := f.validate(format); err != nil {
+			errs[name] = err
+		}
+	}
+	return errs
+}
@@ -0,0 +1,166 @@
+package feeds
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	now := testCreated
+	valid := &Feed{
+		Title:       "jmoiron.net blog",
+		Link:        &Link{Href: "http://jmoiron.net/blog"},
+		Description: "discussion about tech, footie, photos",
+		Created:     now,
+		Items: []*Item{
+			{Title: "Limiting Concurrency in Go", Id: "limiting-concurrency-in-go", Updated: now},
+			{Title: "Logic-less Template Redux", Id: "logicless-template-redux", Link: &Link{Href: "http://jmoiron.net/blog/logicless-template-redux/"}, Created: now},
+		},
+	}
+	for name, validate := range map[string]func() error{
+		"rss":        valid.ValidateRSS,
+		"atom":       valid.ValidateAtom,
+		"json":       valid.ValidateJSON,
+		"amazon-rss": valid.ValidateAmazonRss,
+	} {
+		if err := validate(); err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+		}
+	}
+
+	// valid rss without a feed id or dates for atom
+	rssOnly := &Feed{
+		Description: "discussion about tech, footie, photos",
+		Items: []*Item{
+			{Description: "A discussion on controlled parallelism in golang"},
+			{Title: "Logic-less Template Redux", Enclosure: &Enclosure{Url: "http://jmoiron.net/episode.mp3"}},
+		},
+	}
+	for _, test := range []struct {
+		validate func() error
+		want     []string
+	}{
+		{rssOnly.ValidateRSS, []string{
+			"feed has no title",
+			"feed has no link",
+			"item 1 enclosure needs a url, type and length",
+		}},
+		{rssOnly.ValidateAtom, []string{
+			"feed has no id, which is taken from its link",
+			"feed has no title",
+			"feed has no updated date",
+			"item 0 has no title",
+			"item 0 has no id, nor a link and date to make one from",
+			`item 0: "": updated is required`,
+			"item 1 has no id, nor a link and date to make one from",
+			`item 1: "": updated is required`,
+		}},
+		{rssOnly.ValidateAmazonRss, []string{
+			"feed has no title",
+			"feed has no link",
+		}},
+		{rssOnly.ValidateJSON, []string{
+			"feed has no title",
+			"item 0 has no id",
+			"item 1 has no id",
+		}},
+	} {
+		err, ok := test.validate().(*ValidationError)
+		if !ok {
+			t.Errorf("expected a *ValidationError, got %v", test.validate())
+			continue
+		}
+		if !reflect.DeepEqual(err.Violations, test.want) {
+			t.Errorf("%s: got violations %q, want %q", err.Type, err.Violations, test.want)
+		}
+	}
+}
+
+func TestAtomStrictEntryUpdated(t *testing.T) {
+	now := testCreated
+	feed := &Feed{
+		Title:   "jmoiron.net blog",
+		Link:    &Link{Href: "http://jmoiron.net/blog"},
+		Created: now,
+		Items: []*Item{
+			{Title: "dated", Id: "dated", Created: now},
+			{Title: "undated", Id: "my-id"},
+			{Title: "also undated", Link: &Link{Href: "http://jmoiron.net/blog/undated/"}},
+		},
+	}
+	if _, err := feed.ToAtom(); err != nil {
+		t.Errorf("unexpected error encoding Atom: %v", err)
+	}
+
+	feed.StrictEntryUpdated = true
+	_, err := ToXML(&Atom{Feed: feed})
+	verr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("expected a *ValidationError, got %v", err)
+	}
+	want := []string{
+		`item 1: "my-id": updated is required`,
+		`item 2: "http://jmoiron.net/blog/undated/": updated is required`,
+	}
+	if !reflect.DeepEqual(verr.Violations, want) {
+		t.Errorf("got violations %q, want %q", verr.Violations, want)
+	}
+}
+
+func TestValidateAmazonRss(t *testing.T) {
+	feed := &Feed{
+		Title:       "jmoiron.net blog",
+		Link:        &Link{Href: "http://jmoiron.net/blog"},
+		Description: "discussion about tech, footie, photos",
+		Items: []*Item{
+			{Title: "no video", Amazon: &AmazonItem{ContentKind: AmazonVideo, Position: 1}},
+			{Title: "audio", Amazon: &AmazonItem{ContentKind: AmazonVideo, Position: 1}, Enclosure: &Enclosure{Url: "http://jmoiron.net/a.mp3", Type: "audio/mpeg", Length: "1"}},
+			{Title: "video", Amazon: &AmazonItem{ContentKind: AmazonVideo, Position: -1}, Enclosure: &Enclosure{Url: "http://jmoiron.net/a.mp4", Type: "video/mp4", Length: "1"}},
+			{Title: "article", Amazon: &AmazonItem{Position: 2}},
+		},
+	}
+	err, ok := feed.ValidateAmazonRss().(*ValidationError)
+	if !ok {
+		t.Fatalf("expected a *ValidationError, got %v", feed.ValidateAmazonRss())
+	}
+	want := []string{
+		"item 0: amazon video item has no video enclosure",
+		`item 1: amazon video item enclosure type "audio/mpeg" is not video/*`,
+		"item 1: amzn:position 1 is already used by item 0",
+		"item 2: amzn:position -1 is negative",
+	}
+	if !reflect.DeepEqual(err.Violations, want) {
+		t.Errorf("got violations %q, want %q", err.Violations, want)
+	}
+}
+
+func TestValidateAll(t *testing.T) {
+	now := testCreated
+	feeds := map[string]*Feed{
+		"http://jmoiron.net/blog/feed.rss": {
+			Title:       "jmoiron.net blog",
+			Link:        &Link{Href: "http://jmoiron.net/blog"},
+			Description: "discussion about tech, footie, photos",
+			Created:     now,
+		},
+		"http://jmoiron.net/photos/feed.rss": {Title: "jmoiron.net photos", Link: &Link{Href: "http://jmoiron.net/photos"}},
+		"http://jmoiron.net/footie/feed.rss": nil,
+	}
+	errs := ValidateAll(feeds, FeedTypeRss)
+	if len(errs) != 2 {
+		t.Fatalf("expected errors for 2 feeds, got %v", errs)
+	}
+	if err, ok := errs["http://jmoiron.net/photos/feed.rss"].(*ValidationError); !ok || !reflect.DeepEqual(err.Violations, []string{"feed has no description"}) {
+		t.Errorf("got %v for the photos feed", errs["http://jmoiron.net/photos/feed.rss"])
+	}
+	if errs["http://jmoiron.net/footie/feed.rss"] == nil {
+		t.Errorf("expected an error for the nil feed")
+	}
+
+	if errs := ValidateAll(feeds, FeedType(42)); len(errs) != 3 {
+		t.Errorf("expected errors for all feeds for an unknown type, got %v", errs)
+	}
+	if errs := ValidateAll(nil, FeedTypeAtom); len(errs) != 0 {
+		t.Errorf("expected no errors without feeds, got %v", errs)
+	}
+}
@@ -0,0 +1,79 @@
+package feeds
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Version is the version of this package, written in the generator of
+// feeds with StampGenerator so malformed feeds can be traced to a release.
+const Version = "1.2.0"
+
+// the name of this package in stamped generators
+const generatorName = "feeds"
+
+// Generator is the software generating a feed. The rss and json formats
+// write its Value and Version as text, atom writes its URI and Version as
+// attributes of the generator.
+type Generator struct {
+	Value   string // the name of the software
+	URI     string // an absolute iri of the software, only written in atom
+	Version string
+}
+
+// check the uri of the generator is an absolute iri
+func (g *Generator) validate() error {
+	if g == nil || g.URI == "" {
+		return nil
+	}
+	u, err := url.Parse(g.URI)
+	if err != nil {
+		return fmt.Errorf("generator uri is invalid: %v", err)
+	}
+	if !u.IsAbs() || strings.ContainsAny(g.URI, " \t\r\n") {
+		return fmt.Errorf("generator uri %q is not an absolute iri", g.URI)
+	}
+	return nil
+}
+
+// the stamp StampGenerator appends to the feed's Generator
+func versionStamp() string {
+	return generatorName + " v" + Version
+}
+
+// the generator of the rss and json formats: the value and version of the
+// feed's Generator, followed by the version of this package with
+// StampGenerator
+func (f *Feed) generator() string {
+	var s string
+	if g := f.Generator; g != nil {
+		s = strings.TrimSpace(g.Value + " " + g.Version)
+	}
+	switch {
+	case !f.StampGenerator:
+		return s
+	case s == "":
+		return versionStamp()
+	}
+	return s + " (" + versionStamp() + ")"
+}
+
+// the atom generator, which has a version attribute for the stamp
+func (f *Feed) atomGenerator() *AtomGenerator {
+	var generator AtomGenerator
+	if g := f.Generator; g != nil && g.Value != "" {
+		generator = AtomGenerator{Content: g.Value, Uri: g.URI, Version: g.Version}
+	}
+	switch {
+	case f.StampGenerator && generator.Content == "":
+		return &AtomGenerator{Content: generatorName, Version: "v" + Version}
+	case f.StampGenerator && generator.Version == "":
+		generator.Version = versionStamp()
+	case f.StampGenerator:
+		generator.Version += " (" + versionStamp() + ")"
+	case generator.Content == "":
+		return nil
+	}
+	return &generator
+}
@@ -0,0 +1,129 @@
+package feeds
+
+import (
+	"regexp"
+	"runtime/debug"
+	"strings"
+	"testing"
+)
+
+func TestVersion(t *testing.T) {
+	if !regexp.MustCompile(`^\d+\.\d+\.\d+$`).MatchString(Version) {
+		t.Errorf("Version %q should be a semantic version", Version)
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		t.Skip("no module information in this build")
+	}
+	module := info.Main
+	for _, dep := range info.Deps {
+		if dep.Path == "github.com/gorilla/feeds" {
+			module = *dep
+		}
+	}
+	// a module built from its own source tree has no version
+	if module.Path != "github.com/gorilla/feeds" || module.Version == "" || module.Version == "(devel)" {
+		t.Skipf("no version of the module in this build, built as %q %q", module.Path, module.Version)
+	}
+	if v := strings.SplitN(module.Version, "-", 2)[0]; v != "v"+Version {
+		t.Errorf("Version %q should match the module version %q", Version, module.Version)
+	}
+}
+
+func TestStampGenerator(t *testing.T) {
+	now := testCreated
+	feed := &Feed{
+		Title:       "jmoiron.net blog",
+		Link:        &Link{Href: "http://jmoiron.net/blog"},
+		Description: "discussion about tech",
+		Created:     now,
+	}
+	rss, _ := feed.ToRss()
+	atom, _ := feed.ToAtom()
+	json, _ := feed.ToJSON()
+	for name, out := range map[string]string{"Rss": rss, "Atom": atom, "JSON": json} {
+		if strings.Contains(out, "generator") {
+			t.Errorf("%s should have no generator by default.  Got:\n%s\n", name, out)
+		}
+	}
+
+	feed.StampGenerator = true
+	stamp := "feeds v" + Version
+	rss, _ = feed.ToRss()
+	atom, _ = feed.ToAtom()
+	json, _ = feed.ToJSON()
+	for name, test := range map[string]struct{ out, want string }{
+		"Rss":  {rss, "<generator>" + stamp + "</generator>"},
+		"Atom": {atom, `<generator version="v` + Version + `">feeds</generator>`},
+		"JSON": {json, `"_generator": "` + stamp + `"`},
+	} {
+		if !strings.Contains(test.out, test.want) {
+			t.Errorf("%s missing %s.  Got:\n%s\n", name, test.want, test.out)
+		}
+	}
+
+	feed.Generator = &Generator{Value: "jmoiron.net publisher"}
+	rss, _ = feed.ToRss()
+	atom, _ = feed.ToAtom()
+	amazon, _ := feed.ToAmazonRss()
+	json, _ = feed.ToJSON()
+	for name, test := range map[string]struct{ out, want string }{
+		"Rss":       {rss, "<generator>jmoiron.net publisher (" + stamp + ")</generator>"},
+		"AmazonRss": {amazon, "<generator>jmoiron.net publisher (" + stamp + ")</generator>"},
+		"Atom":      {atom, `<generator version="` + stamp + `">jmoiron.net publisher</generator>`},
+		"JSON":      {json, `"_generator": "jmoiron.net publisher (` + stamp + `)"`},
+	} {
+		if !strings.Contains(test.out, test.want) {
+			t.Errorf("%s missing %s.  Got:\n%s\n", name, test.want, test.out)
+		}
+	}
+}
+
+func TestAtomGenerator(t *testing.T) {
+	now := testCreated
+	feed := &Feed{
+		Title:   "jmoiron.net blog",
+		Link:    &Link{Href: "http://jmoiron.net/blog"},
+		Created: now,
+	}
+	for _, test := range []struct {
+		generator *Generator
+		atom, rss string
+	}{
+		{&Generator{Value: "Hugo"}, `<generator>Hugo</generator>`, `<generator>Hugo</generator>`},
+		{&Generator{Value: "Hugo", URI: "https://gohugo.io/"}, `<generator uri="https://gohugo.io/">Hugo</generator>`, `<generator>Hugo</generator>`},
+		{&Generator{Value: "Hugo", URI: "https://gohugo.io/", Version: "0.120.4"},
+			`<generator uri="https://gohugo.io/" version="0.120.4">Hugo</generator>`, `<generator>Hugo 0.120.4</generator>`},
+	} {
+		feed.Generator = test.generator
+		atom, err := feed.ToAtom()
+		if err != nil {
+			t.Errorf("unexpected error encoding Atom: %v", err)
+		}
+		if !strings.Contains(atom, test.atom) {
+			t.Errorf("Atom missing %s.  Got:\n%s\n", test.atom, atom)
+		}
+		if rss, _ := feed.ToRss(); !strings.Contains(rss, test.rss) {
+			t.Errorf("Rss missing %s.  Got:\n%s\n", test.rss, rss)
+		}
+		if err := feed.ValidateAtom(); err != nil {
+			t.Errorf("unexpected error validating Atom: %v", err)
+		}
+	}
+
+	feed.StampGenerator = true
+	want := `<generator uri="https://gohugo.io/" version="0.120.4 (feeds v` + Version + `)">Hugo</generator>`
+	if atom, _ := feed.ToAtom(); !strings.Contains(atom, want) {
+		t.Errorf("Atom missing %s.  Got:\n%s\n", want, atom)
+	}
+
+	for _, uri := range []string{"gohugo.io", "https://gohugo.io/a b", "%zz"} {
+		feed.Generator = &Generator{Value: "Hugo", URI: uri}
+		if _, err := feed.ToAtom(); err == nil || !strings.Contains(err.Error(), "generator uri") {
+			t.Errorf("ToAtom should fail for the generator uri %q, got %v", uri, err)
+		}
+		if err := feed.ValidateAtom(); err == nil || !strings.Contains(err.Error(), "generator uri") {
+			t.Errorf("ValidateAtom should fail for the generator uri %q, got %v", uri, err)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package feeds
+
+// webfeeds support, the channel elements feedly renders a feed with
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+const webfeedsNamespace = "http://webfeeds.org/rss/1.0"
+
+// Webfeeds holds the images, color and analytics Feedly shows a feed with,
+// written in the webfeeds namespace of rss and amazon rss feeds.
+type Webfeeds struct {
+	CoverImage  string // url of a large image shown as the feed's cover
+	Icon        string // url of a square icon
+	Logo        string // url of a logo shown in the feed's header
+	AccentColor string // six hex digits, like 00FF00, without the #
+	Analytics   string // google analytics id, like UA-12345-1
+}
+
+// RssWebfeeds are the webfeeds elements of an rss channel.
+type RssWebfeeds struct {
+	Cover       *RssWebfeedsCover
+	Icon        string `xml:"webfeeds:icon,omitempty"`
+	Logo        string `xml:"webfeeds:logo,omitempty"`
+	AccentColor string `xml:"webfeeds:accentColor,omitempty"`
+	Analytics   *RssWebfeedsAnalytics
+}
+
+type RssWebfeedsCover struct {
+	XMLName xml.Name `xml:"webfeeds:cover"`
+	Image   string   `xml:"image,attr"`
+}
+
+type RssWebfeedsAnalytics struct {
+	XMLName xml.Name `xml:"webfeeds:analytics"`
+	Id      string   `xml:"id,attr"`
+	Engine  string   `xml:"engine,attr"`
+}
+
+func (w *Webfeeds) validate() error {
+	if c := w.AccentColor; c != "" {
+		valid := len(c) == 6
+		for _, r := range c {
+			valid = valid && (r >= '0' && r <= '9' || r >= 'a' && r <= 'f' || r >= 'A' && r <= 'F')
+		}
+		if !valid {
+			return fmt.Errorf("webfeeds:accentColor %q is not six hex digits without a #", c)
+		}
+	}
+	return nil
+}
+
+// the webfeeds elements of a channel for a generic Webfeeds, or nil
+func newRssWebfeeds(w *Webfeeds) *RssWebfeeds {
+	if w == nil {
+		return nil
+	}
+	x := &RssWebfeeds{Icon: w.Icon, Logo: w.Logo, AccentColor: w.AccentColor}
+	if w.CoverImage != "" {
+		x.Cover = &RssWebfeedsCover{Image: w.CoverImage}
+	}
+	if w.Analytics != "" {
+		x.Analytics = &RssWebfeedsAnalytics{Id: w.Analytics, Engine: "GoogleAnalytics"}
+	}
+	return x
+}
+
+// check the webfeeds fields of a Feed
+func validateWebfeeds(f *Feed) error {
+	if f.Webfeeds != nil {
+		if err := f.Webfeeds.validate(); err != nil {
+			return fmt.Errorf("feeds: %v", err)
+		}
+	}
+	return nil
+}
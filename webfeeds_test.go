@@ -0,0 +1,66 @@
+package feeds
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWebfeeds(t *testing.T) {
+	feed := &Feed{
+		Title: "jmoiron.net blog",
+		Link:  &Link{Href: "http://jmoiron.net/blog"},
+	}
+	rss, err := feed.ToRss()
+	if err != nil {
+		t.Errorf("unexpected error encoding RSS: %v", err)
+	}
+	if strings.Contains(rss, "webfeeds") {
+		t.Errorf("Rss should not use webfeeds.  Got:\n%s\n", rss)
+	}
+
+	feed.Webfeeds = &Webfeeds{
+		CoverImage:  "http://jmoiron.net/cover.jpg",
+		Icon:        "http://jmoiron.net/icon.svg",
+		Logo:        "http://jmoiron.net/logo.svg",
+		AccentColor: "00FF00",
+		Analytics:   "UA-12345-1",
+	}
+	want := []string{
+		`xmlns:webfeeds="http://webfeeds.org/rss/1.0"`,
+		`<webfeeds:cover image="http://jmoiron.net/cover.jpg"></webfeeds:cover>`,
+		"<webfeeds:icon>http://jmoiron.net/icon.svg</webfeeds:icon>",
+		"<webfeeds:logo>http://jmoiron.net/logo.svg</webfeeds:logo>",
+		"<webfeeds:accentColor>00FF00</webfeeds:accentColor>",
+		`<webfeeds:analytics id="UA-12345-1" engine="GoogleAnalytics"></webfeeds:analytics>`,
+	}
+	for name, encode := range map[string]func() (string, error){"Rss": feed.ToRss, "AmazonRss": feed.ToAmazonRss} {
+		out, err := encode()
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", name, err)
+		}
+		for _, w := range want {
+			if !strings.Contains(out, w) {
+				t.Errorf("%s missing %s.  Got:\n%s\n", name, w, out)
+			}
+		}
+	}
+}
+
+func TestWebfeedsInvalid(t *testing.T) {
+	for _, color := range []string{"#00FF00", "00FF0", "00FF0G", "00ff00aa"} {
+		feed := &Feed{
+			Title:    "jmoiron.net blog",
+			Link:     &Link{Href: "http://jmoiron.net/blog"},
+			Webfeeds: &Webfeeds{AccentColor: color},
+		}
+		if _, err := feed.ToRss(); err == nil {
+			t.Errorf("Rss should fail with accent color %q", color)
+		}
+		if _, err := feed.ToAmazonRss(); err == nil {
+			t.Errorf("AmazonRss should fail with accent color %q", color)
+		}
+		if err := feed.ValidateRSS(); err == nil {
+			t.Errorf("ValidateRSS should fail with accent color %q", color)
+		}
+	}
+}